@@ -1,47 +1,329 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"go-sso/internal/config"
+	"go-sso/internal/migrator"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 )
 
+const usage = `Usage: migrator [-config=PATH] [-dry-run] [-lock-timeout=DURATION] COMMAND [ARG]
+
+Commands:
+  up [N]        применить N следующих миграций (по умолчанию все)
+  down [N]      откатить N последних миграций (по умолчанию 1)
+  goto V        перейти к версии V, применяя или откатывая миграции (0 — откатить все)
+  force V       установить версию V без выполнения миграций (снимает dirty)
+  version       вывести текущую версию схемы
+  status        вывести список миграций и их состояние
+  create NAME   создать пустые файлы up/down для новой миграции
+
+Если psql.migrator.path не задан, используются миграции, встроенные в бинарник.
+
+Flags:
+`
+
+const (
+	exitErr   = 1
+	exitUsage = 2
+)
+
+// defaultMigrationsDir каталог, в котором create создает миграции, если psql.migrator.path не задан.
+// Файлы из него встраиваются в бинарники при сборке.
+const defaultMigrationsDir = "migrations"
+
+// errUsage ошибка в аргументах командной строки.
+var errUsage = errors.New("invalid usage")
+
 func main() {
-	cfg := config.MustLoad()
+	dryRun := flag.Bool("dry-run", false, "print what would be done without changing the database")
+	lockTimeout := flag.Duration("lock-timeout", time.Minute, "how long to wait for another migration to finish")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	// Флаги разбираются при загрузке конфигурации.
+	cfg, err := config.LoadFromFlagOrEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(exitErr)
+	}
 
-	if cfg.PSQL.Migrator == nil {
-		panic("migrator config is not set")
+	if err := run(cfg, flag.Args(), *dryRun, *lockTimeout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(exitUsage)
+		}
+
+		os.Exit(exitErr)
+	}
+}
+
+func run(cfg *config.Config, args []string, dryRun bool, lockTimeout time.Duration) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: command is required", errUsage)
+	}
+
+	var path string
+	if cfg.PSQL.Migrator != nil {
+		path = cfg.PSQL.Migrator.Path
+	}
+
+	cmd, args := args[0], args[1:]
+
+	if cmd == "create" {
+		if path == "" {
+			path = defaultMigrationsDir
+		}
+
+		return create(path, args, dryRun)
 	}
 
-	fmt.Println(cfg.PSQL.DSN())
+	fmt.Println("database:", cfg.PSQL.RedactedDSN())
 
 	db, err := sql.Open("postgres", cfg.PSQL.DSN())
 	if err != nil {
-		panic(err)
+		return err
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db, path, cfg.PSQL.MigrationsTable())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	m.SetLogger(stdoutLogger{})
+
+	ctx := context.Background()
+
+	// Чтение состояния не требует блокировки.
+	switch cmd {
+	case "version":
+		return printVersion(m)
+	case "status":
+		return printStatus(m)
 	}
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{
-		MigrationsTable: cfg.PSQL.Migrator.Table,
-	})
+	if !dryRun {
+		if err := m.Lock(ctx, lockTimeout); err != nil {
+			return err
+		}
+	}
+
+	err = apply(m, cmd, args, dryRun)
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("no change")
+		return nil
+	}
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+cfg.PSQL.Migrator.Path,
-		"postgres", driver)
+	if dryRun {
+		return nil
+	}
+
+	return printVersion(m)
+}
+
+// apply выполняет (или, в режиме dry-run, описывает) одну изменяющую схему команду.
+func apply(m *migrator.Migrator, cmd string, args []string, dryRun bool) error {
+	status, err := m.Status()
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	switch cmd {
+	case "up":
+		n, err := optionalCount(args, 0)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return printPlan(status.PlanUp(n))
+		}
+		return m.Up(n)
+
+	case "down":
+		n, err := optionalCount(args, 1)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return printPlan(status.PlanDown(n))
+		}
+		return m.Down(n)
+
+	case "goto":
+		v, err := requiredVersion(args)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return printPlan(status.PlanGoto(uint(v)))
+		}
+		return m.Goto(uint(v))
+
+	case "force":
+		v, err := requiredVersion(args)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			fmt.Printf("would force version %d (current %d, dirty %t)\n", v, status.Version, status.Dirty)
+			return nil
+		}
+		return m.Force(v)
+
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
 	}
+}
+
+// newMigrator создает Migrator с миграциями из каталога path,
+// а если он не задан — со встроенными в бинарник.
+func newMigrator(db *sql.DB, path string, table string) (*migrator.Migrator, error) {
+	if path == "" {
+		fmt.Println("migrations: embedded")
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		panic(err)
+		return migrator.NewEmbedded(db, table)
 	}
 
-	fmt.Println("Migrations applied successfully.")
+	fmt.Println("migrations:", path)
+
+	src, err := source.Open("file://" + path)
+	if err != nil {
+		return nil, fmt.Errorf("open migrations %s: %w", path, err)
+	}
+
+	return migrator.New(db, "file", src, table)
+}
+
+func create(dir string, args []string, dryRun bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: create requires NAME", errUsage)
+	}
+
+	if dryRun {
+		up, down, err := migrator.CreatePaths(dir, args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("would create", up)
+		fmt.Println("would create", down)
+
+		return nil
+	}
+
+	up, down, err := migrator.Create(dir, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("created", up)
+	fmt.Println("created", down)
+
+	return nil
+}
+
+func printVersion(m *migrator.Migrator) error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("version: %d (dirty: %t)\n", version, dirty)
+
+	return nil
+}
+
+func printStatus(m *migrator.Migrator) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("version: %d (dirty: %t), pending: %d\n", status.Version, status.Dirty, len(status.Pending()))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE")
+	for _, mg := range status.Migrations {
+		state := "pending"
+		if mg.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", mg.Version, mg.Identifier, state)
+	}
+
+	return w.Flush()
+}
+
+func printPlan(p migrator.Plan) error {
+	if len(p.Migrations) == 0 {
+		fmt.Println("dry run: nothing to do")
+		return nil
+	}
+
+	fmt.Printf("dry run: would migrate %s:\n", p.Direction)
+	for _, mg := range p.Migrations {
+		fmt.Printf("  %d %s\n", mg.Version, mg.Identifier)
+	}
+
+	return nil
+}
+
+func optionalCount(args []string, def int) (int, error) {
+	switch len(args) {
+	case 0:
+		return def, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%w: N must be a positive integer, got %q", errUsage, args[0])
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("%w: too many arguments", errUsage)
+	}
+}
+
+func requiredVersion(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%w: version is required", errUsage)
+	}
+
+	v, err := strconv.Atoi(args[0])
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%w: version must be a non-negative integer, got %q", errUsage, args[0])
+	}
+
+	return v, nil
+}
+
+// stdoutLogger выводит сообщения golang-migrate о применяемых миграциях.
+type stdoutLogger struct{}
+
+func (stdoutLogger) Printf(format string, v ...interface{}) {
+	fmt.Printf(format, v...)
+}
+
+func (stdoutLogger) Verbose() bool {
+	return false
 }
@@ -7,14 +7,17 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+
+	"go.uber.org/zap"
 )
 
 func main() {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	cfg := config.MustLoad()
 
-	log := config.SetupLogger(cfg.Env)
+	log, logLevel := config.SetupLogger(cfg.Env, cfg.LogLevel)
 
 	log.Info("starting SSO application...")
 	log.Debugw("with config", "config", cfg)
@@ -22,16 +25,63 @@ func main() {
 	application := app.New(ctx, log, cfg)
 
 	go application.GRPCSrv.MustRun()
+	go application.HTTPSrv.MustRun()
+	go application.Health.Run(ctx)
+	go application.Purger.Run(ctx)
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 
 	// Graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 
-	sign := <-stop
+	var sign os.Signal
+	for sign == nil {
+		select {
+		case <-reload:
+			cfg = reloadConfig(log, logLevel, application, cfg)
+		case sign = <-stop:
+		}
+	}
 
 	log.Infow("received signal", "signal", sign)
 
-	application.GRPCSrv.Stop()
+	if err := application.Stop(); err != nil {
+		log.Errorw("SSO application stopped with errors", "error", err)
+		os.Exit(1)
+	}
 
 	log.Infow("stopped SSO application")
 }
+
+// reloadConfig перечитывает конфигурацию и применяет настройки, которые можно
+// менять без перезапуска. При ошибке продолжает работу со старой конфигурацией.
+func reloadConfig(
+	log *zap.SugaredLogger,
+	logLevel zap.AtomicLevel,
+	application *app.App,
+	cur *config.Config,
+) *config.Config {
+	log.Infow("reloading config")
+
+	next, err := cur.Reload()
+	if err != nil {
+		log.Errorw("failed to reload config, keeping current", "error", err)
+		return cur
+	}
+
+	if changed := cur.NonReloadableChanges(next); len(changed) > 0 {
+		log.Warnw("config changes require restart and were not applied", "fields", changed)
+	}
+
+	// Validate уже проверил уровень.
+	lvl, _ := config.ParseLevel(next.Env, next.LogLevel)
+	logLevel.SetLevel(lvl)
+
+	application.Reload(next)
+
+	log.Infow("config reloaded", "log_level", lvl.String(), "token_ttl", next.TokenTTL)
+
+	return cur.WithReloadable(next)
+}
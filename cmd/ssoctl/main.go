@@ -0,0 +1,1028 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go-sso/internal/app/bootstrap"
+	"go-sso/internal/config"
+	"go-sso/internal/domain/models"
+	usershttp "go-sso/internal/http/users"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/admin"
+	"go-sso/internal/services/clients"
+	"go-sso/internal/services/consent"
+	"go-sso/internal/services/organization"
+	"go-sso/internal/services/session"
+	"go-sso/internal/storage/postgres"
+	"os"
+	"os/signal"
+	"os/user"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const usage = `Usage: ssoctl [-config=PATH] [-output=table|json] [-tenant=ID] [-v] COMMAND SUBCOMMAND [ARGS]
+
+Commands:
+  user create EMAIL [-password=P]          создать пользователя
+  user list [-limit=N] [-offset=N]         вывести пользователей
+  user disable EMAIL                       заблокировать вход пользователя и отозвать его сеансы
+  user enable EMAIL                        разблокировать вход пользователя
+  user reset-password EMAIL [-password=P]  сменить пароль пользователя
+  user get EMAIL|UUID                      вывести пользователя и его профиль
+  user update-profile EMAIL [-display-name=N] [-locale=L] [-timezone=TZ]
+                      [-avatar-url=URL] [-metadata=JSON]
+                                           изменить профиль (пустое значение очищает поле)
+  user delete EMAIL                        удалить пользователя (данные стираются после users.retention)
+  user export EMAIL                        выгрузить все данные пользователя в JSON
+  user purge [-older-than=DUR]             стереть пользователей, удаленных раньше DUR назад
+                                           (по умолчанию users.retention)
+  user normalize-emails [-dry-run]         привести сохраненные email к нормализованному виду
+                                           (выполнить перед миграцией 7)
+  user collisions                          вывести пользователей, чьи email совпадают без учета
+                                           регистра (их нужно объединить перед миграцией 7)
+
+  app create NAME                          зарегистрировать приложение и создать ключ подписи
+  app list                                 вывести приложения
+  app rotate-key NAME                      заменить ключ подписи приложения
+  app delete NAME                          удалить приложение, его роли и ключ подписи
+
+  client create APP NAME [-scopes=S1,S2]   зарегистрировать сервис-клиента приложения; секрет
+                                           выводится один раз
+  client list APP                          вывести клиентов приложения
+  client rotate-secret ID [-grace=DUR]     выдать клиенту новый секрет; прежний действует еще DUR
+                                           (по умолчанию clients.secret_grace)
+  client delete ID                         удалить клиента
+
+  role assign EMAIL APP ROLE               назначить роль пользователю в приложении
+  role revoke EMAIL APP ROLE               отозвать роль
+  role list EMAIL                          вывести роли пользователя
+
+  session list EMAIL                       вывести активные сеансы пользователя
+  session revoke EMAIL ID                  отозвать сеанс
+  session revoke-all EMAIL                 отозвать все сеансы пользователя
+
+  consent list EMAIL                       вывести согласия пользователя на области приложений
+  consent revoke EMAIL APP                 отозвать согласие и сеансы пользователя в приложении
+
+  identity list EMAIL                      вывести внешние учетные записи пользователя
+  identity unlink EMAIL PROVIDER           отвязать учетную запись провайдера
+
+  tenant create ID [-name=N]               создать арендатора
+  tenant list                              вывести арендаторов
+
+  org create APP NAME -owner=EMAIL         создать организацию в приложении
+  org list APP                             вывести организации приложения
+  org members ID                           вывести участников организации
+  org invite ID EMAIL [-role=R]            отправить приглашение в организацию (роль owner, admin
+                                           или member, по умолчанию member)
+  org remove-member ID EMAIL               удалить участника из организации
+
+  audit export [-event=E] [-outcome=O] [-actor=A] [-subject=S] [-app=APP]
+               [-since=T] [-until=T] [-limit=N]
+                                           выгрузить журнал аудита (от новых к старым, T в RFC 3339)
+
+Если -password не задан, генерируется случайный пароль и выводится один раз.
+Изменяющие команды записываются в журнал аудита от имени ssoctl:<пользователь ОС>.
+Команды выполняются в арендаторе -tenant (по умолчанию default).
+
+Flags:
+`
+
+const (
+	exitErr   = 1
+	exitUsage = 2
+)
+
+// generatedPasswordLen длина случайного пароля в байтах до кодирования.
+const generatedPasswordLen = 18
+
+// auditPageSize размер страницы при выгрузке журнала аудита.
+const auditPageSize = 1000
+
+// errUsage ошибка в аргументах командной строки.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	output := flag.String("output", "table", "output format: table or json")
+	verbose := flag.Bool("v", false, "log at the level from the config instead of errors only")
+	tenantID := flag.String("tenant", tenant.Default, "tenant to run the command in")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	// Флаги разбираются при загрузке конфигурации.
+	cfg, err := config.LoadFromFlagOrEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(exitErr)
+	}
+
+	if err := run(cfg, flag.Args(), *output, *tenantID, *verbose); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(exitUsage)
+		}
+
+		os.Exit(exitErr)
+	}
+}
+
+func run(cfg *config.Config, args []string, output, tenantID string, verbose bool) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%w: command and subcommand are required", errUsage)
+	}
+	if !tenant.Valid(tenantID) {
+		return fmt.Errorf("%w: invalid tenant %q", errUsage, tenantID)
+	}
+
+	p, err := newPrinter(os.Stdout, output)
+	if err != nil {
+		return err
+	}
+
+	level := "error"
+	if verbose {
+		level = cfg.LogLevel
+	}
+	log, _ := config.SetupLogger(cfg.Env, level)
+	defer log.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	storage, err := bootstrap.Storage(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	vaultClient := bootstrap.Vault(ctx, log, cfg)
+	defer vaultClient.Close()
+
+	ctx = audit.WithActor(ctx, "ssoctl:"+osUser())
+	ctx = audit.WithClient(ctx, audit.Client{UserAgent: "ssoctl"})
+	ctx = tenant.With(ctx, tenantID)
+
+	auditor := audit.New(log, audit.SinkFunc(storage.SaveAuditEvent))
+
+	svc := admin.New(log, storage, storage, storage, vaultClient, storage, storage, auditor, cfg.Users.EmailNormalizer())
+	sessions := session.New(log, storage, vaultClient, auditor, cfg.Issuer())
+	clientsSvc := clients.New(log, storage, vaultClient, auditor, clients.Config{
+		Issuer:   cfg.Issuer(),
+		TokenTTL: cfg.Clients.TokenTTL,
+	})
+
+	scopes := make(map[string]map[string]string, len(cfg.Apps))
+	for name, app := range cfg.Apps {
+		scopes[name] = app.Scopes
+	}
+	consents := consent.New(log, storage, auditor, scopes)
+
+	invitationURLs := make(map[string]string)
+	for name, app := range cfg.Apps {
+		if app.InvitationURL != "" {
+			invitationURLs[name] = app.InvitationURL
+		}
+	}
+	// Регистрация пользователей через ssoctl не нужна: приглашения принимаются по HTTP.
+	orgs := organization.New(log, storage, storage, nil, bootstrap.Mailer(log, cfg), auditor, cfg.Users.EmailNormalizer(),
+		organization.Config{InvitationTTL: cfg.Organizations.InvitationTTL, InvitationURLs: invitationURLs})
+
+	cmd, sub, args := args[0], args[1], args[2:]
+
+	switch cmd {
+	case "user":
+		return runUser(ctx, svc, cfg.Users.Retention, p, sub, args)
+	case "app":
+		return runApp(ctx, svc, p, sub, args)
+	case "client":
+		return runClient(ctx, clientsSvc, cfg.Clients.SecretGrace, p, sub, args)
+	case "role":
+		return runRole(ctx, svc, p, sub, args)
+	case "session":
+		return runSession(ctx, sessions, storage, p, sub, args)
+	case "consent":
+		return runConsent(ctx, consents, storage, p, sub, args)
+	case "identity":
+		return runIdentity(ctx, svc, p, sub, args)
+	case "tenant":
+		return runTenant(ctx, svc, p, sub, args)
+	case "org":
+		return runOrg(ctx, orgs, storage, p, sub, args)
+	case "audit":
+		return runAudit(ctx, storage, p, sub, args)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+}
+
+func runUser(
+	ctx context.Context,
+	svc *admin.Admin,
+	retention time.Duration,
+	p *printer,
+	sub string,
+	args []string,
+) error {
+	fs := newFlagSet("user " + sub)
+
+	switch sub {
+	case "create":
+		password := fs.String("password", "", "password; generated if empty")
+		email, err := parseArgs(fs, args, "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		pass, generated, err := passwordOrGenerate(*password)
+		if err != nil {
+			return err
+		}
+
+		uuid, err := svc.CreateUser(ctx, email[0], pass)
+		if err != nil {
+			return err
+		}
+
+		return p.userCreated(uuid, email[0], pass, generated)
+
+	case "list":
+		limit := fs.Int("limit", 100, "max number of users")
+		offset := fs.Int("offset", 0, "number of users to skip")
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+		if *limit <= 0 || *offset < 0 {
+			return fmt.Errorf("%w: -limit must be positive and -offset non-negative", errUsage)
+		}
+
+		users, err := svc.Users(ctx, *limit, *offset)
+		if err != nil {
+			return err
+		}
+
+		return p.users(users)
+
+	case "disable", "enable":
+		email, err := parseArgs(fs, args, "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		status := models.UserStatusActive
+		if sub == "disable" {
+			status = models.UserStatusDisabled
+		}
+
+		n, err := svc.SetUserStatus(ctx, email[0], status)
+		if err != nil {
+			return err
+		}
+
+		if status == models.UserStatusDisabled {
+			return p.done(fmt.Sprintf("user %s is %s, %d session(s) revoked", email[0], status, n))
+		}
+
+		return p.done(fmt.Sprintf("user %s is %s", email[0], status))
+
+	case "reset-password":
+		password := fs.String("password", "", "new password; generated if empty")
+		email, err := parseArgs(fs, args, "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		pass, generated, err := passwordOrGenerate(*password)
+		if err != nil {
+			return err
+		}
+
+		if err := svc.ResetPassword(ctx, email[0], pass); err != nil {
+			return err
+		}
+
+		return p.passwordReset(email[0], pass, generated)
+
+	case "get":
+		ref, err := parseArgs(fs, args, "EMAIL|UUID")
+		if err != nil {
+			return err
+		}
+
+		var u models.User
+		if strings.Contains(ref[0], "@") {
+			u, err = svc.UserByEmail(ctx, ref[0])
+		} else {
+			u, err = svc.User(ctx, ref[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		return p.user(u)
+
+	case "update-profile":
+		var (
+			upd      models.ProfileUpdate
+			metadata string
+		)
+		fs.Func("display-name", "display name", func(v string) error { upd.DisplayName = &v; return nil })
+		fs.Func("locale", "BCP 47 language tag, e.g. ru-RU", func(v string) error { upd.Locale = &v; return nil })
+		fs.Func("timezone", "IANA time zone, e.g. Europe/Moscow", func(v string) error { upd.Timezone = &v; return nil })
+		fs.Func("avatar-url", "absolute http(s) URL", func(v string) error { upd.AvatarURL = &v; return nil })
+		fs.StringVar(&metadata, "metadata", "", "JSON object replacing the metadata")
+		email, err := parseArgs(fs, args, "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		if metadata != "" {
+			if err := json.Unmarshal([]byte(metadata), &upd.Metadata); err != nil || upd.Metadata == nil {
+				return fmt.Errorf("%w: -metadata must be a JSON object", errUsage)
+			}
+		}
+
+		u, err := svc.UserByEmail(ctx, email[0])
+		if err != nil {
+			return err
+		}
+
+		u, err = svc.UpdateProfile(ctx, u.UUID, upd)
+		if err != nil {
+			return err
+		}
+
+		return p.user(u)
+
+	case "normalize-emails":
+		dryRun := fs.Bool("dry-run", false, "only print emails that would change")
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+
+		changes, err := svc.NormalizeEmails(ctx, *dryRun)
+		if err != nil {
+			return err
+		}
+
+		return p.emailChanges(changes, *dryRun)
+
+	case "collisions":
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+
+		groups, err := svc.EmailCollisions(ctx)
+		if err != nil {
+			return err
+		}
+
+		return p.collisions(groups)
+
+	case "delete":
+		email, err := parseArgs(fs, args, "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		if err := svc.DeleteUser(ctx, email[0]); err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("user %s is deleted, data will be purged in %s", email[0], retention))
+
+	case "export":
+		email, err := parseArgs(fs, args, "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		data, err := svc.ExportUser(ctx, email[0])
+		if err != nil {
+			return err
+		}
+
+		// Выгрузка вложенная, поэтому всегда выводится в JSON.
+		return p.encode(usershttp.NewExport(data))
+
+	case "purge":
+		olderThan := fs.Duration("older-than", retention, "purge users deleted more than this long ago")
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+		if *olderThan < 0 {
+			return fmt.Errorf("%w: -older-than must not be negative", errUsage)
+		}
+
+		n, err := svc.PurgeDeletedUsers(ctx, time.Now().Add(-*olderThan))
+		if err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("%d user(s) purged", n))
+
+	default:
+		return fmt.Errorf("%w: unknown subcommand user %q", errUsage, sub)
+	}
+}
+
+func runApp(ctx context.Context, svc *admin.Admin, p *printer, sub string, args []string) error {
+	fs := newFlagSet("app " + sub)
+
+	switch sub {
+	case "create":
+		name, err := parseArgs(fs, args, "NAME")
+		if err != nil {
+			return err
+		}
+
+		app, err := svc.CreateApp(ctx, name[0])
+		if err != nil {
+			return err
+		}
+
+		return p.apps([]models.App{app})
+
+	case "list":
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+
+		apps, err := svc.Apps(ctx)
+		if err != nil {
+			return err
+		}
+
+		return p.apps(apps)
+
+	case "rotate-key":
+		name, err := parseArgs(fs, args, "NAME")
+		if err != nil {
+			return err
+		}
+
+		if err := svc.RotateKey(ctx, name[0]); err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("signing key of %s rotated", name[0]))
+
+	case "delete":
+		name, err := parseArgs(fs, args, "NAME")
+		if err != nil {
+			return err
+		}
+
+		if err := svc.DeleteApp(ctx, name[0]); err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("app %s deleted", name[0]))
+
+	default:
+		return fmt.Errorf("%w: unknown subcommand app %q", errUsage, sub)
+	}
+}
+
+func runClient(
+	ctx context.Context,
+	svc *clients.Clients,
+	defaultGrace time.Duration,
+	p *printer,
+	sub string,
+	args []string,
+) error {
+	fs := newFlagSet("client " + sub)
+
+	switch sub {
+	case "create":
+		scopes := fs.String("scopes", "", "comma-separated scopes the client may request")
+		a, err := parseArgs(fs, args, "APP", "NAME")
+		if err != nil {
+			return err
+		}
+
+		var list []string
+		for _, s := range strings.Split(*scopes, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+
+		client, secret, err := svc.CreateClient(ctx, a[0], a[1], list)
+		if err != nil {
+			return err
+		}
+
+		return p.clientSecret(client.ID, client.App, secret, "created")
+
+	case "list":
+		app, err := parseArgs(fs, args, "APP")
+		if err != nil {
+			return err
+		}
+
+		list, err := svc.Clients(ctx, app[0])
+		if err != nil {
+			return err
+		}
+
+		return p.clients(list)
+
+	case "rotate-secret":
+		grace := fs.Duration("grace", defaultGrace, "how long the previous secret stays valid")
+		id, err := parseArgs(fs, args, "ID")
+		if err != nil {
+			return err
+		}
+		if *grace < 0 {
+			return fmt.Errorf("%w: -grace must not be negative", errUsage)
+		}
+
+		secret, err := svc.RotateSecret(ctx, id[0], *grace)
+		if err != nil {
+			return err
+		}
+
+		return p.clientSecret(id[0], "", secret, fmt.Sprintf("secret rotated, previous secret is valid for %s", *grace))
+
+	case "delete":
+		id, err := parseArgs(fs, args, "ID")
+		if err != nil {
+			return err
+		}
+
+		if err := svc.DeleteClient(ctx, id[0]); err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("client %s deleted", id[0]))
+
+	default:
+		return fmt.Errorf("%w: unknown subcommand client %q", errUsage, sub)
+	}
+}
+
+func runRole(ctx context.Context, svc *admin.Admin, p *printer, sub string, args []string) error {
+	fs := newFlagSet("role " + sub)
+
+	switch sub {
+	case "assign", "revoke":
+		a, err := parseArgs(fs, args, "EMAIL", "APP", "ROLE")
+		if err != nil {
+			return err
+		}
+
+		verb := "assigned to"
+		if sub == "assign" {
+			err = svc.AssignRole(ctx, a[0], a[1], a[2])
+		} else {
+			verb = "revoked from"
+			err = svc.RevokeRole(ctx, a[0], a[1], a[2])
+		}
+		if err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("role %s in %s %s %s", a[2], a[1], verb, a[0]))
+
+	case "list":
+		email, err := parseArgs(fs, args, "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		roles, err := svc.UserRoles(ctx, email[0])
+		if err != nil {
+			return err
+		}
+
+		return p.roles(roles)
+
+	default:
+		return fmt.Errorf("%w: unknown subcommand role %q", errUsage, sub)
+	}
+}
+
+func runIdentity(ctx context.Context, svc *admin.Admin, p *printer, sub string, args []string) error {
+	fs := newFlagSet("identity " + sub)
+
+	switch sub {
+	case "list":
+		email, err := parseArgs(fs, args, "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		identities, err := svc.Identities(ctx, email[0])
+		if err != nil {
+			return err
+		}
+
+		return p.identities(identities)
+
+	case "unlink":
+		a, err := parseArgs(fs, args, "EMAIL", "PROVIDER")
+		if err != nil {
+			return err
+		}
+
+		if err := svc.UnlinkIdentity(ctx, a[0], a[1]); err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("%s identity unlinked from %s", a[1], a[0]))
+
+	default:
+		return fmt.Errorf("%w: unknown subcommand identity %q", errUsage, sub)
+	}
+}
+
+func runSession(
+	ctx context.Context,
+	sessions *session.Sessions,
+	storage *postgres.Storage,
+	p *printer,
+	sub string,
+	args []string,
+) error {
+	fs := newFlagSet("session " + sub)
+
+	var names []string
+	switch sub {
+	case "list", "revoke-all":
+		names = []string{"EMAIL"}
+	case "revoke":
+		names = []string{"EMAIL", "ID"}
+	default:
+		return fmt.Errorf("%w: unknown subcommand session %q", errUsage, sub)
+	}
+
+	a, err := parseArgs(fs, args, names...)
+	if err != nil {
+		return err
+	}
+
+	user, err := storage.User(ctx, a[0])
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "list":
+		list, err := sessions.List(ctx, user.UUID)
+		if err != nil {
+			return err
+		}
+
+		return p.sessions(list)
+
+	case "revoke":
+		if err := sessions.Revoke(ctx, user.UUID, a[1]); err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("session %s of %s revoked", a[1], a[0]))
+
+	default:
+		n, err := sessions.RevokeAll(ctx, user.UUID)
+		if err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("%d session(s) of %s revoked", n, a[0]))
+	}
+}
+
+func runConsent(
+	ctx context.Context,
+	consents *consent.Consents,
+	storage *postgres.Storage,
+	p *printer,
+	sub string,
+	args []string,
+) error {
+	fs := newFlagSet("consent " + sub)
+
+	var names []string
+	switch sub {
+	case "list":
+		names = []string{"EMAIL"}
+	case "revoke":
+		names = []string{"EMAIL", "APP"}
+	default:
+		return fmt.Errorf("%w: unknown subcommand consent %q", errUsage, sub)
+	}
+
+	a, err := parseArgs(fs, args, names...)
+	if err != nil {
+		return err
+	}
+
+	user, err := storage.User(ctx, a[0])
+	if err != nil {
+		return err
+	}
+
+	if sub == "list" {
+		grants, err := consents.List(ctx, user.UUID)
+		if err != nil {
+			return err
+		}
+
+		return p.consents(grants)
+	}
+
+	n, err := consents.Revoke(ctx, user.UUID, a[1])
+	if err != nil {
+		return err
+	}
+
+	return p.done(fmt.Sprintf("consent of %s for %s revoked, %d session(s) revoked", a[0], a[1], n))
+}
+
+func runTenant(ctx context.Context, svc *admin.Admin, p *printer, sub string, args []string) error {
+	fs := newFlagSet("tenant " + sub)
+
+	switch sub {
+	case "create":
+		name := fs.String("name", "", "display name")
+
+		id, err := parseArgs(fs, args, "ID")
+		if err != nil {
+			return err
+		}
+
+		t, err := svc.CreateTenant(ctx, id[0], *name)
+		if err != nil {
+			return err
+		}
+
+		return p.tenants([]models.Tenant{t})
+
+	case "list":
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+
+		tenants, err := svc.Tenants(ctx)
+		if err != nil {
+			return err
+		}
+
+		return p.tenants(tenants)
+
+	default:
+		return fmt.Errorf("%w: unknown subcommand tenant %q", errUsage, sub)
+	}
+}
+
+func runOrg(
+	ctx context.Context,
+	orgs *organization.Organizations,
+	storage *postgres.Storage,
+	p *printer,
+	sub string,
+	args []string,
+) error {
+	fs := newFlagSet("org " + sub)
+
+	// Оператор действует с правами владельца любой организации арендатора, поэтому
+	// вместо участника передается пустой UUID.
+	const operator = ""
+
+	switch sub {
+	case "create":
+		owner := fs.String("owner", "", "email of the organization owner")
+		a, err := parseArgs(fs, args, "APP", "NAME")
+		if err != nil {
+			return err
+		}
+		if *owner == "" {
+			return fmt.Errorf("%w: -owner is required", errUsage)
+		}
+
+		user, err := storage.User(ctx, *owner)
+		if err != nil {
+			return err
+		}
+
+		org, err := orgs.CreateOrganization(ctx, a[0], a[1], user.UUID)
+		if err != nil {
+			return err
+		}
+
+		return p.organizations([]models.Organization{org})
+
+	case "list":
+		app, err := parseArgs(fs, args, "APP")
+		if err != nil {
+			return err
+		}
+
+		list, err := orgs.Organizations(ctx, app[0], "")
+		if err != nil {
+			return err
+		}
+
+		return p.organizations(list)
+
+	case "members":
+		id, err := parseArgs(fs, args, "ID")
+		if err != nil {
+			return err
+		}
+
+		members, err := orgs.Members(ctx, operator, id[0])
+		if err != nil {
+			return err
+		}
+
+		return p.members(members)
+
+	case "invite":
+		role := fs.String("role", models.OrganizationMember, "role: owner, admin or member")
+		a, err := parseArgs(fs, args, "ID", "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		inv, err := orgs.InviteMember(ctx, operator, a[0], a[1], *role)
+		if err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("invitation sent to %s as %s, expires at %s", inv.Email, inv.Role, inv.ExpiresAt.Format(time.RFC3339)))
+
+	case "remove-member":
+		a, err := parseArgs(fs, args, "ID", "EMAIL")
+		if err != nil {
+			return err
+		}
+
+		user, err := storage.User(ctx, a[1])
+		if err != nil {
+			return err
+		}
+
+		if err := orgs.RemoveMember(ctx, operator, a[0], user.UUID); err != nil {
+			return err
+		}
+
+		return p.done(fmt.Sprintf("%s removed from organization %s", user.Email, a[0]))
+
+	default:
+		return fmt.Errorf("%w: unknown subcommand org %q", errUsage, sub)
+	}
+}
+
+func runAudit(ctx context.Context, storage *postgres.Storage, p *printer, sub string, args []string) error {
+	fs := newFlagSet("audit " + sub)
+
+	switch sub {
+	case "export":
+		var (
+			f            models.AuditFilter
+			outcome      string
+			since, until string
+		)
+		fs.StringVar(&f.Type, "event", "", "event type, e.g. login")
+		fs.StringVar(&outcome, "outcome", "", "success or failure")
+		fs.StringVar(&f.Actor, "actor", "", "actor")
+		fs.StringVar(&f.Subject, "subject", "", "subject, e.g. user email")
+		fs.StringVar(&f.App, "app", "", "app name")
+		fs.StringVar(&since, "since", "", "only events at or after this RFC 3339 time")
+		fs.StringVar(&until, "until", "", "only events before this RFC 3339 time")
+		limit := fs.Int("limit", 0, "max number of events; 0 exports all")
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+
+		var err error
+		f.Outcome = models.AuditOutcome(outcome)
+		if f.Since, err = parseTime("since", since); err != nil {
+			return err
+		}
+		if f.Until, err = parseTime("until", until); err != nil {
+			return err
+		}
+		if *limit < 0 {
+			return fmt.Errorf("%w: -limit must not be negative", errUsage)
+		}
+
+		events, err := exportAudit(ctx, storage, f, *limit)
+		if err != nil {
+			return err
+		}
+
+		return p.auditEvents(events)
+
+	default:
+		return fmt.Errorf("%w: unknown subcommand audit %q", errUsage, sub)
+	}
+}
+
+// exportAudit выбирает события постранично, пока они не закончатся или не наберется limit.
+func exportAudit(ctx context.Context, storage *postgres.Storage, f models.AuditFilter, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+
+	for {
+		f.Limit = auditPageSize
+		if limit > 0 {
+			f.Limit = min(auditPageSize, limit-len(events))
+		}
+
+		page, err := storage.AuditEvents(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, page...)
+		if len(page) < f.Limit || (limit > 0 && len(events) >= limit) {
+			return events, nil
+		}
+
+		f.BeforeID = page[len(page)-1].ID
+	}
+}
+
+func parseTime(name, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: -%s must be an RFC 3339 timestamp, got %q", errUsage, name, s)
+	}
+
+	return t, nil
+}
+
+// osUser возвращает имя пользователя ОС, запустившего ssoctl.
+func osUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return "unknown"
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	return fs
+}
+
+// parseArgs разбирает флаги подкоманды и проверяет, что переданы ровно позиционные
+// аргументы names. Флаги допускаются как до, так и после позиционных аргументов.
+func parseArgs(fs *flag.FlagSet, args []string, names ...string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUsage, err)
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) != len(names) {
+		return nil, fmt.Errorf("%w: %s expects %d argument(s) %v, got %d",
+			errUsage, fs.Name(), len(names), names, len(positional))
+	}
+
+	return positional, nil
+}
+
+// passwordOrGenerate возвращает password, а если он пуст — случайный пароль.
+func passwordOrGenerate(password string) (string, bool, error) {
+	if password != "" {
+		return password, false, nil
+	}
+
+	b := make([]byte, generatedPasswordLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, fmt.Errorf("generate password: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), true, nil
+}
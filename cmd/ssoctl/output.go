@@ -0,0 +1,591 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/services/admin"
+	"go-sso/internal/services/consent"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// printer выводит результаты команд таблицей или JSON.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table":
+		return &printer{w: w}, nil
+	case "json":
+		return &printer{w: w, json: true}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown output format %q", errUsage, format)
+	}
+}
+
+type userOut struct {
+	UUID        string         `json:"uuid"`
+	Email       string         `json:"email"`
+	Status      string         `json:"status"`
+	DisplayName string         `json:"display_name,omitempty"`
+	Locale      string         `json:"locale,omitempty"`
+	Timezone    string         `json:"timezone,omitempty"`
+	AvatarURL   string         `json:"avatar_url,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+func newUserOut(u models.User) userOut {
+	return userOut{
+		UUID:        u.UUID,
+		Email:       u.Email,
+		Status:      string(u.Status),
+		DisplayName: u.Profile.DisplayName,
+		Locale:      u.Profile.Locale,
+		Timezone:    u.Profile.Timezone,
+		AvatarURL:   u.Profile.AvatarURL,
+		Metadata:    u.Profile.Metadata,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+}
+
+type appOut struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type clientOut struct {
+	ID              string     `json:"client_id"`
+	App             string     `json:"app"`
+	Name            string     `json:"name"`
+	Scopes          []string   `json:"scopes"`
+	CreatedAt       time.Time  `json:"created_at"`
+	SecretRotatedAt *time.Time `json:"secret_rotated_at,omitempty"`
+}
+
+type clientSecretOut struct {
+	ID     string `json:"client_id"`
+	App    string `json:"app,omitempty"`
+	Secret string `json:"client_secret"`
+}
+
+type roleOut struct {
+	App       string    `json:"app"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type sessionOut struct {
+	ID         string    `json:"id"`
+	App        string    `json:"app"`
+	Device     string    `json:"device,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type identityOut struct {
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+type consentOut struct {
+	App       string    `json:"app"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type tenantOut struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type organizationOut struct {
+	ID        string    `json:"id"`
+	App       string    `json:"app"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type memberOut struct {
+	UserUUID  string    `json:"user_uuid"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type auditEventOut struct {
+	ID        int64             `json:"id"`
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"event"`
+	Outcome   string            `json:"outcome"`
+	Reason    string            `json:"reason,omitempty"`
+	Actor     string            `json:"actor,omitempty"`
+	Subject   string            `json:"subject,omitempty"`
+	App       string            `json:"app,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+type credentialsOut struct {
+	UUID     string `json:"uuid,omitempty"`
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+}
+
+func (p *printer) users(users []models.User) error {
+	out := make([]userOut, 0, len(users))
+	for _, u := range users {
+		out = append(out, newUserOut(u))
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("UUID", "EMAIL", "STATUS", "CREATED")
+	for _, u := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.UUID, u.Email, u.Status, u.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+// user выводит пользователя с профилем.
+func (p *printer) user(u models.User) error {
+	out := newUserOut(u)
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	metadata := ""
+	if len(out.Metadata) > 0 {
+		b, err := json.Marshal(out.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(b)
+	}
+
+	w := p.table("FIELD", "VALUE")
+	for _, row := range [][2]string{
+		{"uuid", out.UUID},
+		{"email", out.Email},
+		{"status", out.Status},
+		{"display_name", out.DisplayName},
+		{"locale", out.Locale},
+		{"timezone", out.Timezone},
+		{"avatar_url", out.AvatarURL},
+		{"metadata", metadata},
+		{"created_at", out.CreatedAt.Format(time.RFC3339)},
+		{"updated_at", out.UpdatedAt.Format(time.RFC3339)},
+	} {
+		fmt.Fprintf(w, "%s\t%s\n", row[0], row[1])
+	}
+
+	return w.Flush()
+}
+
+// collisions выводит группы пользователей с совпадающими email; в таблице группы разделены пустой строкой.
+func (p *printer) collisions(groups [][]models.User) error {
+	if p.json {
+		out := make([][]userOut, 0, len(groups))
+		for _, g := range groups {
+			users := make([]userOut, 0, len(g))
+			for _, u := range g {
+				users = append(users, newUserOut(u))
+			}
+			out = append(out, users)
+		}
+
+		return p.encode(out)
+	}
+
+	if len(groups) == 0 {
+		return p.done("no collisions found")
+	}
+
+	w := p.table("TENANT", "EMAIL", "UUID", "STATUS", "CREATED")
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		for _, u := range g {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", u.Tenant, u.Email, u.UUID, u.Status, u.CreatedAt.Format(time.RFC3339))
+		}
+	}
+
+	return w.Flush()
+}
+
+type emailChangeOut struct {
+	UUID       string `json:"uuid"`
+	Tenant     string `json:"tenant"`
+	Email      string `json:"email"`
+	Normalized string `json:"normalized"`
+	Updated    bool   `json:"updated"`
+	Error      string `json:"error,omitempty"`
+}
+
+// emailChanges выводит адреса, отличающиеся от нормализованных, и результат их замены.
+func (p *printer) emailChanges(changes []admin.EmailChange, dryRun bool) error {
+	out := make([]emailChangeOut, 0, len(changes))
+	for _, c := range changes {
+		o := emailChangeOut{
+			UUID:       c.User.UUID,
+			Tenant:     c.User.Tenant,
+			Email:      c.User.Email,
+			Normalized: c.Email,
+			Updated:    c.Err == nil && !dryRun,
+		}
+		if c.Err != nil {
+			o.Error = c.Err.Error()
+		}
+		out = append(out, o)
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	if len(out) == 0 {
+		return p.done("all emails are normalized")
+	}
+
+	w := p.table("TENANT", "UUID", "EMAIL", "NORMALIZED", "RESULT")
+	for _, o := range out {
+		result := o.Error
+		switch {
+		case o.Updated:
+			result = "updated"
+		case result == "":
+			result = "would update"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", o.Tenant, o.UUID, o.Email, o.Normalized, result)
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) apps(apps []models.App) error {
+	out := make([]appOut, 0, len(apps))
+	for _, a := range apps {
+		out = append(out, appOut{ID: a.ID, Name: a.Name})
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("ID", "NAME")
+	for _, a := range out {
+		fmt.Fprintf(w, "%d\t%s\n", a.ID, a.Name)
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) clients(clients []models.Client) error {
+	out := make([]clientOut, 0, len(clients))
+	for _, c := range clients {
+		out = append(out, clientOut{
+			ID:              c.ID,
+			App:             c.App,
+			Name:            c.Name,
+			Scopes:          c.Scopes,
+			CreatedAt:       c.CreatedAt,
+			SecretRotatedAt: c.SecretRotatedAt,
+		})
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("CLIENT_ID", "APP", "NAME", "SCOPES", "CREATED_AT")
+	for _, c := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.ID, c.App, c.Name, strings.Join(c.Scopes, " "), c.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+// clientSecret выводит секрет клиента. Он хранится только в виде хэша и выводится один раз.
+func (p *printer) clientSecret(id, app, secret, msg string) error {
+	if p.json {
+		return p.encode(clientSecretOut{ID: id, App: app, Secret: secret})
+	}
+
+	fmt.Fprintf(p.w, "client %s %s\n", id, msg)
+	fmt.Fprintf(p.w, "client_secret: %s\n", secret)
+
+	return nil
+}
+
+func (p *printer) roles(roles []models.Role) error {
+	out := make([]roleOut, 0, len(roles))
+	for _, r := range roles {
+		out = append(out, roleOut{App: r.AppName, Role: r.Role, CreatedAt: r.CreatedAt})
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("APP", "ROLE", "CREATED")
+	for _, r := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.App, r.Role, r.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) sessions(sessions []models.Session) error {
+	out := make([]sessionOut, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, sessionOut{
+			ID:         s.ID,
+			App:        s.App,
+			Device:     s.Device,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+		})
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("ID", "APP", "DEVICE", "IP", "CREATED", "LAST SEEN", "EXPIRES")
+	for _, s := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.App, s.Device, s.IP,
+			s.CreatedAt.Format(time.RFC3339), s.LastSeenAt.Format(time.RFC3339), s.ExpiresAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) identities(identities []models.Identity) error {
+	out := make([]identityOut, 0, len(identities))
+	for _, i := range identities {
+		out = append(out, identityOut{
+			Provider:    i.Provider,
+			Subject:     i.Subject,
+			Email:       i.Email,
+			CreatedAt:   i.CreatedAt,
+			LastLoginAt: i.LastLoginAt,
+		})
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("PROVIDER", "SUBJECT", "EMAIL", "CREATED", "LAST LOGIN")
+	for _, i := range out {
+		lastLogin := ""
+		if i.LastLoginAt != nil {
+			lastLogin = i.LastLoginAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", i.Provider, i.Subject, i.Email, i.CreatedAt.Format(time.RFC3339), lastLogin)
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) consents(grants []consent.Grant) error {
+	out := make([]consentOut, 0, len(grants))
+	for _, g := range grants {
+		c := consentOut{App: g.App, Scopes: make([]string, 0, len(g.Scopes)), CreatedAt: g.CreatedAt, UpdatedAt: g.UpdatedAt}
+		for _, s := range g.Scopes {
+			c.Scopes = append(c.Scopes, s.Name)
+		}
+		out = append(out, c)
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("APP", "SCOPES", "CREATED", "UPDATED")
+	for _, c := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.App, strings.Join(c.Scopes, ","),
+			c.CreatedAt.Format(time.RFC3339), c.UpdatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) tenants(tenants []models.Tenant) error {
+	out := make([]tenantOut, 0, len(tenants))
+	for _, t := range tenants {
+		out = append(out, tenantOut{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt})
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("ID", "NAME", "CREATED")
+	for _, t := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.ID, t.Name, t.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) organizations(orgs []models.Organization) error {
+	out := make([]organizationOut, 0, len(orgs))
+	for _, o := range orgs {
+		out = append(out, organizationOut{ID: o.ID, App: o.App, Name: o.Name, CreatedAt: o.CreatedAt})
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("ID", "APP", "NAME", "CREATED")
+	for _, o := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.ID, o.App, o.Name, o.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) members(members []models.Member) error {
+	out := make([]memberOut, 0, len(members))
+	for _, m := range members {
+		out = append(out, memberOut{UserUUID: m.UserUUID, Email: m.Email, Role: m.Role, CreatedAt: m.CreatedAt})
+	}
+
+	if p.json {
+		return p.encode(out)
+	}
+
+	w := p.table("UUID", "EMAIL", "ROLE", "JOINED")
+	for _, m := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.UserUUID, m.Email, m.Role, m.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) auditEvents(events []models.AuditEvent) error {
+	if p.json {
+		out := make([]auditEventOut, 0, len(events))
+		for _, e := range events {
+			out = append(out, auditEventOut{
+				ID:        e.ID,
+				Time:      e.Time,
+				Type:      e.Type,
+				Outcome:   string(e.Outcome),
+				Reason:    e.Reason,
+				Actor:     e.Actor,
+				Subject:   e.Subject,
+				App:       e.App,
+				IP:        e.IP,
+				UserAgent: e.UserAgent,
+				Details:   e.Details,
+			})
+		}
+
+		return p.encode(out)
+	}
+
+	w := p.table("ID", "TIME", "EVENT", "OUTCOME", "ACTOR", "SUBJECT", "APP", "IP", "REASON")
+	for _, e := range events {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID, e.Time.Format(time.RFC3339), e.Type, e.Outcome, e.Actor, e.Subject, e.App, e.IP, e.Reason)
+	}
+
+	return w.Flush()
+}
+
+// userCreated выводит созданного пользователя. Пароль выводится, только если он сгенерирован.
+func (p *printer) userCreated(uuid, email, password string, generated bool) error {
+	if !generated {
+		password = ""
+	}
+
+	if p.json {
+		return p.encode(credentialsOut{UUID: uuid, Email: email, Password: password})
+	}
+
+	fmt.Fprintf(p.w, "user %s created: %s\n", email, uuid)
+	if generated {
+		fmt.Fprintf(p.w, "password: %s\n", password)
+	}
+
+	return nil
+}
+
+// passwordReset сообщает о смене пароля. Пароль выводится, только если он сгенерирован.
+func (p *printer) passwordReset(email, password string, generated bool) error {
+	if !generated {
+		password = ""
+	}
+
+	if p.json {
+		return p.encode(credentialsOut{Email: email, Password: password})
+	}
+
+	fmt.Fprintf(p.w, "password of %s reset\n", email)
+	if generated {
+		fmt.Fprintf(p.w, "password: %s\n", password)
+	}
+
+	return nil
+}
+
+func (p *printer) done(msg string) error {
+	if p.json {
+		return p.encode(struct {
+			Result string `json:"result"`
+		}{msg})
+	}
+
+	_, err := fmt.Fprintln(p.w, msg)
+
+	return err
+}
+
+func (p *printer) table(header ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	for i, h := range header {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, h)
+	}
+	fmt.Fprintln(w)
+
+	return w
+}
+
+func (p *printer) encode(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
@@ -2,17 +2,63 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"go-sso/internal/app/bootstrap"
 	grpcapp "go-sso/internal/app/grpc"
+	httpapp "go-sso/internal/app/http"
 	"go-sso/internal/config"
-	vaultlib "go-sso/internal/lib/vault"
+	"go-sso/internal/grpc/interceptors"
+	"go-sso/internal/health"
+	audithttp "go-sso/internal/http/audit"
+	consenthttp "go-sso/internal/http/consent"
+	federationhttp "go-sso/internal/http/federation"
+	"go-sso/internal/http/introspect"
+	"go-sso/internal/http/middleware"
+	organizationhttp "go-sso/internal/http/organization"
+	passkeyhttp "go-sso/internal/http/passkey"
+	passwordlesshttp "go-sso/internal/http/passwordless"
+	sessionhttp "go-sso/internal/http/session"
+	"go-sso/internal/http/token"
+	"go-sso/internal/http/userinfo"
+	usershttp "go-sso/internal/http/users"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/idp"
+	"go-sso/internal/lib/keycache"
+	"go-sso/internal/lib/ldap"
+	"go-sso/internal/lib/ratelimit"
+	"go-sso/internal/services/admin"
 	"go-sso/internal/services/auth"
+	"go-sso/internal/services/clients"
+	"go-sso/internal/services/consent"
+	"go-sso/internal/services/directory"
+	"go-sso/internal/services/federation"
+	"go-sso/internal/services/introspection"
+	"go-sso/internal/services/organization"
+	"go-sso/internal/services/passkey"
+	"go-sso/internal/services/passwordless"
+	"go-sso/internal/services/session"
 	"go-sso/internal/storage/postgres"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
 
 	"go.uber.org/zap"
+	grpchealth "google.golang.org/grpc/health"
 )
 
 type App struct {
 	GRPCSrv *grpcapp.App
+	HTTPSrv *httpapp.App
+	Health  *health.Checker
+	Purger  *admin.Purger
+
+	log          *zap.SugaredLogger
+	drainTimeout time.Duration
+	closers      []closer
+	reloaders    []func(cfg *config.Config)
 }
 
 func New(
@@ -20,28 +66,353 @@ func New(
 	log *zap.SugaredLogger,
 	cfg *config.Config,
 ) *App {
-	storage, err := postgres.New(cfg.PSQL.DSN())
+	err := ensureSchema(ctx, log, cfg.PSQL.DSN(), cfg.PSQL.MigrationsTable(), cfg.PSQL.AutoMigrate)
+	if err != nil {
+		log.Fatalw("database schema is not up to date", "error", err)
+	}
+
+	storage, err := bootstrap.Storage(ctx, cfg)
 	if err != nil {
 		log.Fatalw("failed to connect to PostgreSQL", "error", err)
 	}
 
-	vaultClient := vaultlib.New(ctx,
-		log,
-		cfg.Vault.Addr,
-		cfg.Vault.Token,
-		cfg.Vault.Timeout,
+	vaultClient := bootstrap.Vault(ctx, log, cfg)
+
+	keyCache := keycache.New(vaultClient, cfg.Vault.KeyCacheTTL)
+
+	auditor := audit.New(log, audit.SinkFunc(storage.SaveAuditEvent))
+
+	authService := auth.New(log,
+		storage,
+		storage,
+		storage,
+		keyCache,
+		keyCache,
+		storage,
+		storage,
+		auditor,
+		cfg.Users.EmailNormalizer(),
+		authenticators(log, cfg, storage, auditor),
+		tokenConfig(cfg),
+	)
+	sessionService := session.New(log, storage, keyCache, auditor, cfg.Issuer())
+	clientsService := clients.New(log, storage, keyCache, auditor, clientsConfig(cfg))
+	consentService := consent.New(log, storage, auditor, appScopes(cfg))
+	introspector := introspection.New(log, storage, keyCache, sessionService, clientsService, auditor)
+	adminService := admin.New(log, storage, storage, storage, vaultClient, storage, storage, auditor, cfg.Users.EmailNormalizer())
+	federationService := federation.New(log,
+		federationProviders(cfg),
+		storage,
+		storage,
+		storage,
+		storage,
+		authService,
+		auditor,
+		cfg.Users.EmailNormalizer(),
+		cfg.Federation.StateTTL,
+	)
+	mail := bootstrap.Mailer(log, cfg)
+	passwordlessService := passwordless.New(log,
+		storage,
+		storage,
+		storage,
+		authService,
+		mail,
+		ratelimit.NewMemory(),
+		auditor,
+		cfg.Users.EmailNormalizer(),
+		passwordlessConfig(cfg),
+	)
+	organizationService := organization.New(log,
+		storage,
+		storage,
+		authService,
+		mail,
+		auditor,
+		cfg.Users.EmailNormalizer(),
+		organizationConfig(cfg),
 	)
 
-	authService := auth.New(log, storage, storage, storage, vaultClient, vaultClient, cfg.TokenTTL)
+	// Вход по passkey включается заданием webauthn.rp_id. Сервис выпускает токены через
+	// authService и сам служит для него вторым фактором, поэтому подключается после создания.
+	var passkeyService *passkey.Passkey
+	if cfg.WebAuthn.RPID != "" {
+		passkeyService, err = passkey.New(log,
+			storage,
+			storage,
+			storage,
+			storage,
+			authService,
+			auditor,
+			passkey.Config{
+				RPID:          cfg.WebAuthn.RPID,
+				RPDisplayName: cfg.WebAuthn.RPDisplayName,
+				RPOrigins:     cfg.WebAuthn.RPOrigins,
+				Timeout:       cfg.WebAuthn.Timeout,
+			},
+		)
+		if err != nil {
+			log.Fatalw("failed to configure webauthn", "error", err)
+		}
+		authService.SetSecondFactor(passkeyService)
+	}
 
-	grpcApp := grpcapp.New(log,
+	healthServer := grpchealth.NewServer()
+	checker := health.New(log,
+		healthServer,
 		cfg.AppServiceName,
+		cfg.Health.Interval,
+		cfg.Health.Timeout,
+		health.Check{Name: "postgres", Probe: storage.Ping},
+		health.Check{Name: "vault", Probe: vaultClient.Ping},
+	)
+
+	rateLimiter := interceptors.NewRateLimiter(log, ratelimit.NewMemory(), rateLimitRules(cfg))
+
+	grpcApp := grpcapp.New(log,
+		healthServer,
 		vaultClient,
 		authService,
+		rateLimiter,
 		cfg.GRPC.Port,
+		cfg.GRPC.Timeout,
 	)
 
-	return &App{
+	mux := http.NewServeMux()
+	mux.Handle("GET /livez", checker.LivezHandler())
+	mux.Handle("GET /readyz", checker.ReadyzHandler())
+	sessionhttp.Register(mux, log, sessionService)
+	mux.Handle("GET /v1/userinfo", userinfo.Handler(log, sessionService, adminService, authService.ProfileClaims))
+	mux.Handle("POST /v1/introspect", introspect.Handler(log, introspector))
+	mux.Handle("POST /v1/token", middleware.Client(token.Handler(log, clientsService)))
+	consenthttp.Register(mux, log, consentService, sessionService)
+	federationhttp.Register(mux, log, federationService, sessionService)
+	passwordlesshttp.Register(mux, log, passwordlessService)
+	organizationhttp.Register(mux, log, organizationService, sessionService)
+	if passkeyService != nil {
+		passkeyhttp.Register(mux, log, passkeyService, sessionService)
+	}
+	if cfg.HTTP.AdminToken != "" {
+		mux.Handle("GET /v1/audit/events", middleware.BearerToken(cfg.HTTP.AdminToken, audithttp.Handler(log, storage)))
+		usershttp.Register(mux, log, adminService, cfg.HTTP.AdminToken)
+	}
+
+	httpApp := httpapp.New(log, middleware.Tenant(mux), cfg.HTTP.Port)
+
+	app := &App{
 		GRPCSrv: grpcApp,
+		HTTPSrv: httpApp,
+		Health:  checker,
+		Purger:  admin.NewPurger(log, adminService, cfg.Users.PurgeInterval, cfg.Users.Retention),
+
+		log:          log,
+		drainTimeout: cfg.Shutdown.DrainTimeout,
+	}
+
+	app.onReload(func(cfg *config.Config) {
+		authService.SetTokenConfig(tokenConfig(cfg))
+		rateLimiter.SetRules(rateLimitRules(cfg))
+		passwordlessService.SetConfig(passwordlessConfig(cfg))
+		organizationService.SetConfig(organizationConfig(cfg))
+		clientsService.SetConfig(clientsConfig(cfg))
+		consentService.SetScopes(appScopes(cfg))
+	})
+
+	// Письма входа без пароля дописываются в хранилище, поэтому их ждут до его закрытия.
+	app.onStop("passwordless", passwordlessService.Close)
+	app.onStop("postgres", storage.Close)
+	app.onStop("vault", vaultClient.Close)
+
+	return app
+}
+
+// tokenConfig возвращает параметры выпуска токенов из конфигурации.
+func tokenConfig(cfg *config.Config) auth.TokenConfig {
+	apps := make(map[string]auth.AppTokenConfig, len(cfg.Apps))
+	for name, app := range cfg.Apps {
+		apps[name] = auth.AppTokenConfig{
+			TTL:           app.TokenTTL,
+			Claims:        app.Claims,
+			ProfileClaims: app.ProfileClaims,
+			Authenticator: app.Authenticator,
+			MFA:           app.MFA,
+			Scopes:        app.Scopes,
+		}
+	}
+
+	return auth.TokenConfig{
+		Issuer: cfg.Issuer(),
+		TTL:    cfg.TokenTTL,
+		Apps:   apps,
 	}
 }
+
+// appScopes возвращает описания областей доступа по приложениям из конфигурации.
+func appScopes(cfg *config.Config) map[string]map[string]string {
+	scopes := make(map[string]map[string]string, len(cfg.Apps))
+	for name, app := range cfg.Apps {
+		scopes[name] = app.Scopes
+	}
+
+	return scopes
+}
+
+// clientsConfig возвращает параметры токенов клиентов из конфигурации.
+func clientsConfig(cfg *config.Config) clients.Config {
+	return clients.Config{
+		Issuer:   cfg.Issuer(),
+		TokenTTL: cfg.Clients.TokenTTL,
+	}
+}
+
+// authenticators возвращает внешние способы проверки пароля из конфигурации.
+func authenticators(
+	log *zap.SugaredLogger,
+	cfg *config.Config,
+	storage *postgres.Storage,
+	auditor directory.Auditor,
+) map[string]auth.Authenticator {
+	if cfg.LDAP.URL == "" {
+		return nil
+	}
+
+	tlsConfig, err := ldapTLS(cfg.LDAP)
+	if err != nil {
+		log.Fatalw("failed to configure LDAP TLS", "error", err)
+	}
+
+	client := ldap.New(ldap.Config{
+		URL:            cfg.LDAP.URL,
+		StartTLS:       cfg.LDAP.StartTLS,
+		TLS:            tlsConfig,
+		BindDN:         cfg.LDAP.BindDN,
+		BindPassword:   cfg.LDAP.BindPassword,
+		BaseDN:         cfg.LDAP.BaseDN,
+		UserFilter:     cfg.LDAP.UserFilter,
+		IDAttribute:    cfg.LDAP.IDAttribute,
+		EmailAttribute: cfg.LDAP.EmailAttribute,
+		NameAttribute:  cfg.LDAP.NameAttribute,
+		GroupAttribute: cfg.LDAP.GroupAttribute,
+		Timeout:        cfg.LDAP.Timeout,
+	})
+
+	groupRoles := make([]directory.GroupRole, 0, len(cfg.LDAP.GroupRoles))
+	for _, gr := range cfg.LDAP.GroupRoles {
+		groupRoles = append(groupRoles, directory.GroupRole{Group: gr.Group, App: gr.App, Role: gr.Role})
+	}
+
+	return map[string]auth.Authenticator{
+		directory.Provider: directory.New(log,
+			client,
+			storage,
+			storage,
+			storage,
+			storage,
+			auditor,
+			cfg.Users.EmailNormalizer(),
+			cfg.LDAP.LinkExisting,
+			groupRoles,
+		),
+	}
+}
+
+// ldapTLS возвращает настройки TLS для подключения к каталогу.
+func ldapTLS(cfg config.LDAPConfig) (*tls.Config, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// federationProviders возвращает внешних провайдеров identity из конфигурации.
+func federationProviders(cfg *config.Config) map[string]federation.Provider {
+	providers := make(map[string]federation.Provider, len(cfg.Federation.Providers))
+	for name, p := range cfg.Federation.Providers {
+		idpCfg := idp.Config{
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.Federation.RedirectURL(name),
+			Scopes:       p.Scopes,
+		}
+
+		var provider idp.Provider
+		switch p.Type {
+		case "github":
+			provider = idp.NewGitHub(idpCfg, idp.GitHubEndpoints{AuthURL: p.AuthURL, TokenURL: p.TokenURL, APIURL: p.APIURL})
+		default:
+			provider = idp.NewOIDC(p.Issuer, idpCfg)
+		}
+
+		providers[name] = federation.Provider{Provider: provider, JIT: p.JIT, AllowedDomains: p.AllowedDomains}
+	}
+
+	return providers
+}
+
+// passwordlessConfig возвращает параметры входа без пароля из конфигурации.
+func passwordlessConfig(cfg *config.Config) passwordless.Config {
+	apps := make(map[string]passwordless.AppConfig)
+	for name, app := range cfg.Apps {
+		if len(app.Passwordless.Methods) == 0 {
+			continue
+		}
+
+		apps[name] = passwordless.AppConfig{Methods: app.Passwordless.Methods, LinkURL: app.Passwordless.LinkURL}
+	}
+
+	return passwordless.Config{
+		CodeTTL:     cfg.Passwordless.CodeTTL,
+		LinkTTL:     cfg.Passwordless.LinkTTL,
+		MaxAttempts: cfg.Passwordless.MaxAttempts,
+		SendLimit:   ratelimit.Limit{Rate: cfg.Passwordless.SendLimit.Rate, Burst: cfg.Passwordless.SendLimit.Burst},
+		Apps:        apps,
+	}
+}
+
+// organizationConfig возвращает параметры приглашений в организации из конфигурации.
+func organizationConfig(cfg *config.Config) organization.Config {
+	urls := make(map[string]string)
+	for name, app := range cfg.Apps {
+		if app.InvitationURL != "" {
+			urls[name] = app.InvitationURL
+		}
+	}
+
+	return organization.Config{
+		InvitationTTL:  cfg.Organizations.InvitationTTL,
+		InvitationURLs: urls,
+	}
+}
+
+// rateLimitRules возвращает правила ограничения частоты вызовов из конфигурации.
+func rateLimitRules(cfg *config.Config) map[string]interceptors.RateLimitRule {
+	rules := make(map[string]interceptors.RateLimitRule, len(cfg.RateLimit.Methods))
+	for method, rule := range cfg.RateLimit.Methods {
+		rules[method] = interceptors.RateLimitRule{
+			Limit: ratelimit.Limit{Rate: rule.Rate, Burst: rule.Burst},
+			Key:   rule.Key,
+		}
+	}
+
+	return rules
+}
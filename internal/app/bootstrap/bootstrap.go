@@ -0,0 +1,54 @@
+// Package bootstrap создает общие зависимости по конфигурации,
+// чтобы сервис и утилиты (ssoctl) настраивали их одинаково.
+package bootstrap
+
+import (
+	"context"
+	"go-sso/internal/config"
+	"go-sso/internal/lib/breaker"
+	"go-sso/internal/lib/mailer"
+	vaultlib "go-sso/internal/lib/vault"
+	"go-sso/internal/storage/postgres"
+
+	"go.uber.org/zap"
+)
+
+// Storage подключается к PostgreSQL с настройками пула, таймаутов и повторов из cfg.
+func Storage(ctx context.Context, cfg *config.Config) (*postgres.Storage, error) {
+	return postgres.New(ctx, cfg.PSQL.DSN(), postgres.Options{
+		MaxOpenConns:    cfg.PSQL.Pool.MaxOpenConns,
+		MaxIdleConns:    cfg.PSQL.Pool.MaxIdleConns,
+		ConnMaxLifetime: cfg.PSQL.Pool.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.PSQL.Pool.ConnMaxIdleTime,
+		QueryTimeout:    cfg.PSQL.QueryTimeout,
+		Retry:           cfg.PSQL.Retry.Policy(cfg.PSQL.QueryTimeout),
+	})
+}
+
+// Vault создает клиент Vault с повторами и circuit breaker из cfg.
+func Vault(ctx context.Context, log *zap.SugaredLogger, cfg *config.Config) *vaultlib.Client {
+	return vaultlib.New(ctx,
+		log,
+		cfg.Vault.Addr,
+		cfg.Vault.Token,
+		cfg.Vault.Timeout,
+		cfg.Vault.Retry.Policy(cfg.Vault.Timeout),
+		breaker.New(cfg.Vault.Breaker.FailureThreshold, cfg.Vault.Breaker.OpenTimeout),
+	)
+}
+
+// Mailer возвращает способ отправки писем из cfg.
+func Mailer(log *zap.SugaredLogger, cfg *config.Config) mailer.Mailer {
+	if cfg.Mailer.Type != "smtp" {
+		return mailer.NewLog(log.Named("mailer"))
+	}
+
+	return mailer.NewSMTP(mailer.SMTPConfig{
+		Host:        cfg.Mailer.SMTP.Host,
+		Port:        cfg.Mailer.SMTP.Port,
+		Username:    cfg.Mailer.SMTP.Username,
+		Password:    cfg.Mailer.SMTP.Password,
+		ImplicitTLS: cfg.Mailer.SMTP.ImplicitTLS,
+		Timeout:     cfg.Mailer.SMTP.Timeout,
+	}, cfg.Mailer.From)
+}
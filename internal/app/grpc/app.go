@@ -1,9 +1,12 @@
 package grpcapp
 
 import (
+	"context"
 	"fmt"
 	authgrpc "go-sso/internal/grpc/auth"
+	"go-sso/internal/grpc/interceptors"
 	"net"
+	"time"
 
 	vaultlib "go-sso/internal/lib/vault"
 
@@ -21,20 +24,28 @@ type App struct {
 }
 
 // New создает новый экземпляр gRPC сервера.
+// Статусом healthServer управляет вызывающий код.
 func New(
 	log *zap.SugaredLogger,
-	appServiceName string,
+	healthServer *health.Server,
 	vaultClient *vaultlib.Client,
 	authService authgrpc.Auth,
+	rateLimiter *interceptors.RateLimiter,
 	port int,
+	timeout time.Duration,
 ) *App {
-	gRPCServer := grpc.NewServer()
+	gRPCServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			interceptors.Client(),
+			interceptors.Tenant(),
+			rateLimiter.Unary(),
+			interceptors.Timeout(timeout),
+		),
+	)
 
 	authgrpc.Register(gRPCServer, vaultClient, authService)
 
-	healthServer := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(gRPCServer, healthServer)
-	healthServer.SetServingStatus(appServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
 
 	reflection.Register(gRPCServer)
 
@@ -72,15 +83,33 @@ func (a *App) Run() error {
 	return nil
 }
 
-// Stop останавливает gRPC сервер.
-func (a *App) Stop() {
+// Stop останавливает gRPC сервер: перестает принимать новые соединения
+// и дожидается завершения активных RPC. Если ctx отменяется раньше,
+// оставшиеся RPC прерываются принудительно и возвращается ошибка.
+func (a *App) Stop(ctx context.Context) error {
 	const op = "grpcapp.Stop"
 
 	log := a.log.With(zap.String("op", op))
 
 	log.Infow("stopping gRPC server")
 
-	a.gRPCServer.GracefulStop()
+	done := make(chan struct{})
+	go func() {
+		a.gRPCServer.GracefulStop()
+		close(done)
+	}()
 
-	log.Infow("gracefully stopped gRPC server")
+	select {
+	case <-done:
+		log.Infow("gracefully stopped gRPC server")
+
+		return nil
+	case <-ctx.Done():
+		log.Warnw("drain timeout exceeded, forcing gRPC server stop")
+
+		a.gRPCServer.Stop()
+		<-done
+
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
 }
@@ -0,0 +1,80 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const readHeaderTimeout = 5 * time.Second
+
+type App struct {
+	log        *zap.SugaredLogger
+	httpServer *http.Server
+	port       int
+}
+
+// New создает новый экземпляр HTTP сервера.
+func New(
+	log *zap.SugaredLogger,
+	handler http.Handler,
+	port int,
+) *App {
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+		port: port,
+	}
+}
+
+// MustRun запускает HTTP сервер и вызывает панику в случае ошибки.
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		a.log.Panicw("failed to run HTTP server", "err", err)
+	}
+}
+
+// Run запускает HTTP сервер и слушает указанный порт.
+func (a *App) Run() error {
+	const op = "httpapp.Run"
+
+	log := a.log.With(zap.String("op", op))
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Infow("HTTP server started", "port", l.Addr().String())
+
+	if err := a.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Stop останавливает HTTP сервер, дожидаясь завершения активных запросов до отмены ctx.
+func (a *App) Stop(ctx context.Context) error {
+	const op = "httpapp.Stop"
+
+	log := a.log.With(zap.String("op", op))
+
+	log.Infow("stopping HTTP server")
+
+	if err := a.httpServer.Shutdown(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Infow("gracefully stopped HTTP server")
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// closer ресурс, который освобождается при остановке приложения.
+type closer struct {
+	name  string
+	close func() error
+}
+
+// onStop регистрирует ресурс для освобождения при остановке.
+// Ресурсы закрываются в порядке регистрации.
+func (a *App) onStop(name string, close func() error) {
+	a.closers = append(a.closers, closer{name: name, close: close})
+}
+
+// Stop останавливает приложение:
+//  1. переводит health-статус в NOT_SERVING, чтобы балансировщики перестали слать трафик;
+//  2. перестает принимать новые запросы и ждет завершения активных не дольше drainTimeout,
+//     после чего прерывает оставшиеся принудительно;
+//  3. закрывает хранилище, клиент Vault и прочие ресурсы в порядке регистрации.
+//
+// Возвращает все ошибки, возникшие при остановке.
+func (a *App) Stop() error {
+	const op = "app.Stop"
+
+	log := a.log.With("op", op)
+
+	a.Health.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.drainTimeout)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	report := func(err error) {
+		if err == nil {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		errs = append(errs, err)
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		report(a.GRPCSrv.Stop(ctx))
+	}()
+	go func() {
+		defer wg.Done()
+		report(a.HTTPSrv.Stop(ctx))
+	}()
+	wg.Wait()
+
+	for _, c := range a.closers {
+		start := time.Now()
+
+		if err := c.close(); err != nil {
+			log.Errorw("failed to close resource", "resource", c.name, "error", err)
+			report(fmt.Errorf("close %s: %w", c.name, err))
+
+			continue
+		}
+
+		log.Infow("closed resource", "resource", c.name, "took", time.Since(start))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
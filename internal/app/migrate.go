@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/migrator"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"go.uber.org/zap"
+)
+
+// migrateLockTimeout сколько ждать, пока другой экземпляр сервиса закончит миграции.
+const migrateLockTimeout = 2 * time.Minute
+
+// ensureSchema проверяет, что схема базы данных соответствует встроенным миграциям.
+// Если autoMigrate включен, сначала применяет недостающие миграции под advisory lock.
+// Возвращает ошибку, если схема отстает от бинарника или помечена как dirty.
+func ensureSchema(
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	dsn string,
+	table string,
+	autoMigrate bool,
+) error {
+	const op = "app.ensureSchema"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer db.Close()
+
+	m, err := migrator.NewEmbedded(db, table)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer m.Close()
+
+	status, err := m.Status()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if autoMigrate && !status.Dirty && len(status.Pending()) > 0 {
+		log.Infow("applying pending migrations", "version", status.Version, "pending", len(status.Pending()))
+
+		if err := m.Lock(ctx, migrateLockTimeout); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := m.Up(0); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := m.Unlock(ctx); err != nil {
+			log.Warnw("failed to release migration lock", "error", err)
+		}
+
+		if status, err = m.Status(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Infow("migrations applied", "version", status.Version)
+	}
+
+	if status.Dirty {
+		return fmt.Errorf("%s: schema is dirty at version %d, fix it with migrator force", op, status.Version)
+	}
+
+	if pending := status.Pending(); len(pending) > 0 {
+		return fmt.Errorf("%s: schema version %d is behind the binary (latest %d), run migrations or enable psql.auto_migrate",
+			op, status.Version, pending[len(pending)-1].Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package app
+
+import (
+	"go-sso/internal/config"
+)
+
+// onReload регистрирует обработчик, применяющий изменяемые без перезапуска настройки.
+func (a *App) onReload(apply func(cfg *config.Config)) {
+	a.reloaders = append(a.reloaders, apply)
+}
+
+// Reload применяет настройки из cfg, которые можно менять без перезапуска
+// (время жизни токенов и т.п.). Остальные поля cfg игнорируются.
+func (a *App) Reload(cfg *config.Config) {
+	for _, apply := range a.reloaders {
+		apply(cfg)
+	}
+}
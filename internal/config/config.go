@@ -1,9 +1,14 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/retry"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -16,20 +21,252 @@ const (
 )
 
 type Config struct {
-	AppServiceName string        `yaml:"app_service_name" env:"APP_SERVICE_NAME" env-default:"sso"`
-	Env            string        `yaml:"env" env:"ENV" env-required:"true"`
-	TokenTTL       time.Duration `yaml:"token_ttl" env:"TOKEN_TTL" env-required:"true"`
-	GRPC           GRPCConfig    `yaml:"grpc" env-required:"true"`
-	Vault          VaultConfig   `yaml:"vault" env-required:"true"`
-	PSQL           PSQLConfig    `yaml:"psql" env-required:"true"`
+	AppServiceName string               `yaml:"app_service_name" env:"APP_SERVICE_NAME" env-default:"sso"`
+	Env            string               `yaml:"env" env:"ENV" env-required:"true"`
+	LogLevel       string               `yaml:"log_level" env:"LOG_LEVEL"`
+	TokenTTL       time.Duration        `yaml:"token_ttl" env:"TOKEN_TTL" env-required:"true"`
+	TokenIssuer    string               `yaml:"token_issuer" env:"TOKEN_ISSUER"`
+	Apps           map[string]AppConfig `yaml:"apps"`
+	RateLimit      RateLimitConfig      `yaml:"rate_limit"`
+	Users          UsersConfig          `yaml:"users"`
+	Federation     FederationConfig     `yaml:"federation"`
+	LDAP           LDAPConfig           `yaml:"ldap"`
+	Passwordless   PasswordlessConfig   `yaml:"passwordless"`
+	Mailer         MailerConfig         `yaml:"mailer"`
+	Organizations  OrganizationsConfig  `yaml:"organizations"`
+	WebAuthn       WebAuthnConfig       `yaml:"webauthn"`
+	Clients        ClientsConfig        `yaml:"clients"`
+	GRPC           GRPCConfig           `yaml:"grpc" env-required:"true"`
+	HTTP           HTTPConfig           `yaml:"http"`
+	Health         HealthConfig         `yaml:"health"`
+	Shutdown       ShutdownConfig       `yaml:"shutdown"`
+	Vault          VaultConfig          `yaml:"vault" env-required:"true"`
+	PSQL           PSQLConfig           `yaml:"psql" env-required:"true"`
+
+	// path путь к файлу, из которого загружена конфигурация (для Reload).
+	path string
 }
 
 type GRPCConfig struct {
+	// Host адрес, по которому клиенты (в т.ч. функциональные тесты) обращаются к серверу.
+	// Сервер слушает порт на всех интерфейсах.
 	Host    string        `yaml:"host" env:"GRPC_HOST" env-required:"true"`
 	Port    int           `yaml:"port" env:"GRPC_PORT" env-required:"true"`
 	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT" env-required:"true"`
 }
 
+// AppConfig настройки токенов отдельного приложения.
+type AppConfig struct {
+	// TokenTTL время жизни токенов приложения; если не задано, используется token_ttl.
+	TokenTTL time.Duration `yaml:"token_ttl"`
+	// Claims дополнительные claims, добавляемые в токены приложения.
+	Claims map[string]string `yaml:"claims"`
+	// ProfileClaims поля профиля (name, locale, zoneinfo, picture), которые получает приложение
+	// в токенах и в userinfo.
+	ProfileClaims []string `yaml:"profile_claims"`
+	// Authenticator проверка пароля при входе: local (таблица users, по умолчанию) или ldap.
+	Authenticator string `yaml:"authenticator"`
+	// Passwordless вход без пароля по ссылке или коду из письма; по умолчанию выключен.
+	Passwordless AppPasswordlessConfig `yaml:"passwordless"`
+	// MFA второй фактор после проверки пароля: passkey; по умолчанию не требуется.
+	MFA string `yaml:"mfa"`
+	// Scopes области доступа, которые приложение может запрашивать, с описаниями
+	// для экрана согласия.
+	Scopes map[string]string `yaml:"scopes"`
+	// InvitationURL страница приложения, на которую ведет приглашение в организацию;
+	// к ней добавляется параметр token. Если не задана, приглашать в организации приложения нельзя.
+	InvitationURL string `yaml:"invitation_url"`
+}
+
+// AppPasswordlessConfig вход без пароля в приложение.
+type AppPasswordlessConfig struct {
+	// Methods разрешенные способы: link (одноразовая ссылка) и code (шестизначный код).
+	Methods []string `yaml:"methods"`
+	// LinkURL страница приложения, на которую ведет ссылка; к ней добавляются
+	// параметры challenge_id и token.
+	LinkURL string `yaml:"link_url"`
+}
+
+// RateLimitConfig ограничения частоты вызовов gRPC-методов.
+type RateLimitConfig struct {
+	// Methods правила по методам: полное имя (/go_sso.v1.Auth/Login) или только имя метода (Login).
+	Methods map[string]RateLimitRule `yaml:"methods"`
+}
+
+// RateLimitRule token bucket метода: rate запросов в секунду с запасом burst
+// для каждого значения ключа key (ip, app или email).
+type RateLimitRule struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+	Key   string  `yaml:"key"`
+}
+
+// UsersConfig настройки жизненного цикла учетных записей.
+type UsersConfig struct {
+	// Retention сколько хранятся данные удаленного пользователя до окончательного стирания.
+	Retention time.Duration `yaml:"retention" env:"USERS_RETENTION" env-default:"720h"`
+	// PurgeInterval как часто сервис стирает удаленных пользователей; 0 отключает стирание.
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"USERS_PURGE_INTERVAL" env-default:"1h"`
+	// LowercaseEmail приводить к нижнему регистру весь email, а не только домен.
+	LowercaseEmail bool `yaml:"lowercase_email" env:"USERS_LOWERCASE_EMAIL" env-default:"false"`
+}
+
+// EmailNormalizer возвращает правила нормализации email.
+func (c UsersConfig) EmailNormalizer() email.Normalizer {
+	return email.Normalizer{LowercaseLocal: c.LowercaseEmail}
+}
+
+// FederationConfig вход через внешних провайдеров identity.
+type FederationConfig struct {
+	// RedirectBaseURL внешний адрес HTTP-сервера. Callback провайдера <имя> —
+	// <redirect_base_url>/v1/federation/<имя>/callback.
+	RedirectBaseURL string `yaml:"redirect_base_url" env:"FEDERATION_REDIRECT_BASE_URL"`
+	// StateTTL сколько пользователь может проходить вход у провайдера.
+	StateTTL  time.Duration             `yaml:"state_ttl" env:"FEDERATION_STATE_TTL" env-default:"10m"`
+	Providers map[string]ProviderConfig `yaml:"providers"`
+}
+
+// ProviderConfig внешний провайдер identity.
+type ProviderConfig struct {
+	// Type oidc (OpenID Connect с discovery) или github.
+	Type string `yaml:"type"`
+	// Issuer издатель OpenID Connect, например https://accounts.google.com.
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`
+	// AuthURL, TokenURL и APIURL адреса GitHub Enterprise Server; по умолчанию github.com.
+	AuthURL  string `yaml:"auth_url"`
+	TokenURL string `yaml:"token_url"`
+	APIURL   string `yaml:"api_url"`
+	// JIT создавать пользователя при первом входе через провайдера.
+	JIT bool `yaml:"jit"`
+	// AllowedDomains домены email, для которых создаются пользователи; пусто — любые.
+	AllowedDomains []string `yaml:"allowed_domains"`
+}
+
+// RedirectURL возвращает адрес callback провайдера name.
+func (c FederationConfig) RedirectURL(name string) string {
+	return strings.TrimSuffix(c.RedirectBaseURL, "/") + "/v1/federation/" + name + "/callback"
+}
+
+// LDAPConfig каталог LDAP / Active Directory для входа по паролю (apps.<имя>.authenticator: ldap).
+type LDAPConfig struct {
+	// URL адрес сервера: ldap://host:389 или ldaps://host:636.
+	URL string `yaml:"url" env:"LDAP_URL"`
+	// StartTLS включает TLS командой StartTLS на соединении ldap://.
+	StartTLS bool `yaml:"start_tls" env:"LDAP_START_TLS"`
+	// CAFile PEM-файл корневых сертификатов каталога; пусто — системные.
+	CAFile             string `yaml:"ca_file" env:"LDAP_CA_FILE"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+
+	BindDN       string `yaml:"bind_dn" env:"LDAP_BIND_DN"`
+	BindPassword string `yaml:"bind_password" env:"LDAP_BIND_PASSWORD"`
+	BaseDN       string `yaml:"base_dn" env:"LDAP_BASE_DN"`
+	// UserFilter фильтр поиска пользователя; {login} заменяется экранированным логином.
+	UserFilter string `yaml:"user_filter" env-default:"(&(objectClass=person)(mail={login}))"`
+
+	IDAttribute    string `yaml:"id_attribute" env-default:"entryUUID"`
+	EmailAttribute string `yaml:"email_attribute" env-default:"mail"`
+	NameAttribute  string `yaml:"name_attribute" env-default:"displayName"`
+	GroupAttribute string `yaml:"group_attribute" env-default:"memberOf"`
+
+	Timeout time.Duration `yaml:"timeout" env:"LDAP_TIMEOUT" env-default:"5s"`
+	// LinkExisting привязывать запись каталога к существующему пользователю с тем же email.
+	LinkExisting bool `yaml:"link_existing"`
+	// GroupRoles роли, которые получают участники групп каталога.
+	GroupRoles []GroupRoleConfig `yaml:"group_roles"`
+}
+
+// GroupRoleConfig роль role в приложении app для участников группы group (DN).
+type GroupRoleConfig struct {
+	Group string `yaml:"group"`
+	App   string `yaml:"app"`
+	Role  string `yaml:"role"`
+}
+
+// PasswordlessConfig параметры входа без пароля (apps.<имя>.passwordless).
+type PasswordlessConfig struct {
+	CodeTTL time.Duration `yaml:"code_ttl" env:"PASSWORDLESS_CODE_TTL" env-default:"10m"`
+	LinkTTL time.Duration `yaml:"link_ttl" env:"PASSWORDLESS_LINK_TTL" env-default:"15m"`
+	// MaxAttempts сколько раз можно ввести неверный код, прежде чем он перестанет действовать.
+	MaxAttempts int `yaml:"max_attempts" env:"PASSWORDLESS_MAX_ATTEMPTS" env-default:"5"`
+	// SendLimit ограничение частоты писем на один адрес: rate писем в секунду с запасом burst.
+	SendLimit SendLimitConfig `yaml:"send_limit"`
+}
+
+// SendLimitConfig token bucket отправки писем.
+type SendLimitConfig struct {
+	Rate  float64 `yaml:"rate" env-default:"0.0167"`
+	Burst int     `yaml:"burst" env-default:"3"`
+}
+
+// OrganizationsConfig организации приложений и приглашения в них.
+type OrganizationsConfig struct {
+	// InvitationTTL сколько действует приглашение в организацию.
+	InvitationTTL time.Duration `yaml:"invitation_ttl" env:"ORGANIZATIONS_INVITATION_TTL" env-default:"168h"`
+}
+
+// MailerConfig отправка писем пользователям.
+type MailerConfig struct {
+	// Type smtp или log (письма пишутся в лог; только для локальной разработки).
+	Type string     `yaml:"type" env:"MAILER_TYPE" env-default:"log"`
+	From string     `yaml:"from" env:"MAILER_FROM"`
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig SMTP-сервер для отправки писем.
+type SMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	// ImplicitTLS подключаться сразу по TLS (порт 465); иначе STARTTLS, если сервер его поддерживает.
+	ImplicitTLS bool          `yaml:"implicit_tls" env:"SMTP_IMPLICIT_TLS"`
+	Timeout     time.Duration `yaml:"timeout" env:"SMTP_TIMEOUT" env-default:"10s"`
+}
+
+// WebAuthnConfig параметры проверяющей стороны WebAuthn для входа по passkey.
+// Если rp_id не задан, вход по passkey выключен.
+type WebAuthnConfig struct {
+	// RPID домен, к которому привязываются ключи, например example.com.
+	RPID          string `yaml:"rp_id" env:"WEBAUTHN_RP_ID"`
+	RPDisplayName string `yaml:"rp_display_name" env:"WEBAUTHN_RP_DISPLAY_NAME" env-default:"SSO"`
+	// RPOrigins адреса страниц, с которых разрешены регистрация ключей и вход.
+	RPOrigins []string `yaml:"rp_origins" env:"WEBAUTHN_RP_ORIGINS" env-separator:","`
+	// Timeout время на завершение регистрации или входа.
+	Timeout time.Duration `yaml:"timeout" env:"WEBAUTHN_TIMEOUT" env-default:"5m"`
+}
+
+// ClientsConfig токены сервисов, получаемые по client credentials (POST /v1/token).
+type ClientsConfig struct {
+	// TokenTTL время жизни токена клиента.
+	TokenTTL time.Duration `yaml:"token_ttl" env:"CLIENTS_TOKEN_TTL" env-default:"1h"`
+	// SecretGrace сколько после ротации принимается прежний секрет клиента
+	// (по умолчанию для ssoctl client rotate-secret).
+	SecretGrace time.Duration `yaml:"secret_grace" env:"CLIENTS_SECRET_GRACE" env-default:"24h"`
+}
+
+// HTTPConfig настройки служебного HTTP-сервера (health-пробы и административный API).
+type HTTPConfig struct {
+	Port int `yaml:"port" env:"HTTP_PORT" env-default:"8080"`
+	// AdminToken bearer-токен административного API. Если не задан, API выключен.
+	AdminToken string `yaml:"admin_token" env:"HTTP_ADMIN_TOKEN"`
+}
+
+// HealthConfig настройки периодической проверки зависимостей.
+type HealthConfig struct {
+	Interval time.Duration `yaml:"interval" env:"HEALTH_INTERVAL" env-default:"10s"`
+	Timeout  time.Duration `yaml:"timeout" env:"HEALTH_TIMEOUT" env-default:"2s"`
+}
+
+// ShutdownConfig настройки graceful shutdown.
+type ShutdownConfig struct {
+	// DrainTimeout время, которое дается активным запросам на завершение
+	// перед принудительной остановкой серверов.
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"SHUTDOWN_DRAIN_TIMEOUT" env-default:"15s"`
+}
+
 type PSQLConfig struct {
 	Port     int             `yaml:"port" env:"POSTGRES_PORT" env-required:"true"`
 	Host     string          `yaml:"host" env:"POSTGRES_HOST" env-required:"true"`
@@ -37,19 +274,93 @@ type PSQLConfig struct {
 	Pass     string          `yaml:"password" env:"POSTGRES_PASSWORD" env-required:"true"`
 	DB       string          `yaml:"db" env:"POSTGRES_DB" env-required:"true"`
 	Migrator *MigratorConfig `yaml:"migrator" `
+
+	// AutoMigrate применять встроенные миграции при старте сервиса.
+	AutoMigrate bool `yaml:"auto_migrate" env:"POSTGRES_AUTO_MIGRATE" env-default:"false"`
+
+	Pool         PoolConfig    `yaml:"pool"`
+	QueryTimeout time.Duration `yaml:"query_timeout" env:"POSTGRES_QUERY_TIMEOUT" env-default:"5s"`
+	Retry        RetryConfig   `yaml:"retry" env-prefix:"POSTGRES_"`
+}
+
+// PoolConfig настройки пула соединений с PostgreSQL.
+type PoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"POSTGRES_MAX_OPEN_CONNS" env-default:"25"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"POSTGRES_MAX_IDLE_CONNS" env-default:"5"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"POSTGRES_CONN_MAX_LIFETIME" env-default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"POSTGRES_CONN_MAX_IDLE_TIME" env-default:"5m"`
+}
+
+// RetryConfig настройки повторов при временных ошибках зависимостей.
+type RetryConfig struct {
+	Attempts       int           `yaml:"attempts" env:"RETRY_ATTEMPTS" env-default:"3"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" env:"RETRY_INITIAL_BACKOFF" env-default:"100ms"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" env:"RETRY_MAX_BACKOFF" env-default:"2s"`
+}
+
+// BreakerConfig настройки circuit breaker.
+type BreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold" env:"BREAKER_FAILURE_THRESHOLD" env-default:"5"`
+	OpenTimeout      time.Duration `yaml:"open_timeout" env:"BREAKER_OPEN_TIMEOUT" env-default:"30s"`
 }
 
 type VaultConfig struct {
-	Addr    string        `yaml:"addr" env:"VAULT_ADDR" env-required:"true"`
-	Token   string        `yaml:"token" env:"VAULT_TOKEN" env-required:"true"`
-	Timeout time.Duration `yaml:"timeout" env:"VAULT_TIMEOUT" env-required:"true"`
+	Addr        string        `yaml:"addr" env:"VAULT_ADDR" env-required:"true"`
+	Token       string        `yaml:"token" env:"VAULT_TOKEN" env-required:"true"`
+	Timeout     time.Duration `yaml:"timeout" env:"VAULT_TIMEOUT" env-required:"true"`
+	KeyCacheTTL time.Duration `yaml:"key_cache_ttl" env:"VAULT_KEY_CACHE_TTL" env-default:"5m"`
+
+	Retry   RetryConfig   `yaml:"retry" env-prefix:"VAULT_"`
+	Breaker BreakerConfig `yaml:"breaker" env-prefix:"VAULT_"`
 }
 
 type MigratorConfig struct {
-	Path  string `yaml:"path" env:"MIGRATIONS_PATH" env-required:"true"`
+	// Path каталог с миграциями. Если не задан, используются миграции, встроенные в бинарник.
+	Path  string `yaml:"path" env:"MIGRATIONS_PATH"`
 	Table string `yaml:"table" env:"MIGRATIONS_TABLE" env-default:"migrations"`
 }
 
+// defaultMigrationsTable таблица версий, если секция migrator не задана.
+const defaultMigrationsTable = "migrations"
+
+// MigrationsTable возвращает имя таблицы версий миграций.
+func (c *PSQLConfig) MigrationsTable() string {
+	if c.Migrator == nil || c.Migrator.Table == "" {
+		return defaultMigrationsTable
+	}
+
+	return c.Migrator.Table
+}
+
+// Policy возвращает политику повторов с заданным ограничением времени одной попытки.
+func (c RetryConfig) Policy(attemptTimeout time.Duration) retry.Policy {
+	return retry.Policy{
+		Attempts:       c.Attempts,
+		InitialBackoff: c.InitialBackoff,
+		MaxBackoff:     c.MaxBackoff,
+		AttemptTimeout: attemptTimeout,
+	}
+}
+
+// Issuer возвращает claim iss выпускаемых токенов.
+func (c *Config) Issuer() string {
+	if c.TokenIssuer != "" {
+		return c.TokenIssuer
+	}
+
+	return c.AppServiceName
+}
+
+// RedactedDSN возвращает DSN со скрытым паролем для вывода в логи.
+func (c *PSQLConfig) RedactedDSN() string {
+	u, err := url.Parse(c.DSN())
+	if err != nil {
+		return fmt.Sprintf("postgres://%s:xxxxx@%s:%d/%s", c.User, c.Host, c.Port, c.DB)
+	}
+
+	return u.Redacted()
+}
+
 func (c *PSQLConfig) DSN() string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		c.User,
@@ -61,30 +372,56 @@ func (c *PSQLConfig) DSN() string {
 }
 
 func MustLoad() *Config {
+	cfg, err := LoadFromFlagOrEnv()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return cfg
+}
+
+// LoadFromFlagOrEnv загружает конфигурацию по пути из флага -config или переменной CONFIG_PATH.
+func LoadFromFlagOrEnv() (*Config, error) {
 	path := fetchConfigPath()
 	if path == "" {
-		panic("config path is not set")
+		return nil, errors.New("config path is not set")
 	}
 
-	return MustLoadByPath(path)
+	return Load(path)
 }
 
 func MustLoadByPath(configPath string) *Config {
+	cfg, err := Load(configPath)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return cfg
+}
+
+// Load читает конфигурацию из файла и переменных окружения и проверяет ее через Validate.
+func Load(configPath string) (*Config, error) {
 	_, err := os.Stat(configPath)
 	if err != nil && os.IsPermission(err) {
-		panic("no permission to config file: " + configPath)
+		return nil, fmt.Errorf("no permission to config file: %s", configPath)
 	}
 	if err != nil && os.IsNotExist(err) {
-		panic("config file does not exist: " + configPath)
+		return nil, fmt.Errorf("config file does not exist: %s", configPath)
 	}
 
 	var cfg Config
 
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		panic("failed to load config: " + err.Error())
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	return &cfg
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", configPath, err)
+	}
+
+	cfg.path = configPath
+
+	return &cfg, nil
 }
 
 // fetchConfigPath получает путь к конфигурационному файлу из флага или переменной окружения.
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validYAML = `
+env: local
+token_ttl: 1h
+grpc:
+    host: localhost
+    port: 50055
+    timeout: 10s
+psql:
+    host: localhost
+    port: 5432
+    user: sso
+    password: postgres
+    db: go-sso
+vault:
+    addr: http://localhost:8200
+    token: root
+    timeout: 5s
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoad_Valid(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Hour, cfg.TokenTTL)
+	assert.Equal(t, 8080, cfg.HTTP.Port)
+	assert.Equal(t, 3, cfg.PSQL.Retry.Attempts)
+}
+
+func TestValidate_AggregatesErrors(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	cfg.Env = "staging"
+	cfg.TokenTTL = 0
+	cfg.GRPC.Port = 70000
+	cfg.Vault.Addr = "localhost"
+
+	err = cfg.Validate()
+	require.Error(t, err)
+
+	for _, field := range []string{"env:", "token_ttl:", "grpc.port:", "vault.addr:"} {
+		assert.ErrorContains(t, err, field)
+	}
+}
+
+func TestValidate_AppClaims(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	cfg.Apps = map[string]AppConfig{
+		"billing": {TokenTTL: -time.Minute, Claims: map[string]string{"tenant": "acme", "sub": "admin"}},
+	}
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "apps.billing.token_ttl:")
+	assert.ErrorContains(t, err, `apps.billing.claims: "sub"`)
+	assert.NotContains(t, err.Error(), "tenant")
+}
+
+func TestValidate_RateLimit(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	cfg.RateLimit.Methods = map[string]RateLimitRule{
+		"Login":    {Rate: 1, Burst: 5, Key: "email"},
+		"Register": {Rate: 0, Burst: 0, Key: "user"},
+	}
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "rate_limit.methods.Register.rate:")
+	assert.ErrorContains(t, err, "rate_limit.methods.Register.burst:")
+	assert.ErrorContains(t, err, "rate_limit.methods.Register.key:")
+	assert.NotContains(t, err.Error(), "Login")
+}
+
+func TestValidate_Federation(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	cfg.Federation.Providers = map[string]ProviderConfig{
+		"github": {Type: "github", ClientID: "id"},
+		"corp":   {Type: "oidc", Issuer: "accounts.example.com"},
+		"Okta":   {Type: "saml", ClientID: "id"},
+	}
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "federation.redirect_base_url:")
+	assert.ErrorContains(t, err, "federation.providers.corp.issuer:")
+	assert.ErrorContains(t, err, "federation.providers.corp.client_id:")
+	assert.ErrorContains(t, err, "federation.providers.Okta: name")
+	assert.ErrorContains(t, err, "federation.providers.Okta.type:")
+	assert.NotContains(t, err.Error(), "providers.github")
+}
+
+func TestValidate_LDAP(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "(&(objectClass=person)(mail={login}))", cfg.LDAP.UserFilter)
+
+	cfg.Apps = map[string]AppConfig{"billing": {Authenticator: "ldap"}, "crm": {Authenticator: "kerberos"}}
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "apps.billing.authenticator: ldap is used but ldap.url is not set")
+	assert.ErrorContains(t, err, "apps.crm.authenticator:")
+
+	cfg.Apps = map[string]AppConfig{"billing": {Authenticator: "ldap"}}
+	cfg.LDAP.URL = "ldaps://dc.corp.example"
+	cfg.LDAP.StartTLS = true
+	cfg.LDAP.UserFilter = "(mail=alice)"
+	cfg.LDAP.GroupRoles = []GroupRoleConfig{{Group: "cn=admins,dc=corp", App: "billing"}}
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "ldap.start_tls:")
+	assert.ErrorContains(t, err, "ldap.base_dn:")
+	assert.ErrorContains(t, err, "ldap.user_filter:")
+	assert.ErrorContains(t, err, "ldap.group_roles[0]:")
+	assert.NotContains(t, err.Error(), "apps.billing")
+}
+
+func TestValidate_Passwordless(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "log", cfg.Mailer.Type)
+
+	cfg.Apps = map[string]AppConfig{
+		"shop": {Passwordless: AppPasswordlessConfig{Methods: []string{"link", "sms"}}},
+	}
+	cfg.Passwordless.MaxAttempts = 0
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "apps.shop.passwordless.methods: unknown method \"sms\"")
+	assert.ErrorContains(t, err, "apps.shop.passwordless.link_url:")
+	assert.ErrorContains(t, err, "passwordless.max_attempts:")
+
+	cfg.Apps["shop"] = AppConfig{Passwordless: AppPasswordlessConfig{Methods: []string{"code"}}}
+	cfg.Passwordless.MaxAttempts = 5
+	cfg.Env = envProd
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "mailer.type: log must not be used in prod")
+
+	cfg.Mailer = MailerConfig{Type: "smtp", From: "SSO <sso@example.com>", SMTP: SMTPConfig{Host: "smtp.example.com", Port: 587, Timeout: time.Second}}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Invitations(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 168*time.Hour, cfg.Organizations.InvitationTTL)
+
+	cfg.Apps = map[string]AppConfig{"shop": {InvitationURL: "/invite"}}
+	cfg.Organizations.InvitationTTL = 0
+	cfg.Env = envProd
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `apps.shop.invitation_url: must be an absolute URL, got "/invite"`)
+	assert.ErrorContains(t, err, "organizations.invitation_ttl:")
+	assert.ErrorContains(t, err, "mailer.type: log must not be used in prod")
+
+	cfg.Apps["shop"] = AppConfig{InvitationURL: "https://shop.example.com/invite"}
+	cfg.Organizations.InvitationTTL = time.Hour
+	cfg.Mailer = MailerConfig{Type: "smtp", From: "SSO <sso@example.com>", SMTP: SMTPConfig{Host: "smtp.example.com", Port: 587, Timeout: time.Second}}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_WebAuthn(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.WebAuthn.Timeout)
+
+	cfg.Apps = map[string]AppConfig{"admin": {MFA: "passkey"}, "shop": {MFA: "totp"}}
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "apps.admin.mfa: passkey is used but webauthn.rp_id is not set")
+	assert.ErrorContains(t, err, "apps.shop.mfa: unknown second factor \"totp\"")
+
+	cfg.Apps = map[string]AppConfig{"admin": {MFA: "passkey"}}
+	cfg.WebAuthn.RPID = "example.com"
+	cfg.WebAuthn.RPOrigins = []string{"https://admin.example.com", "https://evil.com"}
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "webauthn.rp_origins: \"https://evil.com\" is not within webauthn.rp_id")
+
+	cfg.WebAuthn.RPOrigins = cfg.WebAuthn.RPOrigins[:1]
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Clients(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, cfg.Clients.TokenTTL)
+	assert.Equal(t, 24*time.Hour, cfg.Clients.SecretGrace)
+
+	cfg.Clients.TokenTTL = 0
+	cfg.Clients.SecretGrace = -time.Minute
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "clients.token_ttl: must be positive")
+	assert.ErrorContains(t, err, "clients.secret_grace: must not be negative")
+}
+
+func TestValidate_AppScopes(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	cfg.Apps = map[string]AppConfig{"billing": {Scopes: map[string]string{
+		"invoices:read": "Просмотр счетов",
+		"bad scope":     "",
+	}}}
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `apps.billing.scopes: invalid scope name "bad scope"`)
+	assert.NotContains(t, err.Error(), "invoices:read")
+}
+
+func TestReload_OnlyReloadableFieldsApplied(t *testing.T) {
+	cur, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	next := *cur
+	next.LogLevel = "warn"
+	next.TokenTTL = 2 * time.Hour
+	next.GRPC.Port = 50056
+
+	assert.Equal(t, []string{"grpc"}, cur.NonReloadableChanges(&next))
+
+	merged := cur.WithReloadable(&next)
+	assert.Equal(t, "warn", merged.LogLevel)
+	assert.Equal(t, 2*time.Hour, merged.TokenTTL)
+	assert.Equal(t, 50055, merged.GRPC.Port)
+}
@@ -7,23 +7,51 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func SetupLogger(env string) *zap.SugaredLogger {
-	var log *zap.Logger
-	var err error
+// SetupLogger создает логгер для окружения env с уровнем, определяемым ParseLevel.
+// Возвращаемый AtomicLevel позволяет менять уровень без перезапуска.
+func SetupLogger(env string, level string) (*zap.SugaredLogger, zap.AtomicLevel) {
+	var cfg zap.Config
+	var opts []zap.Option
 
 	switch env {
 	case envLocal, envDevelop:
-		log, err = zap.NewDevelopment()
+		cfg = zap.NewDevelopmentConfig()
 	case envProd:
-		traceOpt := zap.AddStacktrace(zapcore.ErrorLevel)
-		log, err = zap.NewProduction(traceOpt)
+		cfg = zap.NewProductionConfig()
+		opts = append(opts, zap.AddStacktrace(zapcore.ErrorLevel))
 	default:
 		panic("unknown environment: " + env)
 	}
 
+	lvl, err := ParseLevel(env, level)
+	if err != nil {
+		panic(err.Error())
+	}
+	cfg.Level.SetLevel(lvl)
+
+	log, err := cfg.Build(opts...)
 	if err != nil || log == nil {
 		panic(fmt.Sprintf("failed to create logger: %v", err))
 	}
 
-	return log.Sugar()
+	return log.Sugar(), cfg.Level
+}
+
+// ParseLevel возвращает уровень логирования level, а если он не задан —
+// уровень по умолчанию для окружения env (debug для local/dev, info для prod).
+func ParseLevel(env string, level string) (zapcore.Level, error) {
+	if level != "" {
+		lvl, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return lvl, fmt.Errorf("invalid log level %q: %w", level, err)
+		}
+
+		return lvl, nil
+	}
+
+	if env == envProd {
+		return zapcore.InfoLevel, nil
+	}
+
+	return zapcore.DebugLevel, nil
 }
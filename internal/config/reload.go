@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// reloadable yaml-имена верхнеуровневых полей, которые применяются без перезапуска процесса.
+var reloadable = map[string]bool{
+	"log_level":  true,
+	"token_ttl":  true,
+	"apps":       true,
+	"rate_limit": true,
+	// passwordless применяется вместе с apps.<имя>.passwordless.
+	"passwordless": true,
+	"clients":      true,
+	// organizations применяется вместе с apps.<имя>.invitation_url.
+	"organizations": true,
+}
+
+// Reload заново читает конфигурацию из того же файла, из которого она была загружена.
+func (c *Config) Reload() (*Config, error) {
+	if c.path == "" {
+		return nil, errors.New("config was not loaded from file")
+	}
+
+	return Load(c.path)
+}
+
+// NonReloadableChanges возвращает yaml-имена измененных в next полей,
+// которые вступят в силу только после перезапуска процесса.
+func (c *Config) NonReloadableChanges(next *Config) []string {
+	cur, nxt := reflect.ValueOf(*c), reflect.ValueOf(*next)
+	t := cur.Type()
+
+	var changed []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if reloadable[name] {
+			continue
+		}
+
+		if !reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
+
+// WithReloadable возвращает копию конфигурации, в которой изменяемые без перезапуска
+// поля взяты из next, а остальные оставлены текущими.
+func (c *Config) WithReloadable(next *Config) *Config {
+	merged := *c
+
+	dst, src := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(*next)
+	t := dst.Type()
+
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if reloadable[name] {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+
+	return &merged
+}
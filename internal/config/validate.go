@@ -0,0 +1,382 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/lib/scope"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	minPort = 1
+	maxPort = 65535
+
+	minAdminTokenLen = 32
+)
+
+// Validate проверяет семантическую корректность конфигурации.
+// Возвращает все найденные ошибки сразу, объединенные через errors.Join.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.Env {
+	case envLocal, envDevelop, envProd:
+	default:
+		add("env: unknown environment %q (expected %s, %s or %s)", c.Env, envLocal, envDevelop, envProd)
+	}
+
+	if _, err := ParseLevel(c.Env, c.LogLevel); err != nil {
+		add("log_level: %w", err)
+	}
+
+	if c.AppServiceName == "" {
+		add("app_service_name: must not be empty")
+	}
+
+	if c.TokenTTL <= 0 {
+		add("token_ttl: must be positive, got %s", c.TokenTTL)
+	}
+
+	for name, app := range c.Apps {
+		if app.TokenTTL < 0 {
+			add("apps.%s.token_ttl: must not be negative, got %s", name, app.TokenTTL)
+		}
+		for claim := range app.Claims {
+			if jwt.ReservedClaims[claim] {
+				add("apps.%s.claims: %q is set by the service and cannot be overridden", name, claim)
+			}
+		}
+		for _, claim := range app.ProfileClaims {
+			if !jwt.ProfileClaims[claim] {
+				add("apps.%s.profile_claims: unknown claim %q (expected name, locale, zoneinfo or picture)", name, claim)
+			}
+		}
+		switch app.Authenticator {
+		case "", "local":
+		case "ldap":
+			if c.LDAP.URL == "" {
+				add("apps.%s.authenticator: ldap is used but ldap.url is not set", name)
+			}
+		default:
+			add("apps.%s.authenticator: unknown authenticator %q (expected local or ldap)", name, app.Authenticator)
+		}
+		validateAppPasswordless(add, name, app.Passwordless)
+		switch app.MFA {
+		case "":
+		case "passkey":
+			if c.WebAuthn.RPID == "" {
+				add("apps.%s.mfa: passkey is used but webauthn.rp_id is not set", name)
+			}
+		default:
+			add("apps.%s.mfa: unknown second factor %q (expected passkey)", name, app.MFA)
+		}
+		for s := range app.Scopes {
+			if !scope.Valid(s) {
+				add("apps.%s.scopes: invalid scope name %q", name, s)
+			}
+		}
+		if app.InvitationURL != "" {
+			if u, err := url.Parse(app.InvitationURL); err != nil || u.Scheme == "" || u.Host == "" {
+				add("apps.%s.invitation_url: must be an absolute URL, got %q", name, app.InvitationURL)
+			}
+		}
+	}
+
+	for method, rule := range c.RateLimit.Methods {
+		if rule.Rate <= 0 {
+			add("rate_limit.methods.%s.rate: must be positive, got %v", method, rule.Rate)
+		}
+		if rule.Burst < 1 {
+			add("rate_limit.methods.%s.burst: must be at least 1, got %d", method, rule.Burst)
+		}
+		switch rule.Key {
+		case "ip", "app", "email":
+		default:
+			add("rate_limit.methods.%s.key: unknown key %q (expected ip, app or email)", method, rule.Key)
+		}
+	}
+
+	if c.Users.Retention < 0 {
+		add("users.retention: must not be negative, got %s", c.Users.Retention)
+	}
+	if c.Users.PurgeInterval < 0 {
+		add("users.purge_interval: must not be negative, got %s", c.Users.PurgeInterval)
+	}
+
+	if c.passwordlessEnabled() {
+		validatePasswordless(add, c.Passwordless)
+	}
+	if c.invitationsEnabled() {
+		if c.Organizations.InvitationTTL <= 0 {
+			add("organizations.invitation_ttl: must be positive, got %s", c.Organizations.InvitationTTL)
+		}
+	}
+	if c.passwordlessEnabled() || c.invitationsEnabled() {
+		validateMailer(add, c.Env, c.Mailer)
+	}
+
+	validateWebAuthn(add, c.WebAuthn)
+	if c.Clients.TokenTTL <= 0 {
+		add("clients.token_ttl: must be positive, got %s", c.Clients.TokenTTL)
+	}
+	if c.Clients.SecretGrace < 0 {
+		add("clients.secret_grace: must not be negative, got %s", c.Clients.SecretGrace)
+	}
+	validateFederation(add, c.Federation)
+	validateLDAP(add, c.LDAP)
+	if _, ok := c.Federation.Providers["ldap"]; ok && c.LDAP.URL != "" {
+		add("federation.providers.ldap: name is reserved for the ldap directory")
+	}
+
+	validatePort(add, "grpc.port", c.GRPC.Port)
+	validatePort(add, "http.port", c.HTTP.Port)
+	if c.GRPC.Port == c.HTTP.Port {
+		add("http.port: must differ from grpc.port (%d)", c.GRPC.Port)
+	}
+
+	if c.HTTP.AdminToken != "" && len(c.HTTP.AdminToken) < minAdminTokenLen {
+		add("http.admin_token: must be at least %d characters long", minAdminTokenLen)
+	}
+
+	if c.GRPC.Timeout <= 0 {
+		add("grpc.timeout: must be positive, got %s", c.GRPC.Timeout)
+	}
+
+	if c.Health.Interval <= 0 {
+		add("health.interval: must be positive, got %s", c.Health.Interval)
+	}
+	if c.Health.Timeout <= 0 || c.Health.Timeout > c.Health.Interval {
+		add("health.timeout: must be positive and not exceed health.interval, got %s", c.Health.Timeout)
+	}
+
+	if c.Shutdown.DrainTimeout <= 0 {
+		add("shutdown.drain_timeout: must be positive, got %s", c.Shutdown.DrainTimeout)
+	}
+
+	validatePort(add, "psql.port", c.PSQL.Port)
+
+	if c.PSQL.Pool.MaxOpenConns < 0 {
+		add("psql.pool.max_open_conns: must not be negative")
+	}
+	if c.PSQL.Pool.MaxOpenConns > 0 && c.PSQL.Pool.MaxIdleConns > c.PSQL.Pool.MaxOpenConns {
+		add("psql.pool.max_idle_conns: must not exceed max_open_conns (%d)", c.PSQL.Pool.MaxOpenConns)
+	}
+	if c.PSQL.QueryTimeout <= 0 {
+		add("psql.query_timeout: must be positive, got %s", c.PSQL.QueryTimeout)
+	}
+
+	validateRetry(add, "psql.retry", c.PSQL.Retry)
+
+	if u, err := url.Parse(c.Vault.Addr); err != nil || u.Scheme == "" || u.Host == "" {
+		add("vault.addr: must be an absolute URL, got %q", c.Vault.Addr)
+	}
+	if c.Vault.Timeout <= 0 {
+		add("vault.timeout: must be positive, got %s", c.Vault.Timeout)
+	}
+	if c.Vault.KeyCacheTTL < 0 {
+		add("vault.key_cache_ttl: must not be negative")
+	}
+
+	validateRetry(add, "vault.retry", c.Vault.Retry)
+
+	if c.Vault.Breaker.FailureThreshold > 0 && c.Vault.Breaker.OpenTimeout <= 0 {
+		add("vault.breaker.open_timeout: must be positive when breaker is enabled")
+	}
+
+	return errors.Join(errs...)
+}
+
+// providerName имя провайдера входит в путь URL callback.
+var providerName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func validateFederation(add func(string, ...any), c FederationConfig) {
+	if len(c.Providers) == 0 {
+		return
+	}
+
+	if u, err := url.Parse(c.RedirectBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		add("federation.redirect_base_url: must be an absolute URL when providers are configured, got %q", c.RedirectBaseURL)
+	}
+	if c.StateTTL <= 0 {
+		add("federation.state_ttl: must be positive, got %s", c.StateTTL)
+	}
+
+	for name, p := range c.Providers {
+		if !providerName.MatchString(name) {
+			add("federation.providers.%s: name must match %s", name, providerName)
+		}
+
+		switch p.Type {
+		case "oidc":
+			if u, err := url.Parse(p.Issuer); err != nil || u.Scheme == "" || u.Host == "" {
+				add("federation.providers.%s.issuer: must be an absolute URL, got %q", name, p.Issuer)
+			}
+		case "github":
+		default:
+			add("federation.providers.%s.type: unknown type %q (expected oidc or github)", name, p.Type)
+		}
+
+		if p.ClientID == "" {
+			add("federation.providers.%s.client_id: must not be empty", name)
+		}
+	}
+}
+
+func validateLDAP(add func(string, ...any), c LDAPConfig) {
+	if c.URL == "" {
+		return
+	}
+
+	u, err := url.Parse(c.URL)
+	switch {
+	case err != nil || u.Host == "" || (u.Scheme != "ldap" && u.Scheme != "ldaps"):
+		add("ldap.url: must be ldap://host[:port] or ldaps://host[:port], got %q", c.URL)
+	case u.Scheme == "ldaps" && c.StartTLS:
+		add("ldap.start_tls: cannot be used with ldaps://")
+	}
+
+	if c.BaseDN == "" {
+		add("ldap.base_dn: must not be empty")
+	}
+	if !strings.Contains(c.UserFilter, "{login}") {
+		add("ldap.user_filter: must contain {login}, got %q", c.UserFilter)
+	}
+	if c.Timeout <= 0 {
+		add("ldap.timeout: must be positive, got %s", c.Timeout)
+	}
+
+	for i, gr := range c.GroupRoles {
+		if gr.Group == "" || gr.App == "" || gr.Role == "" {
+			add("ldap.group_roles[%d]: group, app and role must not be empty", i)
+		}
+	}
+}
+
+// passwordlessEnabled включен ли вход без пароля хотя бы в одном приложении.
+func (c *Config) passwordlessEnabled() bool {
+	for _, app := range c.Apps {
+		if len(app.Passwordless.Methods) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// invitationsEnabled можно ли приглашать в организации хотя бы одного приложения.
+func (c *Config) invitationsEnabled() bool {
+	for _, app := range c.Apps {
+		if app.InvitationURL != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateAppPasswordless(add func(string, ...any), name string, c AppPasswordlessConfig) {
+	link := false
+	for _, m := range c.Methods {
+		switch m {
+		case "link":
+			link = true
+		case "code":
+		default:
+			add("apps.%s.passwordless.methods: unknown method %q (expected link or code)", name, m)
+		}
+	}
+
+	if link {
+		if u, err := url.Parse(c.LinkURL); err != nil || u.Scheme == "" || u.Host == "" {
+			add("apps.%s.passwordless.link_url: must be an absolute URL when link method is enabled, got %q", name, c.LinkURL)
+		}
+	}
+}
+
+func validatePasswordless(add func(string, ...any), c PasswordlessConfig) {
+	if c.CodeTTL <= 0 {
+		add("passwordless.code_ttl: must be positive, got %s", c.CodeTTL)
+	}
+	if c.LinkTTL <= 0 {
+		add("passwordless.link_ttl: must be positive, got %s", c.LinkTTL)
+	}
+	if c.MaxAttempts < 1 {
+		add("passwordless.max_attempts: must be at least 1, got %d", c.MaxAttempts)
+	}
+	if c.SendLimit.Rate <= 0 {
+		add("passwordless.send_limit.rate: must be positive, got %v", c.SendLimit.Rate)
+	}
+	if c.SendLimit.Burst < 1 {
+		add("passwordless.send_limit.burst: must be at least 1, got %d", c.SendLimit.Burst)
+	}
+}
+
+func validateMailer(add func(string, ...any), env string, c MailerConfig) {
+	switch c.Type {
+	case "log":
+		if env == envProd {
+			add("mailer.type: log must not be used in %s: it writes sign-in codes to the log", envProd)
+		}
+	case "smtp":
+		if c.SMTP.Host == "" {
+			add("mailer.smtp.host: must not be empty")
+		}
+		validatePort(add, "mailer.smtp.port", c.SMTP.Port)
+		if c.SMTP.Timeout <= 0 {
+			add("mailer.smtp.timeout: must be positive, got %s", c.SMTP.Timeout)
+		}
+		if _, err := mail.ParseAddress(c.From); err != nil {
+			add("mailer.from: must be an email address, got %q", c.From)
+		}
+	default:
+		add("mailer.type: unknown type %q (expected smtp or log)", c.Type)
+	}
+}
+
+func validateWebAuthn(add func(string, ...any), c WebAuthnConfig) {
+	if c.RPID == "" {
+		return
+	}
+
+	if strings.Contains(c.RPID, "/") || strings.Contains(c.RPID, ":") {
+		add("webauthn.rp_id: must be a domain without scheme and port, got %q", c.RPID)
+	}
+	if len(c.RPOrigins) == 0 {
+		add("webauthn.rp_origins: must not be empty")
+	}
+	for _, origin := range c.RPOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			add("webauthn.rp_origins: must be an origin like https://host[:port], got %q", origin)
+			continue
+		}
+		if host := u.Hostname(); host != c.RPID && !strings.HasSuffix(host, "."+c.RPID) {
+			add("webauthn.rp_origins: %q is not within webauthn.rp_id %q", origin, c.RPID)
+		}
+	}
+	if c.Timeout <= 0 {
+		add("webauthn.timeout: must be positive, got %s", c.Timeout)
+	}
+}
+
+func validatePort(add func(string, ...any), name string, port int) {
+	if port < minPort || port > maxPort {
+		add("%s: must be in range %d-%d, got %d", name, minPort, maxPort, port)
+	}
+}
+
+func validateRetry(add func(string, ...any), name string, r RetryConfig) {
+	if r.Attempts < 1 {
+		add("%s.attempts: must be at least 1, got %d", name, r.Attempts)
+	}
+	if r.InitialBackoff < 0 || r.MaxBackoff < r.InitialBackoff {
+		add("%s: backoff must satisfy 0 <= initial_backoff <= max_backoff", name)
+	}
+}
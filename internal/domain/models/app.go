@@ -2,6 +2,8 @@ package models
 
 type App struct {
     ID     int
+    // Tenant арендатор, в котором зарегистрировано приложение.
+    Tenant string
     Name   string
     Secret string
 }
@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// AuditOutcome результат события аудита.
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// Типы событий аудита.
+const (
+	AuditRegister           = "register"
+	AuditLogin              = "login"
+	AuditSigningKey         = "signing_key"
+	AuditSigningKeyGenerate = "signing_key.generate"
+	AuditSessionRevoke      = "session.revoke"
+	AuditSessionRevokeAll   = "session.revoke_all"
+
+	AuditIntrospectClientAuth = "introspect.client_auth"
+
+	AuditFederatedLogin = "federation.login"
+	AuditIdentityLink   = "federation.link"
+	AuditIdentityUnlink = "federation.unlink"
+
+	AuditDirectoryProvision = "directory.provision"
+	AuditDirectoryRoleSync  = "directory.role_sync"
+
+	AuditPasswordlessStart = "passwordless.start"
+	AuditPasswordlessLogin = "passwordless.login"
+
+	AuditPasskeyRegister = "passkey.register"
+	AuditPasskeyLogin    = "passkey.login"
+	AuditPasskeyDelete   = "passkey.delete"
+
+	AuditClientToken = "client.token"
+
+	AuditConsentGrant  = "consent.grant"
+	AuditConsentRevoke = "consent.revoke"
+
+	AuditOrganizationCreate       = "organization.create"
+	AuditOrganizationInvite       = "organization.invite"
+	AuditOrganizationAccept       = "organization.accept"
+	AuditOrganizationRemoveMember = "organization.remove_member"
+
+	AuditUserCreate         = "admin.user.create"
+	AuditUserStatus         = "admin.user.status"
+	AuditUserResetPassword  = "admin.user.reset_password"
+	AuditUserUpdateProfile  = "admin.user.update_profile"
+	AuditUserExport         = "admin.user.export"
+	AuditUserDelete         = "admin.user.delete"
+	AuditUserPurge          = "admin.user.purge"
+	AuditUserUnlinkIdentity = "admin.user.unlink_identity"
+	AuditUserNormalizeEmail = "admin.user.normalize_email"
+	AuditAppCreate          = "admin.app.create"
+	AuditAppRotateKey       = "admin.app.rotate_key"
+	AuditAppDelete          = "admin.app.delete"
+	AuditClientCreate       = "admin.client.create"
+	AuditClientRotate       = "admin.client.rotate_secret"
+	AuditClientDelete       = "admin.client.delete"
+	AuditRoleAssign         = "admin.role.assign"
+	AuditRoleRevoke         = "admin.role.revoke"
+	AuditTenantCreate       = "admin.tenant.create"
+)
+
+// AuditEvent запись журнала аудита.
+type AuditEvent struct {
+	ID   int64
+	Time time.Time
+	Type string
+
+	Outcome AuditOutcome
+	// Reason причина неудачи.
+	Reason string
+
+	// Actor тот, кто выполнил действие: email пользователя или оператор ssoctl.
+	Actor string
+	// Subject над кем выполнено действие, например email пользователя.
+	Subject string
+	App     string
+
+	IP        string
+	UserAgent string
+
+	Details map[string]string
+}
+
+// AuditFilter условия выборки событий аудита.
+// Пустые поля не ограничивают выборку. События возвращаются от новых к старым.
+type AuditFilter struct {
+	Type    string
+	Outcome AuditOutcome
+	Actor   string
+	Subject string
+	App     string
+	Since   time.Time
+	Until   time.Time
+
+	// BeforeID возвращает только события с ID меньше заданного (для постраничной выборки).
+	BeforeID int64
+	Limit    int
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Client учетная запись сервиса (OAuth-клиент), получающего токены приложения
+// по client credentials без участия пользователя.
+type Client struct {
+	// ID идентификатор клиента (client_id); он же claim sub выпускаемых токенов.
+	ID    string
+	AppID int
+	// App имя приложения, для которого выпускаются токены клиента.
+	App string
+	// Tenant арендатор приложения.
+	Tenant string
+	Name   string
+	// Scopes области доступа, которые клиент может запросить.
+	Scopes []string
+
+	// SecretHash хэш текущего секрета.
+	SecretHash []byte
+	// PreviousSecretHash хэш секрета до последней ротации; он принимается до PreviousSecretExpiresAt.
+	PreviousSecretHash      []byte
+	PreviousSecretExpiresAt *time.Time
+
+	CreatedAt       time.Time
+	SecretRotatedAt *time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Consent согласие пользователя на области доступа приложения.
+type Consent struct {
+	UserUUID string
+	App      string
+	// Scopes области, на которые пользователь дал согласие.
+	Scopes []string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Identity учетная запись пользователя у внешнего провайдера (Google, GitHub и т.п.),
+// привязанная к локальному пользователю.
+type Identity struct {
+	Provider string
+	// Subject идентификатор пользователя у провайдера: claim sub или id в GitHub.
+	Subject  string
+	UserUUID string
+	// Email адрес, который сообщил провайдер при последнем входе.
+	Email string
+
+	CreatedAt time.Time
+	// LastLoginAt время последнего входа через провайдера; nil, если входа еще не было.
+	LastLoginAt *time.Time
+}
+
+// FederationState незавершенный вход или привязка учетной записи через внешнего провайдера.
+// Значение State передается провайдеру и возвращается им в callback.
+type FederationState struct {
+	State    string
+	Provider string
+	// App приложение, для которого выпускается токен после входа.
+	App string
+	// Tenant арендатор, в котором начат вход; заполняется хранилищем.
+	Tenant string
+	// Nonce связывает ID token провайдера с этим запросом.
+	Nonce string
+	// Verifier PKCE code verifier (RFC 7636).
+	Verifier string
+	// LinkUserUUID пользователь, к которому привязывается учетная запись; пустой при входе.
+	LinkUserUUID string
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Роли участников организации.
+const (
+	// OrganizationOwner управляет организацией и может назначать владельцев.
+	OrganizationOwner = "owner"
+	// OrganizationAdmin приглашает и удаляет участников, кроме владельцев.
+	OrganizationAdmin = "admin"
+	// OrganizationMember обычный участник.
+	OrganizationMember = "member"
+)
+
+// Organization организация — группа пользователей внутри приложения, например компания-клиент.
+type Organization struct {
+	ID string
+	// App приложение, которому принадлежит организация.
+	App       string
+	Name      string
+	CreatedAt time.Time
+}
+
+// Member участник организации.
+type Member struct {
+	OrganizationID string
+	UserUUID       string
+	Email          string
+	Role           string
+	CreatedAt      time.Time
+}
+
+// Invitation приглашение в организацию, отправленное на email.
+// Сам токен не хранится — только его хэш.
+type Invitation struct {
+	ID             string
+	OrganizationID string
+	// Tenant арендатор приложения организации.
+	Tenant string
+	Email  string
+	// Role роль, которую получит принявший приглашение.
+	Role      string
+	TokenHash []byte
+	// InvitedBy UUID пригласившего; пустой, если пригласил оператор.
+	InvitedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
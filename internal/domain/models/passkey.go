@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Passkey учетные данные WebAuthn (passkey или аппаратный ключ), зарегистрированные пользователем.
+type Passkey struct {
+	// ID идентификатор учетных данных, выданный аутентификатором.
+	ID       []byte
+	UserUUID string
+	// Name название, которое пользователь дал ключу.
+	Name string
+	// PublicKey открытый ключ в формате COSE.
+	PublicKey       []byte
+	AttestationType string
+	Transports      []string
+	// AAGUID модель аутентификатора.
+	AAGUID []byte
+	// SignCount последнее значение счетчика подписей; 0 — аутентификатор его не ведет.
+	SignCount uint32
+	// BackupEligible и BackupState ключ может синхронизироваться между устройствами и синхронизирован.
+	BackupEligible bool
+	BackupState    bool
+
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// Виды церемоний WebAuthn.
+const (
+	CeremonyRegistration = "registration"
+	// CeremonyLogin вход по passkey без пароля.
+	CeremonyLogin = "login"
+	// CeremonyMFA подтверждение входа по паролю вторым фактором.
+	CeremonyMFA = "mfa"
+)
+
+// WebAuthnCeremony незавершенная регистрация ключа или вход по нему.
+type WebAuthnCeremony struct {
+	ID   string
+	Kind string
+	// UserUUID пользователь; пустой при входе, когда пользователь определяется по ключу.
+	UserUUID string
+	// App приложение, для которого выпускается токен после входа.
+	App string
+	// Tenant арендатор, в котором начат вход; заполняется хранилищем.
+	Tenant string
+	// Scopes области доступа, запрошенные при входе по паролю, который подтверждается ключом.
+	Scopes []string
+	// Session состояние церемонии для проверки ответа аутентификатора (JSON).
+	Session []byte
+	// Options параметры для navigator.credentials в браузере (JSON).
+	Options   []byte
+	ExpiresAt time.Time
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Способы входа без пароля.
+const (
+	// PasswordlessLink одноразовая ссылка в письме.
+	PasswordlessLink = "link"
+	// PasswordlessCode шестизначный код в письме.
+	PasswordlessCode = "code"
+)
+
+// PasswordlessChallenge отправленная пользователю одноразовая ссылка или код для входа.
+// Сам секрет не хранится — только его хэш.
+type PasswordlessChallenge struct {
+	ID       string
+	UserUUID string
+	// App приложение, для которого выпускается токен после входа.
+	App        string
+	Method     string
+	SecretHash []byte
+	// Attempts число попыток ввести секрет, включая текущую.
+	Attempts  int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Role роль пользователя в приложении.
+type Role struct {
+	UserUUID  string
+	AppID     int
+	AppName   string
+	Role      string
+	CreatedAt time.Time
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Session сеанс пользователя, созданный при входе в приложение.
+// Идентификатор сеанса передается в токене в claim sid.
+type Session struct {
+	ID       string
+	UserUUID string
+	App      string
+	// Tenant арендатор пользователя и приложения сеанса.
+	Tenant string
+
+	Device    string
+	IP        string
+	UserAgent string
+
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	// RevokedAt время отзыва сеанса; nil, если сеанс не отозван.
+	RevokedAt *time.Time
+}
+
+// Active сообщает, что сеанс не отозван и не истек к моменту now.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
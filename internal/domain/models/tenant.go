@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Tenant арендатор — организация-клиент платформы со своими пользователями и приложениями.
+type Tenant struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
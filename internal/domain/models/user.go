@@ -1,7 +1,67 @@
 package models
 
+import "time"
+
+// UserStatus состояние учетной записи пользователя.
+type UserStatus string
+
+const (
+	UserStatusActive   UserStatus = "active"
+	UserStatusDisabled UserStatus = "disabled"
+	// UserStatusPending учетная запись создана, но еще не подтверждена.
+	UserStatusPending UserStatus = "pending"
+	// UserStatusDeleted учетная запись удалена и будет окончательно стерта после срока хранения.
+	UserStatusDeleted UserStatus = "deleted"
+)
+
 type User struct {
-	UUID     string
-	Email    string
-	PassHash []byte
+	UUID string
+	// Tenant арендатор пользователя; email уникален в его пределах.
+	Tenant    string
+	Email     string
+	PassHash  []byte
+	Status    UserStatus
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// DeletedAt время удаления; nil, если учетная запись не удалена.
+	DeletedAt *time.Time
+
+	Profile Profile
+}
+
+// Profile данные профиля пользователя.
+type Profile struct {
+	DisplayName string
+	// Locale языковой тег BCP 47, например ru-RU.
+	Locale string
+	// Timezone часовой пояс из базы IANA, например Europe/Moscow.
+	Timezone  string
+	AvatarURL string
+	// Metadata произвольные данные приложений.
+	Metadata map[string]any
+}
+
+// ProfileUpdate изменение профиля: nil-поля не меняются.
+type ProfileUpdate struct {
+	DisplayName *string
+	Locale      *string
+	Timezone    *string
+	AvatarURL   *string
+	// Metadata, если не nil, заменяет метаданные целиком.
+	Metadata map[string]any
+}
+
+// UserData все данные пользователя, которые хранит сервис (выгрузка по запросу субъекта данных).
+type UserData struct {
+	User       User
+	Roles      []Role
+	Sessions   []Session
+	Identities []Identity
+	Passkeys   []Passkey
+	Consents   []Consent
+	// Memberships участие пользователя в организациях.
+	Memberships []Member
+	// Invitations приглашения в организации, отправленные на email пользователя.
+	Invitations []Invitation
+	AuditEvents []AuditEvent
 }
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"go-sso/internal/lib/scope"
 	"go-sso/internal/services/auth"
 
 	vaultlib "go-sso/internal/lib/vault"
@@ -10,6 +11,7 @@ import (
 	gossov1 "github.com/passwordhash/protos/gen/go/go-sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -44,6 +46,19 @@ const (
 	emptyValue = 0
 )
 
+// mfaCeremonyHeader заголовок ответа Login с идентификатором церемонии второго фактора.
+const mfaCeremonyHeader = "mfa-ceremony-id"
+
+// Пока в контракте нет полей для областей доступа, они передаются в метаданных:
+// запрос Login — области через пробел и подтверждение согласия, ответ — области,
+// на которые нужно согласие.
+const (
+	scopeMetadata       = "scope"
+	consentMetadata     = "consent"
+	consentScopesHeader = "consent-scopes"
+	consentGrantedValue = "granted"
+)
+
 func (s *serverAPI) Register(ctx context.Context, req *gossov1.RegisterRequest,
 ) (*gossov1.RegisterResponse, error) {
 	if err := validateRegister(req); err != nil {
@@ -66,7 +81,26 @@ func (s *serverAPI) Login(ctx context.Context, req *gossov1.LoginRequest,
 		return nil, err
 	}
 
-	token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), req.GetAppName())
+	token, err := s.auth.Login(withScopeRequest(ctx), req.GetEmail(), req.GetPassword(), req.GetAppName())
+
+	// Пока в контракте нет поля для второго фактора, идентификатор церемонии
+	// передается в заголовке ответа.
+	var mfaErr *auth.MFARequiredError
+	if errors.As(err, &mfaErr) {
+		if herr := grpc.SetHeader(ctx, metadata.Pairs(mfaCeremonyHeader, mfaErr.CeremonyID)); herr != nil {
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		return nil, status.Error(codes.FailedPrecondition, auth.ErrMFARequired.Error())
+	}
+	var consentErr *auth.ConsentRequiredError
+	if errors.As(err, &consentErr) {
+		if herr := grpc.SetHeader(ctx, metadata.Pairs(consentScopesHeader, scope.Join(consentErr.Scopes))); herr != nil {
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		return nil, status.Error(codes.FailedPrecondition, auth.ErrConsentRequired.Error())
+	}
 	if serr := s.handleServiceErr(err); serr != nil {
 		return nil, serr
 	}
@@ -104,15 +138,49 @@ func (s *serverAPI) handleServiceErr(err error) error {
 		return status.Error(codes.NotFound, errors.Unwrap(err).Error())
 	case errors.Is(err, auth.ErrInvalidCredentials):
 		return status.Error(codes.Unauthenticated, errors.Unwrap(err).Error())
+	case errors.Is(err, auth.ErrInvalidEmail):
+		return status.Error(codes.InvalidArgument, errors.Unwrap(err).Error())
+	case errors.Is(err, auth.ErrUserInactive):
+		return status.Error(codes.PermissionDenied, errors.Unwrap(err).Error())
 	case errors.Is(err, auth.ErrInvalidAppID):
 		return status.Error(codes.NotFound, errors.Unwrap(err).Error())
+	case errors.Is(err, auth.ErrTenantNotFound):
+		return status.Error(codes.NotFound, errors.Unwrap(err).Error())
+	case errors.Is(err, auth.ErrInvalidScope):
+		return status.Error(codes.InvalidArgument, errors.Unwrap(err).Error())
 	case errors.Is(err, auth.ErrKeyNotFound):
 		return status.Error(codes.NotFound, errors.Unwrap(err).Error())
+	case errors.Is(err, auth.ErrUnavailable):
+		return status.Error(codes.Unavailable, "service temporarily unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
 	default:
 		return status.Error(codes.Internal, "internal error")
 	}
 }
 
+// withScopeRequest переносит запрошенные области доступа из метаданных запроса в контекст.
+func withScopeRequest(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	var req auth.ScopeRequest
+	for _, v := range md.Get(scopeMetadata) {
+		req.Scopes = append(req.Scopes, scope.Parse(v)...)
+	}
+	if len(req.Scopes) == 0 {
+		return ctx
+	}
+
+	if v := md.Get(consentMetadata); len(v) > 0 {
+		req.Consent = v[0] == consentGrantedValue
+	}
+
+	return auth.WithScopeRequest(ctx, req)
+}
+
 func validateLogin(req *gossov1.LoginRequest) error {
 	// TODO: add validate lib
 	if req.GetEmail() == "" {
@@ -0,0 +1,42 @@
+package interceptors
+
+import (
+	"context"
+	"go-sso/internal/lib/audit"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Client возвращает интерсептор, сохраняющий в контексте адрес, user agent и устройство
+// клиента (метаданные x-device) для журнала аудита и сеансов. Адрес берется из соединения: заголовкам прокси не доверяем.
+func Client() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		var c audit.Client
+
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			c.IP = p.Addr.String()
+			if host, _, err := net.SplitHostPort(c.IP); err == nil {
+				c.IP = host
+			}
+		}
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ua := md.Get("user-agent"); len(ua) > 0 {
+				c.UserAgent = ua[0]
+			}
+			if d := md.Get("x-device"); len(d) > 0 {
+				c.Device = d[0]
+			}
+		}
+
+		return handler(audit.WithClient(ctx, c), req)
+	}
+}
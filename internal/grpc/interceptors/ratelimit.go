@@ -0,0 +1,119 @@
+package interceptors
+
+import (
+	"context"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/ratelimit"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Ключи, по которым считаются лимиты.
+const (
+	RateLimitByIP    = "ip"
+	RateLimitByApp   = "app"
+	RateLimitByEmail = "email"
+)
+
+// RateLimitRule лимит одного RPC-метода.
+type RateLimitRule struct {
+	ratelimit.Limit
+	// Key по чему считать запросы: ip, app или email.
+	Key string
+}
+
+// RateLimiter ограничивает частоту вызовов RPC-методов. Правила можно заменить
+// на лету через SetRules.
+type RateLimiter struct {
+	log     *zap.SugaredLogger
+	limiter ratelimit.Limiter
+	rules   atomic.Pointer[map[string]RateLimitRule]
+}
+
+// NewRateLimiter создает RateLimiter. Ключ rules — полное имя метода
+// (/go_sso.v1.Auth/Login) или только его имя (Login).
+func NewRateLimiter(log *zap.SugaredLogger, limiter ratelimit.Limiter, rules map[string]RateLimitRule) *RateLimiter {
+	r := &RateLimiter{log: log, limiter: limiter}
+	r.SetRules(rules)
+
+	return r
+}
+
+// SetRules заменяет правила ограничения.
+func (r *RateLimiter) SetRules(rules map[string]RateLimitRule) {
+	r.rules.Store(&rules)
+}
+
+// Unary возвращает интерсептор, отклоняющий запросы сверх лимита с кодом ResourceExhausted
+// и заголовком retry-after (секунды). Должен стоять после Client: ключ ip берется из контекста.
+// Если хранилище лимитов недоступно, запрос пропускается.
+func (r *RateLimiter) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		const op = "interceptors.RateLimiter"
+
+		rule, ok := r.rule(info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		key := info.FullMethod + ":" + rateLimitKey(ctx, req, rule.Key)
+
+		allowed, retryAfter, err := r.limiter.Allow(ctx, key, rule.Limit)
+		if err != nil {
+			r.log.With("op", op, "method", info.FullMethod).Warnw("rate limiter is unavailable", "error", err)
+
+			return handler(ctx, req)
+		}
+		if allowed {
+			return handler(ctx, req)
+		}
+
+		seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", seconds))
+
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %ss", seconds)
+	}
+}
+
+func (r *RateLimiter) rule(fullMethod string) (RateLimitRule, bool) {
+	rules := *r.rules.Load()
+
+	if rule, ok := rules[fullMethod]; ok {
+		return rule, true
+	}
+
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	rule, ok := rules[name]
+
+	return rule, ok
+}
+
+// rateLimitKey возвращает значение ключа запроса. Если в запросе нет нужного поля,
+// лимит считается по адресу клиента.
+func rateLimitKey(ctx context.Context, req any, key string) string {
+	switch key {
+	case RateLimitByApp:
+		if r, ok := req.(interface{ GetAppName() string }); ok && r.GetAppName() != "" {
+			return "app:" + r.GetAppName()
+		}
+	case RateLimitByEmail:
+		if r, ok := req.(interface{ GetEmail() string }); ok && r.GetEmail() != "" {
+			return "email:" + strings.ToLower(r.GetEmail())
+		}
+	}
+
+	return "ip:" + audit.ClientFrom(ctx).IP
+}
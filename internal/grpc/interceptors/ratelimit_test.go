@@ -0,0 +1,50 @@
+package interceptors
+
+import (
+	"context"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/ratelimit"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type emailRequest struct{ email string }
+
+func (r emailRequest) GetEmail() string { return r.email }
+
+func TestRateLimiter_Unary(t *testing.T) {
+	r := NewRateLimiter(zap.NewNop().Sugar(), ratelimit.NewMemory(), map[string]RateLimitRule{
+		"Login": {Limit: ratelimit.Limit{Rate: 0.1, Burst: 1}, Key: RateLimitByEmail},
+	})
+	interceptor := r.Unary()
+
+	ctx := audit.WithClient(context.Background(), audit.Client{IP: "10.0.0.1"})
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	call := func(method string, req any) error {
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	const login = "/go_sso.v1.Auth/Login"
+
+	assert.NoError(t, call(login, emailRequest{"a@example.com"}))
+
+	err := call(login, emailRequest{"A@example.com"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Лимит считается отдельно для каждого email.
+	assert.NoError(t, call(login, emailRequest{"b@example.com"}))
+
+	// Методы без правил не ограничиваются.
+	for range 3 {
+		assert.NoError(t, call("/go_sso.v1.Auth/Register", emailRequest{"a@example.com"}))
+	}
+
+	r.SetRules(nil)
+	assert.NoError(t, call(login, emailRequest{"a@example.com"}))
+}
@@ -0,0 +1,34 @@
+package interceptors
+
+import (
+	"context"
+	"go-sso/internal/lib/tenant"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Tenant возвращает интерсептор, сохраняющий в контексте арендатора из метаданных x-tenant.
+// Без метаданных запрос выполняется в арендаторе по умолчанию.
+func Tenant() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		t := md.Get("x-tenant")
+		if len(t) == 0 || t[0] == "" {
+			return handler(ctx, req)
+		}
+		if !tenant.Valid(t[0]) {
+			return nil, status.Error(codes.InvalidArgument, "invalid tenant")
+		}
+
+		return handler(tenant.With(ctx, t[0]), req)
+	}
+}
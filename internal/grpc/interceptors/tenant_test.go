@@ -0,0 +1,34 @@
+package interceptors
+
+import (
+	"context"
+	"go-sso/internal/lib/tenant"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTenant(t *testing.T) {
+	interceptor := Tenant()
+	handler := func(ctx context.Context, _ any) (any, error) { return tenant.From(ctx), nil }
+	call := func(md metadata.MD) (any, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		return interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	}
+
+	got, err := call(metadata.Pairs("x-tenant", "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", got)
+
+	got, err = call(metadata.MD{})
+	require.NoError(t, err)
+	assert.Equal(t, tenant.Default, got)
+
+	_, err = call(metadata.Pairs("x-tenant", "../clients"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
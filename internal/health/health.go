@@ -0,0 +1,202 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check проверка одной зависимости сервиса.
+type Check struct {
+	// Name имя зависимости, например "postgres". Статус зависимости публикуется
+	// в gRPC health как "<service>.<name>".
+	Name  string
+	Probe func(ctx context.Context) error
+}
+
+// Checker периодически проверяет зависимости сервиса и публикует их состояние
+// в gRPC health-сервере и через HTTP-обработчики /livez и /readyz.
+type Checker struct {
+	log      *zap.SugaredLogger
+	server   *health.Server
+	service  string
+	interval time.Duration
+	timeout  time.Duration
+	checks   []Check
+
+	mu           sync.RWMutex
+	errs         map[string]error
+	shuttingDown bool
+}
+
+// New возвращает новый Checker. До первой проверки сервис считается неготовым.
+func New(
+	log *zap.SugaredLogger,
+	server *health.Server,
+	service string,
+	interval time.Duration,
+	timeout time.Duration,
+	checks ...Check,
+) *Checker {
+	c := &Checker{
+		log:      log,
+		server:   server,
+		service:  service,
+		interval: interval,
+		timeout:  timeout,
+		checks:   checks,
+		errs:     make(map[string]error, len(checks)),
+	}
+
+	c.setStatus(service, false)
+	c.setStatus("", false)
+
+	return c
+}
+
+// Run проверяет зависимости сразу и затем с заданным интервалом, пока ctx не отменен.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown переводит все сервисы в NOT_SERVING и перестает обновлять их статус.
+// Вызывается в начале graceful shutdown, чтобы балансировщики перестали слать трафик.
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	c.shuttingDown = true
+	c.mu.Unlock()
+
+	c.server.Shutdown()
+}
+
+// Ready сообщает, готов ли сервис принимать трафик, и ошибки по зависимостям.
+func (c *Checker) Ready() (bool, map[string]string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ready := !c.shuttingDown
+	deps := make(map[string]string, len(c.checks))
+
+	for _, check := range c.checks {
+		err, probed := c.errs[check.Name]
+		switch {
+		case !probed:
+			ready = false
+			deps[check.Name] = "unknown"
+		case err != nil:
+			ready = false
+			deps[check.Name] = err.Error()
+		default:
+			deps[check.Name] = "ok"
+		}
+	}
+
+	return ready, deps
+}
+
+// LivezHandler отвечает 200, пока процесс жив. Зависимости не проверяются,
+// чтобы их недоступность не приводила к перезапуску процесса.
+func (c *Checker) LivezHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// ReadyzHandler отвечает 200, если все зависимости доступны, и 503 в остальных случаях.
+func (c *Checker) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		ready, deps := c.Ready()
+
+		code, status := http.StatusOK, "ok"
+		if !ready {
+			code, status = http.StatusServiceUnavailable, "unavailable"
+		}
+
+		writeJSON(w, code, map[string]any{
+			"status": status,
+			"checks": deps,
+		})
+	})
+}
+
+func (c *Checker) probe(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	results := make([]error, len(c.checks))
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			results[i] = check.Probe(probeCtx)
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shuttingDown {
+		return
+	}
+
+	allOK := true
+	for i, check := range c.checks {
+		err := results[i]
+
+		if prev, probed := c.errs[check.Name]; !probed || (prev == nil) != (err == nil) {
+			if err != nil {
+				c.log.Warnw("dependency is unhealthy", "dependency", check.Name, "error", err)
+			} else {
+				c.log.Infow("dependency is healthy", "dependency", check.Name)
+			}
+		}
+
+		c.errs[check.Name] = err
+		c.setStatus(c.service+"."+check.Name, err == nil)
+
+		allOK = allOK && err == nil
+	}
+
+	c.setStatus(c.service, allOK)
+	c.setStatus("", allOK)
+}
+
+func (c *Checker) setStatus(service string, serving bool) {
+	status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+
+	c.server.SetServingStatus(service, status)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func servingStatus(t *testing.T, s *health.Server, service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	resp, err := s.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+
+	return resp.GetStatus()
+}
+
+func TestChecker_Probe(t *testing.T) {
+	server := health.NewServer()
+
+	var vaultErr error
+	c := New(zap.NewNop().Sugar(), server, "sso", time.Minute, time.Second,
+		Check{Name: "postgres", Probe: func(context.Context) error { return nil }},
+		Check{Name: "vault", Probe: func(context.Context) error { return vaultErr }},
+	)
+
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, servingStatus(t, server, "sso"))
+
+	c.probe(context.Background())
+
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, servingStatus(t, server, "sso"))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, servingStatus(t, server, "sso.vault"))
+
+	vaultErr = errors.New("sealed")
+	c.probe(context.Background())
+
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, servingStatus(t, server, "sso"))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, servingStatus(t, server, "sso.postgres"))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, servingStatus(t, server, "sso.vault"))
+
+	rec := httptest.NewRecorder()
+	c.ReadyzHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "sealed")
+}
+
+func TestChecker_Shutdown(t *testing.T) {
+	server := health.NewServer()
+
+	c := New(zap.NewNop().Sugar(), server, "sso", time.Minute, time.Second,
+		Check{Name: "postgres", Probe: func(context.Context) error { return nil }},
+	)
+
+	c.probe(context.Background())
+	ready, _ := c.Ready()
+	require.True(t, ready)
+
+	c.Shutdown()
+	c.probe(context.Background())
+
+	ready, _ = c.Ready()
+	assert.False(t, ready)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, servingStatus(t, server, "sso"))
+
+	rec := httptest.NewRecorder()
+	c.LivezHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
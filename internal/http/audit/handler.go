@@ -0,0 +1,154 @@
+// Package audit предоставляет HTTP API для выборки журнала аудита.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Querier выбирает события аудита по фильтру.
+type Querier interface {
+	AuditEvents(ctx context.Context, f models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+type event struct {
+	ID        int64             `json:"id"`
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"event"`
+	Outcome   string            `json:"outcome"`
+	Reason    string            `json:"reason,omitempty"`
+	Actor     string            `json:"actor,omitempty"`
+	Subject   string            `json:"subject,omitempty"`
+	App       string            `json:"app,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+type response struct {
+	Events []event `json:"events"`
+	// NextPageToken передается в page_token для получения следующей страницы.
+	// Пустой, если страниц больше нет.
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// Handler отвечает на GET-запросы с фильтрами event, outcome, actor, subject, app,
+// since и until (RFC 3339), размером страницы limit и токеном страницы page_token.
+func Handler(log *zap.SugaredLogger, q Querier) http.Handler {
+	const op = "http.audit.Handler"
+
+	log = log.With("op", op)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := ParseFilter(r.URL.Query())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		events, err := q.AuditEvents(r.Context(), f)
+		if err != nil {
+			log.Errorw("failed to query audit events", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+
+		resp := response{Events: make([]event, 0, len(events))}
+		for _, e := range events {
+			resp.Events = append(resp.Events, event{
+				ID:        e.ID,
+				Time:      e.Time,
+				Type:      e.Type,
+				Outcome:   string(e.Outcome),
+				Reason:    e.Reason,
+				Actor:     e.Actor,
+				Subject:   e.Subject,
+				App:       e.App,
+				IP:        e.IP,
+				UserAgent: e.UserAgent,
+				Details:   e.Details,
+			})
+		}
+		if len(events) == f.Limit {
+			resp.NextPageToken = strconv.FormatInt(events[len(events)-1].ID, 10)
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+// ParseFilter разбирает параметры запроса в фильтр аудита.
+func ParseFilter(v url.Values) (models.AuditFilter, error) {
+	f := models.AuditFilter{
+		Type:    v.Get("event"),
+		Outcome: models.AuditOutcome(v.Get("outcome")),
+		Actor:   v.Get("actor"),
+		Subject: v.Get("subject"),
+		App:     v.Get("app"),
+		Limit:   defaultLimit,
+	}
+
+	switch f.Outcome {
+	case "", models.AuditSuccess, models.AuditFailure:
+	default:
+		return f, fmt.Errorf("outcome must be %s or %s", models.AuditSuccess, models.AuditFailure)
+	}
+
+	var err error
+	if f.Since, err = parseTime(v, "since"); err != nil {
+		return f, err
+	}
+	if f.Until, err = parseTime(v, "until"); err != nil {
+		return f, err
+	}
+
+	if s := v.Get("limit"); s != "" {
+		f.Limit, err = strconv.Atoi(s)
+		if err != nil || f.Limit <= 0 || f.Limit > maxLimit {
+			return f, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+
+	if s := v.Get("page_token"); s != "" {
+		f.BeforeID, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || f.BeforeID <= 0 {
+			return f, errors.New("invalid page_token")
+		}
+	}
+
+	return f, nil
+}
+
+func parseTime(v url.Values, key string) (time.Time, error) {
+	s := v.Get(key)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", key)
+	}
+
+	return t, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
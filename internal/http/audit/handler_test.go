@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"go-sso/internal/domain/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeQuerier struct {
+	filter models.AuditFilter
+	events []models.AuditEvent
+}
+
+func (q *fakeQuerier) AuditEvents(_ context.Context, f models.AuditFilter) ([]models.AuditEvent, error) {
+	q.filter = f
+	return q.events, nil
+}
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter(url.Values{
+		"event":      {models.AuditLogin},
+		"outcome":    {"failure"},
+		"since":      {"2025-01-01T00:00:00Z"},
+		"limit":      {"2"},
+		"page_token": {"17"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.AuditFilter{
+		Type:     models.AuditLogin,
+		Outcome:  models.AuditFailure,
+		Since:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		BeforeID: 17,
+		Limit:    2,
+	}, f)
+}
+
+func TestParseFilterRejectsInvalid(t *testing.T) {
+	for _, v := range []url.Values{
+		{"outcome": {"maybe"}},
+		{"since": {"yesterday"}},
+		{"limit": {"0"}},
+		{"limit": {"100000"}},
+		{"page_token": {"abc"}},
+	} {
+		_, err := ParseFilter(v)
+		assert.Error(t, err, v.Encode())
+	}
+}
+
+func TestHandlerPaginates(t *testing.T) {
+	q := &fakeQuerier{events: []models.AuditEvent{
+		{ID: 9, Type: models.AuditLogin, Outcome: models.AuditSuccess},
+		{ID: 5, Type: models.AuditLogin, Outcome: models.AuditFailure, Reason: "wrong password"},
+	}}
+
+	rec := httptest.NewRecorder()
+	Handler(zap.NewNop().Sugar(), q).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?limit=2", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Events, 2)
+	assert.Equal(t, "5", resp.NextPageToken)
+	assert.Equal(t, 2, q.filter.Limit)
+}
@@ -0,0 +1,112 @@
+// Package consent предоставляет HTTP API согласий пользователя на области доступа приложений.
+package consent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/http/middleware"
+	sessionhttp "go-sso/internal/http/session"
+	consentsvc "go-sso/internal/services/consent"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Service сервис согласий.
+type Service interface {
+	Scopes(appName string) []consentsvc.Scope
+	List(ctx context.Context, userUUID string) ([]consentsvc.Grant, error)
+	Revoke(ctx context.Context, userUUID, appName string) (revokedSessions int64, err error)
+}
+
+type scope struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type grant struct {
+	App       string    `json:"app"`
+	Scopes    []scope   `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Register добавляет в mux обработчики согласий:
+//
+//	GET /v1/apps/{app}/scopes — области приложения с описаниями для экрана согласия
+//
+// Запросы ниже аутентифицируются токеном пользователя в заголовке "Authorization: Bearer <token>":
+//
+//	GET    /v1/consents        — согласия пользователя
+//	DELETE /v1/consents/{app}  — отозвать согласие и сеансы в приложении → {revoked_sessions}
+func Register(mux *http.ServeMux, log *zap.SugaredLogger, svc Service, sessions sessionhttp.Authenticator) {
+	const op = "http.consent.Register"
+
+	log = log.With("op", op)
+
+	mux.Handle("GET /v1/apps/{app}/scopes", middleware.Client(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"scopes": toScopes(svc.Scopes(r.PathValue("app")))})
+	})))
+
+	mux.Handle("GET /v1/consents", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		grants, err := svc.List(r.Context(), current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		out := make([]grant, 0, len(grants))
+		for _, g := range grants {
+			out = append(out, grant{
+				App:       g.App,
+				Scopes:    toScopes(g.Scopes),
+				CreatedAt: g.CreatedAt,
+				UpdatedAt: g.UpdatedAt,
+			})
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"consents": out})
+	}))
+
+	mux.Handle("DELETE /v1/consents/{app}", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		n, err := svc.Revoke(r.Context(), current.UserUUID, r.PathValue("app"))
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]int64{"revoked_sessions": n})
+	}))
+}
+
+func toScopes(scopes []consentsvc.Scope) []scope {
+	out := make([]scope, 0, len(scopes))
+	for _, s := range scopes {
+		out = append(out, scope{Name: s.Name, Description: s.Description})
+	}
+	return out
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	switch {
+	case errors.Is(err, consentsvc.ErrConsentNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "consent not found"})
+	case errors.Is(err, consentsvc.ErrUnavailable):
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "service temporarily unavailable"})
+	default:
+		log.Errorw("consent request failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,185 @@
+// Package federation предоставляет HTTP API входа через внешних провайдеров identity.
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/http/middleware"
+	sessionhttp "go-sso/internal/http/session"
+	federationsvc "go-sso/internal/services/federation"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Service сервис федеративного входа.
+type Service interface {
+	Providers() []string
+	Begin(ctx context.Context, provider, appName string) (string, error)
+	BeginLink(ctx context.Context, provider, userUUID string) (string, error)
+	Complete(ctx context.Context, provider, state, code string) (federationsvc.Result, error)
+	Identities(ctx context.Context, userUUID string) ([]models.Identity, error)
+	Unlink(ctx context.Context, userUUID, provider string) error
+}
+
+type identity struct {
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// Register добавляет в mux обработчики входа через внешних провайдеров:
+//
+//	GET    /v1/federation/providers             — настроенные провайдеры
+//	GET    /v1/federation/{provider}/login?app= — перенаправление на вход у провайдера
+//	GET    /v1/federation/{provider}/callback   — возврат от провайдера: токен или итог привязки
+//
+// Запросы ниже аутентифицируются токеном пользователя в заголовке "Authorization: Bearer <token>":
+//
+//	POST   /v1/federation/{provider}/link — адрес привязки учетной записи провайдера
+//	DELETE /v1/federation/{provider}/link — отвязать учетную запись провайдера
+//	GET    /v1/federation/identities      — привязанные учетные записи
+func Register(mux *http.ServeMux, log *zap.SugaredLogger, svc Service, sessions sessionhttp.Authenticator) {
+	const op = "http.federation.Register"
+
+	log = log.With("op", op)
+
+	mux.HandleFunc("GET /v1/federation/providers", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"providers": svc.Providers()})
+	})
+
+	mux.HandleFunc("GET /v1/federation/{provider}/login", func(w http.ResponseWriter, r *http.Request) {
+		app := r.URL.Query().Get("app")
+		if app == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "app is required"})
+			return
+		}
+
+		authURL, err := svc.Begin(r.Context(), r.PathValue("provider"), app)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		http.Redirect(w, r, authURL, http.StatusFound)
+	})
+
+	mux.Handle("GET /v1/federation/{provider}/callback", middleware.Client(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		// Пользователь отказался от входа или провайдер не смог его выполнить.
+		if e := q.Get("error"); e != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "identity provider returned " + e})
+			return
+		}
+		if q.Get("state") == "" || q.Get("code") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "state and code are required"})
+			return
+		}
+
+		res, err := svc.Complete(r.Context(), r.PathValue("provider"), q.Get("state"), q.Get("code"))
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		if res.Linked {
+			writeJSON(w, http.StatusOK, map[string]any{"user_uuid": res.UserUUID, "linked": true})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"token":     res.Token,
+			"user_uuid": res.UserUUID,
+			"created":   res.Created,
+		})
+	})))
+
+	mux.Handle("POST /v1/federation/{provider}/link", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		authURL, err := svc.BeginLink(r.Context(), r.PathValue("provider"), current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"url": authURL})
+	}))
+
+	mux.Handle("DELETE /v1/federation/{provider}/link", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		if err := svc.Unlink(r.Context(), current.UserUUID, r.PathValue("provider")); err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mux.Handle("GET /v1/federation/identities", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		identities, err := svc.Identities(r.Context(), current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		out := make([]identity, 0, len(identities))
+		for _, i := range identities {
+			out = append(out, identity{
+				Provider:    i.Provider,
+				Email:       i.Email,
+				CreatedAt:   i.CreatedAt,
+				LastLoginAt: i.LastLoginAt,
+			})
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"identities": out})
+	}))
+}
+
+// errorStatus коды ответа на ошибки сервиса; в ответ пишется текст самой ошибки сервиса.
+var errorStatus = []struct {
+	err  error
+	code int
+}{
+	{federationsvc.ErrInvalidState, http.StatusBadRequest},
+	{federationsvc.ErrInvalidApp, http.StatusBadRequest},
+	{federationsvc.ErrInvalidGrant, http.StatusBadRequest},
+	{federationsvc.ErrUnknownProvider, http.StatusNotFound},
+	{federationsvc.ErrIdentityNotFound, http.StatusNotFound},
+	{federationsvc.ErrNotLinked, http.StatusForbidden},
+	{federationsvc.ErrUserInactive, http.StatusForbidden},
+	{federationsvc.ErrIdentityExists, http.StatusConflict},
+	{federationsvc.ErrLastIdentity, http.StatusConflict},
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	for _, e := range errorStatus {
+		if errors.Is(err, e.err) {
+			writeJSON(w, e.code, map[string]string{"error": e.err.Error()})
+			return
+		}
+	}
+
+	if errors.Is(err, federationsvc.ErrUnavailable) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "service temporarily unavailable"})
+		return
+	}
+
+	log.Errorw("federation request failed", "error", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,126 @@
+// Package introspect предоставляет HTTP-эндпоинт интроспекции токенов (RFC 7662).
+package introspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/services/introspection"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// Service сервис интроспекции.
+type Service interface {
+	AuthenticateApp(ctx context.Context, appName, secret string) error
+	Introspect(ctx context.Context, callerApp, token string) (*jwt.Claims, error)
+}
+
+// Handler обрабатывает POST-запросы с формой "token=<token>". Вызывающее приложение
+// аутентифицируется по HTTP Basic: имя приложения и его ключ подписи.
+func Handler(log *zap.SugaredLogger, svc Service) http.Handler {
+	const op = "http.introspect.Handler"
+
+	log = log.With("op", op)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app, secret, ok := r.BasicAuth()
+		if !ok {
+			unauthorized(w)
+			return
+		}
+
+		if err := svc.AuthenticateApp(r.Context(), app, secret); err != nil {
+			if errors.Is(err, introspection.ErrInvalidClient) {
+				unauthorized(w)
+				return
+			}
+
+			writeError(w, log, err)
+			return
+		}
+
+		token := r.PostFormValue("token")
+		if token == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error":             "invalid_request",
+				"error_description": "token is required",
+			})
+			return
+		}
+
+		claims, err := svc.Introspect(r.Context(), app, token)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, response(claims))
+	})
+}
+
+// response формирует ответ RFC 7662. Для неактивного токена возвращается только active=false.
+func response(c *jwt.Claims) map[string]any {
+	if c == nil {
+		return map[string]any{"active": false}
+	}
+
+	resp := make(map[string]any, len(c.Custom)+12)
+	for k, v := range c.Custom {
+		resp[k] = v
+	}
+
+	resp["active"] = true
+	resp["token_type"] = "Bearer"
+	resp["sub"] = c.Subject
+	resp["aud"] = c.Audience
+	resp["iss"] = c.Issuer
+	resp["jti"] = c.ID
+	if c.Tenant != "" {
+		resp["tid"] = c.Tenant
+	}
+	if c.ClientID != "" {
+		// Токен клиента, выпущенный по client credentials: пользователя и сеанса у него нет.
+		resp["client_id"] = c.ClientID
+		resp["scope"] = c.Scope
+	} else {
+		resp["client_id"] = c.App
+		resp["username"] = c.Email
+		resp["sid"] = c.SessionID
+	}
+	if c.ExpiresAt != nil {
+		resp["exp"] = c.ExpiresAt.Unix()
+	}
+	if c.IssuedAt != nil {
+		resp["iat"] = c.IssuedAt.Unix()
+	}
+	if c.NotBefore != nil {
+		resp["nbf"] = c.NotBefore.Unix()
+	}
+
+	return resp
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="introspect"`)
+	writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	if errors.Is(err, introspection.ErrUnavailable) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "temporarily_unavailable"})
+		return
+	}
+
+	log.Errorw("introspection failed", "error", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,94 @@
+package introspect
+
+import (
+	"context"
+	"encoding/json"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/services/introspection"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeService struct{}
+
+func (fakeService) AuthenticateApp(_ context.Context, app, secret string) error {
+	if app != "billing" || secret != "key" {
+		return introspection.ErrInvalidClient
+	}
+	return nil
+}
+
+func (fakeService) Introspect(_ context.Context, _ string, token string) (*jwt.Claims, error) {
+	if token == "client" {
+		return &jwt.Claims{
+			RegisteredClaims: gojwt.RegisteredClaims{Subject: "c1"},
+			App:              "billing",
+			ClientID:         "c1",
+			Scope:            "invoices:read",
+		}, nil
+	}
+	if token != "good" {
+		return nil, nil
+	}
+
+	return &jwt.Claims{
+		RegisteredClaims: gojwt.RegisteredClaims{
+			Subject:   "u1",
+			ExpiresAt: gojwt.NewNumericDate(time.Unix(2000000000, 0)),
+		},
+		Email:  "user@example.com",
+		App:    "billing",
+		Custom: map[string]any{"tenant": "acme"},
+	}, nil
+}
+
+func introspect(t *testing.T, user, pass, token string) (int, map[string]any) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(user, pass)
+
+	rec := httptest.NewRecorder()
+	Handler(zap.NewNop().Sugar(), fakeService{}).ServeHTTP(rec, req)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+	return rec.Code, body
+}
+
+func TestHandler(t *testing.T) {
+	code, body := introspect(t, "billing", "key", "good")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, "u1", body["sub"])
+	assert.Equal(t, "billing", body["client_id"])
+	assert.Equal(t, "user@example.com", body["username"])
+	assert.Equal(t, "acme", body["tenant"])
+	assert.Equal(t, float64(2000000000), body["exp"])
+
+	code, body = introspect(t, "billing", "key", "client")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "c1", body["client_id"])
+	assert.Equal(t, "invoices:read", body["scope"])
+	assert.NotContains(t, body, "username")
+	assert.NotContains(t, body, "sid")
+
+	code, body = introspect(t, "billing", "key", "bad")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]any{"active": false}, body)
+
+	code, body = introspect(t, "billing", "wrong", "good")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "invalid_client", body["error"])
+}
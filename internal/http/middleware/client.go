@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"go-sso/internal/lib/audit"
+	"net"
+	"net/http"
+)
+
+// Client сохраняет в контексте запроса адрес, user agent и устройство (заголовок X-Device)
+// клиента для журнала аудита и сеансов. Адрес берется из соединения: заголовкам прокси не доверяем.
+func Client(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := audit.Client{
+			IP:        r.RemoteAddr,
+			UserAgent: r.UserAgent(),
+			Device:    r.Header.Get("X-Device"),
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			c.IP = host
+		}
+
+		next.ServeHTTP(w, r.WithContext(audit.WithClient(r.Context(), c)))
+	})
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"go-sso/internal/lib/tenant"
+	"net/http"
+)
+
+// Tenant сохраняет в контексте запроса арендатора из заголовка X-Tenant.
+// Без заголовка запрос выполняется в арендаторе по умолчанию.
+func Tenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := r.Header.Get("X-Tenant")
+		if t == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !tenant.Valid(t) {
+			http.Error(w, "invalid tenant", http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.With(r.Context(), t)))
+	})
+}
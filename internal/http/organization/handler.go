@@ -0,0 +1,252 @@
+// Package organization предоставляет HTTP API организаций приложений и приглашений в них.
+package organization
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/http/middleware"
+	sessionhttp "go-sso/internal/http/session"
+	organizationsvc "go-sso/internal/services/organization"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxBodySize ограничение тела запроса.
+const maxBodySize = 4 << 10
+
+// Service сервис организаций.
+type Service interface {
+	CreateOrganization(ctx context.Context, appName, name, ownerUUID string) (models.Organization, error)
+	Organizations(ctx context.Context, appName, userUUID string) ([]models.Organization, error)
+	Members(ctx context.Context, actorUUID, orgID string) ([]models.Member, error)
+	InviteMember(ctx context.Context, actorUUID, orgID, email, role string) (models.Invitation, error)
+	AcceptInvitation(ctx context.Context, token, password string) (models.Member, error)
+	RemoveMember(ctx context.Context, actorUUID, orgID, userUUID string) error
+}
+
+type organization struct {
+	ID        string    `json:"id"`
+	App       string    `json:"app"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type member struct {
+	OrganizationID string    `json:"organization_id"`
+	UserUUID       string    `json:"user_uuid"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type invitation struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type createRequest struct {
+	Name string `json:"name"`
+}
+
+type inviteRequest struct {
+	Email string `json:"email"`
+	// Role роль приглашенного; по умолчанию member.
+	Role string `json:"role"`
+}
+
+type acceptRequest struct {
+	Token string `json:"token"`
+	// Password пароль для регистрации; не нужен, если приглашенный уже зарегистрирован.
+	Password string `json:"password"`
+}
+
+// Register добавляет в mux обработчики организаций. Запросы ниже аутентифицируются
+// токеном пользователя в заголовке "Authorization: Bearer <token>"; организации берутся
+// из приложения, для которого выдан токен:
+//
+//	POST   /v1/organizations                            — {name} → 201 организация; создатель становится владельцем
+//	GET    /v1/organizations                            — организации, в которых состоит пользователь
+//	GET    /v1/organizations/{id}/members               — участники организации
+//	POST   /v1/organizations/{id}/invitations           — {email, role} → 201 приглашение; токен отправляется письмом
+//	DELETE /v1/organizations/{id}/members/{user_uuid}   — удалить участника или выйти из организации
+//
+// Принять приглашение можно без токена пользователя:
+//
+//	POST /v1/invitations/accept — {token, password} → участник; password нужен для регистрации нового пользователя
+func Register(mux *http.ServeMux, log *zap.SugaredLogger, svc Service, sessions sessionhttp.Authenticator) {
+	const op = "http.organization.Register"
+
+	log = log.With("op", op)
+
+	mux.Handle("POST /v1/organizations", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		var req createRequest
+		if !decode(w, r, &req) {
+			return
+		}
+
+		org, err := svc.CreateOrganization(r.Context(), current.App, req.Name, current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, toOrganization(org))
+	}))
+
+	mux.Handle("GET /v1/organizations", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		orgs, err := svc.Organizations(r.Context(), current.App, current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		out := make([]organization, 0, len(orgs))
+		for _, org := range orgs {
+			out = append(out, toOrganization(org))
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"organizations": out})
+	}))
+
+	mux.Handle("GET /v1/organizations/{id}/members", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		members, err := svc.Members(r.Context(), current.UserUUID, r.PathValue("id"))
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		out := make([]member, 0, len(members))
+		for _, m := range members {
+			out = append(out, toMember(m))
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"members": out})
+	}))
+
+	mux.Handle("POST /v1/organizations/{id}/invitations", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		var req inviteRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.Email == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email is required"})
+			return
+		}
+		if req.Role == "" {
+			req.Role = models.OrganizationMember
+		}
+
+		inv, err := svc.InviteMember(r.Context(), current.UserUUID, r.PathValue("id"), req.Email, req.Role)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, invitation{ID: inv.ID, Email: inv.Email, Role: inv.Role, ExpiresAt: inv.ExpiresAt})
+	}))
+
+	mux.Handle("DELETE /v1/organizations/{id}/members/{user_uuid}", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		if err := svc.RemoveMember(r.Context(), current.UserUUID, r.PathValue("id"), r.PathValue("user_uuid")); err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mux.Handle("POST /v1/invitations/accept", middleware.Client(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req acceptRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.Token == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token is required"})
+			return
+		}
+
+		m, err := svc.AcceptInvitation(r.Context(), req.Token, req.Password)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, toMember(m))
+	})))
+}
+
+func toOrganization(org models.Organization) organization {
+	return organization{ID: org.ID, App: org.App, Name: org.Name, CreatedAt: org.CreatedAt}
+}
+
+func toMember(m models.Member) member {
+	return member{OrganizationID: m.OrganizationID, UserUUID: m.UserUUID, Email: m.Email, Role: m.Role, CreatedAt: m.CreatedAt}
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return false
+	}
+
+	return true
+}
+
+// errorStatus коды ответа на ошибки сервиса; в ответ пишется текст самой ошибки сервиса.
+var errorStatus = []struct {
+	err  error
+	code int
+}{
+	{organizationsvc.ErrInvalidName, http.StatusBadRequest},
+	{organizationsvc.ErrInvalidRole, http.StatusBadRequest},
+	{organizationsvc.ErrInvalidEmail, http.StatusBadRequest},
+	{organizationsvc.ErrPasswordRequired, http.StatusBadRequest},
+	{organizationsvc.ErrInvalidInvitation, http.StatusNotFound},
+	{organizationsvc.ErrAppNotFound, http.StatusNotFound},
+	{organizationsvc.ErrOrganizationNotFound, http.StatusNotFound},
+	{organizationsvc.ErrMemberNotFound, http.StatusNotFound},
+	{organizationsvc.ErrUserNotFound, http.StatusNotFound},
+	{organizationsvc.ErrOrganizationExists, http.StatusConflict},
+	{organizationsvc.ErrLastOwner, http.StatusConflict},
+	{organizationsvc.ErrPermissionDenied, http.StatusForbidden},
+	{organizationsvc.ErrInvitationsNotEnabled, http.StatusForbidden},
+	{organizationsvc.ErrUserInactive, http.StatusForbidden},
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	for _, e := range errorStatus {
+		if errors.Is(err, e.err) {
+			writeJSON(w, e.code, map[string]string{"error": e.err.Error()})
+			return
+		}
+	}
+
+	if errors.Is(err, organizationsvc.ErrUnavailable) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "service temporarily unavailable"})
+		return
+	}
+
+	log.Errorw("organization request failed", "error", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,126 @@
+package organization
+
+import (
+	"context"
+	"encoding/json"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/jwt"
+	organizationsvc "go-sso/internal/services/organization"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeSessions struct{}
+
+func (fakeSessions) Authenticate(_ context.Context, token string) (models.Session, *jwt.Claims, error) {
+	return models.Session{ID: "s1", UserUUID: "user-1", App: "shop"}, &jwt.Claims{}, nil
+}
+
+// fakeService запоминает аргументы последнего вызова.
+type fakeService struct {
+	args []string
+}
+
+func (s *fakeService) CreateOrganization(_ context.Context, appName, name, ownerUUID string) (models.Organization, error) {
+	s.args = []string{appName, name, ownerUUID}
+	return models.Organization{ID: "org-1", App: appName, Name: name}, nil
+}
+
+func (s *fakeService) Organizations(_ context.Context, appName, userUUID string) ([]models.Organization, error) {
+	s.args = []string{appName, userUUID}
+	return nil, nil
+}
+
+func (s *fakeService) Members(_ context.Context, actorUUID, orgID string) ([]models.Member, error) {
+	s.args = []string{actorUUID, orgID}
+	return nil, organizationsvc.ErrOrganizationNotFound
+}
+
+func (s *fakeService) InviteMember(_ context.Context, actorUUID, orgID, email, role string) (models.Invitation, error) {
+	s.args = []string{actorUUID, orgID, email, role}
+	return models.Invitation{ID: "inv-1", Email: email, Role: role}, nil
+}
+
+func (s *fakeService) AcceptInvitation(_ context.Context, token, password string) (models.Member, error) {
+	s.args = []string{token, password}
+	if password == "" {
+		return models.Member{}, organizationsvc.ErrPasswordRequired
+	}
+	return models.Member{OrganizationID: "org-1", UserUUID: "user-2", Role: models.OrganizationMember}, nil
+}
+
+func (s *fakeService) RemoveMember(_ context.Context, actorUUID, orgID, userUUID string) error {
+	s.args = []string{actorUUID, orgID, userUUID}
+	return organizationsvc.ErrLastOwner
+}
+
+func do(t *testing.T, svc *fakeService, method, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	Register(mux, zap.NewNop().Sugar(), svc, fakeSessions{})
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	return rec, resp
+}
+
+func TestCreate_UsesSessionAppAndUser(t *testing.T) {
+	svc := &fakeService{}
+
+	rec, resp := do(t, svc, http.MethodPost, "/v1/organizations", `{"name":"Acme"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "org-1", resp["id"])
+	assert.Equal(t, []string{"shop", "Acme", "user-1"}, svc.args)
+}
+
+func TestInvite_DefaultRole(t *testing.T) {
+	svc := &fakeService{}
+
+	rec, resp := do(t, svc, http.MethodPost, "/v1/organizations/org-1/invitations", `{"email":"bob@example.com"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "member", resp["role"])
+	assert.NotContains(t, resp, "token")
+	assert.Equal(t, []string{"user-1", "org-1", "bob@example.com", "member"}, svc.args)
+
+	rec, _ = do(t, svc, http.MethodPost, "/v1/organizations/org-1/invitations", `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestErrors(t *testing.T) {
+	svc := &fakeService{}
+
+	rec, resp := do(t, svc, http.MethodGet, "/v1/organizations/org-2/members", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, organizationsvc.ErrOrganizationNotFound.Error(), resp["error"])
+
+	rec, _ = do(t, svc, http.MethodDelete, "/v1/organizations/org-1/members/user-1", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, []string{"user-1", "org-1", "user-1"}, svc.args)
+}
+
+func TestAccept(t *testing.T) {
+	svc := &fakeService{}
+
+	rec, resp := do(t, svc, http.MethodPost, "/v1/invitations/accept", `{"token":"t1"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, organizationsvc.ErrPasswordRequired.Error(), resp["error"])
+
+	rec, resp = do(t, svc, http.MethodPost, "/v1/invitations/accept", `{"token":"t1","password":"secret"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "user-2", resp["user_uuid"])
+	assert.Equal(t, []string{"t1", "secret"}, svc.args)
+}
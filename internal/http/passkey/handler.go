@@ -0,0 +1,272 @@
+// Package passkey предоставляет HTTP API регистрации ключей WebAuthn (passkey) и входа по ним.
+package passkey
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/http/middleware"
+	sessionhttp "go-sso/internal/http/session"
+	"go-sso/internal/lib/scope"
+	"go-sso/internal/services/auth"
+	passkeysvc "go-sso/internal/services/passkey"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxBodySize ограничение тела запроса; ответ аутентификатора с аттестацией может занимать несколько КБ.
+const maxBodySize = 64 << 10
+
+// Service сервис ключей WebAuthn.
+type Service interface {
+	BeginRegistration(ctx context.Context, userUUID string) (passkeysvc.Ceremony, error)
+	FinishRegistration(ctx context.Context, userUUID, ceremonyID, name string, response []byte) (models.Passkey, error)
+	BeginLogin(ctx context.Context, appName string) (passkeysvc.Ceremony, error)
+	Options(ctx context.Context, ceremonyID string) (json.RawMessage, error)
+	FinishLogin(ctx context.Context, ceremonyID string, response []byte) (token string, err error)
+	Passkeys(ctx context.Context, userUUID string) ([]models.Passkey, error)
+	DeletePasskey(ctx context.Context, userUUID string, id []byte) error
+}
+
+type ceremony struct {
+	CeremonyID string          `json:"ceremony_id"`
+	Options    json.RawMessage `json:"options"`
+}
+
+type registerRequest struct {
+	CeremonyID string `json:"ceremony_id"`
+	Name       string `json:"name"`
+	// Credential результат navigator.credentials.create в формате PublicKeyCredential.toJSON().
+	Credential json.RawMessage `json:"credential"`
+}
+
+type loginBeginRequest struct {
+	App string `json:"app"`
+	// Scope области доступа через пробел.
+	Scope string `json:"scope"`
+}
+
+type loginRequest struct {
+	CeremonyID string `json:"ceremony_id"`
+	// Credential результат navigator.credentials.get в формате PublicKeyCredential.toJSON().
+	Credential json.RawMessage `json:"credential"`
+	// Consent пользователь согласился на области, запрошенные при начале входа.
+	Consent bool `json:"consent"`
+}
+
+type passkey struct {
+	// ID идентификатор ключа в base64url.
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Synced     bool       `json:"synced"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Register добавляет в mux обработчики входа по passkey:
+//
+//	POST /v1/passkeys/login/begin           — {app, scope} → {ceremony_id, options}
+//	GET  /v1/passkeys/login/{ceremony_id}   — options второго фактора, начатого auth.Login
+//	POST /v1/passkeys/login/finish          — {ceremony_id, credential, consent} → {token}
+//
+// Если пользователь не согласился на запрошенные области, finish отвечает 403
+// {error: "consent required", scopes}.
+//
+// Запросы ниже аутентифицируются токеном пользователя в заголовке "Authorization: Bearer <token>":
+//
+//	POST   /v1/passkeys/register/begin  — {ceremony_id, options}
+//	POST   /v1/passkeys/register/finish — {ceremony_id, name, credential} → 201 ключ
+//	GET    /v1/passkeys                 — ключи пользователя
+//	DELETE /v1/passkeys/{id}            — удалить ключ
+func Register(mux *http.ServeMux, log *zap.SugaredLogger, svc Service, sessions sessionhttp.Authenticator) {
+	const op = "http.passkey.Register"
+
+	log = log.With("op", op)
+
+	mux.Handle("POST /v1/passkeys/login/begin", middleware.Client(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req loginBeginRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.App == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "app is required"})
+			return
+		}
+
+		ctx := auth.WithScopeRequest(r.Context(), auth.ScopeRequest{Scopes: scope.Parse(req.Scope)})
+
+		c, err := svc.BeginLogin(ctx, req.App)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ceremony{CeremonyID: c.ID, Options: c.Options})
+	})))
+
+	mux.HandleFunc("GET /v1/passkeys/login/{ceremony_id}", func(w http.ResponseWriter, r *http.Request) {
+		options, err := svc.Options(r.Context(), r.PathValue("ceremony_id"))
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ceremony{CeremonyID: r.PathValue("ceremony_id"), Options: options})
+	})
+
+	mux.Handle("POST /v1/passkeys/login/finish", middleware.Client(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req loginRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.CeremonyID == "" || len(req.Credential) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ceremony_id and credential are required"})
+			return
+		}
+
+		ctx := auth.WithScopeRequest(r.Context(), auth.ScopeRequest{Consent: req.Consent})
+
+		token, err := svc.FinishLogin(ctx, req.CeremonyID, req.Credential)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"token": token})
+	})))
+
+	mux.Handle("POST /v1/passkeys/register/begin", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		c, err := svc.BeginRegistration(r.Context(), current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ceremony{CeremonyID: c.ID, Options: c.Options})
+	}))
+
+	mux.Handle("POST /v1/passkeys/register/finish", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		var req registerRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.CeremonyID == "" || len(req.Credential) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ceremony_id and credential are required"})
+			return
+		}
+
+		p, err := svc.FinishRegistration(r.Context(), current.UserUUID, req.CeremonyID, req.Name, req.Credential)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, toPasskey(p))
+	}))
+
+	mux.Handle("GET /v1/passkeys", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		passkeys, err := svc.Passkeys(r.Context(), current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		out := make([]passkey, 0, len(passkeys))
+		for _, p := range passkeys {
+			out = append(out, toPasskey(p))
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"passkeys": out})
+	}))
+
+	mux.Handle("DELETE /v1/passkeys/{id}", sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		current := sessionhttp.CurrentSession(r.Context())
+
+		id, err := base64.RawURLEncoding.DecodeString(r.PathValue("id"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": passkeysvc.ErrPasskeyNotFound.Error()})
+			return
+		}
+
+		if err := svc.DeletePasskey(r.Context(), current.UserUUID, id); err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func toPasskey(p models.Passkey) passkey {
+	return passkey{
+		ID:         base64.RawURLEncoding.EncodeToString(p.ID),
+		Name:       p.Name,
+		Synced:     p.BackupState,
+		CreatedAt:  p.CreatedAt,
+		LastUsedAt: p.LastUsedAt,
+	}
+}
+
+// decode читает тело запроса в v; при ошибке отвечает 400 и возвращает false.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return false
+	}
+	return true
+}
+
+// errorStatus коды ответа на ошибки сервиса; в ответ пишется текст самой ошибки сервиса.
+var errorStatus = []struct {
+	err  error
+	code int
+}{
+	{passkeysvc.ErrInvalidApp, http.StatusBadRequest},
+	{passkeysvc.ErrInvalidName, http.StatusBadRequest},
+	{passkeysvc.ErrInvalidCeremony, http.StatusBadRequest},
+	{passkeysvc.ErrInvalidCredential, http.StatusUnauthorized},
+	{passkeysvc.ErrUserInactive, http.StatusForbidden},
+	{passkeysvc.ErrUserNotFound, http.StatusNotFound},
+	{passkeysvc.ErrPasskeyNotFound, http.StatusNotFound},
+	{passkeysvc.ErrPasskeyExists, http.StatusConflict},
+	{auth.ErrInvalidScope, http.StatusBadRequest},
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	var consentErr *auth.ConsentRequiredError
+	if errors.As(err, &consentErr) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": auth.ErrConsentRequired.Error(), "scopes": consentErr.Scopes})
+		return
+	}
+
+	for _, e := range errorStatus {
+		if errors.Is(err, e.err) {
+			writeJSON(w, e.code, map[string]string{"error": e.err.Error()})
+			return
+		}
+	}
+
+	if errors.Is(err, passkeysvc.ErrUnavailable) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "service temporarily unavailable"})
+		return
+	}
+
+	log.Errorw("passkey request failed", "error", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
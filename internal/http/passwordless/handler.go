@@ -0,0 +1,146 @@
+// Package passwordless предоставляет HTTP API входа без пароля по ссылке или коду из письма.
+package passwordless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/http/middleware"
+	"go-sso/internal/lib/scope"
+	"go-sso/internal/services/auth"
+	passwordlesssvc "go-sso/internal/services/passwordless"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// maxBodySize ограничение тела запроса.
+const maxBodySize = 4 << 10
+
+// Service сервис входа без пароля.
+type Service interface {
+	Start(ctx context.Context, email, appName, method string) (challengeID string, err error)
+	Complete(ctx context.Context, challengeID, secret string) (token string, err error)
+}
+
+type startRequest struct {
+	Email  string `json:"email"`
+	App    string `json:"app"`
+	Method string `json:"method"`
+}
+
+type completeRequest struct {
+	ChallengeID string `json:"challenge_id"`
+	// Code шестизначный код из письма или параметр token из ссылки.
+	Code string `json:"code"`
+	// Scope области доступа через пробел.
+	Scope string `json:"scope"`
+	// Consent пользователь согласился на запрошенные области.
+	Consent bool `json:"consent"`
+}
+
+// Register добавляет в mux обработчики входа без пароля:
+//
+//	POST /v1/passwordless/start    — {email, app, method} → 202 {challenge_id}; method: link или code
+//	POST /v1/passwordless/complete — {challenge_id, code, scope, consent} → {token}
+//
+// Запрос на вход одноразовый, поэтому согласие на области нужно получить до complete:
+// без него complete отвечает 403 {error: "consent required", scopes}, и вход начинается заново.
+func Register(mux *http.ServeMux, log *zap.SugaredLogger, svc Service) {
+	const op = "http.passwordless.Register"
+
+	log = log.With("op", op)
+
+	mux.Handle("POST /v1/passwordless/start", middleware.Client(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req startRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		if req.Email == "" || req.App == "" || req.Method == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email, app and method are required"})
+			return
+		}
+
+		challengeID, err := svc.Start(r.Context(), req.Email, req.App, req.Method)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, map[string]string{"challenge_id": challengeID})
+	})))
+
+	mux.Handle("POST /v1/passwordless/complete", middleware.Client(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req completeRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		if req.ChallengeID == "" || req.Code == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "challenge_id and code are required"})
+			return
+		}
+
+		ctx := auth.WithScopeRequest(r.Context(), auth.ScopeRequest{Scopes: scope.Parse(req.Scope), Consent: req.Consent})
+
+		token, err := svc.Complete(ctx, req.ChallengeID, req.Code)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"token": token})
+	})))
+}
+
+// errorStatus коды ответа на ошибки сервиса; в ответ пишется текст самой ошибки сервиса.
+var errorStatus = []struct {
+	err  error
+	code int
+}{
+	{passwordlesssvc.ErrInvalidEmail, http.StatusBadRequest},
+	{passwordlesssvc.ErrInvalidApp, http.StatusBadRequest},
+	{passwordlesssvc.ErrInvalidCode, http.StatusUnauthorized},
+	{passwordlesssvc.ErrNotEnabled, http.StatusForbidden},
+	{passwordlesssvc.ErrUserInactive, http.StatusForbidden},
+	{auth.ErrInvalidScope, http.StatusBadRequest},
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	for _, e := range errorStatus {
+		if errors.Is(err, e.err) {
+			writeJSON(w, e.code, map[string]string{"error": e.err.Error()})
+			return
+		}
+	}
+
+	var consentErr *auth.ConsentRequiredError
+	if errors.As(err, &consentErr) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": auth.ErrConsentRequired.Error(), "scopes": consentErr.Scopes})
+		return
+	}
+
+	var rlErr *passwordlesssvc.RateLimitError
+	if errors.As(err, &rlErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rlErr.RetryAfter.Seconds()))))
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": passwordlesssvc.ErrTooManyRequests.Error()})
+		return
+	}
+
+	if errors.Is(err, passwordlesssvc.ErrUnavailable) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "service temporarily unavailable"})
+		return
+	}
+
+	log.Errorw("passwordless request failed", "error", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
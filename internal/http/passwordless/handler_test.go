@@ -0,0 +1,84 @@
+package passwordless
+
+import (
+	"context"
+	"encoding/json"
+	"go-sso/internal/services/auth"
+	passwordlesssvc "go-sso/internal/services/passwordless"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeService struct{}
+
+func (fakeService) Start(_ context.Context, email, _, _ string) (string, error) {
+	if email == "flood@example.com" {
+		return "", &passwordlesssvc.RateLimitError{RetryAfter: 1500 * time.Millisecond}
+	}
+	return "challenge", nil
+}
+
+func (fakeService) Complete(ctx context.Context, _, code string) (string, error) {
+	if code != "123456" {
+		return "", passwordlesssvc.ErrInvalidCode
+	}
+	if req := auth.ScopeRequestFrom(ctx); len(req.Scopes) > 0 && !req.Consent {
+		return "", &auth.ConsentRequiredError{Scopes: req.Scopes}
+	}
+	return "token", nil
+}
+
+func do(t *testing.T, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	Register(mux, zap.NewNop().Sugar(), fakeService{})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	return rec, resp
+}
+
+func TestStart(t *testing.T) {
+	rec, resp := do(t, "/v1/passwordless/start", `{"email":"alice@example.com","app":"shop","method":"code"}`)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "challenge", resp["challenge_id"])
+
+	rec, _ = do(t, "/v1/passwordless/start", `{"email":"alice@example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, resp = do(t, "/v1/passwordless/start", `{"email":"flood@example.com","app":"shop","method":"code"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "too many requests", resp["error"])
+}
+
+func TestComplete(t *testing.T) {
+	rec, resp := do(t, "/v1/passwordless/complete", `{"challenge_id":"challenge","code":"123456"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "token", resp["token"])
+
+	rec, resp = do(t, "/v1/passwordless/complete", `{"challenge_id":"challenge","code":"000000"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "invalid or expired code", resp["error"])
+
+	rec, resp = do(t, "/v1/passwordless/complete", `{"challenge_id":"challenge","code":"123456","scope":"orders:read"}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "consent required", resp["error"])
+	assert.Equal(t, []any{"orders:read"}, resp["scopes"])
+
+	rec, resp = do(t, "/v1/passwordless/complete", `{"challenge_id":"challenge","code":"123456","scope":"orders:read","consent":true}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "token", resp["token"])
+}
@@ -0,0 +1,160 @@
+// Package session предоставляет HTTP API, через которое пользователь управляет своими сеансами.
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/http/middleware"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/lib/tenant"
+	sessionsvc "go-sso/internal/services/session"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Authenticator проверяет токен пользователя и возвращает его сеанс.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (models.Session, *jwt.Claims, error)
+}
+
+// Service сервис сеансов.
+type Service interface {
+	Authenticator
+	List(ctx context.Context, userUUID string) ([]models.Session, error)
+	Revoke(ctx context.Context, userUUID, id string) error
+	RevokeAll(ctx context.Context, userUUID string) (int64, error)
+}
+
+type session struct {
+	ID         string    `json:"id"`
+	App        string    `json:"app"`
+	Device     string    `json:"device,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type currentKey struct{}
+
+// Register добавляет в mux обработчики сеансов пользователя. Запросы аутентифицируются
+// токеном, выданным Login, в заголовке "Authorization: Bearer <token>":
+//
+//	GET    /v1/sessions       — активные сеансы
+//	DELETE /v1/sessions/{id}  — отозвать сеанс
+//	DELETE /v1/sessions       — отозвать все сеансы, включая текущий
+func Register(mux *http.ServeMux, log *zap.SugaredLogger, svc Service) {
+	const op = "http.session.Register"
+
+	log = log.With("op", op)
+
+	mux.Handle("GET /v1/sessions", Authenticate(log, svc, func(w http.ResponseWriter, r *http.Request) {
+		current := CurrentSession(r.Context())
+
+		sessions, err := svc.List(r.Context(), current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		out := make([]session, 0, len(sessions))
+		for _, s := range sessions {
+			out = append(out, session{
+				ID:         s.ID,
+				App:        s.App,
+				Device:     s.Device,
+				IP:         s.IP,
+				UserAgent:  s.UserAgent,
+				CreatedAt:  s.CreatedAt,
+				LastSeenAt: s.LastSeenAt,
+				ExpiresAt:  s.ExpiresAt,
+				Current:    s.ID == current.ID,
+			})
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": out})
+	}))
+
+	mux.Handle("DELETE /v1/sessions/{id}", Authenticate(log, svc, func(w http.ResponseWriter, r *http.Request) {
+		current := CurrentSession(r.Context())
+
+		if err := svc.Revoke(r.Context(), current.UserUUID, r.PathValue("id")); err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mux.Handle("DELETE /v1/sessions", Authenticate(log, svc, func(w http.ResponseWriter, r *http.Request) {
+		current := CurrentSession(r.Context())
+
+		n, err := svc.RevokeAll(r.Context(), current.UserUUID)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]int64{"revoked": n})
+	}))
+}
+
+// Authenticate проверяет токен пользователя из заголовка "Authorization: Bearer <token>"
+// и сохраняет его сеанс в контексте (см. CurrentSession). Запрос выполняется в арендаторе сеанса.
+func Authenticate(log *zap.SugaredLogger, svc Authenticator, next http.HandlerFunc) http.Handler {
+	return middleware.Client(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "token is required"})
+			return
+		}
+
+		s, _, err := svc.Authenticate(r.Context(), token)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), currentKey{}, s)
+		ctx = audit.WithActor(ctx, s.UserUUID)
+		ctx = tenant.With(ctx, s.Tenant)
+
+		next(w, r.WithContext(ctx))
+	}))
+}
+
+// CurrentSession возвращает сеанс, сохраненный Authenticate.
+func CurrentSession(ctx context.Context) models.Session {
+	s, _ := ctx.Value(currentKey{}).(models.Session)
+	return s
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	switch {
+	case errors.Is(err, sessionsvc.ErrInvalidToken):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	case errors.Is(err, sessionsvc.ErrSessionNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+	case errors.Is(err, sessionsvc.ErrUnavailable):
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "service temporarily unavailable"})
+	default:
+		log.Errorw("session request failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,137 @@
+// Package token предоставляет эндпоинт выдачи токенов OAuth 2.0 (RFC 6749)
+// для grant_type=client_credentials.
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/lib/scope"
+	"go-sso/internal/services/clients"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+// maxBodySize ограничение тела запроса.
+const maxBodySize = 16 << 10
+
+// Service сервис клиентов.
+type Service interface {
+	ClientCredentialsToken(ctx context.Context, clientID, secret string, scopes []string) (clients.Token, error)
+}
+
+type response struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// Handler обрабатывает POST-запросы с формой "grant_type=client_credentials[&scope=a b]".
+// Клиент аутентифицируется по HTTP Basic (client_id и client_secret) или параметрами
+// client_id и client_secret в теле формы.
+func Handler(log *zap.SugaredLogger, svc Service) http.Handler {
+	const op = "http.token.Handler"
+
+	log = log.With("op", op)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		if err := r.ParseForm(); err != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "invalid form body")
+			return
+		}
+
+		clientID, secret, ok := credentials(r)
+		if !ok {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request",
+				"client credentials must be passed exactly once: basic auth or form parameters")
+			return
+		}
+
+		switch grant := r.PostForm.Get("grant_type"); grant {
+		case "client_credentials":
+		case "":
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "grant_type is required")
+			return
+		default:
+			writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+			return
+		}
+
+		token, err := svc.ClientCredentialsToken(r.Context(), clientID, secret, scope.Parse(r.PostForm.Get("scope")))
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+		writeJSON(w, http.StatusOK, response{
+			AccessToken: token.AccessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int64(token.ExpiresIn.Seconds()),
+			Scope:       scope.Join(token.Scopes),
+		})
+	})
+}
+
+// credentials возвращает client_id и client_secret из заголовка Authorization или формы.
+// Передача обоими способами сразу запрещена (RFC 6749, раздел 2.3).
+func credentials(r *http.Request) (clientID, secret string, ok bool) {
+	formID, formSecret := r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+
+	basicID, basicSecret, basic := r.BasicAuth()
+	if !basic {
+		return formID, formSecret, formID != ""
+	}
+	if formSecret != "" {
+		return "", "", false
+	}
+
+	// В Basic значения закодированы как application/x-www-form-urlencoded (раздел 2.3.1).
+	id, err := url.QueryUnescape(basicID)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err = url.QueryUnescape(basicSecret)
+	if err != nil {
+		return "", "", false
+	}
+
+	return id, secret, true
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	switch {
+	case errors.Is(err, clients.ErrInvalidClient):
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "")
+	case errors.Is(err, clients.ErrInvalidScope):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", errors.Unwrap(err).Error())
+	case errors.Is(err, clients.ErrUnavailable):
+		writeOAuthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "")
+	default:
+		log.Errorw("token request failed", "error", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+	}
+}
+
+// writeOAuthError пишет ошибку в формате RFC 6749, раздел 5.2.
+func writeOAuthError(w http.ResponseWriter, code int, kind, description string) {
+	body := map[string]string{"error": kind}
+	if description != "" {
+		body["error_description"] = description
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, code, body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
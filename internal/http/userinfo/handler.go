@@ -0,0 +1,74 @@
+// Package userinfo предоставляет эндпоинт userinfo в духе OpenID Connect.
+package userinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-sso/internal/domain/models"
+	sessionhttp "go-sso/internal/http/session"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/services/admin"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// UserProvider возвращает пользователя по UUID.
+type UserProvider interface {
+	User(ctx context.Context, uuid string) (models.User, error)
+}
+
+// ProfileClaims возвращает claims профиля, доступные приложению.
+type ProfileClaims func(app string) []string
+
+// Handler отвечает на GET /v1/userinfo с токеном пользователя в заголовке
+// "Authorization: Bearer <token>": sub, email и поля профиля, разрешенные
+// приложению токена в apps.<имя>.profile_claims.
+func Handler(
+	log *zap.SugaredLogger,
+	sessions sessionhttp.Authenticator,
+	users UserProvider,
+	profileClaims ProfileClaims,
+) http.Handler {
+	const op = "http.userinfo.Handler"
+
+	log = log.With("op", op)
+
+	return sessionhttp.Authenticate(log, sessions, func(w http.ResponseWriter, r *http.Request) {
+		session := sessionhttp.CurrentSession(r.Context())
+
+		user, err := users.User(r.Context(), session.UserUUID)
+		if errors.Is(err, admin.ErrUserNotFound) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+			return
+		}
+		if err != nil {
+			log.Errorw("failed to get user", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+
+		resp := map[string]any{
+			"sub":   user.UUID,
+			"email": user.Email,
+		}
+
+		claims := profileClaims(session.App)
+		for k, v := range jwt.ProfileClaimValues(user.Profile, claims) {
+			resp[k] = v
+		}
+		if len(claims) > 0 {
+			resp["updated_at"] = user.UpdatedAt.Unix()
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
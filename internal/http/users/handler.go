@@ -0,0 +1,409 @@
+// Package users предоставляет административный HTTP API жизненного цикла учетных записей.
+package users
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/http/middleware"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/services/admin"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Actor инициатор действий, выполненных через административный API, в журнале аудита.
+const Actor = "http:admin"
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+
+	// maxBodySize ограничение тела запроса изменения профиля.
+	maxBodySize = 64 << 10
+)
+
+// Service сервис администрирования пользователей.
+type Service interface {
+	User(ctx context.Context, uuid string) (models.User, error)
+	UserByEmail(ctx context.Context, email string) (models.User, error)
+	Users(ctx context.Context, limit, offset int) ([]models.User, error)
+	UpdateProfile(ctx context.Context, uuid string, upd models.ProfileUpdate) (models.User, error)
+	ExportUser(ctx context.Context, email string) (models.UserData, error)
+	DeleteUser(ctx context.Context, email string) error
+}
+
+type user struct {
+	UUID        string         `json:"uuid"`
+	Email       string         `json:"email"`
+	Status      string         `json:"status"`
+	DisplayName string         `json:"display_name,omitempty"`
+	Locale      string         `json:"locale,omitempty"`
+	Timezone    string         `json:"timezone,omitempty"`
+	AvatarURL   string         `json:"avatar_url,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   *time.Time     `json:"deleted_at,omitempty"`
+}
+
+func newUser(u models.User) user {
+	return user{
+		UUID:        u.UUID,
+		Email:       u.Email,
+		Status:      string(u.Status),
+		DisplayName: u.Profile.DisplayName,
+		Locale:      u.Profile.Locale,
+		Timezone:    u.Profile.Timezone,
+		AvatarURL:   u.Profile.AvatarURL,
+		Metadata:    u.Profile.Metadata,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		DeletedAt:   u.DeletedAt,
+	}
+}
+
+// profileUpdate тело PATCH-запроса профиля: отсутствующие поля не меняются.
+type profileUpdate struct {
+	DisplayName *string        `json:"display_name"`
+	Locale      *string        `json:"locale"`
+	Timezone    *string        `json:"timezone"`
+	AvatarURL   *string        `json:"avatar_url"`
+	Metadata    map[string]any `json:"metadata"`
+}
+
+type role struct {
+	App       string    `json:"app"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type session struct {
+	ID         string     `json:"id"`
+	App        string     `json:"app"`
+	Device     string     `json:"device,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type identity struct {
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// passkey ключ WebAuthn без открытого ключа.
+type passkey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type consent struct {
+	App       string    `json:"app"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type membership struct {
+	OrganizationID string    `json:"organization_id"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// invitation приглашение в организацию без хэша токена.
+type invitation struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	InvitedBy      string    `json:"invited_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type auditEvent struct {
+	ID        int64             `json:"id"`
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"event"`
+	Outcome   string            `json:"outcome"`
+	Reason    string            `json:"reason,omitempty"`
+	Actor     string            `json:"actor,omitempty"`
+	Subject   string            `json:"subject,omitempty"`
+	App       string            `json:"app,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// Export выгрузка данных пользователя. Хэш пароля не выгружается.
+type Export struct {
+	User        user         `json:"user"`
+	Roles       []role       `json:"roles"`
+	Sessions    []session    `json:"sessions"`
+	Identities  []identity   `json:"identities"`
+	Passkeys    []passkey    `json:"passkeys"`
+	Consents    []consent    `json:"consents"`
+	Memberships []membership `json:"memberships"`
+	Invitations []invitation `json:"invitations"`
+	AuditEvents []auditEvent `json:"audit_events"`
+}
+
+// NewExport преобразует данные пользователя в формат выгрузки.
+func NewExport(data models.UserData) Export {
+	out := Export{
+		User:        newUser(data.User),
+		Roles:       make([]role, 0, len(data.Roles)),
+		Sessions:    make([]session, 0, len(data.Sessions)),
+		Identities:  make([]identity, 0, len(data.Identities)),
+		Passkeys:    make([]passkey, 0, len(data.Passkeys)),
+		Consents:    make([]consent, 0, len(data.Consents)),
+		Memberships: make([]membership, 0, len(data.Memberships)),
+		Invitations: make([]invitation, 0, len(data.Invitations)),
+		AuditEvents: make([]auditEvent, 0, len(data.AuditEvents)),
+	}
+
+	for _, r := range data.Roles {
+		out.Roles = append(out.Roles, role{App: r.AppName, Role: r.Role, CreatedAt: r.CreatedAt})
+	}
+
+	for _, s := range data.Sessions {
+		out.Sessions = append(out.Sessions, session{
+			ID:         s.ID,
+			App:        s.App,
+			Device:     s.Device,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			RevokedAt:  s.RevokedAt,
+		})
+	}
+
+	for _, i := range data.Identities {
+		out.Identities = append(out.Identities, identity{
+			Provider:    i.Provider,
+			Subject:     i.Subject,
+			Email:       i.Email,
+			CreatedAt:   i.CreatedAt,
+			LastLoginAt: i.LastLoginAt,
+		})
+	}
+
+	for _, p := range data.Passkeys {
+		out.Passkeys = append(out.Passkeys, passkey{
+			ID:         base64.RawURLEncoding.EncodeToString(p.ID),
+			Name:       p.Name,
+			Transports: p.Transports,
+			CreatedAt:  p.CreatedAt,
+			LastUsedAt: p.LastUsedAt,
+		})
+	}
+
+	for _, c := range data.Consents {
+		out.Consents = append(out.Consents, consent{
+			App:       c.App,
+			Scopes:    c.Scopes,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+		})
+	}
+
+	for _, m := range data.Memberships {
+		out.Memberships = append(out.Memberships, membership{
+			OrganizationID: m.OrganizationID,
+			Role:           m.Role,
+			CreatedAt:      m.CreatedAt,
+		})
+	}
+
+	for _, inv := range data.Invitations {
+		out.Invitations = append(out.Invitations, invitation{
+			ID:             inv.ID,
+			OrganizationID: inv.OrganizationID,
+			Email:          inv.Email,
+			Role:           inv.Role,
+			InvitedBy:      inv.InvitedBy,
+			CreatedAt:      inv.CreatedAt,
+			ExpiresAt:      inv.ExpiresAt,
+		})
+	}
+
+	for _, e := range data.AuditEvents {
+		out.AuditEvents = append(out.AuditEvents, auditEvent{
+			ID:        e.ID,
+			Time:      e.Time,
+			Type:      e.Type,
+			Outcome:   string(e.Outcome),
+			Reason:    e.Reason,
+			Actor:     e.Actor,
+			Subject:   e.Subject,
+			App:       e.App,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			Details:   e.Details,
+		})
+	}
+
+	return out
+}
+
+// Register добавляет в mux административные обработчики, доступные с токеном adminToken:
+//
+//	GET    /v1/users                 — список пользователей (limit, offset) или поиск по email
+//	GET    /v1/users/{uuid}          — пользователь по UUID
+//	PATCH  /v1/users/{uuid}/profile  — изменить профиль
+//	GET    /v1/users/{email}/export  — выгрузить все данные пользователя
+//	DELETE /v1/users/{email}         — удалить пользователя
+func Register(mux *http.ServeMux, log *zap.SugaredLogger, svc Service, adminToken string) {
+	const op = "http.users.Register"
+
+	log = log.With("op", op)
+
+	protect := func(next http.HandlerFunc) http.Handler {
+		return middleware.BearerToken(adminToken, middleware.Client(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				next(w, r.WithContext(audit.WithActor(r.Context(), Actor)))
+			},
+		)))
+	}
+
+	mux.Handle("GET /v1/users", protect(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		if email := q.Get("email"); email != "" {
+			u, err := svc.UserByEmail(r.Context(), email)
+			if err != nil {
+				writeError(w, log, err)
+				return
+			}
+
+			writeJSON(w, http.StatusOK, map[string]any{"users": []user{newUser(u)}})
+			return
+		}
+
+		limit, offset, err := parsePage(q)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		users, err := svc.Users(r.Context(), limit, offset)
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		out := make([]user, 0, len(users))
+		for _, u := range users {
+			out = append(out, newUser(u))
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"users": out})
+	}))
+
+	mux.Handle("GET /v1/users/{uuid}", protect(func(w http.ResponseWriter, r *http.Request) {
+		u, err := svc.User(r.Context(), r.PathValue("uuid"))
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newUser(u))
+	}))
+
+	mux.Handle("PATCH /v1/users/{uuid}/profile", protect(func(w http.ResponseWriter, r *http.Request) {
+		var body profileUpdate
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+
+		u, err := svc.UpdateProfile(r.Context(), r.PathValue("uuid"), models.ProfileUpdate{
+			DisplayName: body.DisplayName,
+			Locale:      body.Locale,
+			Timezone:    body.Timezone,
+			AvatarURL:   body.AvatarURL,
+			Metadata:    body.Metadata,
+		})
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newUser(u))
+	}))
+
+	mux.Handle("GET /v1/users/{email}/export", protect(func(w http.ResponseWriter, r *http.Request) {
+		data, err := svc.ExportUser(r.Context(), r.PathValue("email"))
+		if err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, NewExport(data))
+	}))
+
+	mux.Handle("DELETE /v1/users/{email}", protect(func(w http.ResponseWriter, r *http.Request) {
+		if err := svc.DeleteUser(r.Context(), r.PathValue("email")); err != nil {
+			writeError(w, log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func writeError(w http.ResponseWriter, log *zap.SugaredLogger, err error) {
+	switch {
+	case errors.Is(err, admin.ErrUserNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+	case errors.Is(err, admin.ErrInvalidProfile), errors.Is(err, admin.ErrInvalidEmail):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		log.Errorw("user request failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	}
+}
+
+// parsePage разбирает параметры страницы limit и offset.
+func parsePage(q url.Values) (limit, offset int, err error) {
+	limit, offset = defaultLimit, 0
+
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxLimit {
+			return 0, 0, fmt.Errorf("limit must be in range 1-%d", maxLimit)
+		}
+	}
+
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must not be negative")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,146 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/services/admin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testToken = "0123456789abcdef0123456789abcdef"
+
+type fakeService struct {
+	Service
+
+	deleted []string
+	actor   string
+}
+
+func (s *fakeService) UpdateProfile(_ context.Context, uuid string, upd models.ProfileUpdate) (models.User, error) {
+	if upd.Locale != nil && *upd.Locale == "bad" {
+		return models.User{}, fmt.Errorf("admin.UpdateProfile: %w: locale", admin.ErrInvalidProfile)
+	}
+
+	u := models.User{UUID: uuid, Email: "user@example.com", Status: models.UserStatusActive}
+	if upd.DisplayName != nil {
+		u.Profile.DisplayName = *upd.DisplayName
+	}
+	u.Profile.Metadata = upd.Metadata
+
+	return u, nil
+}
+
+func (s *fakeService) ExportUser(_ context.Context, email string) (models.UserData, error) {
+	if email != "user@example.com" {
+		return models.UserData{}, fmt.Errorf("admin.ExportUser: %w", admin.ErrUserNotFound)
+	}
+
+	revoked := time.Unix(1700000100, 0).UTC()
+
+	return models.UserData{
+		User:        models.User{UUID: "u1", Email: email, PassHash: []byte("hash"), Status: models.UserStatusActive},
+		Roles:       []models.Role{{AppName: "billing", Role: "admin"}},
+		Sessions:    []models.Session{{ID: "s1", App: "billing", RevokedAt: &revoked}},
+		Memberships: []models.Member{{OrganizationID: "org-1", UserUUID: "u1", Role: models.OrganizationOwner}},
+		Invitations: []models.Invitation{{ID: "inv-1", OrganizationID: "org-2", Email: email, TokenHash: []byte("token-hash")}},
+		AuditEvents: []models.AuditEvent{
+			{ID: 7, Type: models.AuditLogin, Outcome: models.AuditSuccess, Actor: email},
+		},
+	}, nil
+}
+
+func (s *fakeService) DeleteUser(ctx context.Context, email string) error {
+	s.actor = audit.ActorFrom(ctx)
+	if email != "user@example.com" {
+		return fmt.Errorf("admin.DeleteUser: %w", admin.ErrUserNotFound)
+	}
+	s.deleted = append(s.deleted, email)
+	return nil
+}
+
+func serve(t *testing.T, svc Service, method, target, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serveBody(t, svc, method, target, token, "")
+}
+
+func serveBody(t *testing.T, svc Service, method, target, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	Register(mux, zap.NewNop().Sugar(), svc, testToken)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestExport(t *testing.T) {
+	rec := serve(t, &fakeService{}, http.MethodGet, "/v1/users/user@example.com/export", testToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+	assert.Equal(t, "u1", body["user"].(map[string]any)["uuid"])
+	assert.NotContains(t, rec.Body.String(), "hash", "password hash must not be exported")
+	assert.Len(t, body["roles"], 1)
+	assert.Equal(t, "2023-11-14T22:15:00Z", body["sessions"].([]any)[0].(map[string]any)["revoked_at"])
+	assert.Equal(t, "owner", body["memberships"].([]any)[0].(map[string]any)["role"])
+	assert.Equal(t, "inv-1", body["invitations"].([]any)[0].(map[string]any)["id"])
+	assert.NotContains(t, rec.Body.String(), "token", "invitation token hash must not be exported")
+	assert.Equal(t, "login", body["audit_events"].([]any)[0].(map[string]any)["event"])
+
+	rec = serve(t, &fakeService{}, http.MethodGet, "/v1/users/other@example.com/export", testToken)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDelete(t *testing.T) {
+	svc := &fakeService{}
+
+	rec := serve(t, svc, http.MethodDelete, "/v1/users/user@example.com", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, svc.deleted)
+
+	rec = serve(t, svc, http.MethodDelete, "/v1/users/user@example.com", testToken)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, []string{"user@example.com"}, svc.deleted)
+	assert.Equal(t, Actor, svc.actor)
+
+	rec = serve(t, svc, http.MethodDelete, "/v1/users/other@example.com", testToken)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	rec := serveBody(t, &fakeService{}, http.MethodPatch, "/v1/users/u1/profile", testToken,
+		`{"display_name": "Alice", "metadata": {"team": "core"}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "Alice", body["display_name"])
+	assert.Equal(t, map[string]any{"team": "core"}, body["metadata"])
+
+	rec = serveBody(t, &fakeService{}, http.MethodPatch, "/v1/users/u1/profile", testToken, `{"locale": "bad"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serveBody(t, &fakeService{}, http.MethodPatch, "/v1/users/u1/profile", testToken, `not json`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,105 @@
+// Package audit записывает события аудита в подключаемые хранилища (sinks).
+package audit
+
+import (
+	"context"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Sink хранилище событий аудита.
+type Sink interface {
+	Write(ctx context.Context, e models.AuditEvent) error
+}
+
+// SinkFunc позволяет использовать функцию как Sink.
+type SinkFunc func(ctx context.Context, e models.AuditEvent) error
+
+func (f SinkFunc) Write(ctx context.Context, e models.AuditEvent) error {
+	return f(ctx, e)
+}
+
+// Recorder дополняет события данными из контекста запроса и пишет их во все sinks.
+type Recorder struct {
+	log   *zap.SugaredLogger
+	sinks []Sink
+	now   func() time.Time
+}
+
+// New возвращает Recorder, пишущий события в sinks.
+func New(log *zap.SugaredLogger, sinks ...Sink) *Recorder {
+	return &Recorder{
+		log:   log,
+		sinks: sinks,
+		now:   time.Now,
+	}
+}
+
+// Record записывает событие. Время, адрес, user agent и инициатор берутся из
+// контекста, если не заданы в событии. Арендатор, отличный от арендатора по умолчанию,
+// попадает в Details["tenant"].
+//
+// Ошибка записи не прерывает аудируемую операцию: событие целиком попадает
+// в лог с уровнем error, чтобы его можно было восстановить.
+// Отмена ctx (например, разрыв соединения клиентом) не прерывает запись.
+func (r *Recorder) Record(ctx context.Context, e models.AuditEvent) {
+	const op = "audit.Record"
+
+	if e.Time.IsZero() {
+		e.Time = r.now()
+	}
+
+	c := ClientFrom(ctx)
+	if e.IP == "" {
+		e.IP = c.IP
+	}
+	if e.UserAgent == "" {
+		e.UserAgent = c.UserAgent
+	}
+	if e.Actor == "" {
+		e.Actor = ActorFrom(ctx)
+	}
+	if t := tenant.From(ctx); t != tenant.Default {
+		details := make(map[string]string, len(e.Details)+1)
+		for k, v := range e.Details {
+			details[k] = v
+		}
+		details["tenant"] = t
+		e.Details = details
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	for _, s := range r.sinks {
+		if err := s.Write(ctx, e); err != nil {
+			r.log.Errorw("failed to write audit event",
+				"op", op,
+				"error", err,
+				"event", e.Type,
+				"outcome", e.Outcome,
+				"reason", e.Reason,
+				"actor", e.Actor,
+				"subject", e.Subject,
+				"app", e.App,
+				"ip", e.IP,
+				"userAgent", e.UserAgent,
+				"details", e.Details,
+				"time", e.Time,
+			)
+		}
+	}
+}
+
+// Event возвращает событие типа typ с результатом, определяемым err.
+func Event(typ string, err error) models.AuditEvent {
+	e := models.AuditEvent{Type: typ, Outcome: models.AuditSuccess}
+	if err != nil {
+		e.Outcome = models.AuditFailure
+		e.Reason = err.Error()
+	}
+
+	return e
+}
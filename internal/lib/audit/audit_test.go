@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRecordFillsEventFromContext(t *testing.T) {
+	var got []models.AuditEvent
+	sink := SinkFunc(func(ctx context.Context, e models.AuditEvent) error {
+		// Запись не должна прерываться отменой контекста запроса.
+		require.NoError(t, ctx.Err())
+		got = append(got, e)
+		return nil
+	})
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := New(zap.NewNop().Sugar(), sink)
+	r.now = func() time.Time { return now }
+
+	ctx := WithClient(context.Background(), Client{IP: "10.0.0.1", UserAgent: "grpc-go"})
+	ctx = WithActor(ctx, "ssoctl:root")
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	r.Record(ctx, Event(models.AuditLogin, nil))
+
+	require.Len(t, got, 1)
+	assert.Equal(t, models.AuditEvent{
+		Time:      now,
+		Type:      models.AuditLogin,
+		Outcome:   models.AuditSuccess,
+		Actor:     "ssoctl:root",
+		IP:        "10.0.0.1",
+		UserAgent: "grpc-go",
+	}, got[0])
+}
+
+func TestRecordKeepsExplicitFields(t *testing.T) {
+	var got models.AuditEvent
+	r := New(zap.NewNop().Sugar(), SinkFunc(func(_ context.Context, e models.AuditEvent) error {
+		got = e
+		return nil
+	}))
+
+	ctx := WithClient(context.Background(), Client{IP: "10.0.0.1"})
+	ctx = WithActor(ctx, "ssoctl:root")
+
+	e := Event(models.AuditLogin, errors.New("invalid credentials"))
+	e.Actor = "user@example.com"
+	e.IP = "192.0.2.1"
+	r.Record(ctx, e)
+
+	assert.Equal(t, models.AuditFailure, got.Outcome)
+	assert.Equal(t, "invalid credentials", got.Reason)
+	assert.Equal(t, "user@example.com", got.Actor)
+	assert.Equal(t, "192.0.2.1", got.IP)
+	assert.False(t, got.Time.IsZero())
+}
+
+func TestRecordAddsTenant(t *testing.T) {
+	var got models.AuditEvent
+	r := New(zap.NewNop().Sugar(), SinkFunc(func(_ context.Context, e models.AuditEvent) error {
+		got = e
+		return nil
+	}))
+
+	details := map[string]string{"sid": "1"}
+	e := Event(models.AuditLogin, nil)
+	e.Details = details
+	r.Record(tenant.With(context.Background(), "acme"), e)
+
+	assert.Equal(t, map[string]string{"sid": "1", "tenant": "acme"}, got.Details)
+	assert.Equal(t, map[string]string{"sid": "1"}, details, "caller's details are not modified")
+
+	r.Record(context.Background(), Event(models.AuditLogin, nil))
+	assert.Nil(t, got.Details)
+}
+
+func TestRecordWritesToAllSinksDespiteErrors(t *testing.T) {
+	var calls int
+	failing := SinkFunc(func(context.Context, models.AuditEvent) error {
+		calls++
+		return errors.New("boom")
+	})
+	ok := SinkFunc(func(context.Context, models.AuditEvent) error {
+		calls++
+		return nil
+	})
+
+	New(zap.NewNop().Sugar(), failing, ok).Record(context.Background(), Event(models.AuditRegister, nil))
+
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,37 @@
+package audit
+
+import "context"
+
+// Client сведения о клиенте, выполнившем запрос.
+type Client struct {
+	IP        string
+	UserAgent string
+	// Device название устройства, переданное клиентом.
+	Device string
+}
+
+type clientKey struct{}
+
+type actorKey struct{}
+
+// WithClient возвращает контекст со сведениями о клиенте.
+func WithClient(ctx context.Context, c Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// ClientFrom возвращает сведения о клиенте из контекста.
+func ClientFrom(ctx context.Context) Client {
+	c, _ := ctx.Value(clientKey{}).(Client)
+	return c
+}
+
+// WithActor возвращает контекст с инициатором действий, например оператором ssoctl.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom возвращает инициатора действий из контекста.
+func ActorFrom(ctx context.Context) string {
+	a, _ := ctx.Value(actorKey{}).(string)
+	return a
+}
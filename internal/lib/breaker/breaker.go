@@ -0,0 +1,129 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen возвращается, когда автомат разомкнут и вызов не выполняется.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State состояние автомата.
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker простой автоматический выключатель (circuit breaker).
+//
+// После threshold последовательных неудач автомат размыкается и в течение
+// openTimeout сразу возвращает ErrOpen. Затем пропускает один пробный вызов:
+// при успехе замыкается, при неудаче снова размыкается.
+type Breaker struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	now func() time.Time
+}
+
+// New возвращает новый автомат в замкнутом состоянии.
+// Если threshold <= 0, автомат никогда не размыкается.
+func New(threshold int, openTimeout time.Duration) *Breaker {
+	return &Breaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		now:         time.Now,
+	}
+}
+
+// Do выполняет fn, если автомат это разрешает.
+// isFailure определяет, какие ошибки считаются отказом зависимости
+// (например, 404 отказом не является).
+func (b *Breaker) Do(fn func() error, isFailure func(error) bool) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+
+	b.record(err != nil && isFailure(err))
+
+	return err
+}
+
+// State возвращает текущее состояние автомата.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.currentState()
+}
+
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case StateOpen:
+		return ErrOpen
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+func (b *Breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbe := b.probing
+	b.probing = false
+
+	if !failed {
+		b.state = StateClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if wasProbe || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// currentState должен вызываться под мьютексом.
+func (b *Breaker) currentState() State {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		return StateHalfOpen
+	}
+
+	return b.state
+}
@@ -0,0 +1,62 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = errors.New("down")
+
+func always(error) bool { return true }
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b := New(2, time.Minute)
+
+	fail := func() error { return errDown }
+
+	require.ErrorIs(t, b.Do(fail, always), errDown)
+	assert.Equal(t, StateClosed, b.State())
+
+	require.ErrorIs(t, b.Do(fail, always), errDown)
+	assert.Equal(t, StateOpen, b.State())
+
+	called := false
+	err := b.Do(func() error { called = true; return nil }, always)
+	require.ErrorIs(t, err, ErrOpen)
+	assert.False(t, called)
+}
+
+func TestBreaker_IgnoresNonFailures(t *testing.T) {
+	b := New(1, time.Minute)
+
+	errNotFound := errors.New("not found")
+	isFailure := func(err error) bool { return !errors.Is(err, errNotFound) }
+
+	require.ErrorIs(t, b.Do(func() error { return errNotFound }, isFailure), errNotFound)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	b := New(1, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	require.Error(t, b.Do(func() error { return errDown }, always))
+	assert.Equal(t, StateOpen, b.State())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// Неудачная проба снова размыкает автомат.
+	require.ErrorIs(t, b.Do(func() error { return errDown }, always), errDown)
+	assert.Equal(t, StateOpen, b.State())
+
+	now = now.Add(time.Minute)
+
+	require.NoError(t, b.Do(func() error { return nil }, always))
+	assert.Equal(t, StateClosed, b.State())
+}
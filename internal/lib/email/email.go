@@ -0,0 +1,59 @@
+// Package email приводит адреса электронной почты к единому виду,
+// чтобы один и тот же адрес не регистрировался дважды в разном написании.
+package email
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// maxLen максимальная длина адреса (RFC 5321).
+const maxLen = 254
+
+// ErrInvalid адрес не похож на адрес электронной почты.
+var ErrInvalid = errors.New("invalid email")
+
+// Normalizer приводит адреса к единому виду.
+type Normalizer struct {
+	// LowercaseLocal приводить к нижнему регистру и локальную часть (до @).
+	// По RFC 5321 она чувствительна к регистру, но почти все почтовые сервисы его игнорируют.
+	LowercaseLocal bool
+}
+
+// Normalize убирает пробелы по краям, переводит домен в нижний регистр и punycode
+// (пример.рф -> xn--e1afmkfd.xn--p1ai) и, если включено LowercaseLocal, приводит
+// к нижнему регистру локальную часть.
+func (n Normalizer) Normalize(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+
+	at := strings.LastIndexByte(addr, '@')
+	if at <= 0 || at == len(addr)-1 {
+		return "", ErrInvalid
+	}
+
+	local, domain := addr[:at], addr[at+1:]
+
+	if !utf8.ValidString(local) || strings.ContainsAny(local, " \t\r\n") {
+		return "", ErrInvalid
+	}
+
+	domain, err := idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", ErrInvalid
+	}
+	domain = strings.ToLower(domain)
+
+	if n.LowercaseLocal {
+		local = strings.ToLower(local)
+	}
+
+	addr = local + "@" + domain
+	if len(addr) > maxLen {
+		return "", ErrInvalid
+	}
+
+	return addr, nil
+}
@@ -0,0 +1,31 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"  Alice@Example.COM ": "Alice@example.com",
+		"bob@EXAMPLE.com.":     "bob@example.com",
+		"Иван@Пример.РФ":       "Иван@xn--e1afmkfd.xn--p1ai",
+	} {
+		got, err := Normalizer{}.Normalize(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	got, err := Normalizer{LowercaseLocal: true}.Normalize("Alice@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", got)
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	for _, in := range []string{"", "alice", "@example.com", "alice@", "alice@localhost", "a b@example.com", "alice@exa mple.com"} {
+		_, err := Normalizer{}.Normalize(in)
+		assert.ErrorIs(t, err, ErrInvalid, in)
+	}
+}
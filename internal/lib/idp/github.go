@@ -0,0 +1,141 @@
+package idp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// Адреса github.com. Для GitHub Enterprise Server их заменяют через GitHubEndpoints.
+const (
+	githubAuthURL  = "https://github.com/login/oauth/authorize"
+	githubTokenURL = "https://github.com/login/oauth/access_token"
+	githubAPIURL   = "https://api.github.com"
+)
+
+// GitHubEndpoints адреса GitHub; пустые поля заменяются адресами github.com.
+type GitHubEndpoints struct {
+	AuthURL  string
+	TokenURL string
+	APIURL   string
+}
+
+// GitHub провайдер OAuth 2.0 GitHub. GitHub не выпускает ID token, поэтому сведения
+// о пользователе запрашиваются через REST API, а nonce не используется.
+type GitHub struct {
+	cfg    Config
+	oauth  *oauth2.Config
+	apiURL string
+}
+
+// NewGitHub возвращает провайдера GitHub. Если scopes не заданы, запрашиваются
+// read:user и user:email.
+func NewGitHub(cfg Config, endpoints GitHubEndpoints) *GitHub {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"read:user", "user:email"}
+	}
+
+	endpoint := oauth2.Endpoint{
+		AuthURL:   cmp.Or(endpoints.AuthURL, githubAuthURL),
+		TokenURL:  cmp.Or(endpoints.TokenURL, githubTokenURL),
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+
+	return &GitHub{
+		cfg: cfg,
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     endpoint,
+			Scopes:       cfg.Scopes,
+		},
+		apiURL: strings.TrimSuffix(cmp.Or(endpoints.APIURL, githubAPIURL), "/"),
+	}
+}
+
+func (p *GitHub) AuthCodeURL(_ context.Context, state, _, verifier string) (string, error) {
+	return p.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (p *GitHub) Exchange(ctx context.Context, code, _, verifier string) (UserInfo, error) {
+	ctx = p.cfg.withClient(ctx)
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return UserInfo{}, exchangeErr(err)
+	}
+
+	client := p.oauth.Client(ctx, token)
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := p.get(ctx, client, "/user", &user); err != nil {
+		return UserInfo{}, err
+	}
+	if user.ID == 0 {
+		return UserInfo{}, fmt.Errorf("%w: user id is missing", ErrInvalidGrant)
+	}
+
+	// В /user адрес есть, только если пользователь сделал его публичным, и без признака
+	// подтверждения. Поэтому основной адрес и его подтверждение берутся из /user/emails.
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, client, "/user/emails", &emails); err != nil {
+		return UserInfo{}, err
+	}
+
+	info := UserInfo{
+		Subject: strconv.FormatInt(user.ID, 10),
+		Name:    cmp.Or(user.Name, user.Login),
+		Picture: user.AvatarURL,
+	}
+	for _, e := range emails {
+		if e.Primary {
+			info.Email, info.EmailVerified = e.Email, e.Verified
+		}
+	}
+
+	return info, nil
+}
+
+// get выполняет GET-запрос к API GitHub и декодирует ответ в v.
+func (p *GitHub) get(ctx context.Context, client *http.Client, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: GET %s: %v", ErrUnavailable, path, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: GET %s: %s", ErrInvalidGrant, path, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%w: GET %s: %s", ErrUnavailable, path, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: GET %s: %v", ErrInvalidGrant, path, err)
+	}
+
+	return nil
+}
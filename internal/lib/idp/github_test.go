@@ -0,0 +1,67 @@
+package idp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGitHubExchange(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("code") != "good" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"error":"bad_verification_code"}`))
+			return
+		}
+		assert.NotEmpty(t, r.PostForm.Get("code_verifier"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"gho_1","token_type":"bearer"}`))
+	})
+	mux.HandleFunc("GET /api/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gho_1", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"id":42,"login":"alice","name":"","avatar_url":"https://avatars/42"}`))
+	})
+	mux.HandleFunc("GET /api/user/emails", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"email":"old@example.com","primary":false,"verified":true},
+			{"email":"alice@example.com","primary":true,"verified":true}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	p := NewGitHub(Config{ClientID: "id", ClientSecret: "secret"}, GitHubEndpoints{
+		AuthURL:  srv.URL + "/login/oauth/authorize",
+		TokenURL: srv.URL + "/login/oauth/access_token",
+		APIURL:   srv.URL + "/api/",
+	})
+
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", "", verifier)
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "state-1", u.Query().Get("state"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+	info, err := p.Exchange(context.Background(), "good", "", verifier)
+	require.NoError(t, err)
+	assert.Equal(t, UserInfo{
+		Subject:       "42",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Name:          "alice",
+		Picture:       "https://avatars/42",
+	}, info)
+
+	_, err = p.Exchange(context.Background(), "bad", "", verifier)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
@@ -0,0 +1,69 @@
+// Package idp подключает внешних провайдеров identity по OpenID Connect и OAuth 2.0
+// (authorization code flow с PKCE).
+package idp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// Ошибки, которые могут вернуть провайдеры.
+var (
+	// ErrInvalidGrant провайдер отклонил код авторизации или вернул недействительный ответ.
+	ErrInvalidGrant = errors.New("invalid grant")
+	ErrUnavailable  = errors.New("identity provider unavailable")
+)
+
+// UserInfo сведения о пользователе, полученные от провайдера.
+type UserInfo struct {
+	// Subject неизменяемый идентификатор пользователя у провайдера.
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Provider внешний провайдер identity.
+type Provider interface {
+	// AuthCodeURL возвращает адрес, на который перенаправляется пользователь для входа.
+	// state и nonce возвращаются провайдером, verifier — PKCE code verifier.
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	// Exchange обменивает код авторизации на сведения о пользователе.
+	Exchange(ctx context.Context, code, nonce, verifier string) (UserInfo, error)
+}
+
+// Config параметры клиента провайдера.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURL адрес callback, зарегистрированный у провайдера.
+	RedirectURL string
+	Scopes      []string
+	// HTTPClient клиент для запросов к провайдеру; nil — http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// withClient передает HTTP-клиент провайдера в oauth2 и go-oidc.
+func (c Config) withClient(ctx context.Context) context.Context {
+	if c.HTTPClient == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, oauth2.HTTPClient, c.HTTPClient)
+}
+
+// exchangeErr переводит ошибку обмена кода в ошибку пакета: ответ провайдера с ошибкой
+// означает недействительный код, остальное — недоступность провайдера.
+func exchangeErr(err error) error {
+	var re *oauth2.RetrieveError
+	if errors.As(err, &re) {
+		return fmt.Errorf("%w: %v", ErrInvalidGrant, err)
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
@@ -0,0 +1,117 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDC провайдер OpenID Connect (Google Workspace, Keycloak, Okta и т.п.).
+// Адреса провайдера берутся из discovery-документа издателя при первом обращении,
+// так что недоступность провайдера не мешает запуску сервиса.
+type OIDC struct {
+	issuer string
+	cfg    Config
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDC возвращает провайдера OpenID Connect с издателем issuer.
+// Если scopes не заданы, запрашиваются openid, email и profile.
+func NewOIDC(issuer string, cfg Config) *OIDC {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	if !slices.Contains(cfg.Scopes, oidc.ScopeOpenID) {
+		cfg.Scopes = append([]string{oidc.ScopeOpenID}, cfg.Scopes...)
+	}
+
+	return &OIDC{issuer: issuer, cfg: cfg}
+}
+
+func (p *OIDC) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauth, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (p *OIDC) Exchange(ctx context.Context, code, nonce, verifier string) (UserInfo, error) {
+	oauth, idTokenVerifier, err := p.discover(ctx)
+	if err != nil {
+		return UserInfo{}, err
+	}
+
+	ctx = p.cfg.withClient(ctx)
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return UserInfo{}, exchangeErr(err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return UserInfo{}, fmt.Errorf("%w: id_token is missing in token response", ErrInvalidGrant)
+	}
+
+	idToken, err := idTokenVerifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return UserInfo{}, fmt.Errorf("%w: %v", ErrInvalidGrant, err)
+	}
+	if idToken.Nonce != nonce {
+		return UserInfo{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidGrant)
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return UserInfo{}, fmt.Errorf("%w: %v", ErrInvalidGrant, err)
+	}
+
+	return UserInfo{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+	}, nil
+}
+
+// discover загружает discovery-документ издателя. Успешный результат кэшируется,
+// после ошибки загрузка повторяется при следующем обращении.
+func (p *OIDC) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(p.cfg.withClient(ctx), p.issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: discovery of %s: %v", ErrUnavailable, p.issuer, err)
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+
+	return p.oauth, p.verifier, nil
+}
@@ -0,0 +1,78 @@
+package idp
+
+import (
+	"context"
+	"go-sso/internal/lib/idp/idptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func newTestOIDC(t *testing.T) (*OIDC, *idptest.Server) {
+	t.Helper()
+
+	srv := idptest.NewServer(t, "go-sso", "secret")
+	srv.SetUser(idptest.User{Subject: "1001", Email: "alice@example.com", EmailVerified: true, Name: "Alice"})
+
+	return NewOIDC(srv.Issuer(), Config{
+		ClientID:     "go-sso",
+		ClientSecret: "secret",
+		RedirectURL:  "http://sso.local/v1/federation/corp/callback",
+	}), srv
+}
+
+func TestOIDCExchange(t *testing.T) {
+	p, srv := newTestOIDC(t)
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", "nonce-1", verifier)
+	require.NoError(t, err)
+
+	callback, err := srv.Authorize(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "state-1", callback.Get("state"))
+
+	info, err := p.Exchange(context.Background(), callback.Get("code"), "nonce-1", verifier)
+	require.NoError(t, err)
+	assert.Equal(t, UserInfo{Subject: "1001", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}, info)
+}
+
+func TestOIDCExchangeRejects(t *testing.T) {
+	p, srv := newTestOIDC(t)
+	ctx := context.Background()
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	require.NoError(t, err)
+
+	for name, exchange := range map[string]func(code string) error{
+		"nonce mismatch": func(code string) error {
+			_, err := p.Exchange(ctx, code, "other", verifier)
+			return err
+		},
+		"wrong verifier": func(code string) error {
+			_, err := p.Exchange(ctx, code, "nonce-1", oauth2.GenerateVerifier())
+			return err
+		},
+		"unknown code": func(string) error {
+			_, err := p.Exchange(ctx, "garbage", "nonce-1", verifier)
+			return err
+		},
+	} {
+		callback, err := srv.Authorize(authURL)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, exchange(callback.Get("code")), ErrInvalidGrant, name)
+	}
+}
+
+func TestOIDCDiscoveryUnavailable(t *testing.T) {
+	srv := idptest.NewServer(t, "go-sso", "secret")
+	issuer := srv.Issuer()
+	srv.Close()
+
+	_, err := NewOIDC(issuer, Config{ClientID: "go-sso"}).AuthCodeURL(context.Background(), "s", "n", "v")
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
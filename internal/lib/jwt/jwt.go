@@ -3,24 +3,174 @@ package jwt
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/scope"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// TODO: test
-func NewToken(user models.User, secret string, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+// ErrInvalidToken токен поврежден, подписан другим ключом или истек.
+var ErrInvalidToken = errors.New("invalid token")
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["uuid"] = user.UUID
-	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
-	// claims["app_id"] = app.ID
+// ReservedClaims claims, которые выставляет сервис. Дополнительные claims приложений
+// не могут их переопределить.
+var ReservedClaims = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+	"uuid": true, "email": true, "sid": true, "app": true, "client_id": true, "scope": true,
+	"tid": true,
+}
+
+// ProfileClaims claims OpenID Connect, в которые попадают поля профиля пользователя.
+var ProfileClaims = map[string]bool{
+	"name": true, "locale": true, "zoneinfo": true, "picture": true,
+}
+
+// ProfileClaimValues возвращает непустые поля профиля под именами claims из names.
+func ProfileClaimValues(p models.Profile, names []string) map[string]string {
+	values := map[string]string{
+		"name":     p.DisplayName,
+		"locale":   p.Locale,
+		"zoneinfo": p.Timezone,
+		"picture":  p.AvatarURL,
+	}
+
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		if v := values[name]; v != "" {
+			out[name] = v
+		}
+	}
+
+	return out
+}
+
+// Claims содержимое токена пользователя или клиента (client credentials).
+type Claims struct {
+	jwt.RegisteredClaims
+
+	// UUID дублирует sub для клиентов, читающих claim uuid.
+	UUID      string `json:"uuid,omitempty"`
+	Email     string `json:"email,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	App       string `json:"app"`
+	// Tenant арендатор пользователя или клиента.
+	Tenant string `json:"tid,omitempty"`
+
+	// ClientID клиент, получивший токен по client credentials; у токенов пользователей пуст.
+	ClientID string `json:"client_id,omitempty"`
+	// Scope области доступа клиента через пробел (RFC 8693).
+	Scope string `json:"scope,omitempty"`
+
+	// Custom дополнительные claims приложения. В JSON они лежат на верхнем уровне.
+	Custom map[string]any `json:"-"`
+}
+
+// claimsJSON позволяет сериализовать Claims без рекурсии в MarshalJSON.
+type claimsJSON Claims
+
+func (c Claims) MarshalJSON() ([]byte, error) {
+	base, err := json.Marshal(claimsJSON(c))
+	if err != nil || len(c.Custom) == 0 {
+		return base, err
+	}
+
+	m := make(map[string]any, len(c.Custom))
+	for k, v := range c.Custom {
+		if !ReservedClaims[k] {
+			m[k] = v
+		}
+	}
+	if err := json.Unmarshal(base, &m); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(m)
+}
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*claimsJSON)(c)); err != nil {
+		return err
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	c.Custom = nil
+	for k, v := range m {
+		if ReservedClaims[k] {
+			continue
+		}
+		if c.Custom == nil {
+			c.Custom = make(map[string]any)
+		}
+		c.Custom[k] = v
+	}
+
+	return nil
+}
+
+// TokenOptions параметры выпускаемого токена.
+type TokenOptions struct {
+	Issuer    string
+	App       string
+	SessionID string
+	TTL       time.Duration
+	// Claims дополнительные claims приложения.
+	Claims map[string]string
+	// ProfileClaims claims профиля пользователя (см. ProfileClaims). Они переопределяют
+	// одноименные claims из Claims.
+	ProfileClaims []string
+	// Scopes области доступа, на которые пользователь дал согласие; попадают в claim scope.
+	Scopes []string
+}
+
+// NewToken выпускает токен пользователя для приложения opts.App.
+func NewToken(user models.User, opts TokenOptions, secret string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    opts.Issuer,
+			Subject:   user.UUID,
+			Audience:  jwt.ClaimStrings{opts.App},
+			ExpiresAt: jwt.NewNumericDate(now.Add(opts.TTL)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+		UUID:      user.UUID,
+		Email:     user.Email,
+		SessionID: opts.SessionID,
+		App:       opts.App,
+		Tenant:    user.Tenant,
+		Scope:     scope.Join(opts.Scopes),
+	}
+
+	profile := ProfileClaimValues(user.Profile, opts.ProfileClaims)
+	if len(opts.Claims)+len(profile) > 0 {
+		claims.Custom = make(map[string]any, len(opts.Claims)+len(profile))
+		for k, v := range opts.Claims {
+			claims.Custom[k] = v
+		}
+		for k, v := range profile {
+			claims.Custom[k] = v
+		}
+	}
 
 	// TODO: подумать о безопасном хранении секретов
-	tokenString, err := token.SignedString([]byte(secret))
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
 	if err != nil {
 		return "", err
 	}
@@ -28,6 +178,87 @@ func NewToken(user models.User, secret string, duration time.Duration) (string,
 	return tokenString, nil
 }
 
+// NewClientToken выпускает токен клиента client для приложения opts.App с областями scopes.
+// Токен не привязан к сеансу: его sub и client_id — идентификатор клиента.
+func NewClientToken(client models.Client, scopes []string, opts TokenOptions, secret string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    opts.Issuer,
+			Subject:   client.ID,
+			Audience:  jwt.ClaimStrings{opts.App},
+			ExpiresAt: jwt.NewNumericDate(now.Add(opts.TTL)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+		App:      opts.App,
+		Tenant:   client.Tenant,
+		ClientID: client.ID,
+		Scope:    scope.Join(scopes),
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// Unverified возвращает claims токена без проверки подписи. По ним находят ключ,
+// которым затем проверяют токен через Verify.
+func Unverified(tokenString string) (*Claims, error) {
+	var claims Claims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &claims, nil
+}
+
+// SessionID возвращает claim sid без проверки подписи.
+// По нему находят сеанс и ключ приложения, которым затем проверяют токен через Verify.
+func SessionID(tokenString string) (string, error) {
+	var claims Claims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.SessionID == "" {
+		return "", fmt.Errorf("%w: sid claim is missing", ErrInvalidToken)
+	}
+
+	return claims.SessionID, nil
+}
+
+// Verify проверяет подпись, срок действия и, если они заданы, издателя
+// и получателя (aud) токена и возвращает его claims.
+func Verify(tokenString, secret, issuer, audience string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (any, error) {
+		return []byte(secret), nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &claims, nil
+}
+
 func GenerateHS256Secret() (string, error) {
 	bytes := make([]byte, 32) // 256 бит
 	_, err := rand.Read(bytes)
@@ -36,3 +267,13 @@ func GenerateHS256Secret() (string, error) {
 	}
 	return base64.StdEncoding.EncodeToString(bytes), nil
 }
+
+// newTokenID возвращает случайный идентификатор токена для claim jti.
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
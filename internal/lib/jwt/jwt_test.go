@@ -0,0 +1,132 @@
+package jwt
+
+import (
+	"go-sso/internal/domain/models"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestToken(t *testing.T, ttl time.Duration, claims map[string]string) string {
+	t.Helper()
+
+	token, err := NewToken(models.User{UUID: "6f1c", Email: "user@example.com"}, TokenOptions{
+		Issuer:    "sso",
+		App:       "billing",
+		SessionID: "sid-1",
+		TTL:       ttl,
+		Claims:    claims,
+	}, "secret")
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestNewTokenRoundTrip(t *testing.T) {
+	token := newTestToken(t, time.Minute, map[string]string{"tenant": "acme", "sub": "spoofed"})
+
+	sid, err := SessionID(token)
+	require.NoError(t, err)
+	assert.Equal(t, "sid-1", sid)
+
+	claims, err := Verify(token, "secret", "sso", "billing")
+	require.NoError(t, err)
+
+	assert.Equal(t, "sso", claims.Issuer)
+	assert.Equal(t, "6f1c", claims.Subject)
+	assert.Equal(t, jwt.ClaimStrings{"billing"}, claims.Audience)
+	assert.Equal(t, "6f1c", claims.UUID)
+	assert.Equal(t, "user@example.com", claims.Email)
+	assert.Equal(t, "billing", claims.App)
+	assert.NotEmpty(t, claims.ID)
+	assert.NotNil(t, claims.IssuedAt)
+	assert.NotNil(t, claims.NotBefore)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), claims.ExpiresAt.Time, 2*time.Second)
+
+	// Дополнительные claims не переопределяют зарезервированные.
+	assert.Equal(t, map[string]any{"tenant": "acme"}, claims.Custom)
+}
+
+func TestNewTokenProfileClaims(t *testing.T) {
+	user := models.User{UUID: "6f1c", Profile: models.Profile{DisplayName: "Alice", Locale: "ru-RU", AvatarURL: "https://x/a.png"}}
+
+	token, err := NewToken(user, TokenOptions{
+		App:           "billing",
+		TTL:           time.Minute,
+		Claims:        map[string]string{"name": "static", "tenant": "acme"},
+		ProfileClaims: []string{"name", "locale", "zoneinfo"},
+	}, "secret")
+	require.NoError(t, err)
+
+	claims, err := Verify(token, "secret", "", "")
+	require.NoError(t, err)
+
+	// Пустой часовой пояс не попадает в токен, а аватар не запрошен.
+	assert.Equal(t, map[string]any{"name": "Alice", "locale": "ru-RU", "tenant": "acme"}, claims.Custom)
+}
+
+func TestNewTokenScopes(t *testing.T) {
+	token, err := NewToken(models.User{UUID: "6f1c"}, TokenOptions{
+		App:    "billing",
+		TTL:    time.Minute,
+		Scopes: []string{"invoices:read", "invoices:write"},
+	}, "secret")
+	require.NoError(t, err)
+
+	claims, err := Verify(token, "secret", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "invoices:read invoices:write", claims.Scope)
+
+	// Без областей claim не попадает в токен.
+	claims, err = Verify(newTestToken(t, time.Minute, nil), "secret", "", "")
+	require.NoError(t, err)
+	assert.Empty(t, claims.Scope)
+}
+
+func TestNewTokenTenant(t *testing.T) {
+	token, err := NewToken(models.User{UUID: "6f1c", Tenant: "acme"}, TokenOptions{
+		App:    "billing",
+		TTL:    time.Minute,
+		Claims: map[string]string{"tid": "other"},
+	}, "secret")
+	require.NoError(t, err)
+
+	// Claim tid нельзя переопределить дополнительными claims приложения.
+	claims, err := Verify(token, "secret", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.Tenant)
+	assert.Empty(t, claims.Custom)
+}
+
+func TestNewTokenUniqueID(t *testing.T) {
+	a, err := Verify(newTestToken(t, time.Minute, nil), "secret", "", "")
+	require.NoError(t, err)
+	b, err := Verify(newTestToken(t, time.Minute, nil), "secret", "", "")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, a.ID, b.ID)
+}
+
+func TestVerifyRejects(t *testing.T) {
+	token := newTestToken(t, time.Minute, nil)
+
+	for name, args := range map[string][3]string{
+		"wrong key":      {"other", "", ""},
+		"wrong issuer":   {"secret", "evil", ""},
+		"wrong audience": {"secret", "", "crm"},
+	} {
+		_, err := Verify(token, args[0], args[1], args[2])
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+
+	_, err := Verify(newTestToken(t, -time.Minute, nil), "secret", "", "")
+	assert.ErrorIs(t, err, ErrInvalidToken, "expired")
+}
+
+func TestSessionIDRejectsGarbage(t *testing.T) {
+	_, err := SessionID("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,148 @@
+package keycache
+
+import (
+	"context"
+	"go-sso/internal/lib/tenant"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// KeyStore хранилище ключей подписи, которое оборачивает кэш.
+type KeyStore interface {
+	Key(ctx context.Context, appName string) (string, error)
+	SaveKey(ctx context.Context, appName string, key string) error
+}
+
+// fetchTimeout ограничивает общий запрос к хранилищу: он не зависит от контекста
+// вызывающего, поэтому без ограничения мог бы зависнуть навсегда.
+const fetchTimeout = 30 * time.Second
+
+type entry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// Cache кэширующий декоратор над хранилищем ключей подписи.
+// Одновременные промахи по одному приложению схлопываются в один запрос к хранилищу.
+type Cache struct {
+	store        KeyStore
+	ttl          time.Duration
+	fetchTimeout time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]entry
+
+	group singleflight.Group
+
+	now func() time.Time
+}
+
+// New возвращает новый кэш ключей подписи.
+// Если ttl <= 0, ключи не кэшируются, но одновременные запросы по-прежнему схлопываются.
+func New(store KeyStore, ttl time.Duration) *Cache {
+	return &Cache{
+		store:        store,
+		ttl:          ttl,
+		fetchTimeout: fetchTimeout,
+		entries:      make(map[string]entry),
+		now:          time.Now,
+	}
+}
+
+// Key возвращает ключ подписи приложения арендатора из ctx из кэша или из хранилища.
+// Ошибки хранилища (в том числе отсутствие ключа) не кэшируются.
+// Общий запрос к хранилищу не отменяется вместе с ctx первого вызывающего:
+// каждый вызывающий перестает ждать только по своему ctx.
+func (c *Cache) Key(ctx context.Context, appName string) (string, error) {
+	id := entryID(ctx, appName)
+
+	if key, ok := c.get(id); ok {
+		return key, nil
+	}
+
+	ch := c.group.DoChan(id, func() (interface{}, error) {
+		if key, ok := c.get(id); ok {
+			return key, nil
+		}
+
+		// WithoutCancel сохраняет арендатора и прочие значения ctx.
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.fetchTimeout)
+		defer cancel()
+
+		key, err := c.store.Key(fetchCtx, appName)
+		if err != nil {
+			return "", err
+		}
+
+		c.set(id, key)
+
+		return key, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+
+		return res.Val.(string), nil
+	}
+}
+
+// SaveKey сохраняет ключ в хранилище и, в случае успеха, кладет его в кэш.
+// При ошибке запись приложения в кэше сбрасывается.
+func (c *Cache) SaveKey(ctx context.Context, appName string, key string) error {
+	if err := c.store.SaveKey(ctx, appName, key); err != nil {
+		c.Invalidate(ctx, appName)
+
+		return err
+	}
+
+	c.set(entryID(ctx, appName), key)
+
+	return nil
+}
+
+// Invalidate удаляет ключ приложения арендатора из ctx из кэша.
+func (c *Cache) Invalidate(ctx context.Context, appName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, entryID(ctx, appName))
+}
+
+// entryID возвращает ключ записи кэша: одноименные приложения разных арендаторов
+// подписывают токены разными ключами.
+func entryID(ctx context.Context, appName string) string {
+	return tenant.From(ctx) + "/" + appName
+}
+
+func (c *Cache) get(id string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[id]
+	if !ok || !c.now().Before(e.expiresAt) {
+		return "", false
+	}
+
+	return e.key, true
+}
+
+func (c *Cache) set(id, key string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[id] = entry{
+		key:       key,
+		expiresAt: c.now().Add(c.ttl),
+	}
+}
@@ -0,0 +1,205 @@
+package keycache
+
+import (
+	"context"
+	"errors"
+	"go-sso/internal/lib/tenant"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNotFound = errors.New("not found")
+
+type fakeStore struct {
+	mu    sync.Mutex
+	keys  map[string]string
+	reads atomic.Int32
+
+	// block, если не nil, задерживает чтение до закрытия канала.
+	block   chan struct{}
+	saveErr error
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{keys: make(map[string]string)}
+}
+
+func (s *fakeStore) Key(ctx context.Context, appName string) (string, error) {
+	s.reads.Add(1)
+
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[appName]
+	if !ok {
+		return "", errNotFound
+	}
+
+	return key, nil
+}
+
+func (s *fakeStore) SaveKey(_ context.Context, appName string, key string) error {
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[appName] = key
+
+	return nil
+}
+
+func TestCache_Key_CachesUntilTTL(t *testing.T) {
+	store := newFakeStore()
+	store.keys["app"] = "secret"
+
+	c := New(store, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for range 3 {
+		key, err := c.Key(context.Background(), "app")
+		require.NoError(t, err)
+		assert.Equal(t, "secret", key)
+	}
+	assert.EqualValues(t, 1, store.reads.Load())
+
+	now = now.Add(2 * time.Minute)
+
+	_, err := c.Key(context.Background(), "app")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, store.reads.Load())
+}
+
+func TestCache_Key_ErrorsAreNotCached(t *testing.T) {
+	store := newFakeStore()
+	c := New(store, time.Minute)
+
+	_, err := c.Key(context.Background(), "app")
+	require.ErrorIs(t, err, errNotFound)
+
+	store.keys["app"] = "secret"
+
+	key, err := c.Key(context.Background(), "app")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", key)
+}
+
+func TestCache_Key_DeduplicatesConcurrentMisses(t *testing.T) {
+	store := newFakeStore()
+	store.keys["app"] = "secret"
+	store.block = make(chan struct{})
+
+	c := New(store, time.Minute)
+
+	const callers = 10
+
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			key, err := c.Key(context.Background(), "app")
+			assert.NoError(t, err)
+			assert.Equal(t, "secret", key)
+		}()
+	}
+
+	// Даем горутинам встать в очередь singleflight.
+	time.Sleep(50 * time.Millisecond)
+	close(store.block)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, store.reads.Load())
+}
+
+func TestCache_Key_CancelledCallerDoesNotFailOthers(t *testing.T) {
+	store := newFakeStore()
+	store.keys["app"] = "secret"
+	store.block = make(chan struct{})
+
+	c := New(store, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.Key(ctx, "app")
+		first <- err
+	}()
+
+	// Даем первому вызову начать запрос к хранилищу.
+	time.Sleep(50 * time.Millisecond)
+
+	second := make(chan string, 1)
+	go func() {
+		key, err := c.Key(context.Background(), "app")
+		assert.NoError(t, err)
+		second <- key
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-first, context.Canceled)
+
+	close(store.block)
+	assert.Equal(t, "secret", <-second)
+	assert.EqualValues(t, 1, store.reads.Load())
+}
+
+func TestCache_SaveKey(t *testing.T) {
+	store := newFakeStore()
+	c := New(store, time.Minute)
+
+	require.NoError(t, c.SaveKey(context.Background(), "app", "secret"))
+
+	key, err := c.Key(context.Background(), "app")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", key)
+	assert.EqualValues(t, 0, store.reads.Load())
+
+	store.saveErr = errors.New("conflict")
+	require.Error(t, c.SaveKey(context.Background(), "app", "other"))
+
+	_, err = c.Key(context.Background(), "app")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, store.reads.Load())
+}
+
+func TestCache_Key_SeparatesTenants(t *testing.T) {
+	store := newFakeStore()
+	store.keys["app"] = "default-secret"
+	c := New(store, time.Minute)
+
+	key, err := c.Key(context.Background(), "app")
+	require.NoError(t, err)
+	assert.Equal(t, "default-secret", key)
+
+	// Одноименное приложение другого арендатора не должно получить закэшированный ключ.
+	store.keys["app"] = "acme-secret"
+	key, err = c.Key(tenant.With(context.Background(), "acme"), "app")
+	require.NoError(t, err)
+	assert.Equal(t, "acme-secret", key)
+	assert.EqualValues(t, 2, store.reads.Load())
+
+	c.Invalidate(tenant.With(context.Background(), "acme"), "app")
+	key, err = c.Key(context.Background(), "app")
+	require.NoError(t, err)
+	assert.Equal(t, "default-secret", key)
+	assert.EqualValues(t, 2, store.reads.Load())
+}
@@ -0,0 +1,215 @@
+// Package ldap проверяет учетные данные пользователей в каталоге LDAP / Active Directory
+// по схеме search-then-bind: служебная учетная запись находит запись пользователя,
+// затем выполняется bind от имени найденного DN с паролем пользователя.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// LoginPlaceholder заменяется в UserFilter экранированным логином пользователя.
+const LoginPlaceholder = "{login}"
+
+// Ошибки, которые может вернуть Client.
+var (
+	// ErrInvalidCredentials пользователь не найден, найден неоднозначно или пароль неверный.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUnavailable        = errors.New("directory unavailable")
+)
+
+// Config параметры подключения к каталогу и поиска пользователей.
+type Config struct {
+	// URL адрес сервера: ldap://host:389 или ldaps://host:636.
+	URL string
+	// StartTLS включает TLS командой StartTLS на соединении ldap://.
+	StartTLS bool
+	// TLS настройки TLS для ldaps:// и StartTLS; nil — системные корневые сертификаты.
+	TLS *tls.Config
+
+	// BindDN и BindPassword служебная учетная запись для поиска; пустой BindDN — анонимный поиск.
+	BindDN       string
+	BindPassword string
+
+	BaseDN string
+	// UserFilter фильтр поиска пользователя, содержащий LoginPlaceholder,
+	// например (&(objectClass=person)(mail={login})).
+	UserFilter string
+
+	// IDAttribute неизменяемый идентификатор записи (entryUUID, в AD — objectGUID).
+	IDAttribute    string
+	EmailAttribute string
+	NameAttribute  string
+	// GroupAttribute атрибут со списком DN групп пользователя (memberOf).
+	GroupAttribute string
+
+	// Timeout ограничивает подключение и каждую операцию с каталогом.
+	Timeout time.Duration
+}
+
+// Entry запись пользователя в каталоге.
+type Entry struct {
+	DN string
+	// ID значение IDAttribute; двоичные значения (objectGUID) кодируются в hex.
+	ID     string
+	Email  string
+	Name   string
+	Groups []string
+}
+
+// Client клиент каталога. Для каждой проверки открывается отдельное соединение.
+type Client struct {
+	cfg Config
+}
+
+// New возвращает клиент каталога. Незаданные атрибуты заменяются значениями
+// по умолчанию: entryUUID, mail, displayName и memberOf.
+func New(cfg Config) *Client {
+	if cfg.IDAttribute == "" {
+		cfg.IDAttribute = "entryUUID"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "displayName"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+
+	return &Client{cfg: cfg}
+}
+
+// Authenticate находит пользователя по логину и проверяет его пароль.
+func (c *Client) Authenticate(ctx context.Context, login, password string) (Entry, error) {
+	// Bind с пустым паролем — неаутентифицированный bind (RFC 4513), многие серверы
+	// считают его успешным для любого DN.
+	if login == "" || password == "" {
+		return Entry{}, fmt.Errorf("%w: empty login or password", ErrInvalidCredentials)
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer conn.Close()
+
+	// go-ldap не принимает контекст: при его отмене соединение закрывается.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if c.cfg.BindDN != "" {
+		if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+			// Ошибка служебной учетной записи — проблема конфигурации, а не пользователя.
+			return Entry{}, fmt.Errorf("%w: service bind: %v", ErrUnavailable, err)
+		}
+	}
+
+	entry, err := c.search(conn, login)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return Entry{}, fmt.Errorf("%w: wrong password", ErrInvalidCredentials)
+		}
+
+		return Entry{}, fmt.Errorf("%w: user bind: %v", ErrUnavailable, err)
+	}
+
+	return entry, nil
+}
+
+// dial подключается к каталогу и при необходимости включает StartTLS.
+func (c *Client) dial(ctx context.Context) (*goldap.Conn, error) {
+	timeout := c.cfg.Timeout
+	if deadline, ok := ctx.Deadline(); ok && (timeout == 0 || time.Until(deadline) < timeout) {
+		timeout = time.Until(deadline)
+	}
+
+	conn, err := goldap.DialURL(c.cfg.URL,
+		goldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		goldap.DialWithTLSConfig(c.cfg.TLS),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	if timeout > 0 {
+		conn.SetTimeout(timeout)
+	}
+
+	if c.cfg.StartTLS {
+		if err := conn.StartTLS(c.cfg.TLS); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: start tls: %v", ErrUnavailable, err)
+		}
+	}
+
+	return conn, nil
+}
+
+// search находит единственную запись пользователя по логину.
+func (c *Client) search(conn *goldap.Conn, login string) (Entry, error) {
+	filter := strings.ReplaceAll(c.cfg.UserFilter, LoginPlaceholder, goldap.EscapeFilter(login))
+
+	// Двух записей достаточно, чтобы обнаружить неоднозначный логин.
+	req := goldap.NewSearchRequest(
+		c.cfg.BaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		2,
+		int(c.cfg.Timeout.Seconds()),
+		false,
+		filter,
+		[]string{c.cfg.IDAttribute, c.cfg.EmailAttribute, c.cfg.NameAttribute, c.cfg.GroupAttribute},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	switch {
+	case goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded):
+		return Entry{}, fmt.Errorf("%w: login matches several entries", ErrInvalidCredentials)
+	case err != nil:
+		return Entry{}, fmt.Errorf("%w: search: %v", ErrUnavailable, err)
+	case len(res.Entries) == 0:
+		return Entry{}, fmt.Errorf("%w: user not found", ErrInvalidCredentials)
+	case len(res.Entries) > 1:
+		return Entry{}, fmt.Errorf("%w: login matches several entries", ErrInvalidCredentials)
+	}
+
+	e := res.Entries[0]
+
+	entry := Entry{
+		DN:     e.DN,
+		ID:     attributeString(e.GetEqualFoldRawAttributeValue(c.cfg.IDAttribute)),
+		Email:  e.GetEqualFoldAttributeValue(c.cfg.EmailAttribute),
+		Name:   e.GetEqualFoldAttributeValue(c.cfg.NameAttribute),
+		Groups: e.GetEqualFoldAttributeValues(c.cfg.GroupAttribute),
+	}
+	if entry.ID == "" {
+		return Entry{}, fmt.Errorf("%w: entry %s has no %s", ErrUnavailable, e.DN, c.cfg.IDAttribute)
+	}
+
+	return entry, nil
+}
+
+// attributeString возвращает текстовое значение атрибута как есть, а двоичное — в hex.
+func attributeString(v []byte) string {
+	if utf8.Valid(v) {
+		return string(v)
+	}
+
+	return hex.EncodeToString(v)
+}
@@ -0,0 +1,231 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer минимальный сервер LDAP: simple bind по паролям из binds и поиск,
+// результаты которого заданы для каждого фильтра в results.
+type fakeServer struct {
+	ln      net.Listener
+	binds   map[string]string
+	results map[string][]*goldap.Entry
+}
+
+func newFakeServer(t *testing.T, ln net.Listener) *fakeServer {
+	t.Helper()
+
+	s := &fakeServer{
+		ln:      ln,
+		binds:   map[string]string{"cn=sso,dc=corp": "svc-pass"},
+		results: make(map[string][]*goldap.Entry),
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		req, err := ber.ReadPacket(conn)
+		if err != nil || len(req.Children) < 2 {
+			return
+		}
+
+		id := req.Children[0].Value.(int64)
+		op := req.Children[1]
+
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			name := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+
+			code := goldap.LDAPResultSuccess
+			if want, ok := s.binds[name]; !ok || want != password {
+				code = goldap.LDAPResultInvalidCredentials
+			}
+			s.write(conn, id, result(goldap.ApplicationBindResponse, code))
+		case goldap.ApplicationSearchRequest:
+			filter, err := goldap.DecompileFilter(op.Children[6])
+			if err != nil {
+				s.write(conn, id, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultProtocolError))
+				continue
+			}
+			for _, e := range s.results[filter] {
+				s.write(conn, id, searchEntry(e))
+			}
+			s.write(conn, id, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess))
+		default:
+			return
+		}
+	}
+}
+
+func (s *fakeServer) write(conn net.Conn, id int64, op *ber.Packet) {
+	msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "message")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "id"))
+	msg.AppendChild(op)
+
+	_, _ = conn.Write(msg.Bytes())
+}
+
+func result(tag ber.Tag, code int) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matched dn"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "message"))
+
+	return p
+}
+
+func searchEntry(e *goldap.Entry) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "dn"))
+
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for _, a := range e.Attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, a.Name, "type"))
+
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+		for _, v := range a.Values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(vals)
+		attrs.AppendChild(attr)
+	}
+	p.AppendChild(attrs)
+
+	return p
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return ln
+}
+
+func alice() *goldap.Entry {
+	return goldap.NewEntry("uid=alice,ou=people,dc=corp", map[string][]string{
+		"entryUUID":   {"6f1c0e9a-6c3e-4d5b-8a1f-2b7f3c9d1e00"},
+		"mail":        {"alice@corp.example"},
+		"displayName": {"Alice"},
+		"memberOf":    {"cn=billing-admins,ou=groups,dc=corp", "cn=staff,ou=groups,dc=corp"},
+	})
+}
+
+func testConfig(url string) Config {
+	return Config{
+		URL:          url,
+		BindDN:       "cn=sso,dc=corp",
+		BindPassword: "svc-pass",
+		BaseDN:       "dc=corp",
+		UserFilter:   "(&(objectClass=person)(mail={login}))",
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	srv := newFakeServer(t, listen(t))
+	srv.binds["uid=alice,ou=people,dc=corp"] = "alice-pass"
+	srv.results["(&(objectClass=person)(mail=alice@corp.example))"] = []*goldap.Entry{alice()}
+
+	c := New(testConfig("ldap://" + srv.addr()))
+
+	entry, err := c.Authenticate(context.Background(), "alice@corp.example", "alice-pass")
+	require.NoError(t, err)
+	assert.Equal(t, Entry{
+		DN:     "uid=alice,ou=people,dc=corp",
+		ID:     "6f1c0e9a-6c3e-4d5b-8a1f-2b7f3c9d1e00",
+		Email:  "alice@corp.example",
+		Name:   "Alice",
+		Groups: []string{"cn=billing-admins,ou=groups,dc=corp", "cn=staff,ou=groups,dc=corp"},
+	}, entry)
+
+	_, err = c.Authenticate(context.Background(), "alice@corp.example", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAuthenticateRejects(t *testing.T) {
+	srv := newFakeServer(t, listen(t))
+	srv.binds["uid=alice,ou=people,dc=corp"] = "alice-pass"
+	srv.results["(&(objectClass=person)(mail=alice@corp.example))"] = []*goldap.Entry{alice()}
+	srv.results["(&(objectClass=person)(mail=dup@corp.example))"] = []*goldap.Entry{alice(), alice()}
+
+	c := New(testConfig("ldap://" + srv.addr()))
+
+	for name, tc := range map[string]struct {
+		login, password string
+		want            error
+	}{
+		"empty password": {"alice@corp.example", "", ErrInvalidCredentials},
+		"unknown user":   {"bob@corp.example", "x", ErrInvalidCredentials},
+		"ambiguous":      {"dup@corp.example", "alice-pass", ErrInvalidCredentials},
+		// Спецсимволы логина экранируются и не меняют фильтр.
+		"filter injection": {"*)(mail=alice@corp.example", "alice-pass", ErrInvalidCredentials},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.Authenticate(context.Background(), tc.login, tc.password)
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+
+	cfg := testConfig("ldap://" + srv.addr())
+	cfg.BindPassword = "wrong"
+	_, err := New(cfg).Authenticate(context.Background(), "alice@corp.example", "alice-pass")
+	assert.ErrorIs(t, err, ErrUnavailable, "service bind failed")
+
+	_, err = New(testConfig("ldap://127.0.0.1:1")).Authenticate(context.Background(), "alice@corp.example", "alice-pass")
+	assert.ErrorIs(t, err, ErrUnavailable, "server is down")
+}
+
+func TestAuthenticateLDAPS(t *testing.T) {
+	// Сертификат для 127.0.0.1 берем у тестового HTTPS-сервера.
+	https := httptest.NewTLSServer(nil)
+	https.Close()
+
+	srv := newFakeServer(t, tls.NewListener(listen(t), https.TLS))
+	srv.binds["uid=alice,ou=people,dc=corp"] = "alice-pass"
+	srv.results["(&(objectClass=person)(mail=alice@corp.example))"] = []*goldap.Entry{alice()}
+
+	cfg := testConfig("ldaps://" + srv.addr())
+
+	_, err := New(cfg).Authenticate(context.Background(), "alice@corp.example", "alice-pass")
+	assert.ErrorIs(t, err, ErrUnavailable, "untrusted certificate")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(https.Certificate())
+	cfg.TLS = &tls.Config{RootCAs: roots}
+
+	entry, err := New(cfg).Authenticate(context.Background(), "alice@corp.example", "alice-pass")
+	require.NoError(t, err)
+	assert.Equal(t, "uid=alice,ou=people,dc=corp", entry.DN)
+}
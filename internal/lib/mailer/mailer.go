@@ -0,0 +1,194 @@
+// Package mailer отправляет служебные письма пользователям: через SMTP или,
+// для локальной разработки, в лог.
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidMessage письмо нельзя отправить: неверный адрес или перевод строки в заголовке.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Message текстовое письмо одному получателю.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Log пишет письма в лог вместо отправки. Только для локальной разработки:
+// в лог попадают одноразовые ссылки и коды.
+type Log struct {
+	log *zap.SugaredLogger
+}
+
+// NewLog возвращает Mailer, пишущий письма в лог.
+func NewLog(log *zap.SugaredLogger) *Log {
+	return &Log{log: log}
+}
+
+func (l *Log) Send(_ context.Context, msg Message) error {
+	l.log.Infow("email", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+
+	return nil
+}
+
+// SMTPConfig параметры SMTP-сервера.
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username и Password для AUTH PLAIN; пустой Username — без аутентификации.
+	Username string
+	Password string
+	// ImplicitTLS подключаться сразу по TLS (порт 465). Иначе используется STARTTLS,
+	// если сервер его поддерживает.
+	ImplicitTLS bool
+	// TLS настройки TLS; nil — системные корневые сертификаты.
+	TLS *tls.Config
+	// Timeout ограничивает отправку одного письма.
+	Timeout time.Duration
+}
+
+// SMTP отправляет письма через SMTP-сервер. Для каждого письма открывается отдельное соединение.
+type SMTP struct {
+	cfg  SMTPConfig
+	from string
+}
+
+// NewSMTP возвращает Mailer, отправляющий письма от адреса from.
+func NewSMTP(cfg SMTPConfig, from string) *SMTP {
+	return &SMTP{cfg: cfg, from: from}
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := s.compose(msg)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	// net/smtp не принимает контекст: срок контекста переносится на соединение.
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+	defer c.Close()
+
+	if !s.cfg.ImplicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(s.tlsConfig()); err != nil {
+				return fmt.Errorf("starttls: %w", err)
+			}
+		}
+	}
+
+	if s.cfg.Username != "" {
+		// PlainAuth отказывается передавать пароль без TLS, кроме соединений с localhost.
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.from); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("rcpt to: %w", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+
+	return c.Quit()
+}
+
+func (s *SMTP) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+
+	if s.cfg.ImplicitTLS {
+		d := &tls.Dialer{Config: s.tlsConfig()}
+		return d.DialContext(ctx, "tcp", addr)
+	}
+
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+func (s *SMTP) tlsConfig() *tls.Config {
+	if s.cfg.TLS != nil {
+		return s.cfg.TLS
+	}
+
+	return &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}
+}
+
+// compose собирает письмо с заголовками. Значения заголовков проверяются на переводы строк,
+// чтобы через адрес или тему нельзя было добавить свои заголовки или получателей.
+func (s *SMTP) compose(msg Message) ([]byte, error) {
+	for _, v := range []string{s.from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("%w: line break in header", ErrInvalidMessage)
+		}
+	}
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("%w: recipient: %v", ErrInvalidMessage, err)
+	}
+
+	var b strings.Builder
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", s.from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return []byte(b.String()), nil
+}
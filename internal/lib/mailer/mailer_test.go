@@ -0,0 +1,119 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP минимальный SMTP-сервер без TLS и аутентификации, сохраняющий принятые письма.
+type fakeSMTP struct {
+	ln   net.Listener
+	msgs chan string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &fakeSMTP{ln: ln, msgs: make(chan string, 1)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 fake ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, _, _ := strings.Cut(strings.ToUpper(line), " ")
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 fake")
+		case "MAIL", "RCPT":
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.msgs <- string(data)
+			_ = tp.PrintfLine("250 queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) config() SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	return SMTPConfig{Host: host, Port: p, Timeout: 5 * time.Second}
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := newFakeSMTP(t)
+	m := NewSMTP(srv.config(), "sso@example.com")
+
+	err := m.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "Код входа",
+		Body:    "Your code: 123456\nIt expires in 10 minutes.",
+	})
+	require.NoError(t, err)
+
+	data := <-srv.msgs
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(data))).ReadMIMEHeader()
+	require.NoError(t, err)
+	assert.Equal(t, "sso@example.com", msg.Get("From"))
+	assert.Equal(t, "alice@example.com", msg.Get("To"))
+	assert.Equal(t, "=?utf-8?q?=D0=9A=D0=BE=D0=B4_=D0=B2=D1=85=D0=BE=D0=B4=D0=B0?=", msg.Get("Subject"))
+	// ReadDotBytes заменяет CRLF на LF.
+	assert.Contains(t, data, "\n\nYour code: 123456\nIt expires in 10 minutes.")
+}
+
+func TestSMTPRejectsHeaderInjection(t *testing.T) {
+	srv := newFakeSMTP(t)
+	m := NewSMTP(srv.config(), "sso@example.com")
+
+	for _, msg := range []Message{
+		{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "hi"},
+		{To: "alice@example.com", Subject: "hi\nBcc: eve@example.com"},
+		{To: "not an address", Subject: "hi"},
+	} {
+		err := m.Send(context.Background(), msg)
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	}
+}
@@ -0,0 +1,95 @@
+// Package ratelimit ограничивает частоту запросов по алгоритму token bucket.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit параметры token bucket: Rate токенов в секунду и емкость Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limiter хранилище состояния ограничителей. Реализация в памяти — Memory;
+// для нескольких экземпляров сервиса можно подключить общее хранилище (например, Redis).
+type Limiter interface {
+	// Allow расходует один токен из корзины key. Если токенов нет, возвращает false
+	// и время, через которое появится следующий.
+	Allow(ctx context.Context, key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// full заполнилась бы корзина к моменту now.
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst)
+}
+
+// Memory хранит корзины в памяти процесса. Заполненные корзины, к которым
+// долго не обращались, удаляются, чтобы память не росла с числом клиентов.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// sweepInterval как часто удалять неиспользуемые корзины.
+const sweepInterval = time.Minute
+
+// NewMemory возвращает Limiter, хранящий корзины в памяти.
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (m *Memory) Allow(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.limit = limit
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+
+	return false, wait, nil
+}
+
+// sweep удаляет корзины, которые успели бы заполниться полностью.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if b.full(now) {
+			delete(m.buckets, key)
+		}
+	}
+}
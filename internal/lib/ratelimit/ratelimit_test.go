@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory_Allow(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := m.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	ok, retry, err := m.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retry)
+
+	// Другой ключ расходует свою корзину.
+	ok, _, _ = m.Allow(ctx, "b", limit)
+	assert.True(t, ok)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, retry, _ = m.Allow(ctx, "a", limit)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retry)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _, _ = m.Allow(ctx, "a", limit)
+	assert.True(t, ok)
+}
+
+func TestMemory_Sweep(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 1}
+	ctx := context.Background()
+
+	_, _, _ = m.Allow(ctx, "idle", limit)
+
+	now = now.Add(2 * sweepInterval)
+	_, _, _ = m.Allow(ctx, "active", limit)
+
+	assert.NotContains(t, m.buckets, "idle")
+	assert.Contains(t, m.buckets, "active")
+}
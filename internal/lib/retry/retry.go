@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy описывает политику повторов.
+type Policy struct {
+	// Attempts общее число попыток, включая первую. Значение <= 1 отключает повторы.
+	Attempts int
+	// InitialBackoff задержка перед второй попыткой; далее удваивается.
+	InitialBackoff time.Duration
+	// MaxBackoff верхняя граница задержки между попытками.
+	MaxBackoff time.Duration
+	// AttemptTimeout ограничение времени одной попытки. 0 — без ограничения.
+	AttemptTimeout time.Duration
+}
+
+// Do выполняет fn и повторяет ее с экспоненциальной задержкой (с джиттером),
+// пока retryable возвращает true и не исчерпаны попытки.
+// Возвращает ошибку последней попытки.
+func Do(
+	ctx context.Context,
+	p Policy,
+	retryable func(error) bool,
+	fn func(ctx context.Context) error,
+) error {
+	attempts := max(p.Attempts, 1)
+	backoff := p.InitialBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = try(ctx, p.AttemptTimeout, fn)
+		if err == nil || attempt >= attempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(jitter(backoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+func try(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return fn(ctx)
+}
+
+// jitter возвращает случайную задержку в диапазоне [d/2, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+
+	half := d / 2
+
+	return half + rand.N(half)
+}
@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errFatal     = errors.New("fatal")
+)
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func testPolicy() Policy {
+	return Policy{
+		Attempts:       3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+	}
+}
+
+func TestDo_RetriesTransientErrors(t *testing.T) {
+	calls := 0
+
+	err := Do(context.Background(), testPolicy(), isTransient, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestDo_StopsOnNonRetryableError(t *testing.T) {
+	calls := 0
+
+	err := Do(context.Background(), testPolicy(), isTransient, func(context.Context) error {
+		calls++
+		return errFatal
+	})
+
+	require.ErrorIs(t, err, errFatal)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDo_ReturnsLastErrorWhenAttemptsExhausted(t *testing.T) {
+	calls := 0
+
+	err := Do(context.Background(), testPolicy(), isTransient, func(context.Context) error {
+		calls++
+		return errTransient
+	})
+
+	require.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 3, calls)
+}
+
+func TestDo_AppliesAttemptTimeout(t *testing.T) {
+	p := testPolicy()
+	p.Attempts = 1
+	p.AttemptTimeout = 10 * time.Millisecond
+
+	err := Do(context.Background(), p, isTransient, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDo_StopsWhenContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+
+	err := Do(ctx, testPolicy(), isTransient, func(context.Context) error {
+		calls++
+		cancel()
+		return errTransient
+	})
+
+	require.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,45 @@
+// Package scope содержит правила записи областей доступа OAuth 2.0 (RFC 6749, раздел 3.3).
+package scope
+
+import (
+	"slices"
+	"strings"
+)
+
+// Valid проверяет, что область непустая и состоит из допустимых символов: %x21 / %x23-5B / %x5D-7E.
+func Valid(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if b < 0x21 || b > 0x7e || b == '"' || b == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Parse разбирает значение параметра или claim scope — области через пробел.
+func Parse(s string) []string {
+	return strings.Fields(s)
+}
+
+// Join собирает значение параметра или claim scope.
+func Join(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// Missing возвращает области из want, которых нет в have, без повторов.
+func Missing(have, want []string) []string {
+	var missing []string
+	for _, s := range want {
+		if !slices.Contains(have, s) && !slices.Contains(missing, s) {
+			missing = append(missing, s)
+		}
+	}
+
+	return missing
+}
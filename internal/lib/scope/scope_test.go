@@ -0,0 +1,21 @@
+package scope
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	for _, s := range []string{"openid", "invoices:read", "https://api.example.com/x"} {
+		assert.True(t, Valid(s), s)
+	}
+	for _, s := range []string{"", "a b", `a"b`, `a\b`, "счета"} {
+		assert.False(t, Valid(s), s)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	assert.Equal(t, []string{"b", "c"}, Missing([]string{"a"}, []string{"a", "b", "c", "b"}))
+	assert.Empty(t, Missing([]string{"a", "b"}, []string{"b"}))
+}
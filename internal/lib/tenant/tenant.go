@@ -0,0 +1,37 @@
+// Package tenant определяет арендатора — организацию-клиента платформы, в рамках которой
+// выполняется запрос. Пользователи, приложения и ключи подписи изолированы по арендаторам.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default арендатор запросов, в которых он не указан. К нему относятся все данные,
+// созданные до появления арендаторов.
+const Default = "default"
+
+// idPattern допустимый идентификатор: строчные латинские буквы, цифры и дефис.
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Valid проверяет идентификатор арендатора. Он входит в путь секретов Vault,
+// поэтому набор символов ограничен.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type key struct{}
+
+// With возвращает контекст с арендатором id.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From возвращает арендатора из контекста или Default, если он не задан.
+func From(ctx context.Context) string {
+	if id, _ := ctx.Value(key{}).(string); id != "" {
+		return id
+	}
+
+	return Default
+}
@@ -0,0 +1,23 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	for _, id := range []string{"default", "acme", "acme-eu-1", "42"} {
+		assert.True(t, Valid(id), id)
+	}
+	for _, id := range []string{"", "Acme", "-acme", "acme/eu", "acme eu", "../x"} {
+		assert.False(t, Valid(id), id)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, Default, From(ctx))
+	assert.Equal(t, "acme", From(With(ctx, "acme")))
+}
@@ -2,9 +2,14 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go-sso/internal/lib/breaker"
+	"go-sso/internal/lib/retry"
+	"go-sso/internal/lib/tenant"
 	"go-sso/internal/services/auth"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/vault-client-go"
@@ -16,21 +21,34 @@ const (
 	signingKeyDataKey = "key"
 	mountPath         = "kv"
 	secretsPath       = "go-sso/clients"
+	tenantsPath       = "go-sso/tenants"
+
+	// casCreateOnly значение check-and-set, при котором запись разрешена,
+	// только если секрета еще не существует.
+	casCreateOnly = 0
+	casMismatch   = "check-and-set parameter did not match"
 )
 
 type Client struct {
 	api *vault.Client
+
+	retryPolicy retry.Policy
+	breaker     *breaker.Breaker
 }
 
 // TODO: переместить в storage слой ?
 
-// Создать новый клиент Vault
+// Создать новый клиент Vault.
+// Временные ошибки повторяются согласно retryPolicy, а при серии отказов
+// cb размыкается и вызовы сразу завершаются с auth.ErrUnavailable.
 func New(
 	ctx context.Context,
 	log *zap.SugaredLogger,
 	addr string,
 	token string,
 	timeout time.Duration,
+	retryPolicy retry.Policy,
+	cb *breaker.Breaker,
 ) *Client {
 	c, err := vault.New(
 		vault.WithAddress(addr),
@@ -47,25 +65,107 @@ func New(
 	}
 
 	return &Client{
-		api: c,
+		api:         c,
+		retryPolicy: retryPolicy,
+		breaker:     cb,
+	}
+}
+
+// Close сбрасывает токен и закрывает простаивающие соединения с Vault.
+func (c *Client) Close() error {
+	c.api.ClearToken()
+
+	if httpClient := c.api.Configuration().HTTPClient; httpClient != nil {
+		httpClient.CloseIdleConnections()
 	}
+
+	return nil
+}
+
+// Ping проверяет, что Vault инициализирован, распечатан и отвечает.
+// Проверка не проходит через circuit breaker, чтобы не влиять на его состояние.
+func (c *Client) Ping(ctx context.Context) error {
+	const op = "vault.Ping"
+
+	_, err := c.api.System.ReadHealthStatus(ctx)
+	// 429 возвращает исправный standby-узел.
+	if err != nil && !vault.IsErrorStatus(err, http.StatusTooManyRequests) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
 }
 
+// SaveKey атомарно создает ключ подписи приложения (KV v2 check-and-set).
+// Если ключ уже существует, возвращает auth.ErrKeyExists и не перезаписывает его.
 func (c *Client) SaveKey(ctx context.Context, appName string, key string) error {
 	const op = "vault.SaveKey"
 
-	appPath := fmt.Sprintf("%s/%s", secretsPath, appName)
+	appPath := keyPath(ctx, appName)
 
 	secret := map[string]interface{}{
 		signingKeyDataKey: key,
 	}
 
-	_, err := c.api.Secrets.KvV2Write(ctx,
-		appPath,
-		schema.KvV2WriteRequest{
-			Data: secret,
-		},
-		vault.WithMountPath(mountPath))
+	// Повтор безопасен: если первая запись дошла до Vault, повтор вернет
+	// несовпадение check-and-set, и вызывающий перечитает сохраненный ключ.
+	err := c.call(ctx, func(ctx context.Context) error {
+		_, err := c.api.Secrets.KvV2Write(ctx,
+			appPath,
+			schema.KvV2WriteRequest{
+				Data: secret,
+				Options: map[string]interface{}{
+					"cas": casCreateOnly,
+				},
+			},
+			vault.WithMountPath(mountPath))
+		return err
+	})
+	if isCASMismatch(err) {
+		return fmt.Errorf("%s: %w", op, auth.ErrKeyExists)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReplaceKey безусловно записывает новую версию ключа подписи приложения (ротация).
+// Токены, подписанные предыдущим ключом, перестают проходить проверку.
+func (c *Client) ReplaceKey(ctx context.Context, appName string, key string) error {
+	const op = "vault.ReplaceKey"
+
+	appPath := keyPath(ctx, appName)
+
+	err := c.call(ctx, func(ctx context.Context) error {
+		_, err := c.api.Secrets.KvV2Write(ctx,
+			appPath,
+			schema.KvV2WriteRequest{
+				Data: map[string]interface{}{
+					signingKeyDataKey: key,
+				},
+			},
+			vault.WithMountPath(mountPath))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteKey удаляет ключ подписи приложения вместе со всеми версиями.
+func (c *Client) DeleteKey(ctx context.Context, appName string) error {
+	const op = "vault.DeleteKey"
+
+	appPath := keyPath(ctx, appName)
+
+	err := c.call(ctx, func(ctx context.Context) error {
+		_, err := c.api.Secrets.KvV2DeleteMetadataAndAllVersions(ctx, appPath, vault.WithMountPath(mountPath))
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -76,24 +176,87 @@ func (c *Client) SaveKey(ctx context.Context, appName string, key string) error
 func (c *Client) Key(ctx context.Context, appName string) (string, error) {
 	const op = "vault.Key"
 
-	appPath := fmt.Sprintf("%s/%s", secretsPath, appName)
+	appPath := keyPath(ctx, appName)
 
-	resp, err := c.api.Secrets.KvV2Read(ctx, appPath, vault.WithMountPath(mountPath))
+	var resp *vault.Response[schema.KvV2ReadResponse]
+	err := c.call(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.api.Secrets.KvV2Read(ctx, appPath, vault.WithMountPath(mountPath))
+		return err
+	})
+	if vault.IsErrorStatus(err, http.StatusNotFound) {
+		return "", fmt.Errorf("%s: %w: %v", op, auth.ErrKeyNotFound, err)
+	}
 	if err != nil {
-		respErr := err.(*vault.ResponseError)
-		if respErr != nil {
-			if respErr.StatusCode == http.StatusNotFound {
-				return "", fmt.Errorf("%w: %v", auth.ErrKeyNotFound, err)
-			}
-		}
-
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	key, ok := resp.Data.Data[signingKeyDataKey].(string)
 	if !ok || key == "" {
-		return "nil", fmt.Errorf("%s: %w", op, auth.ErrKeyNotFound)
+		return "", fmt.Errorf("%s: %w", op, auth.ErrKeyNotFound)
 	}
 
 	return key, nil
 }
+
+// keyPath возвращает путь ключа подписи приложения арендатора из ctx.
+// Ключи арендатора по умолчанию остаются по прежнему пути.
+func keyPath(ctx context.Context, appName string) string {
+	if t := tenant.From(ctx); t != tenant.Default {
+		return fmt.Sprintf("%s/%s/%s", tenantsPath, t, appName)
+	}
+
+	return fmt.Sprintf("%s/%s", secretsPath, appName)
+}
+
+// call выполняет запрос к Vault через circuit breaker с повторами временных ошибок.
+// Если Vault недоступен, возвращает ошибку, оборачивающую auth.ErrUnavailable.
+// Ошибки после отмены или истечения ctx вызывающего временными не считаются:
+// они не повторяются, не размыкают cb и не выдаются за недоступность Vault.
+func (c *Client) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	transient := func(err error) bool {
+		return ctx.Err() == nil && isTransient(err)
+	}
+
+	err := c.breaker.Do(func() error {
+		return retry.Do(ctx, c.retryPolicy, transient, fn)
+	}, transient)
+	if errors.Is(err, breaker.ErrOpen) || transient(err) {
+		return fmt.Errorf("%w: %v", auth.ErrUnavailable, err)
+	}
+
+	return err
+}
+
+// isTransient сообщает, является ли ошибка Vault временной: сетевые ошибки,
+// таймауты, 429 и 5xx. Ответы 4xx (например, 404) временными не считаются.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var respErr *vault.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == http.StatusTooManyRequests ||
+			(respErr.StatusCode >= http.StatusInternalServerError &&
+				respErr.StatusCode != http.StatusNotImplemented)
+	}
+
+	return true
+}
+
+// isCASMismatch сообщает, отклонил ли Vault запись из-за несовпадения check-and-set.
+func isCASMismatch(err error) bool {
+	var respErr *vault.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+
+	for _, e := range respErr.Errors {
+		if strings.Contains(e, casMismatch) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,65 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+var migrationNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// CreatePaths возвращает пути к файлам новой миграции name в каталоге dir.
+// Номер версии на единицу больше максимального среди существующих миграций.
+func CreatePaths(dir, name string) (up, down string, err error) {
+	const op = "migrator.CreatePaths"
+
+	if !migrationNameRe.MatchString(name) {
+		return "", "", fmt.Errorf("%s: name must match %s", op, migrationNameRe)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var last uint
+	for _, e := range entries {
+		m, err := source.DefaultParse(e.Name())
+		if err != nil {
+			continue
+		}
+
+		last = max(last, m.Version)
+	}
+
+	base := fmt.Sprintf("%d_%s", last+1, name)
+
+	return filepath.Join(dir, base+".up.sql"), filepath.Join(dir, base+".down.sql"), nil
+}
+
+// Create создает пустые файлы up и down для новой миграции name в каталоге dir.
+func Create(dir, name string) (up, down string, err error) {
+	const op = "migrator.Create"
+
+	up, down, err = CreatePaths(dir, name)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, path := range []string{up, down} {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := f.Close(); err != nil {
+			return "", "", fmt.Errorf("%s: %w", op, errors.Join(err, os.Remove(path)))
+		}
+	}
+
+	return up, down, nil
+}
@@ -0,0 +1,21 @@
+package migrator
+
+import (
+	"database/sql"
+	"fmt"
+	"go-sso/migrations"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// NewEmbedded создает Migrator с миграциями, встроенными в бинарник.
+func NewEmbedded(db *sql.DB, table string) (*Migrator, error) {
+	const op = "migrator.NewEmbedded"
+
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return New(db, "iofs", src, table)
+}
@@ -0,0 +1,262 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// ErrLockTimeout возвращается, если за отведенное время не удалось захватить
+// advisory lock, т.е. миграции уже выполняет другой процесс.
+var ErrLockTimeout = errors.New("timed out waiting for migration lock")
+
+const lockPollInterval = time.Second
+
+// Migration миграция из источника и ее состояние в базе данных.
+type Migration struct {
+	Version    uint
+	Identifier string
+	Applied    bool
+}
+
+// Status состояние схемы базы данных.
+type Status struct {
+	// Version текущая версия схемы; 0, если миграции не применялись.
+	Version uint
+	Dirty   bool
+	// Migrations все миграции источника по возрастанию версии.
+	Migrations []Migration
+}
+
+// Pending возвращает версии непримененных миграций.
+func (s Status) Pending() []Migration {
+	var pending []Migration
+	for _, m := range s.Migrations {
+		if !m.Applied {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending
+}
+
+// Migrator применяет миграции из source к базе данных PostgreSQL.
+type Migrator struct {
+	m       *migrate.Migrate
+	db      *sql.DB
+	src     source.Driver
+	lockKey int64
+
+	// lockConn соединение, на котором удерживается advisory lock.
+	lockConn *sql.Conn
+}
+
+// New создает Migrator. sourceName используется только в сообщениях golang-migrate.
+// Migrator владеет src и закрывает его в Close; db вызывающий код закрывает сам.
+func New(db *sql.DB, sourceName string, src source.Driver, table string) (*Migrator, error) {
+	const op = "migrator.New"
+
+	driver, err := postgres.WithInstance(db, &postgres.Config{
+		MigrationsTable: table,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	m, err := migrate.NewWithInstance(sourceName, src, "postgres", driver)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{
+		m:       m,
+		db:      db,
+		src:     src,
+		lockKey: lockKey(table),
+	}, nil
+}
+
+// SetLogger задает логгер, в который golang-migrate пишет применяемые миграции.
+func (m *Migrator) SetLogger(l migrate.Logger) {
+	m.m.Log = l
+}
+
+// Lock захватывает сессионный advisory lock, чтобы несколько процессов
+// (например, параллельные деплои) не мигрировали базу одновременно.
+// Ждет освобождения блокировки не дольше timeout.
+func (m *Migrator) Lock(ctx context.Context, timeout time.Duration) error {
+	const op = "migrator.Lock"
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var locked bool
+		err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, m.lockKey).Scan(&locked)
+		if err == nil && locked {
+			m.lockConn = conn
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			conn.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return fmt.Errorf("%s: %w", op, ErrLockTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock освобождает advisory lock, захваченный Lock.
+func (m *Migrator) Unlock(ctx context.Context) error {
+	const op = "migrator.Unlock"
+
+	if m.lockConn == nil {
+		return nil
+	}
+
+	defer func() {
+		m.lockConn.Close()
+		m.lockConn = nil
+	}()
+
+	if _, err := m.lockConn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, m.lockKey); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Close освобождает блокировку и закрывает источник и соединение golang-migrate.
+func (m *Migrator) Close() error {
+	unlockErr := m.Unlock(context.Background())
+	srcErr, dbErr := m.m.Close()
+
+	return errors.Join(unlockErr, srcErr, dbErr)
+}
+
+// Up применяет n следующих миграций; при n <= 0 — все непримененные.
+// Возвращает migrate.ErrNoChange, если применять нечего.
+func (m *Migrator) Up(n int) error {
+	if n <= 0 {
+		return m.m.Up()
+	}
+
+	return m.m.Steps(n)
+}
+
+// Down откатывает n последних миграций; при n <= 0 — все.
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return m.m.Down()
+	}
+
+	return m.m.Steps(-n)
+}
+
+// Goto переводит схему к версии v, применяя или откатывая миграции.
+// Версия 0 означает откат всех миграций: файла с такой версией нет,
+// поэтому golang-migrate не может перейти к ней через Migrate.
+func (m *Migrator) Goto(v uint) error {
+	if v == 0 {
+		return m.m.Down()
+	}
+
+	return m.m.Migrate(v)
+}
+
+// Force устанавливает версию v без выполнения миграций и снимает флаг dirty.
+// При v < 0 таблица версий очищается.
+func (m *Migrator) Force(v int) error {
+	return m.m.Force(v)
+}
+
+// Version возвращает текущую версию схемы; 0, если миграции не применялись.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// Status возвращает текущую версию и список миграций источника.
+func (m *Migrator) Status() (Status, error) {
+	const op = "migrator.Status"
+
+	version, dirty, err := m.Version()
+	if err != nil {
+		return Status{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	migrations, err := m.list()
+	if err != nil {
+		return Status{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range migrations {
+		migrations[i].Applied = version != 0 && migrations[i].Version <= version
+	}
+
+	return Status{
+		Version:    version,
+		Dirty:      dirty,
+		Migrations: migrations,
+	}, nil
+}
+
+// list возвращает все миграции источника по возрастанию версии.
+func (m *Migrator) list() ([]Migration, error) {
+	var migrations []Migration
+
+	v, err := m.src.First()
+	for err == nil {
+		r, identifier, readErr := m.src.ReadUp(v)
+		if readErr != nil && !errors.Is(readErr, fs.ErrNotExist) {
+			return nil, readErr
+		}
+		if r != nil {
+			r.Close()
+		}
+
+		migrations = append(migrations, Migration{Version: v, Identifier: identifier})
+
+		v, err = m.src.Next(v)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return migrations, nil
+}
+
+// lockKey вычисляет ключ advisory lock по имени таблицы миграций.
+// Ключ отличается от ключа, который golang-migrate берет на время каждой операции,
+// поэтому блокировки не конфликтуют.
+func lockKey(table string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("go-sso:migrator:" + table))
+
+	return int64(h.Sum64())
+}
@@ -0,0 +1,76 @@
+package migrator
+
+import (
+	"slices"
+)
+
+// Direction направление применения миграций.
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+// Plan миграции, которые будут выполнены командой, в порядке выполнения.
+type Plan struct {
+	Direction  Direction
+	Migrations []Migration
+}
+
+// PlanUp возвращает план для Up(n) без изменения базы данных.
+func (s Status) PlanUp(n int) Plan {
+	pending := s.Pending()
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+
+	return Plan{Direction: DirectionUp, Migrations: pending}
+}
+
+// PlanDown возвращает план для Down(n) без изменения базы данных.
+func (s Status) PlanDown(n int) Plan {
+	applied := s.applied()
+	slices.Reverse(applied)
+
+	if n > 0 && n < len(applied) {
+		applied = applied[:n]
+	}
+
+	return Plan{Direction: DirectionDown, Migrations: applied}
+}
+
+// PlanGoto возвращает план для Goto(v) без изменения базы данных.
+func (s Status) PlanGoto(v uint) Plan {
+	if v >= s.Version {
+		var up []Migration
+		for _, m := range s.Pending() {
+			if m.Version <= v {
+				up = append(up, m)
+			}
+		}
+
+		return Plan{Direction: DirectionUp, Migrations: up}
+	}
+
+	var down []Migration
+	for _, m := range s.applied() {
+		if m.Version > v {
+			down = append(down, m)
+		}
+	}
+	slices.Reverse(down)
+
+	return Plan{Direction: DirectionDown, Migrations: down}
+}
+
+func (s Status) applied() []Migration {
+	var applied []Migration
+	for _, m := range s.Migrations {
+		if m.Applied {
+			applied = append(applied, m)
+		}
+	}
+
+	return applied
+}
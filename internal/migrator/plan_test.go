@@ -0,0 +1,54 @@
+package migrator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func versions(p Plan) []uint {
+	var vs []uint
+	for _, m := range p.Migrations {
+		vs = append(vs, m.Version)
+	}
+
+	return vs
+}
+
+func testStatus() Status {
+	return Status{
+		Version: 2,
+		Migrations: []Migration{
+			{Version: 1, Applied: true},
+			{Version: 2, Applied: true},
+			{Version: 3},
+			{Version: 4},
+		},
+	}
+}
+
+func TestStatus_Plan(t *testing.T) {
+	s := testStatus()
+
+	tests := []struct {
+		name string
+		plan Plan
+		dir  Direction
+		want []uint
+	}{
+		{"up all", s.PlanUp(0), DirectionUp, []uint{3, 4}},
+		{"up one", s.PlanUp(1), DirectionUp, []uint{3}},
+		{"down all", s.PlanDown(0), DirectionDown, []uint{2, 1}},
+		{"down one", s.PlanDown(1), DirectionDown, []uint{2}},
+		{"goto forward", s.PlanGoto(3), DirectionUp, []uint{3}},
+		{"goto backward", s.PlanGoto(0), DirectionDown, []uint{2, 1}},
+		{"goto current", s.PlanGoto(2), DirectionUp, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.dir, tt.plan.Direction)
+			assert.Equal(t, tt.want, versions(tt.plan))
+		})
+	}
+}
@@ -0,0 +1,629 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Admin сервис администрирования пользователей, приложений и ролей.
+type Admin struct {
+	log *zap.SugaredLogger
+
+	users UserStorage
+	apps  AppStorage
+	roles RoleStorage
+	keys  KeyStorage
+
+	identities IdentityStorage
+	tenants    TenantStorage
+
+	auditor Auditor
+	emails  email.Normalizer
+}
+
+type UserStorage interface {
+	SaveUser(ctx context.Context, email string, passHash []byte) (uuid string, err error)
+	User(ctx context.Context, email string) (models.User, error)
+	UserByUUID(ctx context.Context, uuid string) (models.User, error)
+	UpdateProfile(ctx context.Context, uuid string, upd models.ProfileUpdate) (models.User, error)
+	Users(ctx context.Context, limit, offset int) ([]models.User, error)
+	AllUsers(ctx context.Context, limit, offset int) ([]models.User, error)
+	SetUserEmail(ctx context.Context, uuid, email string) error
+	SetUserStatus(ctx context.Context, email string, status models.UserStatus) (revoked int64, err error)
+	UpdatePassword(ctx context.Context, email string, passHash []byte) error
+	DeleteUser(ctx context.Context, email string) (uuid string, err error)
+	UserData(ctx context.Context, email string) (models.UserData, error)
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+}
+
+type AppStorage interface {
+	SaveApp(ctx context.Context, name string) (models.App, error)
+	AppByName(ctx context.Context, name string) (models.App, error)
+	Apps(ctx context.Context) ([]models.App, error)
+	DeleteApp(ctx context.Context, name string) error
+}
+
+type RoleStorage interface {
+	AssignRole(ctx context.Context, userUUID string, appID int, role string) error
+	RevokeRole(ctx context.Context, userUUID string, appID int, role string) error
+	UserRoles(ctx context.Context, userUUID string) ([]models.Role, error)
+}
+
+type KeyStorage interface {
+	SaveKey(ctx context.Context, appName string, key string) error
+	ReplaceKey(ctx context.Context, appName string, key string) error
+	DeleteKey(ctx context.Context, appName string) error
+}
+
+type IdentityStorage interface {
+	Identities(ctx context.Context, userUUID string) ([]models.Identity, error)
+	UnlinkIdentity(ctx context.Context, userUUID, provider string) error
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Ошибки, которые могут возникнуть при работе с сервисом администрирования.
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrUserExists     = errors.New("user already exists")
+	ErrAppNotFound    = errors.New("app not found")
+	ErrAppExists      = errors.New("app already exists")
+	ErrRoleNotFound   = errors.New("role not found")
+	ErrInvalidProfile = errors.New("invalid profile")
+	ErrInvalidEmail   = errors.New("invalid email")
+	ErrEmailCollision = errors.New("email collides with another user")
+
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrLastIdentity     = errors.New("cannot unlink the only sign-in method")
+
+	ErrInvalidTenant  = errors.New("invalid tenant id")
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrTenantExists   = errors.New("tenant already exists")
+)
+
+// New возвращает новый экземпляр сервиса администрирования.
+func New(
+	log *zap.SugaredLogger,
+	users UserStorage,
+	apps AppStorage,
+	roles RoleStorage,
+	keys KeyStorage,
+	identities IdentityStorage,
+	tenants TenantStorage,
+	auditor Auditor,
+	emails email.Normalizer,
+) *Admin {
+	return &Admin{
+		log:   log,
+		users: users,
+		apps:  apps,
+		roles: roles,
+		keys:  keys,
+
+		identities: identities,
+		tenants:    tenants,
+
+		auditor: auditor,
+		emails:  emails,
+	}
+}
+
+// CreateUser создает пользователя с заданным паролем.
+func (a *Admin) CreateUser(ctx context.Context, email, password string) (uuid string, err error) {
+	const op = "admin.CreateUser"
+
+	log := a.log.With("op", op, "email", email)
+
+	defer func() { a.record(ctx, models.AuditUserCreate, err, email, "", nil) }()
+
+	if email, err = a.normalize(op, email); err != nil {
+		return "", err
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", handleInternalErr(log, "failed to hash password", op, err)
+	}
+
+	uuid, err = a.users.SaveUser(ctx, email, passHash)
+	if err != nil {
+		return "", handleStorageErr(log, "failed to save user", op, err)
+	}
+
+	log.Infow("user created", "userUUID", uuid)
+
+	return uuid, nil
+}
+
+// Users возвращает страницу пользователей.
+func (a *Admin) Users(ctx context.Context, limit, offset int) ([]models.User, error) {
+	const op = "admin.Users"
+
+	users, err := a.users.Users(ctx, limit, offset)
+	if err != nil {
+		return nil, handleStorageErr(a.log.With("op", op), "failed to list users", op, err)
+	}
+
+	return users, nil
+}
+
+// User возвращает пользователя арендатора из ctx по UUID.
+func (a *Admin) User(ctx context.Context, uuid string) (models.User, error) {
+	const op = "admin.User"
+
+	user, err := a.users.UserByUUID(ctx, uuid)
+	if err != nil {
+		return models.User{}, handleStorageErr(a.log.With("op", op, "userUUID", uuid), "failed to get user", op, err)
+	}
+	if user.Tenant != tenant.From(ctx) {
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
+	return user, nil
+}
+
+// UserByEmail возвращает пользователя по email.
+func (a *Admin) UserByEmail(ctx context.Context, email string) (models.User, error) {
+	const op = "admin.UserByEmail"
+
+	email, err := a.normalize(op, email)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user, err := a.users.User(ctx, email)
+	if err != nil {
+		return models.User{}, handleStorageErr(a.log.With("op", op, "email", email), "failed to get user", op, err)
+	}
+
+	return user, nil
+}
+
+// UpdateProfile проверяет и применяет изменение профиля пользователя.
+func (a *Admin) UpdateProfile(ctx context.Context, uuid string, upd models.ProfileUpdate) (user models.User, err error) {
+	const op = "admin.UpdateProfile"
+
+	log := a.log.With("op", op, "userUUID", uuid)
+
+	defer func() { a.record(ctx, models.AuditUserUpdateProfile, err, uuid, "", nil) }()
+
+	if err := validateProfile(upd); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err = a.users.UpdateProfile(ctx, uuid, upd)
+	if err != nil {
+		return models.User{}, handleStorageErr(log, "failed to update profile", op, err)
+	}
+
+	log.Infow("profile updated")
+
+	return user, nil
+}
+
+// SetUserStatus блокирует или разблокирует пользователя. Если новый статус не active,
+// сеансы пользователя отзываются, чтобы выданные токены перестали приниматься.
+// Возвращает число отозванных сеансов.
+func (a *Admin) SetUserStatus(ctx context.Context, email string, status models.UserStatus) (revoked int64, err error) {
+	const op = "admin.SetUserStatus"
+
+	log := a.log.With("op", op, "email", email, "status", status)
+
+	defer func() {
+		a.record(ctx, models.AuditUserStatus, err, email, "", map[string]string{
+			"status":  string(status),
+			"revoked": strconv.FormatInt(revoked, 10),
+		})
+	}()
+
+	if email, err = a.normalize(op, email); err != nil {
+		return 0, err
+	}
+
+	revoked, err = a.users.SetUserStatus(ctx, email, status)
+	if err != nil {
+		return 0, handleStorageErr(log, "failed to set user status", op, err)
+	}
+
+	log.Infow("user status changed", "revokedSessions", revoked)
+
+	return revoked, nil
+}
+
+// ResetPassword заменяет пароль пользователя.
+func (a *Admin) ResetPassword(ctx context.Context, email, password string) (err error) {
+	const op = "admin.ResetPassword"
+
+	log := a.log.With("op", op, "email", email)
+
+	defer func() { a.record(ctx, models.AuditUserResetPassword, err, email, "", nil) }()
+
+	if email, err = a.normalize(op, email); err != nil {
+		return err
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return handleInternalErr(log, "failed to hash password", op, err)
+	}
+
+	if err := a.users.UpdatePassword(ctx, email, passHash); err != nil {
+		return handleStorageErr(log, "failed to update password", op, err)
+	}
+
+	log.Infow("password reset")
+
+	return nil
+}
+
+// ExportUser возвращает все данные пользователя, которые хранит сервис.
+func (a *Admin) ExportUser(ctx context.Context, email string) (data models.UserData, err error) {
+	const op = "admin.ExportUser"
+
+	log := a.log.With("op", op, "email", email)
+
+	defer func() { a.record(ctx, models.AuditUserExport, err, email, "", nil) }()
+
+	if email, err = a.normalize(op, email); err != nil {
+		return models.UserData{}, err
+	}
+
+	data, err = a.users.UserData(ctx, email)
+	if err != nil {
+		return models.UserData{}, handleStorageErr(log, "failed to export user data", op, err)
+	}
+
+	log.Infow("user data exported")
+
+	return data, nil
+}
+
+// DeleteUser удаляет пользователя: он не может войти, его сеансы отзываются,
+// а данные стираются через PurgeDeletedUsers по истечении срока хранения.
+// До этого удаление можно отменить, вернув статус active.
+func (a *Admin) DeleteUser(ctx context.Context, email string) (err error) {
+	const op = "admin.DeleteUser"
+
+	log := a.log.With("op", op, "email", email)
+
+	defer func() { a.record(ctx, models.AuditUserDelete, err, email, "", nil) }()
+
+	if email, err = a.normalize(op, email); err != nil {
+		return err
+	}
+
+	uuid, err := a.users.DeleteUser(ctx, email)
+	if err != nil {
+		return handleStorageErr(log, "failed to delete user", op, err)
+	}
+
+	log.Infow("user deleted", "userUUID", uuid)
+
+	return nil
+}
+
+// PurgeDeletedUsers окончательно стирает пользователей, удаленных раньше before,
+// и обезличивает их записи в журнале аудита.
+func (a *Admin) PurgeDeletedUsers(ctx context.Context, before time.Time) (purged int64, err error) {
+	const op = "admin.PurgeDeletedUsers"
+
+	log := a.log.With("op", op, "before", before)
+
+	defer func() {
+		if err != nil || purged > 0 {
+			a.record(ctx, models.AuditUserPurge, err, "", "", map[string]string{"count": strconv.FormatInt(purged, 10)})
+		}
+	}()
+
+	purged, err = a.users.PurgeDeletedUsers(ctx, before)
+	if err != nil {
+		return 0, handleStorageErr(log, "failed to purge deleted users", op, err)
+	}
+
+	if purged > 0 {
+		log.Infow("deleted users purged", "count", purged)
+	}
+
+	return purged, nil
+}
+
+// CreateApp регистрирует приложение и создает для него ключ подписи.
+// Если ключ уже существует (приложение раньше использовалось без регистрации),
+// он сохраняется, чтобы не инвалидировать выданные токены.
+func (a *Admin) CreateApp(ctx context.Context, name string) (app models.App, err error) {
+	const op = "admin.CreateApp"
+
+	log := a.log.With("op", op, "appName", name)
+
+	defer func() { a.record(ctx, models.AuditAppCreate, err, "", name, nil) }()
+
+	app, err = a.apps.SaveApp(ctx, name)
+	if err != nil {
+		return models.App{}, handleStorageErr(log, "failed to save app", op, err)
+	}
+
+	secret, err := jwt.GenerateHS256Secret()
+	if err != nil {
+		return models.App{}, handleInternalErr(log, "failed to generate signing key", op, err)
+	}
+
+	err = a.keys.SaveKey(ctx, name, secret)
+	if errors.Is(err, auth.ErrKeyExists) {
+		log.Infow("signing key already exists, keeping it")
+	} else if err != nil {
+		return models.App{}, handleInternalErr(log, "failed to save signing key", op, err)
+	}
+
+	log.Infow("app created", "appID", app.ID)
+
+	return app, nil
+}
+
+// Apps возвращает зарегистрированные приложения.
+func (a *Admin) Apps(ctx context.Context) ([]models.App, error) {
+	const op = "admin.Apps"
+
+	apps, err := a.apps.Apps(ctx)
+	if err != nil {
+		return nil, handleStorageErr(a.log.With("op", op), "failed to list apps", op, err)
+	}
+
+	return apps, nil
+}
+
+// RotateKey заменяет ключ подписи приложения новым.
+// Токены, подписанные старым ключом, перестают проходить проверку.
+func (a *Admin) RotateKey(ctx context.Context, name string) (err error) {
+	const op = "admin.RotateKey"
+
+	log := a.log.With("op", op, "appName", name)
+
+	defer func() { a.record(ctx, models.AuditAppRotateKey, err, "", name, nil) }()
+
+	if _, err := a.apps.AppByName(ctx, name); err != nil {
+		return handleStorageErr(log, "failed to get app", op, err)
+	}
+
+	secret, err := jwt.GenerateHS256Secret()
+	if err != nil {
+		return handleInternalErr(log, "failed to generate signing key", op, err)
+	}
+
+	if err := a.keys.ReplaceKey(ctx, name, secret); err != nil {
+		return handleInternalErr(log, "failed to replace signing key", op, err)
+	}
+
+	log.Infow("signing key rotated")
+
+	return nil
+}
+
+// DeleteApp удаляет приложение, его роли и ключ подписи.
+func (a *Admin) DeleteApp(ctx context.Context, name string) (err error) {
+	const op = "admin.DeleteApp"
+
+	log := a.log.With("op", op, "appName", name)
+
+	defer func() { a.record(ctx, models.AuditAppDelete, err, "", name, nil) }()
+
+	if err := a.apps.DeleteApp(ctx, name); err != nil {
+		return handleStorageErr(log, "failed to delete app", op, err)
+	}
+
+	if err := a.keys.DeleteKey(ctx, name); err != nil {
+		return handleInternalErr(log, "app deleted, but failed to delete signing key", op, err)
+	}
+
+	log.Infow("app deleted")
+
+	return nil
+}
+
+// AssignRole назначает пользователю роль в приложении.
+func (a *Admin) AssignRole(ctx context.Context, email, appName, role string) (err error) {
+	const op = "admin.AssignRole"
+
+	log := a.log.With("op", op, "email", email, "appName", appName, "role", role)
+
+	defer func() { a.record(ctx, models.AuditRoleAssign, err, email, appName, map[string]string{"role": role}) }()
+
+	if email, err = a.normalize(op, email); err != nil {
+		return err
+	}
+
+	user, app, err := a.userAndApp(ctx, email, appName)
+	if err != nil {
+		return handleStorageErr(log, "failed to resolve user or app", op, err)
+	}
+
+	if err := a.roles.AssignRole(ctx, user.UUID, app.ID, role); err != nil {
+		return handleStorageErr(log, "failed to assign role", op, err)
+	}
+
+	log.Infow("role assigned")
+
+	return nil
+}
+
+// RevokeRole снимает с пользователя роль в приложении.
+func (a *Admin) RevokeRole(ctx context.Context, email, appName, role string) (err error) {
+	const op = "admin.RevokeRole"
+
+	log := a.log.With("op", op, "email", email, "appName", appName, "role", role)
+
+	defer func() { a.record(ctx, models.AuditRoleRevoke, err, email, appName, map[string]string{"role": role}) }()
+
+	if email, err = a.normalize(op, email); err != nil {
+		return err
+	}
+
+	user, app, err := a.userAndApp(ctx, email, appName)
+	if err != nil {
+		return handleStorageErr(log, "failed to resolve user or app", op, err)
+	}
+
+	if err := a.roles.RevokeRole(ctx, user.UUID, app.ID, role); err != nil {
+		return handleStorageErr(log, "failed to revoke role", op, err)
+	}
+
+	log.Infow("role revoked")
+
+	return nil
+}
+
+// UserRoles возвращает роли пользователя во всех приложениях.
+func (a *Admin) UserRoles(ctx context.Context, email string) ([]models.Role, error) {
+	const op = "admin.UserRoles"
+
+	log := a.log.With("op", op, "email", email)
+
+	email, err := a.normalize(op, email)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.users.User(ctx, email)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to get user", op, err)
+	}
+
+	roles, err := a.roles.UserRoles(ctx, user.UUID)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to list roles", op, err)
+	}
+
+	return roles, nil
+}
+
+// Identities возвращает внешние учетные записи пользователя.
+func (a *Admin) Identities(ctx context.Context, email string) ([]models.Identity, error) {
+	const op = "admin.Identities"
+
+	log := a.log.With("op", op, "email", email)
+
+	email, err := a.normalize(op, email)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.users.User(ctx, email)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to get user", op, err)
+	}
+
+	identities, err := a.identities.Identities(ctx, user.UUID)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to list identities", op, err)
+	}
+
+	return identities, nil
+}
+
+// UnlinkIdentity отвязывает от пользователя учетную запись провайдера.
+func (a *Admin) UnlinkIdentity(ctx context.Context, email, provider string) (err error) {
+	const op = "admin.UnlinkIdentity"
+
+	log := a.log.With("op", op, "email", email, "provider", provider)
+
+	defer func() {
+		a.record(ctx, models.AuditUserUnlinkIdentity, err, email, "", map[string]string{"provider": provider})
+	}()
+
+	if email, err = a.normalize(op, email); err != nil {
+		return err
+	}
+
+	user, err := a.users.User(ctx, email)
+	if err != nil {
+		return handleStorageErr(log, "failed to get user", op, err)
+	}
+
+	if err := a.identities.UnlinkIdentity(ctx, user.UUID, provider); err != nil {
+		return handleStorageErr(log, "failed to unlink identity", op, err)
+	}
+
+	log.Infow("identity unlinked")
+
+	return nil
+}
+
+// normalize нормализует email. При ошибке возвращает исходный адрес, чтобы он попал в журнал аудита.
+func (a *Admin) normalize(op, addr string) (string, error) {
+	normalized, err := a.emails.Normalize(addr)
+	if err != nil {
+		return addr, fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+
+	return normalized, nil
+}
+
+// record записывает событие аудита административного действия.
+func (a *Admin) record(ctx context.Context, typ string, err error, subject, app string, details map[string]string) {
+	e := audit.Event(typ, err)
+	e.Subject = subject
+	e.App = app
+	e.Details = details
+
+	a.auditor.Record(ctx, e)
+}
+
+func (a *Admin) userAndApp(ctx context.Context, email, appName string) (models.User, models.App, error) {
+	user, err := a.users.User(ctx, email)
+	if err != nil {
+		return models.User{}, models.App{}, err
+	}
+
+	app, err := a.apps.AppByName(ctx, appName)
+	if err != nil {
+		return models.User{}, models.App{}, err
+	}
+
+	return user, app, nil
+}
+
+// handleStorageErr переводит ошибки хранилища в ошибки сервиса и логгирует их.
+func handleStorageErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	case errors.Is(err, storage.ErrUserExists):
+		return fmt.Errorf("%s: %w", op, ErrUserExists)
+	case errors.Is(err, storage.ErrAppNotFound):
+		return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+	case errors.Is(err, storage.ErrAppExists):
+		return fmt.Errorf("%s: %w", op, ErrAppExists)
+	case errors.Is(err, storage.ErrRoleNotFound):
+		return fmt.Errorf("%s: %w", op, ErrRoleNotFound)
+	case errors.Is(err, storage.ErrIdentityNotFound):
+		return fmt.Errorf("%s: %w", op, ErrIdentityNotFound)
+	case errors.Is(err, storage.ErrLastIdentity):
+		return fmt.Errorf("%s: %w", op, ErrLastIdentity)
+	case errors.Is(err, storage.ErrTenantNotFound):
+		return fmt.Errorf("%s: %w", op, ErrTenantNotFound)
+	case errors.Is(err, storage.ErrTenantExists):
+		return fmt.Errorf("%s: %w", op, ErrTenantExists)
+	default:
+		return handleInternalErr(log, msg, op, err)
+	}
+}
+
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+	"strings"
+)
+
+// collisionPageSize размер страницы при чтении пользователей всех арендаторов.
+const collisionPageSize = 1000
+
+// EmailChange результат нормализации email пользователя.
+type EmailChange struct {
+	User models.User
+	// Email нормализованный адрес.
+	Email string
+	// Err причина, по которой адрес не изменен: ErrInvalidEmail или ErrEmailCollision.
+	Err error
+}
+
+// EmailCollisions возвращает группы пользователей одного арендатора, чьи адреса совпадают
+// по collisionKey. Такие учетные записи нужно объединить или переименовать перед
+// миграцией на регистронезависимые email.
+func (a *Admin) EmailCollisions(ctx context.Context) ([][]models.User, error) {
+	const op = "admin.EmailCollisions"
+
+	log := a.log.With("op", op)
+
+	users, err := a.allUsers(ctx)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to list users", op, err)
+	}
+
+	var (
+		keys   []string
+		groups = make(map[string][]models.User)
+	)
+
+	for _, u := range users {
+		key, _, _ := a.collisionKey(u)
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], u)
+	}
+
+	var collisions [][]models.User
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			collisions = append(collisions, groups[key])
+		}
+	}
+
+	return collisions, nil
+}
+
+// NormalizeEmails приводит сохраненные адреса всех арендаторов к виду, который
+// сервис получает из email при входе (см. email.Normalizer), например переводит
+// домен в punycode. Адреса, которые совпадут с адресом другого пользователя,
+// и некорректные адреса не меняются. С dryRun база не меняется.
+// Возвращает все адреса, которые отличаются от нормализованных.
+func (a *Admin) NormalizeEmails(ctx context.Context, dryRun bool) ([]EmailChange, error) {
+	const op = "admin.NormalizeEmails"
+
+	log := a.log.With("op", op, "dryRun", dryRun)
+
+	users, err := a.allUsers(ctx)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to list users", op, err)
+	}
+
+	counts := make(map[string]int, len(users))
+	for _, u := range users {
+		key, _, _ := a.collisionKey(u)
+		counts[key]++
+	}
+
+	var changes []EmailChange
+	for _, u := range users {
+		key, normalized, err := a.collisionKey(u)
+		switch {
+		case err != nil:
+			changes = append(changes, EmailChange{User: u, Email: u.Email, Err: ErrInvalidEmail})
+			continue
+		case normalized == u.Email:
+			continue
+		case counts[key] > 1:
+			changes = append(changes, EmailChange{User: u, Email: normalized, Err: ErrEmailCollision})
+			continue
+		}
+
+		if !dryRun {
+			if err := a.setUserEmail(ctx, u, normalized); err != nil {
+				if !errors.Is(err, storage.ErrUserExists) {
+					return changes, handleStorageErr(log, "failed to update email", op, err)
+				}
+
+				changes = append(changes, EmailChange{User: u, Email: normalized, Err: ErrEmailCollision})
+				continue
+			}
+
+			log.Infow("email normalized", "uuid", u.UUID, "from", u.Email, "to", normalized)
+		}
+
+		changes = append(changes, EmailChange{User: u, Email: normalized})
+	}
+
+	return changes, nil
+}
+
+// setUserEmail заменяет email пользователя и записывает событие аудита в его арендаторе.
+func (a *Admin) setUserEmail(ctx context.Context, u models.User, email string) (err error) {
+	ctx = tenant.With(ctx, u.Tenant)
+
+	defer func() {
+		a.record(ctx, models.AuditUserNormalizeEmail, err, email, "", map[string]string{"from": u.Email})
+	}()
+
+	return a.users.SetUserEmail(ctx, u.UUID, email)
+}
+
+// allUsers возвращает пользователей всех арендаторов.
+func (a *Admin) allUsers(ctx context.Context) ([]models.User, error) {
+	var all []models.User
+
+	for offset := 0; ; offset += collisionPageSize {
+		users, err := a.users.AllUsers(ctx, collisionPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, users...)
+
+		if len(users) < collisionPageSize {
+			return all, nil
+		}
+	}
+}
+
+// collisionKey возвращает нормализованный адрес пользователя и ключ, по которому адреса
+// совпадают: арендатор и нормализованный адрес без пробелов по краям в нижнем регистре.
+// Миграция 7_email_citext сравнивает lower(btrim(email)) и применяется, только когда
+// адреса уже нормализованы, поэтому ключи в ней и здесь одинаковы. Некорректный адрес
+// сравнивается как есть.
+func (a *Admin) collisionKey(u models.User) (key, normalized string, err error) {
+	normalized, err = a.emails.Normalize(u.Email)
+	if err != nil {
+		normalized = u.Email
+	}
+
+	return u.Tenant + "/" + strings.ToLower(strings.TrimSpace(normalized)), normalized, err
+}
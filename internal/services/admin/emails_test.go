@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"context"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/email"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeUsers struct {
+	UserStorage
+
+	users   []models.User
+	updated map[string]string
+}
+
+func (s *fakeUsers) AllUsers(_ context.Context, limit, offset int) ([]models.User, error) {
+	if offset >= len(s.users) {
+		return nil, nil
+	}
+
+	return s.users[offset:min(offset+limit, len(s.users))], nil
+}
+
+func (s *fakeUsers) SetUserEmail(_ context.Context, uuid, email string) error {
+	s.updated[uuid] = email
+	return nil
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, models.AuditEvent) {}
+
+func newEmailsAdmin(users *fakeUsers) *Admin {
+	return New(zap.NewNop().Sugar(), users, nil, nil, nil, nil, nil, nopAuditor{}, email.Normalizer{})
+}
+
+func TestNormalizeEmails(t *testing.T) {
+	users := &fakeUsers{
+		users: []models.User{
+			{UUID: "1", Tenant: "default", Email: "alice@пример.рф"},
+			{UUID: "2", Tenant: "default", Email: "bob@example.com."},
+			{UUID: "3", Tenant: "default", Email: "Carol@Example.com"},
+			{UUID: "4", Tenant: "default", Email: "carol@example.com"},
+			{UUID: "5", Tenant: "default", Email: "not-an-email"},
+			{UUID: "6", Tenant: "acme", Email: "bob@example.com"},
+			{UUID: "7", Tenant: "default", Email: "dave@example.com"},
+		},
+		updated: make(map[string]string),
+	}
+	a := newEmailsAdmin(users)
+
+	_, err := a.NormalizeEmails(context.Background(), true)
+	require.NoError(t, err)
+	assert.Empty(t, users.updated)
+
+	changes, err := a.NormalizeEmails(context.Background(), false)
+	require.NoError(t, err)
+
+	// Адрес другого арендатора не мешает нормализации.
+	assert.Equal(t, map[string]string{"1": "alice@xn--e1afmkfd.xn--p1ai", "2": "bob@example.com"}, users.updated)
+
+	result := make(map[string]error, len(changes))
+	for _, c := range changes {
+		result[c.User.UUID] = c.Err
+	}
+	assert.Equal(t, map[string]error{
+		"1": nil,
+		"2": nil,
+		"3": ErrEmailCollision,
+		"5": ErrInvalidEmail,
+	}, result)
+}
+
+func TestEmailCollisions(t *testing.T) {
+	users := &fakeUsers{
+		users: []models.User{
+			{UUID: "1", Tenant: "default", Email: "Alice@пример.рф"},
+			{UUID: "2", Tenant: "default", Email: " alice@XN--E1AFMKFD.xn--p1ai"},
+			{UUID: "3", Tenant: "acme", Email: "alice@xn--e1afmkfd.xn--p1ai"},
+			{UUID: "4", Tenant: "default", Email: "bob@example.com"},
+		},
+	}
+
+	groups, err := newEmailsAdmin(users).EmailCollisions(context.Background())
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "1", groups[0][0].UUID)
+	assert.Equal(t, "2", groups[0][1].UUID)
+}
@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	maxDisplayNameLen = 200
+	maxAvatarURLLen   = 2048
+	// maxMetadataSize ограничение размера метаданных в JSON.
+	maxMetadataSize = 16 << 10
+)
+
+// validateProfile проверяет заданные поля изменения профиля.
+// Пустая строка очищает поле и всегда допустима.
+func validateProfile(upd models.ProfileUpdate) error {
+	if v := upd.DisplayName; v != nil && utf8.RuneCountInString(*v) > maxDisplayNameLen {
+		return fmt.Errorf("%w: display name is longer than %d characters", ErrInvalidProfile, maxDisplayNameLen)
+	}
+
+	if v := upd.Locale; v != nil && *v != "" {
+		if _, err := language.Parse(*v); err != nil {
+			return fmt.Errorf("%w: locale %q is not a BCP 47 language tag", ErrInvalidProfile, *v)
+		}
+	}
+
+	if v := upd.Timezone; v != nil && *v != "" {
+		if _, err := time.LoadLocation(*v); err != nil || *v == "Local" {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidProfile, *v)
+		}
+	}
+
+	if v := upd.AvatarURL; v != nil && *v != "" {
+		u, err := url.Parse(*v)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(*v) > maxAvatarURLLen {
+			return fmt.Errorf("%w: avatar URL must be an absolute http(s) URL", ErrInvalidProfile)
+		}
+	}
+
+	if upd.Metadata != nil {
+		b, err := json.Marshal(upd.Metadata)
+		if err != nil {
+			return fmt.Errorf("%w: metadata: %v", ErrInvalidProfile, err)
+		}
+		if len(b) > maxMetadataSize {
+			return fmt.Errorf("%w: metadata is larger than %d bytes", ErrInvalidProfile, maxMetadataSize)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"go-sso/internal/domain/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr(s string) *string { return &s }
+
+func TestValidateProfile(t *testing.T) {
+	valid := models.ProfileUpdate{
+		DisplayName: ptr("Алиса"),
+		Locale:      ptr("ru-RU"),
+		Timezone:    ptr("Europe/Moscow"),
+		AvatarURL:   ptr("https://cdn.example.com/a.png"),
+		Metadata:    map[string]any{"team": "core"},
+	}
+	assert.NoError(t, validateProfile(valid))
+
+	// Пустые строки очищают поля.
+	assert.NoError(t, validateProfile(models.ProfileUpdate{Locale: ptr(""), Timezone: ptr(""), AvatarURL: ptr("")}))
+
+	for name, upd := range map[string]models.ProfileUpdate{
+		"long name":      {DisplayName: ptr(strings.Repeat("a", maxDisplayNameLen+1))},
+		"bad locale":     {Locale: ptr("not a locale")},
+		"bad timezone":   {Timezone: ptr("Mars/Olympus")},
+		"local timezone": {Timezone: ptr("Local")},
+		"relative url":   {AvatarURL: ptr("/a.png")},
+		"js url":         {AvatarURL: ptr("javascript:alert(1)")},
+		"big metadata":   {Metadata: map[string]any{"x": strings.Repeat("a", maxMetadataSize)}},
+	} {
+		assert.ErrorIs(t, validateProfile(upd), ErrInvalidProfile, name)
+	}
+}
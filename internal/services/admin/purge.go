@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Purger периодически стирает пользователей, удаленных больше retention назад.
+type Purger struct {
+	log   *zap.SugaredLogger
+	admin *Admin
+
+	interval  time.Duration
+	retention time.Duration
+}
+
+// NewPurger создает Purger. При interval <= 0 Run ничего не делает.
+func NewPurger(log *zap.SugaredLogger, admin *Admin, interval, retention time.Duration) *Purger {
+	return &Purger{
+		log:   log,
+		admin: admin,
+
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Run стирает удаленных пользователей каждые interval, пока не отменен ctx.
+func (p *Purger) Run(ctx context.Context) {
+	if p.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		// Ошибку уже залогировал и записал в журнал аудита сервис.
+		_, _ = p.admin.PurgeDeletedUsers(ctx, time.Now().Add(-p.retention))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+)
+
+type TenantStorage interface {
+	SaveTenant(ctx context.Context, t models.Tenant) (models.Tenant, error)
+	Tenants(ctx context.Context) ([]models.Tenant, error)
+}
+
+// CreateTenant создает арендатора с идентификатором id. Идентификатор входит в путь
+// ключей подписи в Vault, поэтому допускает только строчные латинские буквы, цифры и дефис.
+func (a *Admin) CreateTenant(ctx context.Context, id, name string) (t models.Tenant, err error) {
+	const op = "admin.CreateTenant"
+
+	log := a.log.With("op", op, "tenantID", id)
+
+	defer func() { a.record(ctx, models.AuditTenantCreate, err, id, "", nil) }()
+
+	if !tenant.Valid(id) {
+		return models.Tenant{}, fmt.Errorf("%s: %w", op, ErrInvalidTenant)
+	}
+
+	t, err = a.tenants.SaveTenant(ctx, models.Tenant{ID: id, Name: name})
+	if err != nil {
+		return models.Tenant{}, handleStorageErr(log, "failed to save tenant", op, err)
+	}
+
+	log.Infow("tenant created")
+
+	return t, nil
+}
+
+// Tenants возвращает всех арендаторов.
+func (a *Admin) Tenants(ctx context.Context) ([]models.Tenant, error) {
+	const op = "admin.Tenants"
+
+	tenants, err := a.tenants.Tenants(ctx)
+	if err != nil {
+		return nil, handleStorageErr(a.log.With("op", op), "failed to list tenants", op, err)
+	}
+
+	return tenants, nil
+}
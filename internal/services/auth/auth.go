@@ -5,8 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/email"
 	"go-sso/internal/lib/jwt"
+	"go-sso/internal/lib/tenant"
 	"go-sso/internal/storage"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -21,8 +25,68 @@ type Auth struct {
 	appProvider        AppProvider
 	signingKeySaver    SigningKeySaver
 	signingKeyProvider SigningKeyProvider
+	sessionSaver       SessionSaver
+	consents           ConsentStorage
+	auditor            Auditor
+	emails             email.Normalizer
+	authenticators     map[string]Authenticator
 
-	tokenTTL time.Duration
+	// secondFactor задается после создания, т.к. сервис второго фактора сам выпускает токены через Auth.
+	secondFactor atomic.Pointer[SecondFactor]
+
+	// tokens хранятся атомарно, т.к. могут меняться при перезагрузке конфигурации.
+	tokens atomic.Pointer[TokenConfig]
+}
+
+// TokenConfig параметры выпуска токенов.
+type TokenConfig struct {
+	Issuer string
+	// TTL время жизни токенов приложений, для которых оно не задано в Apps.
+	TTL  time.Duration
+	Apps map[string]AppTokenConfig
+}
+
+// AppTokenConfig параметры токенов отдельного приложения.
+type AppTokenConfig struct {
+	// TTL время жизни токенов приложения; 0 — использовать TokenConfig.TTL.
+	TTL time.Duration
+	// Claims дополнительные claims токенов приложения.
+	Claims map[string]string
+	// ProfileClaims claims профиля пользователя, которые получает приложение
+	// в токенах и в userinfo.
+	ProfileClaims []string
+	// Authenticator имя способа проверки пароля при входе в приложение;
+	// пусто или LocalAuthenticator — пароль из таблицы users.
+	Authenticator string
+	// MFA второй фактор после проверки пароля; пусто — не требуется.
+	MFA string
+	// Scopes области доступа, которые может запрашивать приложение, с описаниями.
+	Scopes map[string]string
+}
+
+// LocalAuthenticator проверка пароля по хэшу в таблице users.
+const LocalAuthenticator = "local"
+
+// MFAPasskey подтверждение входа по паролю зарегистрированным passkey.
+const MFAPasskey = "passkey"
+
+// options возвращает параметры токена для приложения appName.
+func (c *TokenConfig) options(appName string) jwt.TokenOptions {
+	opts := jwt.TokenOptions{
+		Issuer: c.Issuer,
+		App:    appName,
+		TTL:    c.TTL,
+	}
+
+	if app, ok := c.Apps[appName]; ok {
+		if app.TTL > 0 {
+			opts.TTL = app.TTL
+		}
+		opts.Claims = app.Claims
+		opts.ProfileClaims = app.ProfileClaims
+	}
+
+	return opts
 }
 
 type UserSaver interface {
@@ -38,6 +102,8 @@ type AppProvider interface {
 }
 
 type SigningKeySaver interface {
+	// SaveKey создает ключ подписи приложения, если его еще нет.
+	// Если ключ уже существует, возвращает ErrKeyExists.
 	SaveKey(ctx context.Context, appName string, key string) error
 }
 
@@ -45,8 +111,38 @@ type SigningKeyProvider interface {
 	Key(ctx context.Context, appName string) (string, error)
 }
 
+type SessionSaver interface {
+	SaveSession(ctx context.Context, session models.Session) (id string, err error)
+}
+
+// ConsentStorage хранит согласия пользователей на области доступа приложений.
+type ConsentStorage interface {
+	Consent(ctx context.Context, userUUID, app string) (models.Consent, error)
+	GrantConsent(ctx context.Context, userUUID, app string, scopes []string) (models.Consent, error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Authenticator проверяет учетные данные во внешнем каталоге и возвращает локального
+// пользователя, при необходимости создавая его. Неверные учетные данные возвращаются
+// как ErrInvalidCredentials, недоступность каталога — как ErrUnavailable.
+type Authenticator interface {
+	Authenticate(ctx context.Context, login, password string) (models.User, error)
+}
+
+// SecondFactor начинает подтверждение входа по паролю вторым фактором и возвращает
+// идентификатор, по которому клиент завершает вход. Пустой идентификатор — у пользователя
+// нет зарегистрированного второго фактора.
+type SecondFactor interface {
+	BeginSecondFactor(ctx context.Context, user models.User, appName string) (ceremonyID string, err error)
+}
+
 var (
 	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyExists   = errors.New("key already exists")
 )
 
 // Ошибки, которые могут возникнуть при работе с сервисом аутентификации.
@@ -54,9 +150,46 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserNotFound       = errors.New("user not found")
 	ErrUserExists         = errors.New("user already exists")
+	ErrUserInactive       = errors.New("user is not active")
+	ErrInvalidEmail       = errors.New("invalid email")
 	ErrInvalidAppID       = errors.New("invalid app id")
+	ErrUnavailable        = errors.New("dependency unavailable")
+	ErrTenantNotFound     = errors.New("tenant not found")
+	// ErrMFARequired пароль верный, но вход нужно подтвердить вторым фактором.
+	ErrMFARequired = errors.New("second factor required")
+	// ErrInvalidScope запрошена область, не определенная для приложения.
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrConsentRequired пользователь еще не дал согласие на запрошенные области.
+	ErrConsentRequired = errors.New("consent required")
 )
 
+// MFARequiredError вход нужно завершить вторым фактором по идентификатору CeremonyID.
+type MFARequiredError struct {
+	CeremonyID string
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Unwrap() error {
+	return ErrMFARequired
+}
+
+// ConsentRequiredError пользователь должен согласиться на области Scopes,
+// после чего вход повторяется с подтверждением согласия.
+type ConsentRequiredError struct {
+	Scopes []string
+}
+
+func (e *ConsentRequiredError) Error() string {
+	return ErrConsentRequired.Error()
+}
+
+func (e *ConsentRequiredError) Unwrap() error {
+	return ErrConsentRequired
+}
+
 // New возвращает новый экземпляр сервиса аутентификации.
 func New(
 	log *zap.SugaredLogger,
@@ -65,9 +198,14 @@ func New(
 	appProvider AppProvider,
 	signingKeySaver SigningKeySaver,
 	signingKeyProvider SigningKeyProvider,
-	tokenTTL time.Duration,
+	sessionSaver SessionSaver,
+	consents ConsentStorage,
+	auditor Auditor,
+	emails email.Normalizer,
+	authenticators map[string]Authenticator,
+	tokens TokenConfig,
 ) *Auth {
-	return &Auth{
+	a := &Auth{
 		log: log,
 
 		userSaver:          userSaver,
@@ -75,79 +213,279 @@ func New(
 		appProvider:        appProvider,
 		signingKeySaver:    signingKeySaver,
 		signingKeyProvider: signingKeyProvider,
-
-		tokenTTL: tokenTTL,
+		sessionSaver:       sessionSaver,
+		consents:           consents,
+		auditor:            auditor,
+		emails:             emails,
+		authenticators:     authenticators,
 	}
+
+	a.SetTokenConfig(tokens)
+
+	return a
+}
+
+// SetTokenConfig задает параметры новых токенов.
+func (a *Auth) SetTokenConfig(cfg TokenConfig) {
+	a.tokens.Store(&cfg)
+}
+
+// SetSecondFactor задает сервис второго фактора для приложений с MFA.
+func (a *Auth) SetSecondFactor(sf SecondFactor) {
+	a.secondFactor.Store(&sf)
+}
+
+// ProfileClaims возвращает claims профиля, доступные приложению appName.
+func (a *Auth) ProfileClaims(appName string) []string {
+	return a.tokens.Load().Apps[appName].ProfileClaims
 }
 
 // Login проверяет логин и пароль пользователя и возвращает токен.
-func (a *Auth) Login(ctx context.Context, email, password string, appName string) (string, error) {
+// Запрошенные области доступа берутся из контекста (см. WithScopeRequest); если
+// пользователь на них еще не согласился, возвращается *ConsentRequiredError.
+// Если для приложения включен второй фактор и у пользователя он есть, токен не
+// выпускается: возвращается *MFARequiredError с идентификатором церемонии.
+func (a *Auth) Login(ctx context.Context, email, password string, appName string) (token string, err error) {
 	const op = "auth.Login"
 
 	log := a.log.With("op", op, "email", email, "appName", appName)
 
+	var sessionID, mfa string
+
+	defer func() {
+		e := audit.Event(models.AuditLogin, err)
+		e.Actor, e.Subject, e.App = email, email, appName
+		if sessionID != "" || mfa != "" {
+			e.Details = make(map[string]string)
+		}
+		if sessionID != "" {
+			e.Details["sid"] = sessionID
+		}
+		if mfa != "" {
+			e.Details["mfa"] = mfa
+		}
+		a.auditor.Record(ctx, e)
+	}()
+
 	log.Infow("logging in user")
 
-	user, err := a.userProvider.User(ctx, email)
-	if err := handleStorageErr(log, err, op); err != nil {
+	user, err := a.authenticate(ctx, log, op, email, password, appName)
+	if err != nil {
 		return "", err
 	}
+	// В аудит попадает адрес учетной записи, а не введенный логин.
+	email = user.Email
+
+	if user.Status != models.UserStatusActive {
+		log.Infow("user is not active", "userID", user.UUID, "status", user.Status)
+
+		return "", fmt.Errorf("%s: %w: user is %s", op, ErrUserInactive, user.Status)
+	}
+
+	// Согласие проверяется до второго фактора, чтобы пользователь не подтверждал
+	// вход ключом впустую.
+	scopes, err := a.authorizeScopes(ctx, log, op, user, appName)
 	if err != nil {
-		return "", handleInternalErr(log, "failed to get user", op, err)
+		return "", err
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		a.log.Infow("invalid credentials", "error", err)
+	if mfa = a.tokens.Load().Apps[appName].MFA; mfa != "" {
+		ceremonyID, err := a.beginSecondFactor(ctx, log, op, user, appName)
+		if err != nil {
+			return "", err
+		}
+		if ceremonyID != "" {
+			log.Infow("second factor required", "userID", user.UUID, "mfa", mfa)
+
+			return "", fmt.Errorf("%s: %w", op, &MFARequiredError{CeremonyID: ceremonyID})
+		}
 
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		// Пока у пользователя нет второго фактора, вход по паролю разрешен, иначе он
+		// не смог бы его зарегистрировать.
+		log.Infow("second factor is not enrolled", "userID", user.UUID, "mfa", mfa)
+		mfa += ":not_enrolled"
 	}
 
 	log.Infow("user logged in", "userID", user.UUID)
 
-	secret, err := a.signingKeyProvider.Key(ctx, appName)
-	if errors.Is(err, ErrKeyNotFound) {
-		log.Infow("key not found, generating new key", "error", err)
+	token, sessionID, err = a.issueToken(ctx, log, op, user, appName, scopes)
+	if err != nil {
+		return "", err
+	}
 
-		secret, err = jwt.GenerateHS256Secret()
-		if err != nil {
-			log.Errorw("failed to generate signing key", "error", err)
+	return token, nil
+}
 
-			return "", fmt.Errorf("%s: %w", op, err)
-		}
+// authenticate проверяет учетные данные способом, заданным для приложения appName.
+func (a *Auth) authenticate(ctx context.Context, log *zap.SugaredLogger, op, email, password, appName string) (models.User, error) {
+	name := a.tokens.Load().Apps[appName].Authenticator
+	if name == "" || name == LocalAuthenticator {
+		return a.checkPassword(ctx, log, op, email, password)
+	}
 
-		if saveErr := a.signingKeySaver.SaveKey(ctx, appName, secret); saveErr != nil {
-			log.Errorw("failed to save signing key", "error", saveErr)
+	authenticator, ok := a.authenticators[name]
+	if !ok {
+		return models.User{}, handleInternalErr(log, "unknown authenticator", op, fmt.Errorf("authenticator %q is not configured", name))
+	}
 
-			return "", fmt.Errorf("%s: %w", op, saveErr)
-		}
-	} else if err != nil {
-		log.Errorw("failed to get signing key", "error", err)
+	user, err := authenticator.Authenticate(ctx, email, password)
+	switch {
+	// Подробности остаются в логе: клиент не должен отличать неизвестного пользователя от неверного пароля.
+	case errors.Is(err, ErrInvalidCredentials):
+		log.Infow("invalid credentials", "authenticator", name, "error", err)
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	case errors.Is(err, ErrUnavailable):
+		log.Errorw("authenticator unavailable", "authenticator", name, "error", err)
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrUnavailable)
+	case err != nil:
+		return models.User{}, handleInternalErr(log, "failed to authenticate user", op, err)
+	}
 
-		return "", fmt.Errorf("%s: %w", op, err)
+	return user, nil
+}
+
+// beginSecondFactor начинает подтверждение входа вторым фактором.
+func (a *Auth) beginSecondFactor(ctx context.Context, log *zap.SugaredLogger, op string, user models.User, appName string) (string, error) {
+	sf := a.secondFactor.Load()
+	if sf == nil {
+		return "", handleInternalErr(log, "second factor is not configured", op, fmt.Errorf("second factor service is not set"))
 	}
 
-	token, err := jwt.NewToken(user, secret, a.tokenTTL)
+	ceremonyID, err := (*sf).BeginSecondFactor(ctx, user, appName)
+	if errors.Is(err, ErrUnavailable) {
+		log.Errorw("second factor unavailable", "error", err)
+		return "", fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
 	if err != nil {
-		return "", handleInternalErr(log, "failed to create token", op, err)
+		return "", handleInternalErr(log, "failed to begin second factor", op, err)
 	}
 
-	return token, nil
+	return ceremonyID, nil
+}
+
+// checkPassword проверяет пароль пользователя по хэшу из хранилища.
+func (a *Auth) checkPassword(ctx context.Context, log *zap.SugaredLogger, op, email, password string) (models.User, error) {
+	// Адрес, который нельзя нормализовать, не может принадлежать пользователю.
+	normalized, err := a.emails.Normalize(email)
+	if err != nil {
+		log.Infow("invalid email", "error", err)
+
+		return models.User{}, fmt.Errorf("%s: %w: user not found", op, ErrInvalidCredentials)
+	}
+
+	user, err := a.userProvider.User(ctx, normalized)
+	if err := handleStorageErr(log, err, op); err != nil {
+		return models.User{}, err
+	}
+	if err != nil {
+		return models.User{}, handleInternalErr(log, "failed to get user", op, err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
+		log.Infow("invalid credentials", "error", err)
+
+		return models.User{}, fmt.Errorf("%s: %w: wrong password", op, ErrInvalidCredentials)
+	}
+
+	return user, nil
+}
+
+// IssueToken создает сеанс пользователя в приложении appName и выпускает для него токен.
+// Пользователь должен быть уже аутентифицирован вызывающим, например внешним провайдером.
+// Области доступа проверяются так же, как при Login.
+func (a *Auth) IssueToken(ctx context.Context, user models.User, appName string) (token, sessionID string, err error) {
+	const op = "auth.IssueToken"
+
+	log := a.log.With("op", op, "userID", user.UUID, "appName", appName)
+
+	scopes, err := a.authorizeScopes(ctx, log, op, user, appName)
+	if err != nil {
+		return "", "", err
+	}
+
+	return a.issueToken(ctx, log, op, user, appName, scopes)
+}
+
+// issueToken создает сеанс и подписывает токен ключом приложения.
+// Ошибки возвращаются с префиксом op вызывающего метода.
+func (a *Auth) issueToken(
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	op string,
+	user models.User,
+	appName string,
+	scopes []string,
+) (string, string, error) {
+	// Пользователь другого арендатора не получает токен, даже если его учетную запись
+	// нашли в обход поиска по email (внешний провайдер, passkey).
+	if user.Tenant != tenant.From(ctx) {
+		log.Warnw("user belongs to another tenant", "userTenant", user.Tenant, "tenant", tenant.From(ctx))
+
+		return "", "", fmt.Errorf("%s: %w: user belongs to another tenant", op, ErrInvalidCredentials)
+	}
+
+	secret, err := a.signingKey(ctx, log, appName)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	opts := a.tokens.Load().options(appName)
+
+	client := audit.ClientFrom(ctx)
+	sessionID, err := a.sessionSaver.SaveSession(ctx, models.Session{
+		UserUUID:  user.UUID,
+		App:       appName,
+		Device:    client.Device,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		ExpiresAt: time.Now().Add(opts.TTL),
+	})
+	if sterr := handleStorageErr(log, err, op); sterr != nil {
+		return "", "", sterr
+	}
+	if err != nil {
+		return "", "", handleInternalErr(log, "failed to save session", op, err)
+	}
+
+	opts.SessionID = sessionID
+	opts.Scopes = scopes
+
+	token, err := jwt.NewToken(user, opts, secret)
+	if err != nil {
+		return "", "", handleInternalErr(log, "failed to create token", op, err)
+	}
+
+	return token, sessionID, nil
 }
 
 // RegisterNewUser регистрирует нового пользователя и возвращает токен.
 // Если пользователь с таким email уже существует, возвращает ошибку.
-func (a *Auth) RegisterNewUser(ctx context.Context, email, password string) (string, error) {
+func (a *Auth) RegisterNewUser(ctx context.Context, email, password string) (userUUID string, err error) {
 	const op = "auth.RegisterNewUser"
 
 	log := a.log.With("op", op, "email", email)
 	log.Infow("registering new user")
 
+	defer func() {
+		e := audit.Event(models.AuditRegister, err)
+		e.Actor, e.Subject = email, email
+		a.auditor.Record(ctx, e)
+	}()
+
+	normalized, err := a.emails.Normalize(email)
+	if err != nil {
+		log.Infow("invalid email", "error", err)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+	email = normalized
+
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", handleInternalErr(log, "failed to hash password", op, err)
 	}
 
-	userUUID, err := a.userSaver.SaveUser(ctx, email, passHash)
+	userUUID, err = a.userSaver.SaveUser(ctx, email, passHash)
 	if sterr := handleStorageErr(log, err, op); sterr != nil {
 		return "", sterr
 	}
@@ -161,36 +499,64 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email, password string) (str
 }
 
 // SigningKey возвращает ключ подписи для приложения с заданным именем.
-func (a *Auth) SigningKey(ctx context.Context, appName string) (string, error) {
+func (a *Auth) SigningKey(ctx context.Context, appName string) (key string, err error) {
 	const op = "auth.SigningKey"
 
 	log := a.log.With("op", op, "appName", appName)
 	log.Infow("getting signing key")
 
+	defer func() {
+		e := audit.Event(models.AuditSigningKey, err)
+		e.App = appName
+		a.auditor.Record(ctx, e)
+	}()
+
+	key, err = a.signingKey(ctx, log, appName)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// signingKey возвращает ключ подписи приложения, генерируя его при отсутствии.
+// Если ключ одновременно создал другой запрос, используется сохраненный ключ,
+// чтобы не инвалидировать уже выданные им токены.
+func (a *Auth) signingKey(ctx context.Context, log *zap.SugaredLogger, appName string) (string, error) {
 	key, err := a.signingKeyProvider.Key(ctx, appName)
-	// TODO: refactor flat
-	if errors.Is(err, ErrKeyNotFound) {
-		log.Infow("key not found, generating new key", "error", err)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		log.Errorw("failed to get signing key", "error", err)
+		return "", err
+	}
 
-		secret, err := jwt.GenerateHS256Secret()
-		if err != nil {
-			log.Errorw("failed to generate signing key", "error", err)
-			return "", fmt.Errorf("%s: %w", op, err)
-		}
+	log.Infow("key not found, generating new key", "error", err)
 
-		if saveErr := a.signingKeySaver.SaveKey(ctx, appName, secret); saveErr != nil {
-			log.Errorw("failed to save signing key", "error", saveErr)
-			return "", fmt.Errorf("%s: %w", op, saveErr)
-		}
+	secret, err := jwt.GenerateHS256Secret()
+	if err != nil {
+		log.Errorw("failed to generate signing key", "error", err)
+		return "", err
+	}
 
-		return secret, nil
+	err = a.signingKeySaver.SaveKey(ctx, appName, secret)
+	if errors.Is(err, ErrKeyExists) {
+		log.Infow("signing key was created concurrently, using stored key")
+
+		return a.signingKeyProvider.Key(ctx, appName)
 	}
+
+	e := audit.Event(models.AuditSigningKeyGenerate, err)
+	e.App = appName
+	a.auditor.Record(ctx, e)
+
 	if err != nil {
-		log.Errorw("failed to get signing key", "error", err)
-		return "", fmt.Errorf("%s: %w", op, err)
+		log.Errorw("failed to save signing key", "error", err)
+		return "", err
 	}
 
-	return key, nil
+	return secret, nil
 }
 
 // handleStorageErr обрабатывает ошибки, возвращаемые хранилищем и логгирует их.
@@ -203,12 +569,20 @@ func handleStorageErr(log *zap.SugaredLogger, err error, op string) error {
 
 	case errors.Is(err, storage.ErrUserNotFound):
 		log.Infow("user not found", "error", err)
-		return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		return fmt.Errorf("%s: %w: user not found", op, ErrInvalidCredentials)
 
 	case errors.Is(err, storage.ErrAppNotFound):
 		log.Infow("app not found", "error", err)
 		return fmt.Errorf("%s: %w", op, ErrInvalidAppID)
 
+	case errors.Is(err, storage.ErrTenantNotFound):
+		log.Infow("tenant not found", "error", err)
+		return fmt.Errorf("%s: %w", op, ErrTenantNotFound)
+
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Errorw("storage unavailable", "error", err)
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+
 	default:
 		return nil // значит это не "известная" ошибка
 	}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/scope"
+	"go-sso/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// ScopeRequest области доступа, запрошенные клиентом при входе.
+type ScopeRequest struct {
+	Scopes []string
+	// Consent пользователь согласился на запрошенные области на экране согласия.
+	Consent bool
+}
+
+type scopeRequestKey struct{}
+
+// WithScopeRequest возвращает контекст с запрошенными областями доступа.
+func WithScopeRequest(ctx context.Context, r ScopeRequest) context.Context {
+	return context.WithValue(ctx, scopeRequestKey{}, r)
+}
+
+// ScopeRequestFrom возвращает запрошенные области доступа из контекста.
+func ScopeRequestFrom(ctx context.Context) ScopeRequest {
+	r, _ := ctx.Value(scopeRequestKey{}).(ScopeRequest)
+	return r
+}
+
+// authorizeScopes проверяет запрошенные в контексте области и возвращает те, что попадут в токен.
+// Области должны быть определены для приложения. На недостающие пользователь должен
+// согласиться: если согласие передано в запросе, оно сохраняется, иначе возвращается
+// *ConsentRequiredError.
+func (a *Auth) authorizeScopes(
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	op string,
+	user models.User,
+	appName string,
+) ([]string, error) {
+	req := ScopeRequestFrom(ctx)
+	if len(req.Scopes) == 0 {
+		return nil, nil
+	}
+
+	// Missing с пустым have убирает повторы.
+	scopes := scope.Missing(nil, req.Scopes)
+
+	defined := a.tokens.Load().Apps[appName].Scopes
+	for _, s := range scopes {
+		if _, ok := defined[s]; !ok {
+			log.Infow("unknown scope requested", "scope", s)
+			return nil, fmt.Errorf("%s: %w: %q", op, ErrInvalidScope, s)
+		}
+	}
+
+	consent, err := a.consents.Consent(ctx, user.UUID, appName)
+	switch {
+	case errors.Is(err, storage.ErrConsentNotFound):
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Errorw("storage unavailable", "error", err)
+		return nil, fmt.Errorf("%s: %w", op, ErrUnavailable)
+	case err != nil:
+		return nil, handleInternalErr(log, "failed to get consent", op, err)
+	}
+
+	missing := scope.Missing(consent.Scopes, scopes)
+	if len(missing) == 0 {
+		return scopes, nil
+	}
+
+	if !req.Consent {
+		log.Infow("consent required", "userID", user.UUID, "scopes", missing)
+		return nil, fmt.Errorf("%s: %w", op, &ConsentRequiredError{Scopes: missing})
+	}
+
+	_, err = a.consents.GrantConsent(ctx, user.UUID, appName, missing)
+
+	e := audit.Event(models.AuditConsentGrant, err)
+	e.Actor, e.Subject, e.App = user.Email, user.Email, appName
+	e.Details = map[string]string{"scopes": scope.Join(missing)}
+	a.auditor.Record(ctx, e)
+
+	if sterr := handleStorageErr(log, err, op); sterr != nil {
+		return nil, sterr
+	}
+	if err != nil {
+		return nil, handleInternalErr(log, "failed to grant consent", op, err)
+	}
+
+	log.Infow("consent granted", "userID", user.UUID, "scopes", missing)
+
+	return scopes, nil
+}
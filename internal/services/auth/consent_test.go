@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeConsents хранит согласия одного пользователя в памяти по приложениям.
+type fakeConsents map[string][]string
+
+func (f fakeConsents) Consent(_ context.Context, _, app string) (models.Consent, error) {
+	scopes, ok := f[app]
+	if !ok {
+		return models.Consent{}, storage.ErrConsentNotFound
+	}
+	return models.Consent{App: app, Scopes: scopes}, nil
+}
+
+func (f fakeConsents) GrantConsent(_ context.Context, _, app string, scopes []string) (models.Consent, error) {
+	f[app] = append(f[app], scopes...)
+	return models.Consent{App: app, Scopes: f[app]}, nil
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, models.AuditEvent) {}
+
+func TestAuthorizeScopes(t *testing.T) {
+	consents := fakeConsents{"billing": {"invoices:read"}}
+	log := zap.NewNop().Sugar()
+
+	a := New(log, nil, nil, nil, nil, nil, nil, consents, nopAuditor{}, email.Normalizer{}, nil, TokenConfig{
+		Apps: map[string]AppTokenConfig{"billing": {Scopes: map[string]string{
+			"invoices:read":  "Просмотр счетов",
+			"invoices:write": "Выставление счетов",
+		}}},
+	})
+	user := models.User{UUID: "u1", Email: "user@example.com"}
+
+	authorize := func(req ScopeRequest) ([]string, error) {
+		return a.authorizeScopes(WithScopeRequest(context.Background(), req), log, "test", user, "billing")
+	}
+
+	scopes, err := authorize(ScopeRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, scopes, "no scopes requested")
+
+	scopes, err = authorize(ScopeRequest{Scopes: []string{"invoices:read", "invoices:read"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoices:read"}, scopes)
+
+	_, err = authorize(ScopeRequest{Scopes: []string{"users:read"}})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	_, err = authorize(ScopeRequest{Scopes: []string{"invoices:read", "invoices:write"}})
+	var consentErr *ConsentRequiredError
+	require.ErrorAs(t, err, &consentErr)
+	assert.Equal(t, []string{"invoices:write"}, consentErr.Scopes)
+
+	scopes, err = authorize(ScopeRequest{Scopes: []string{"invoices:read", "invoices:write"}, Consent: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoices:read", "invoices:write"}, scopes)
+	assert.Equal(t, []string{"invoices:read", "invoices:write"}, consents["billing"])
+
+	_, err = authorize(ScopeRequest{Scopes: []string{"invoices:write"}})
+	assert.NoError(t, err, "consent is remembered")
+}
@@ -0,0 +1,462 @@
+// Package clients реализует учетные записи сервисов (OAuth-клиенты) и выпуск им токенов
+// по client credentials (RFC 6749, раздел 4.4) для вызовов между сервисами без пользователя.
+package clients
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/lib/scope"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Clients сервис клиентов приложений.
+type Clients struct {
+	log *zap.SugaredLogger
+
+	clients ClientStorage
+	keys    SigningKeyProvider
+	auditor Auditor
+
+	cfg atomic.Pointer[Config]
+	now func() time.Time
+}
+
+// Config параметры токенов клиентов.
+type Config struct {
+	Issuer string
+	// TokenTTL время жизни токена клиента.
+	TokenTTL time.Duration
+}
+
+type ClientStorage interface {
+	SaveClient(ctx context.Context, c models.Client) (models.Client, error)
+	Client(ctx context.Context, id string) (models.Client, error)
+	Clients(ctx context.Context, appName string) ([]models.Client, error)
+	RotateClientSecret(ctx context.Context, id string, secretHash []byte, previousExpiresAt *time.Time) error
+	DeleteClient(ctx context.Context, id string) error
+}
+
+type SigningKeyProvider interface {
+	Key(ctx context.Context, appName string) (string, error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Token токен, выпущенный клиенту.
+type Token struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+	// Scopes области доступа, попавшие в токен.
+	Scopes []string
+}
+
+// Ошибки, которые могут возникнуть при работе с клиентами.
+var (
+	ErrAppNotFound    = errors.New("app not found")
+	ErrClientNotFound = errors.New("client not found")
+	// ErrInvalidClient клиент не найден или секрет неверный.
+	ErrInvalidClient = errors.New("invalid client")
+	// ErrInvalidScope запрошена область доступа, не разрешенная клиенту, или область неверного формата.
+	ErrInvalidScope = errors.New("invalid scope")
+	ErrInvalidName  = errors.New("invalid client name")
+	ErrInvalidGrace = errors.New("invalid grace period")
+	// ErrInvalidToken токен не является действительным токеном существующего клиента.
+	ErrInvalidToken = errors.New("invalid token")
+	ErrUnavailable  = errors.New("dependency unavailable")
+)
+
+// maxNameLen ограничение длины названия клиента в символах.
+const maxNameLen = 128
+
+// New возвращает новый экземпляр сервиса клиентов.
+func New(
+	log *zap.SugaredLogger,
+	clients ClientStorage,
+	keys SigningKeyProvider,
+	auditor Auditor,
+	cfg Config,
+) *Clients {
+	c := &Clients{
+		log: log,
+
+		clients: clients,
+		keys:    keys,
+		auditor: auditor,
+
+		now: time.Now,
+	}
+
+	c.SetConfig(cfg)
+
+	return c
+}
+
+// SetConfig задает параметры токенов клиентов.
+func (c *Clients) SetConfig(cfg Config) {
+	c.cfg.Store(&cfg)
+}
+
+// CreateClient регистрирует клиента приложения appName, которому разрешены области scopes,
+// и возвращает его вместе с секретом. Секрет хранится только в виде хэша и больше не выводится.
+func (c *Clients) CreateClient(ctx context.Context, appName, name string, scopes []string) (client models.Client, secret string, err error) {
+	const op = "clients.CreateClient"
+
+	log := c.log.With("op", op, "appName", appName)
+
+	defer func() {
+		c.record(ctx, models.AuditClientCreate, err, client.ID, appName, map[string]string{"scope": scope.Join(scopes)})
+	}()
+
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxNameLen {
+		return models.Client{}, "", fmt.Errorf("%s: %w: name must be 1 to %d characters", op, ErrInvalidName, maxNameLen)
+	}
+
+	scopes, err = normalizeScopes(scopes)
+	if err != nil {
+		return models.Client{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := randomString(16)
+	if err != nil {
+		return models.Client{}, "", handleInternalErr(log, "failed to generate client id", op, err)
+	}
+
+	secret, err = randomString(32)
+	if err != nil {
+		return models.Client{}, "", handleInternalErr(log, "failed to generate client secret", op, err)
+	}
+
+	client, err = c.clients.SaveClient(ctx, models.Client{
+		ID:         id,
+		App:        appName,
+		Name:       name,
+		Scopes:     scopes,
+		SecretHash: hashSecret(secret),
+	})
+	if err != nil {
+		return models.Client{}, "", handleStorageErr(log, "failed to save client", op, err)
+	}
+
+	log.Infow("client created", "clientID", client.ID)
+
+	return client, secret, nil
+}
+
+// Clients возвращает клиентов приложения appName.
+func (c *Clients) Clients(ctx context.Context, appName string) ([]models.Client, error) {
+	const op = "clients.Clients"
+
+	clients, err := c.clients.Clients(ctx, appName)
+	if err != nil {
+		return nil, handleStorageErr(c.log.With("op", op), "failed to list clients", op, err)
+	}
+
+	return clients, nil
+}
+
+// RotateSecret выдает клиенту новый секрет. Прежний секрет принимается еще grace,
+// чтобы сервисы успели перейти на новый; при нулевом grace он перестает действовать сразу.
+// Секрет, оставшийся от предыдущей ротации, перестает действовать в любом случае.
+func (c *Clients) RotateSecret(ctx context.Context, clientID string, grace time.Duration) (secret string, err error) {
+	const op = "clients.RotateSecret"
+
+	log := c.log.With("op", op, "clientID", clientID)
+
+	client, err := c.client(ctx, clientID)
+	defer func() {
+		c.record(ctx, models.AuditClientRotate, err, clientID, client.App, map[string]string{"grace": grace.String()})
+	}()
+	if err != nil {
+		return "", handleStorageErr(log, "failed to get client", op, err)
+	}
+
+	if grace < 0 {
+		return "", fmt.Errorf("%s: %w: must not be negative", op, ErrInvalidGrace)
+	}
+
+	secret, err = randomString(32)
+	if err != nil {
+		return "", handleInternalErr(log, "failed to generate client secret", op, err)
+	}
+
+	var previousExpiresAt *time.Time
+	if grace > 0 {
+		t := c.now().Add(grace)
+		previousExpiresAt = &t
+	}
+
+	if err := c.clients.RotateClientSecret(ctx, clientID, hashSecret(secret), previousExpiresAt); err != nil {
+		return "", handleStorageErr(log, "failed to rotate client secret", op, err)
+	}
+
+	log.Infow("client secret rotated", "grace", grace)
+
+	return secret, nil
+}
+
+// DeleteClient удаляет клиента. Выданные ему токены перестают проходить интроспекцию.
+func (c *Clients) DeleteClient(ctx context.Context, clientID string) (err error) {
+	const op = "clients.DeleteClient"
+
+	log := c.log.With("op", op, "clientID", clientID)
+
+	var client models.Client
+	defer func() { c.record(ctx, models.AuditClientDelete, err, clientID, client.App, nil) }()
+
+	client, err = c.client(ctx, clientID)
+	if err != nil {
+		return handleStorageErr(log, "failed to get client", op, err)
+	}
+
+	if err := c.clients.DeleteClient(ctx, clientID); err != nil {
+		return handleStorageErr(log, "failed to delete client", op, err)
+	}
+
+	log.Infow("client deleted", "appName", client.App)
+
+	return nil
+}
+
+// ClientCredentialsToken проверяет секрет клиента и выпускает ему токен приложения,
+// к которому он привязан. Если scopes пуст, токен получает все разрешенные клиенту области.
+func (c *Clients) ClientCredentialsToken(ctx context.Context, clientID, secret string, scopes []string) (token Token, err error) {
+	const op = "clients.ClientCredentialsToken"
+
+	log := c.log.With("op", op, "clientID", clientID)
+
+	var client models.Client
+	defer func() {
+		e := audit.Event(models.AuditClientToken, err)
+		e.Actor, e.Subject, e.App = clientID, clientID, client.App
+		// При отказе в журнал попадают запрошенные области, при успехе — выданные.
+		requested := scope.Join(scopes)
+		if err == nil {
+			requested = scope.Join(token.Scopes)
+		}
+		e.Details = map[string]string{"scope": requested}
+		c.auditor.Record(ctx, e)
+	}()
+
+	if clientID == "" || secret == "" {
+		return Token{}, fmt.Errorf("%s: %w", op, ErrInvalidClient)
+	}
+
+	client, err = c.clients.Client(ctx, clientID)
+	if errors.Is(err, storage.ErrClientNotFound) {
+		return Token{}, fmt.Errorf("%s: %w: client not found", op, ErrInvalidClient)
+	}
+	if err != nil {
+		return Token{}, handleStorageErr(log, "failed to get client", op, err)
+	}
+
+	if !c.secretMatches(client, secret) {
+		log.Infow("wrong client secret")
+		return Token{}, fmt.Errorf("%s: %w: wrong secret", op, ErrInvalidClient)
+	}
+
+	granted, err := grantScopes(client.Scopes, scopes)
+	if err != nil {
+		return Token{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Идентификатор клиента глобален, поэтому арендатор берется из его записи, а не из запроса.
+	key, err := c.keys.Key(tenant.With(ctx, client.Tenant), client.App)
+	if err != nil {
+		return Token{}, handleKeyErr(log, op, err)
+	}
+
+	cfg := c.cfg.Load()
+	access, err := jwt.NewClientToken(client, granted, jwt.TokenOptions{
+		Issuer: cfg.Issuer,
+		App:    client.App,
+		TTL:    cfg.TokenTTL,
+	}, key)
+	if err != nil {
+		return Token{}, handleInternalErr(log, "failed to generate token", op, err)
+	}
+
+	log.Infow("client token issued", "appName", client.App)
+
+	return Token{AccessToken: access, ExpiresIn: cfg.TokenTTL, Scopes: granted}, nil
+}
+
+// AuthenticateToken проверяет токен клиента: подпись ключом его приложения, срок действия
+// и то, что клиент все еще существует.
+func (c *Clients) AuthenticateToken(ctx context.Context, token string) (*jwt.Claims, error) {
+	const op = "clients.AuthenticateToken"
+
+	unverified, err := jwt.Unverified(token)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", op, ErrInvalidToken, err)
+	}
+	if unverified.ClientID == "" {
+		return nil, fmt.Errorf("%s: %w: client_id claim is missing", op, ErrInvalidToken)
+	}
+
+	log := c.log.With("op", op, "clientID", unverified.ClientID)
+
+	client, err := c.clients.Client(ctx, unverified.ClientID)
+	if errors.Is(err, storage.ErrClientNotFound) {
+		return nil, fmt.Errorf("%s: %w: client not found", op, ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to get client", op, err)
+	}
+
+	key, err := c.keys.Key(tenant.With(ctx, client.Tenant), client.App)
+	if errors.Is(err, auth.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%s: %w: app has no signing key", op, ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, handleKeyErr(log, op, err)
+	}
+
+	claims, err := jwt.Verify(token, key, c.cfg.Load().Issuer, client.App)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", op, ErrInvalidToken, err)
+	}
+	if claims.ClientID != client.ID || claims.Subject != client.ID || claims.App != client.App {
+		return nil, fmt.Errorf("%s: %w: claims do not match client", op, ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// client возвращает клиента арендатора из ctx. Клиент другого арендатора считается
+// несуществующим, чтобы администратор не мог управлять им по идентификатору.
+func (c *Clients) client(ctx context.Context, id string) (models.Client, error) {
+	client, err := c.clients.Client(ctx, id)
+	if err != nil {
+		return models.Client{}, err
+	}
+	if client.Tenant != tenant.From(ctx) {
+		return models.Client{}, storage.ErrClientNotFound
+	}
+
+	return client, nil
+}
+
+// secretMatches сравнивает секрет с текущим и, пока не истек срок, с предыдущим.
+func (c *Clients) secretMatches(client models.Client, secret string) bool {
+	hash := hashSecret(secret)
+
+	if subtle.ConstantTimeCompare(hash, client.SecretHash) == 1 {
+		return true
+	}
+
+	return len(client.PreviousSecretHash) > 0 &&
+		client.PreviousSecretExpiresAt != nil &&
+		c.now().Before(*client.PreviousSecretExpiresAt) &&
+		subtle.ConstantTimeCompare(hash, client.PreviousSecretHash) == 1
+}
+
+// record записывает событие аудита действия с клиентом.
+func (c *Clients) record(ctx context.Context, typ string, err error, subject, app string, details map[string]string) {
+	e := audit.Event(typ, err)
+	e.Subject = subject
+	e.App = app
+	e.Details = details
+
+	c.auditor.Record(ctx, e)
+}
+
+// normalizeScopes проверяет формат областей доступа (RFC 6749, раздел 3.3) и убирает повторы.
+func normalizeScopes(scopes []string) ([]string, error) {
+	out := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !scope.Valid(s) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+
+	return out, nil
+}
+
+// grantScopes возвращает области, которые получит токен: запрошенные, если все они
+// разрешены клиенту, или все разрешенные, если ничего не запрошено.
+func grantScopes(allowed, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	requested, err := normalizeScopes(requested)
+	if err != nil {
+		return nil, err
+	}
+
+	if missing := scope.Missing(allowed, requested); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %q is not allowed for the client", ErrInvalidScope, missing[0])
+	}
+
+	return requested, nil
+}
+
+// hashSecret хэш секрета для хранения. Секреты случайные, поэтому соль не нужна.
+func hashSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// randomString возвращает n случайных байт в base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// handleKeyErr переводит ошибки получения ключа подписи в ошибки сервиса и логгирует их.
+func handleKeyErr(log *zap.SugaredLogger, op string, err error) error {
+	log.Errorw("failed to get signing key", "error", err)
+
+	if errors.Is(err, auth.ErrUnavailable) {
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// handleStorageErr переводит ошибки хранилища в ошибки сервиса и логгирует их.
+func handleStorageErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrAppNotFound):
+		return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+	case errors.Is(err, storage.ErrClientNotFound):
+		return fmt.Errorf("%s: %w", op, ErrClientNotFound)
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Errorw("storage unavailable", "error", err)
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	default:
+		return handleInternalErr(log, msg, op, err)
+	}
+}
+
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+	return fmt.Errorf("%s: %w", op, err)
+}
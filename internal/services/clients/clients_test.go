@@ -0,0 +1,208 @@
+package clients
+
+import (
+	"context"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStorage хранит клиентов в памяти.
+type fakeStorage struct {
+	clients map[string]models.Client
+}
+
+func (s *fakeStorage) SaveClient(ctx context.Context, c models.Client) (models.Client, error) {
+	if c.App != "billing" {
+		return models.Client{}, storage.ErrAppNotFound
+	}
+	c.AppID, c.Tenant, c.CreatedAt = 1, tenant.From(ctx), time.Now()
+	s.clients[c.ID] = c
+	return c, nil
+}
+
+func (s *fakeStorage) Client(_ context.Context, id string) (models.Client, error) {
+	c, ok := s.clients[id]
+	if !ok {
+		return models.Client{}, storage.ErrClientNotFound
+	}
+	return c, nil
+}
+
+func (s *fakeStorage) Clients(_ context.Context, appName string) ([]models.Client, error) {
+	var out []models.Client
+	for _, c := range s.clients {
+		if c.App == appName {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStorage) RotateClientSecret(_ context.Context, id string, secretHash []byte, previousExpiresAt *time.Time) error {
+	c, ok := s.clients[id]
+	if !ok {
+		return storage.ErrClientNotFound
+	}
+	c.PreviousSecretHash, c.PreviousSecretExpiresAt = nil, previousExpiresAt
+	if previousExpiresAt != nil {
+		c.PreviousSecretHash = c.SecretHash
+	}
+	c.SecretHash = secretHash
+	s.clients[id] = c
+	return nil
+}
+
+func (s *fakeStorage) DeleteClient(_ context.Context, id string) error {
+	if _, ok := s.clients[id]; !ok {
+		return storage.ErrClientNotFound
+	}
+	delete(s.clients, id)
+	return nil
+}
+
+type fakeKeys map[string]string
+
+func (k fakeKeys) Key(_ context.Context, appName string) (string, error) {
+	return k[appName], nil
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, models.AuditEvent) {}
+
+const testKey = "billing-signing-key"
+
+func newTestService() *Clients {
+	st := &fakeStorage{clients: make(map[string]models.Client)}
+
+	return New(zap.NewNop().Sugar(), st, fakeKeys{"billing": testKey}, nopAuditor{}, Config{
+		Issuer:   "sso",
+		TokenTTL: time.Hour,
+	})
+}
+
+func TestClientCredentialsToken(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	client, secret, err := svc.CreateClient(ctx, "billing", "reports", []string{"invoices:read", "invoices:write", "invoices:read"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoices:read", "invoices:write"}, client.Scopes)
+
+	token, err := svc.ClientCredentialsToken(ctx, client.ID, secret, nil)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, token.ExpiresIn)
+	assert.Equal(t, []string{"invoices:read", "invoices:write"}, token.Scopes)
+
+	claims, err := jwt.Verify(token.AccessToken, testKey, "sso", "billing")
+	require.NoError(t, err)
+	assert.Equal(t, client.ID, claims.Subject)
+	assert.Equal(t, client.ID, claims.ClientID)
+	assert.Equal(t, "invoices:read invoices:write", claims.Scope)
+	assert.Empty(t, claims.SessionID)
+
+	token, err = svc.ClientCredentialsToken(ctx, client.ID, secret, []string{"invoices:read"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoices:read"}, token.Scopes)
+
+	_, err = svc.ClientCredentialsToken(ctx, client.ID, secret, []string{"users:read"})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	_, err = svc.ClientCredentialsToken(ctx, client.ID, "wrong", nil)
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	_, err = svc.ClientCredentialsToken(ctx, "unknown", secret, nil)
+	assert.ErrorIs(t, err, ErrInvalidClient)
+}
+
+func TestRotateSecret_Grace(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	client, oldSecret, err := svc.CreateClient(ctx, "billing", "reports", nil)
+	require.NoError(t, err)
+
+	newSecret, err := svc.RotateSecret(ctx, client.ID, time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldSecret, newSecret)
+
+	_, err = svc.ClientCredentialsToken(ctx, client.ID, newSecret, nil)
+	require.NoError(t, err)
+	_, err = svc.ClientCredentialsToken(ctx, client.ID, oldSecret, nil)
+	require.NoError(t, err, "previous secret is valid during the grace period")
+
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = svc.ClientCredentialsToken(ctx, client.ID, oldSecret, nil)
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	svc.now = time.Now
+	latest, err := svc.RotateSecret(ctx, client.ID, 0)
+	require.NoError(t, err)
+	_, err = svc.ClientCredentialsToken(ctx, client.ID, newSecret, nil)
+	assert.ErrorIs(t, err, ErrInvalidClient, "zero grace revokes the previous secret at once")
+	_, err = svc.ClientCredentialsToken(ctx, client.ID, latest, nil)
+	assert.NoError(t, err)
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	client, secret, err := svc.CreateClient(ctx, "billing", "reports", []string{"invoices:read"})
+	require.NoError(t, err)
+
+	token, err := svc.ClientCredentialsToken(ctx, client.ID, secret, nil)
+	require.NoError(t, err)
+
+	claims, err := svc.AuthenticateToken(ctx, token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "invoices:read", claims.Scope)
+
+	require.NoError(t, svc.DeleteClient(ctx, client.ID))
+
+	_, err = svc.AuthenticateToken(ctx, token.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken, "tokens of a deleted client are not active")
+}
+
+func TestClientOfAnotherTenant(t *testing.T) {
+	ctx := tenant.With(context.Background(), "acme")
+	svc := newTestService()
+
+	client, secret, err := svc.CreateClient(ctx, "billing", "reports", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", client.Tenant)
+
+	// Токен выпускается без арендатора в запросе: он берется из записи клиента.
+	token, err := svc.ClientCredentialsToken(context.Background(), client.ID, secret, nil)
+	require.NoError(t, err)
+
+	claims, err := svc.AuthenticateToken(context.Background(), token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.Tenant)
+
+	_, err = svc.RotateSecret(context.Background(), client.ID, 0)
+	assert.ErrorIs(t, err, ErrClientNotFound)
+	assert.ErrorIs(t, svc.DeleteClient(context.Background(), client.ID), ErrClientNotFound)
+}
+
+func TestCreateClient_Invalid(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	_, _, err := svc.CreateClient(ctx, "billing", " ", nil)
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	_, _, err = svc.CreateClient(ctx, "billing", "reports", []string{`bad"scope`})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	_, _, err = svc.CreateClient(ctx, "unknown", "reports", nil)
+	assert.ErrorIs(t, err, ErrAppNotFound)
+}
@@ -0,0 +1,168 @@
+// Package consent реализует просмотр и отзыв согласий пользователей на области доступа приложений.
+package consent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/storage"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Consents сервис согласий пользователей.
+type Consents struct {
+	log *zap.SugaredLogger
+
+	storage ConsentStorage
+	auditor Auditor
+
+	// scopes описания областей по приложениям; меняются при перезагрузке конфигурации.
+	scopes atomic.Pointer[map[string]map[string]string]
+}
+
+type ConsentStorage interface {
+	Consents(ctx context.Context, userUUID string) ([]models.Consent, error)
+	RevokeConsent(ctx context.Context, userUUID, app string) (revokedSessions int64, err error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Scope область доступа с описанием для экрана согласия.
+type Scope struct {
+	Name        string
+	Description string
+}
+
+// Grant согласие пользователя на области приложения.
+type Grant struct {
+	App       string
+	Scopes    []Scope
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Ошибки, которые могут возникнуть при работе с сервисом согласий.
+var (
+	ErrConsentNotFound = errors.New("consent not found")
+	ErrUnavailable     = errors.New("dependency unavailable")
+)
+
+// New возвращает новый экземпляр сервиса согласий. scopes — описания областей
+// по приложениям, как в auth.AppTokenConfig.Scopes.
+func New(
+	log *zap.SugaredLogger,
+	consentStorage ConsentStorage,
+	auditor Auditor,
+	scopes map[string]map[string]string,
+) *Consents {
+	c := &Consents{
+		log:     log,
+		storage: consentStorage,
+		auditor: auditor,
+	}
+
+	c.SetScopes(scopes)
+
+	return c
+}
+
+// SetScopes задает описания областей по приложениям.
+func (c *Consents) SetScopes(scopes map[string]map[string]string) {
+	c.scopes.Store(&scopes)
+}
+
+// Scopes возвращает области, которые может запрашивать приложение appName, по имени.
+func (c *Consents) Scopes(appName string) []Scope {
+	defined := (*c.scopes.Load())[appName]
+
+	names := make([]string, 0, len(defined))
+	for name := range defined {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	out := make([]Scope, 0, len(names))
+	for _, name := range names {
+		out = append(out, Scope{Name: name, Description: defined[name]})
+	}
+
+	return out
+}
+
+// List возвращает согласия пользователя. Области, удаленные из конфигурации приложения,
+// возвращаются без описания.
+func (c *Consents) List(ctx context.Context, userUUID string) ([]Grant, error) {
+	const op = "consent.List"
+
+	consents, err := c.storage.Consents(ctx, userUUID)
+	if err != nil {
+		return nil, handleInternalErr(c.log.With("op", op, "userUUID", userUUID), "failed to list consents", op, err)
+	}
+
+	scopes := *c.scopes.Load()
+
+	grants := make([]Grant, 0, len(consents))
+	for _, consent := range consents {
+		g := Grant{
+			App:       consent.App,
+			Scopes:    make([]Scope, 0, len(consent.Scopes)),
+			CreatedAt: consent.CreatedAt,
+			UpdatedAt: consent.UpdatedAt,
+		}
+		for _, name := range consent.Scopes {
+			g.Scopes = append(g.Scopes, Scope{Name: name, Description: scopes[consent.App][name]})
+		}
+		grants = append(grants, g)
+	}
+
+	return grants, nil
+}
+
+// Revoke отзывает согласие пользователя для приложения appName вместе с его сеансами
+// в этом приложении, чтобы выданные по согласию токены перестали приниматься.
+// При следующем входе согласие запрашивается заново.
+func (c *Consents) Revoke(ctx context.Context, userUUID, appName string) (revokedSessions int64, err error) {
+	const op = "consent.Revoke"
+
+	log := c.log.With("op", op, "userUUID", userUUID, "appName", appName)
+
+	defer func() {
+		e := audit.Event(models.AuditConsentRevoke, err)
+		e.Subject, e.App = userUUID, appName
+		e.Details = map[string]string{"revoked_sessions": strconv.FormatInt(revokedSessions, 10)}
+		c.auditor.Record(ctx, e)
+	}()
+
+	revokedSessions, err = c.storage.RevokeConsent(ctx, userUUID, appName)
+	if errors.Is(err, storage.ErrConsentNotFound) {
+		return 0, fmt.Errorf("%s: %w", op, ErrConsentNotFound)
+	}
+	if err != nil {
+		return 0, handleInternalErr(log, "failed to revoke consent", op, err)
+	}
+
+	log.Infow("consent revoked", "revokedSessions", revokedSessions)
+
+	return revokedSessions, nil
+}
+
+// handleInternalErr логгирует ошибку и переводит недоступность хранилища в ErrUnavailable.
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+
+	if errors.Is(err, storage.ErrUnavailable) {
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
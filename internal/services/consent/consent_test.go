@@ -0,0 +1,106 @@
+package consent
+
+import (
+	"context"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStorage хранит согласия в памяти; sessions — число активных сеансов по приложениям.
+type fakeStorage struct {
+	consents map[string]models.Consent
+	sessions map[string]int64
+}
+
+func (s *fakeStorage) Consents(_ context.Context, userUUID string) ([]models.Consent, error) {
+	var out []models.Consent
+	for _, c := range s.consents {
+		if c.UserUUID == userUUID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStorage) RevokeConsent(_ context.Context, userUUID, app string) (int64, error) {
+	c, ok := s.consents[app]
+	if !ok || c.UserUUID != userUUID {
+		return 0, storage.ErrConsentNotFound
+	}
+	delete(s.consents, app)
+
+	n := s.sessions[app]
+	s.sessions[app] = 0
+	return n, nil
+}
+
+type recordingAuditor struct {
+	events []models.AuditEvent
+}
+
+func (a *recordingAuditor) Record(_ context.Context, e models.AuditEvent) {
+	a.events = append(a.events, e)
+}
+
+func newTestService() (*Consents, *fakeStorage, *recordingAuditor) {
+	st := &fakeStorage{
+		consents: map[string]models.Consent{
+			"billing": {UserUUID: "u1", App: "billing", Scopes: []string{"invoices:read", "legacy"}},
+		},
+		sessions: map[string]int64{"billing": 2},
+	}
+	auditor := &recordingAuditor{}
+
+	svc := New(zap.NewNop().Sugar(), st, auditor, map[string]map[string]string{
+		"billing": {"invoices:write": "Выставление счетов", "invoices:read": "Просмотр счетов"},
+	})
+
+	return svc, st, auditor
+}
+
+func TestScopes(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	assert.Equal(t, []Scope{
+		{Name: "invoices:read", Description: "Просмотр счетов"},
+		{Name: "invoices:write", Description: "Выставление счетов"},
+	}, svc.Scopes("billing"))
+	assert.Empty(t, svc.Scopes("unknown"))
+}
+
+func TestList(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	grants, err := svc.List(context.Background(), "u1")
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	assert.Equal(t, "billing", grants[0].App)
+	assert.Equal(t, []Scope{
+		{Name: "invoices:read", Description: "Просмотр счетов"},
+		{Name: "legacy"},
+	}, grants[0].Scopes, "scopes removed from config are listed without description")
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	svc, st, auditor := newTestService()
+
+	n, err := svc.Revoke(ctx, "u1", "billing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Empty(t, st.consents)
+
+	require.Len(t, auditor.events, 1)
+	assert.Equal(t, models.AuditConsentRevoke, auditor.events[0].Type)
+	assert.Equal(t, "billing", auditor.events[0].App)
+	assert.Equal(t, "2", auditor.events[0].Details["revoked_sessions"])
+
+	_, err = svc.Revoke(ctx, "u1", "billing")
+	assert.ErrorIs(t, err, ErrConsentNotFound)
+	assert.Equal(t, models.AuditFailure, auditor.events[1].Outcome)
+}
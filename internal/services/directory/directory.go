@@ -0,0 +1,337 @@
+// Package directory реализует вход по паролю из каталога LDAP / Active Directory:
+// создание локальных пользователей при первом входе и синхронизацию ролей по группам каталога.
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/ldap"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Provider имя провайдера, под которым учетные записи каталога хранятся в identities.
+const Provider = "ldap"
+
+// Directory проверяет пароли в каталоге и сопоставляет его записи локальным пользователям.
+// Реализует auth.Authenticator.
+type Directory struct {
+	log *zap.SugaredLogger
+
+	client     Client
+	identities IdentityStorage
+	users      UserProvider
+	apps       AppProvider
+	roles      RoleStorage
+	auditor    Auditor
+	emails     email.Normalizer
+
+	linkExisting bool
+	groupRoles   []GroupRole
+	now          func() time.Time
+}
+
+// GroupRole роль, которую получают в приложении App участники группы каталога Group.
+type GroupRole struct {
+	// Group DN группы; сравнивается без учета регистра.
+	Group string
+	App   string
+	Role  string
+}
+
+// Client проверяет пароль пользователя в каталоге.
+type Client interface {
+	Authenticate(ctx context.Context, login, password string) (ldap.Entry, error)
+}
+
+type IdentityStorage interface {
+	Identity(ctx context.Context, provider, subject string) (models.Identity, error)
+	SaveIdentity(ctx context.Context, identity models.Identity) error
+	SaveFederatedUser(ctx context.Context, email, displayName string, identity models.Identity) (uuid string, err error)
+	TouchIdentity(ctx context.Context, provider, subject, email string) error
+}
+
+type UserProvider interface {
+	User(ctx context.Context, email string) (models.User, error)
+	UserByUUID(ctx context.Context, uuid string) (models.User, error)
+}
+
+type AppProvider interface {
+	AppByName(ctx context.Context, name string) (models.App, error)
+}
+
+type RoleStorage interface {
+	AssignRole(ctx context.Context, userUUID string, appID int, role string) error
+	RevokeRole(ctx context.Context, userUUID string, appID int, role string) error
+	UserRoles(ctx context.Context, userUUID string) ([]models.Role, error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// New возвращает сервис входа через каталог. Если linkExisting, запись каталога при первом
+// входе привязывается к существующему пользователю с тем же email; иначе вход отклоняется.
+func New(
+	log *zap.SugaredLogger,
+	client Client,
+	identities IdentityStorage,
+	users UserProvider,
+	apps AppProvider,
+	roles RoleStorage,
+	auditor Auditor,
+	emails email.Normalizer,
+	linkExisting bool,
+	groupRoles []GroupRole,
+) *Directory {
+	return &Directory{
+		log: log,
+
+		client:     client,
+		identities: identities,
+		users:      users,
+		apps:       apps,
+		roles:      roles,
+		auditor:    auditor,
+		emails:     emails,
+
+		linkExisting: linkExisting,
+		groupRoles:   groupRoles,
+		now:          time.Now,
+	}
+}
+
+// Authenticate проверяет пароль в каталоге и возвращает локального пользователя,
+// создавая его при первом входе, и приводит его роли в соответствие с группами каталога.
+func (d *Directory) Authenticate(ctx context.Context, login, password string) (models.User, error) {
+	const op = "directory.Authenticate"
+
+	log := d.log.With("op", op, "login", login)
+
+	entry, err := d.client.Authenticate(ctx, login, password)
+	switch {
+	case errors.Is(err, ldap.ErrInvalidCredentials):
+		return models.User{}, fmt.Errorf("%s: %w: %v", op, auth.ErrInvalidCredentials, err)
+	case errors.Is(err, ldap.ErrUnavailable):
+		return models.User{}, fmt.Errorf("%s: %w: %v", op, auth.ErrUnavailable, err)
+	case err != nil:
+		return models.User{}, handleInternalErr(log, "directory request failed", op, err)
+	}
+
+	log = log.With("dn", entry.DN)
+
+	user, err := d.resolve(ctx, log, op, entry)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if err := d.syncRoles(ctx, log, op, user, entry.Groups); err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// resolve находит пользователя, привязанного к записи каталога, или создает его.
+func (d *Directory) resolve(ctx context.Context, log *zap.SugaredLogger, op string, entry ldap.Entry) (models.User, error) {
+	identity, err := d.identities.Identity(ctx, Provider, entry.ID)
+	if err == nil {
+		if err := d.identities.TouchIdentity(ctx, Provider, entry.ID, entry.Email); err != nil {
+			log.Warnw("failed to update identity", "error", err)
+		}
+
+		user, err := d.users.UserByUUID(ctx, identity.UserUUID)
+		if err != nil {
+			return models.User{}, handleStorageErr(log, "failed to get user", op, err)
+		}
+
+		return user, nil
+	}
+	if !errors.Is(err, storage.ErrIdentityNotFound) {
+		return models.User{}, handleStorageErr(log, "failed to get identity", op, err)
+	}
+
+	uuid, err := d.provision(ctx, log, op, entry)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user, err := d.users.UserByUUID(ctx, uuid)
+	if err != nil {
+		return models.User{}, handleStorageErr(log, "failed to get user", op, err)
+	}
+
+	return user, nil
+}
+
+// provision создает пользователя для записи каталога или, если разрешено linkExisting,
+// привязывает запись к существующему пользователю с тем же email.
+func (d *Directory) provision(ctx context.Context, log *zap.SugaredLogger, op string, entry ldap.Entry) (uuid string, err error) {
+	addr, err := d.emails.Normalize(entry.Email)
+	if err != nil {
+		log.Warnw("directory entry has no valid email", "email", entry.Email, "error", err)
+		return "", fmt.Errorf("%s: %w: entry has no valid email", op, auth.ErrInvalidCredentials)
+	}
+
+	created := false
+	defer func() {
+		e := audit.Event(models.AuditDirectoryProvision, err)
+		e.Actor, e.Subject = Provider, addr
+		e.Details = map[string]string{"dn": entry.DN, "created": strconv.FormatBool(created)}
+		d.auditor.Record(ctx, e)
+	}()
+
+	now := d.now()
+	identity := models.Identity{
+		Provider:    Provider,
+		Subject:     entry.ID,
+		Email:       entry.Email,
+		LastLoginAt: &now,
+	}
+
+	uuid, err = d.identities.SaveFederatedUser(ctx, addr, entry.Name, identity)
+	switch {
+	case err == nil:
+		created = true
+	case errors.Is(err, storage.ErrUserExists) && d.linkExisting:
+		uuid, err = d.link(ctx, addr, identity)
+	case errors.Is(err, storage.ErrUserExists):
+		log.Warnw("local user with the same email exists, linking is disabled", "email", addr)
+		return "", fmt.Errorf("%s: %w: user with email %s already exists", op, auth.ErrInvalidCredentials, addr)
+	}
+	if err != nil {
+		return "", handleStorageErr(log, "failed to provision user", op, err)
+	}
+
+	log.Infow("directory user provisioned", "userUUID", uuid, "created", created)
+
+	return uuid, nil
+}
+
+// link привязывает запись каталога к существующему пользователю с адресом addr.
+func (d *Directory) link(ctx context.Context, addr string, identity models.Identity) (string, error) {
+	user, err := d.users.User(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+
+	identity.UserUUID = user.UUID
+	if err := d.identities.SaveIdentity(ctx, identity); err != nil {
+		return "", err
+	}
+
+	return user.UUID, nil
+}
+
+// syncRoles назначает роли групп, в которых состоит пользователь, и снимает роли из
+// groupRoles, которые ему больше не положены. Роли, не упомянутые в groupRoles, не меняются.
+func (d *Directory) syncRoles(ctx context.Context, log *zap.SugaredLogger, op string, user models.User, groups []string) (err error) {
+	if len(d.groupRoles) == 0 {
+		return nil
+	}
+
+	// managed роли под управлением каталога, want — положенные пользователю сейчас.
+	managed := make(map[appRole]bool)
+	want := make(map[appRole]bool)
+	for _, gr := range d.groupRoles {
+		r := appRole{gr.App, gr.Role}
+		managed[r] = true
+		if slices.ContainsFunc(groups, func(g string) bool { return strings.EqualFold(g, gr.Group) }) {
+			want[r] = true
+		}
+	}
+
+	current, err := d.roles.UserRoles(ctx, user.UUID)
+	if err != nil {
+		return handleStorageErr(log, "failed to get user roles", op, err)
+	}
+
+	have := make(map[appRole]bool, len(current))
+	for _, r := range current {
+		have[appRole{r.AppName, r.Role}] = true
+	}
+
+	var granted, revoked []string
+	defer func() {
+		if len(granted) == 0 && len(revoked) == 0 && err == nil {
+			return
+		}
+
+		slices.Sort(granted)
+		slices.Sort(revoked)
+		log.Infow("roles synced from directory", "granted", granted, "revoked", revoked)
+
+		e := audit.Event(models.AuditDirectoryRoleSync, err)
+		e.Actor, e.Subject = Provider, user.Email
+		e.Details = map[string]string{"granted": strings.Join(granted, ","), "revoked": strings.Join(revoked, ",")}
+		d.auditor.Record(ctx, e)
+	}()
+
+	for r := range managed {
+		if want[r] == have[r] {
+			continue
+		}
+
+		app, err := d.apps.AppByName(ctx, r.app)
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warnw("app from group_roles is not registered", "app", r.app)
+			continue
+		}
+		if err != nil {
+			return handleStorageErr(log, "failed to get app", op, err)
+		}
+
+		if want[r] {
+			if err := d.roles.AssignRole(ctx, user.UUID, app.ID, r.role); err != nil {
+				return handleStorageErr(log, "failed to assign role", op, err)
+			}
+			granted = append(granted, r.String())
+			continue
+		}
+
+		err = d.roles.RevokeRole(ctx, user.UUID, app.ID, r.role)
+		if err != nil && !errors.Is(err, storage.ErrRoleNotFound) {
+			return handleStorageErr(log, "failed to revoke role", op, err)
+		}
+		revoked = append(revoked, r.String())
+	}
+
+	return nil
+}
+
+// appRole роль в приложении.
+type appRole struct {
+	app  string
+	role string
+}
+
+func (r appRole) String() string {
+	return r.app + "/" + r.role
+}
+
+// handleStorageErr переводит ошибки хранилища в ошибки сервиса аутентификации и логгирует их.
+func handleStorageErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Errorw("storage unavailable", "error", err)
+		return fmt.Errorf("%s: %w", op, auth.ErrUnavailable)
+	default:
+		return handleInternalErr(log, msg, op, err)
+	}
+}
+
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,211 @@
+package directory
+
+import (
+	"context"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/ldap"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeClient каталог с одним паролем на запись.
+type fakeClient struct {
+	entries   map[string]ldap.Entry
+	passwords map[string]string
+}
+
+func (c *fakeClient) Authenticate(_ context.Context, login, password string) (ldap.Entry, error) {
+	e, ok := c.entries[login]
+	if !ok || c.passwords[login] != password {
+		return ldap.Entry{}, ldap.ErrInvalidCredentials
+	}
+	return e, nil
+}
+
+// fakeStorage хранит пользователей, учетные записи каталога и роли в памяти.
+type fakeStorage struct {
+	users      map[string]models.User
+	identities map[string]models.Identity
+	roles      map[string][]models.Role
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		users:      make(map[string]models.User),
+		identities: make(map[string]models.Identity),
+		roles:      make(map[string][]models.Role),
+	}
+}
+
+func (s *fakeStorage) addUser(addr string) models.User {
+	u := models.User{UUID: "user-" + strconv.Itoa(len(s.users)+1), Email: addr, Status: models.UserStatusActive}
+	s.users[u.UUID] = u
+	return u
+}
+
+func (s *fakeStorage) Identity(_ context.Context, provider, subject string) (models.Identity, error) {
+	i, ok := s.identities[provider+"/"+subject]
+	if !ok {
+		return models.Identity{}, storage.ErrIdentityNotFound
+	}
+	return i, nil
+}
+
+func (s *fakeStorage) SaveIdentity(_ context.Context, identity models.Identity) error {
+	if _, ok := s.identities[identity.Provider+"/"+identity.Subject]; ok {
+		return storage.ErrIdentityExists
+	}
+	s.identities[identity.Provider+"/"+identity.Subject] = identity
+	return nil
+}
+
+func (s *fakeStorage) SaveFederatedUser(ctx context.Context, addr, _ string, identity models.Identity) (string, error) {
+	if _, err := s.User(ctx, addr); err == nil {
+		return "", storage.ErrUserExists
+	}
+	identity.UserUUID = s.addUser(addr).UUID
+	return identity.UserUUID, s.SaveIdentity(ctx, identity)
+}
+
+func (s *fakeStorage) TouchIdentity(context.Context, string, string, string) error { return nil }
+
+func (s *fakeStorage) User(_ context.Context, addr string) (models.User, error) {
+	for _, u := range s.users {
+		if u.Email == addr {
+			return u, nil
+		}
+	}
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (s *fakeStorage) UserByUUID(_ context.Context, uuid string) (models.User, error) {
+	u, ok := s.users[uuid]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *fakeStorage) AppByName(_ context.Context, name string) (models.App, error) {
+	if name != "billing" {
+		return models.App{}, storage.ErrAppNotFound
+	}
+	return models.App{ID: 1, Name: name}, nil
+}
+
+func (s *fakeStorage) AssignRole(_ context.Context, userUUID string, appID int, role string) error {
+	s.roles[userUUID] = append(s.roles[userUUID], models.Role{UserUUID: userUUID, AppID: appID, AppName: "billing", Role: role})
+	return nil
+}
+
+func (s *fakeStorage) RevokeRole(_ context.Context, userUUID string, appID int, role string) error {
+	for i, r := range s.roles[userUUID] {
+		if r.AppID == appID && r.Role == role {
+			s.roles[userUUID] = append(s.roles[userUUID][:i], s.roles[userUUID][i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrRoleNotFound
+}
+
+func (s *fakeStorage) UserRoles(_ context.Context, userUUID string) ([]models.Role, error) {
+	return s.roles[userUUID], nil
+}
+
+func (s *fakeStorage) roleNames(userUUID string) []string {
+	var names []string
+	for _, r := range s.roles[userUUID] {
+		names = append(names, r.Role)
+	}
+	return names
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, models.AuditEvent) {}
+
+const adminsGroup = "cn=billing-admins,ou=groups,dc=corp"
+
+func newTestDirectory(linkExisting bool) (*Directory, *fakeClient, *fakeStorage) {
+	client := &fakeClient{
+		entries: map[string]ldap.Entry{
+			"alice": {
+				DN:     "uid=alice,ou=people,dc=corp",
+				ID:     "guid-alice",
+				Email:  "Alice@Corp.example",
+				Name:   "Alice",
+				Groups: []string{"CN=Billing-Admins,OU=Groups,DC=corp"},
+			},
+		},
+		passwords: map[string]string{"alice": "pass"},
+	}
+	st := newFakeStorage()
+
+	d := New(zap.NewNop().Sugar(), client, st, st, st, st, nopAuditor{}, email.Normalizer{}, linkExisting, []GroupRole{
+		{Group: adminsGroup, App: "billing", Role: "admin"},
+		{Group: "cn=billing-viewers,ou=groups,dc=corp", App: "billing", Role: "viewer"},
+		{Group: adminsGroup, App: "unknown", Role: "admin"},
+	})
+
+	return d, client, st
+}
+
+func TestAuthenticateProvisionsUser(t *testing.T) {
+	d, _, st := newTestDirectory(false)
+	ctx := context.Background()
+
+	user, err := d.Authenticate(ctx, "alice", "pass")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice@corp.example", user.Email)
+	assert.Equal(t, []string{"admin"}, st.roleNames(user.UUID), "group is matched case-insensitively")
+
+	// Повторный вход находит пользователя по записи каталога.
+	again, err := d.Authenticate(ctx, "alice", "pass")
+	require.NoError(t, err)
+	assert.Equal(t, user.UUID, again.UUID)
+	assert.Len(t, st.users, 1)
+
+	_, err = d.Authenticate(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+func TestAuthenticateSyncsRoles(t *testing.T) {
+	d, client, st := newTestDirectory(false)
+	ctx := context.Background()
+
+	user, err := d.Authenticate(ctx, "alice", "pass")
+	require.NoError(t, err)
+
+	// Роль вне group_roles каталог не трогает.
+	require.NoError(t, st.AssignRole(ctx, user.UUID, 1, "owner"))
+
+	alice := client.entries["alice"]
+	alice.Groups = []string{"cn=billing-viewers,ou=groups,dc=corp"}
+	client.entries["alice"] = alice
+
+	_, err = d.Authenticate(ctx, "alice", "pass")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"owner", "viewer"}, st.roleNames(user.UUID))
+}
+
+func TestAuthenticateExistingEmail(t *testing.T) {
+	d, _, st := newTestDirectory(false)
+	existing := st.addUser("Alice@corp.example")
+
+	_, err := d.Authenticate(context.Background(), "alice", "pass")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials, "linking is disabled")
+
+	d.linkExisting = true
+
+	user, err := d.Authenticate(context.Background(), "alice", "pass")
+	require.NoError(t, err)
+	assert.Equal(t, existing.UUID, user.UUID)
+	assert.Equal(t, existing.UUID, st.identities[Provider+"/guid-alice"].UserUUID)
+}
@@ -0,0 +1,483 @@
+// Package federation реализует вход через внешних провайдеров identity (OpenID Connect,
+// GitHub): привязку их учетных записей к пользователям и создание пользователей при первом входе.
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/idp"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// Federation сервис входа через внешних провайдеров.
+type Federation struct {
+	log *zap.SugaredLogger
+
+	providers  map[string]Provider
+	states     StateStorage
+	identities IdentityStorage
+	users      UserProvider
+	apps       AppProvider
+	tokens     TokenIssuer
+	auditor    Auditor
+	emails     email.Normalizer
+
+	stateTTL time.Duration
+	now      func() time.Time
+}
+
+// Provider внешний провайдер и правила создания пользователей при первом входе через него.
+type Provider struct {
+	idp.Provider
+
+	// JIT создавать пользователя при первом входе через провайдера.
+	JIT bool
+	// AllowedDomains домены email, для которых создаются пользователи; пусто — любые.
+	AllowedDomains []string
+}
+
+type StateStorage interface {
+	SaveFederationState(ctx context.Context, st models.FederationState) error
+	ConsumeFederationState(ctx context.Context, state string) (models.FederationState, error)
+}
+
+type IdentityStorage interface {
+	Identity(ctx context.Context, provider, subject string) (models.Identity, error)
+	Identities(ctx context.Context, userUUID string) ([]models.Identity, error)
+	SaveIdentity(ctx context.Context, identity models.Identity) error
+	SaveFederatedUser(ctx context.Context, email, displayName string, identity models.Identity) (uuid string, err error)
+	TouchIdentity(ctx context.Context, provider, subject, email string) error
+	UnlinkIdentity(ctx context.Context, userUUID, provider string) error
+}
+
+type UserProvider interface {
+	User(ctx context.Context, email string) (models.User, error)
+	UserByUUID(ctx context.Context, uuid string) (models.User, error)
+}
+
+type AppProvider interface {
+	AppByName(ctx context.Context, name string) (models.App, error)
+}
+
+// TokenIssuer создает сеанс и выпускает токен аутентифицированному пользователю.
+type TokenIssuer interface {
+	IssueToken(ctx context.Context, user models.User, appName string) (token, sessionID string, err error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Ошибки, которые могут возникнуть при входе через внешнего провайдера.
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidApp      = errors.New("invalid app")
+	ErrInvalidState    = errors.New("invalid or expired state")
+	ErrInvalidGrant    = errors.New("identity provider rejected the authorization")
+	// ErrNotLinked учетная запись провайдера не привязана ни к одному пользователю,
+	// а создать пользователя нельзя.
+	ErrNotLinked        = errors.New("identity is not linked to any user")
+	ErrIdentityExists   = errors.New("identity is already linked")
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrLastIdentity     = errors.New("cannot unlink the only sign-in method")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUserInactive     = errors.New("user is not active")
+	ErrUnavailable      = errors.New("dependency unavailable")
+)
+
+// Result итог входа или привязки через внешнего провайдера.
+type Result struct {
+	UserUUID string
+	// Token токен приложения; пустой при привязке.
+	Token string
+	// Created пользователь создан при этом входе.
+	Created bool
+	// Linked учетная запись провайдера привязана к пользователю (BeginLink).
+	Linked bool
+}
+
+// New возвращает новый экземпляр сервиса федеративного входа.
+func New(
+	log *zap.SugaredLogger,
+	providers map[string]Provider,
+	states StateStorage,
+	identities IdentityStorage,
+	users UserProvider,
+	apps AppProvider,
+	tokens TokenIssuer,
+	auditor Auditor,
+	emails email.Normalizer,
+	stateTTL time.Duration,
+) *Federation {
+	return &Federation{
+		log: log,
+
+		providers:  providers,
+		states:     states,
+		identities: identities,
+		users:      users,
+		apps:       apps,
+		tokens:     tokens,
+		auditor:    auditor,
+		emails:     emails,
+
+		stateTTL: stateTTL,
+		now:      time.Now,
+	}
+}
+
+// Providers возвращает имена настроенных провайдеров.
+func (f *Federation) Providers() []string {
+	names := make([]string, 0, len(f.providers))
+	for name := range f.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Begin начинает вход через провайдера для приложения appName и возвращает адрес,
+// на который нужно перенаправить пользователя.
+func (f *Federation) Begin(ctx context.Context, provider, appName string) (string, error) {
+	const op = "federation.Begin"
+
+	log := f.log.With("op", op, "provider", provider, "appName", appName)
+
+	if _, err := f.apps.AppByName(ctx, appName); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return "", fmt.Errorf("%s: %w: app %q is not registered", op, ErrInvalidApp, appName)
+		}
+
+		return "", handleStorageErr(log, "failed to get app", op, err)
+	}
+
+	return f.begin(ctx, log, op, models.FederationState{Provider: provider, App: appName})
+}
+
+// BeginLink начинает привязку учетной записи провайдера к пользователю userUUID
+// и возвращает адрес, на который нужно перенаправить пользователя.
+func (f *Federation) BeginLink(ctx context.Context, provider, userUUID string) (string, error) {
+	const op = "federation.BeginLink"
+
+	log := f.log.With("op", op, "provider", provider, "userUUID", userUUID)
+
+	return f.begin(ctx, log, op, models.FederationState{Provider: provider, LinkUserUUID: userUUID})
+}
+
+func (f *Federation) begin(ctx context.Context, log *zap.SugaredLogger, op string, st models.FederationState) (string, error) {
+	p, ok := f.providers[st.Provider]
+	if !ok {
+		return "", fmt.Errorf("%s: %w: %q", op, ErrUnknownProvider, st.Provider)
+	}
+
+	var err error
+	if st.State, err = randomToken(); err != nil {
+		return "", handleInternalErr(log, "failed to generate state", op, err)
+	}
+	if st.Nonce, err = randomToken(); err != nil {
+		return "", handleInternalErr(log, "failed to generate nonce", op, err)
+	}
+	st.Verifier = oauth2.GenerateVerifier()
+	st.ExpiresAt = f.now().Add(f.stateTTL)
+
+	authURL, err := p.AuthCodeURL(ctx, st.State, st.Nonce, st.Verifier)
+	if err != nil {
+		return "", handleProviderErr(log, op, err)
+	}
+
+	if err := f.states.SaveFederationState(ctx, st); err != nil {
+		return "", handleStorageErr(log, "failed to save state", op, err)
+	}
+
+	return authURL, nil
+}
+
+// Complete завершает вход или привязку по коду авторизации, который провайдер передал в callback.
+//
+// При входе пользователь находится по привязанной учетной записи провайдера. Если ее нет
+// и для провайдера включен JIT, создается новый пользователь с подтвержденным у провайдера
+// email. Существующий пользователь с тем же email автоматически не привязывается: это
+// позволило бы захватить его учетную запись через провайдера с чужим адресом.
+func (f *Federation) Complete(ctx context.Context, provider, state, code string) (res Result, err error) {
+	const op = "federation.Complete"
+
+	log := f.log.With("op", op, "provider", provider)
+
+	var st models.FederationState
+
+	defer func() {
+		typ := models.AuditFederatedLogin
+		if st.LinkUserUUID != "" {
+			typ = models.AuditIdentityLink
+		}
+
+		e := audit.Event(typ, err)
+		e.Subject, e.App = res.UserUUID, st.App
+		e.Details = map[string]string{"provider": provider}
+		if res.Created {
+			e.Details["created"] = "true"
+		}
+		f.auditor.Record(ctx, e)
+	}()
+
+	st, err = f.states.ConsumeFederationState(ctx, state)
+	if errors.Is(err, storage.ErrStateNotFound) {
+		return Result{}, fmt.Errorf("%s: %w", op, ErrInvalidState)
+	}
+	if err != nil {
+		return Result{}, handleStorageErr(log, "failed to get state", op, err)
+	}
+	if st.Provider != provider || !f.now().Before(st.ExpiresAt) {
+		return Result{}, fmt.Errorf("%s: %w", op, ErrInvalidState)
+	}
+	// Обратный вызов провайдера не передает арендатора: вход завершается в том, где начат.
+	ctx = tenant.With(ctx, st.Tenant)
+
+	p, ok := f.providers[provider]
+	if !ok {
+		return Result{}, fmt.Errorf("%s: %w: %q", op, ErrUnknownProvider, provider)
+	}
+
+	info, err := p.Exchange(ctx, code, st.Nonce, st.Verifier)
+	if err != nil {
+		return Result{}, handleProviderErr(log, op, err)
+	}
+
+	if st.LinkUserUUID != "" {
+		res.UserUUID = st.LinkUserUUID
+		if err := f.link(ctx, log, op, provider, st.LinkUserUUID, info); err != nil {
+			return res, err
+		}
+		res.Linked = true
+
+		return res, nil
+	}
+
+	user, created, err := f.resolve(ctx, log, op, provider, p, info)
+	if err != nil {
+		return Result{}, err
+	}
+	res.UserUUID, res.Created = user.UUID, created
+
+	// Учетная запись провайдера привязана к пользователю одного арендатора.
+	if user.Tenant != st.Tenant {
+		log.Warnw("identity belongs to another tenant", "userUUID", user.UUID, "userTenant", user.Tenant)
+
+		return res, fmt.Errorf("%s: %w: identity belongs to another tenant", op, ErrNotLinked)
+	}
+
+	if user.Status != models.UserStatusActive {
+		log.Infow("user is not active", "userUUID", user.UUID, "status", user.Status)
+
+		return res, fmt.Errorf("%s: %w: user is %s", op, ErrUserInactive, user.Status)
+	}
+
+	res.Token, _, err = f.tokens.IssueToken(ctx, user, st.App)
+	if errors.Is(err, auth.ErrUnavailable) {
+		return res, fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+	if err != nil {
+		return res, handleInternalErr(log, "failed to issue token", op, err)
+	}
+
+	log.Infow("user logged in via identity provider", "userUUID", user.UUID, "created", created)
+
+	return res, nil
+}
+
+// resolve находит пользователя, привязанного к учетной записи провайдера, или создает его.
+func (f *Federation) resolve(
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	op, provider string,
+	p Provider,
+	info idp.UserInfo,
+) (models.User, bool, error) {
+	identity, err := f.identities.Identity(ctx, provider, info.Subject)
+	if err == nil {
+		if err := f.identities.TouchIdentity(ctx, provider, info.Subject, info.Email); err != nil {
+			log.Warnw("failed to update identity", "error", err)
+		}
+
+		user, err := f.users.UserByUUID(ctx, identity.UserUUID)
+		if err != nil {
+			return models.User{}, false, handleStorageErr(log, "failed to get user", op, err)
+		}
+
+		return user, false, nil
+	}
+	if !errors.Is(err, storage.ErrIdentityNotFound) {
+		return models.User{}, false, handleStorageErr(log, "failed to get identity", op, err)
+	}
+
+	if !p.JIT {
+		return models.User{}, false, fmt.Errorf("%s: %w", op, ErrNotLinked)
+	}
+
+	addr, err := f.provisionEmail(p, info)
+	if err != nil {
+		return models.User{}, false, fmt.Errorf("%s: %w: %v", op, ErrNotLinked, err)
+	}
+
+	now := f.now()
+	uuid, err := f.identities.SaveFederatedUser(ctx, addr, info.Name, models.Identity{
+		Provider:    provider,
+		Subject:     info.Subject,
+		Email:       info.Email,
+		LastLoginAt: &now,
+	})
+	if errors.Is(err, storage.ErrUserExists) {
+		return models.User{}, false, fmt.Errorf("%s: %w: user with email %s already exists, "+
+			"sign in and link the identity first", op, ErrNotLinked, addr)
+	}
+	if err != nil {
+		return models.User{}, false, handleStorageErr(log, "failed to create user", op, err)
+	}
+
+	log.Infow("user provisioned", "userUUID", uuid)
+
+	user, err := f.users.UserByUUID(ctx, uuid)
+	if err != nil {
+		return models.User{}, false, handleStorageErr(log, "failed to get user", op, err)
+	}
+
+	return user, true, nil
+}
+
+// provisionEmail возвращает нормализованный email для нового пользователя.
+func (f *Federation) provisionEmail(p Provider, info idp.UserInfo) (string, error) {
+	if info.Email == "" || !info.EmailVerified {
+		return "", errors.New("provider did not return a verified email")
+	}
+
+	addr, err := f.emails.Normalize(info.Email)
+	if err != nil {
+		return "", err
+	}
+
+	if len(p.AllowedDomains) > 0 {
+		_, domain, _ := strings.Cut(addr, "@")
+		if !slices.ContainsFunc(p.AllowedDomains, func(d string) bool { return strings.EqualFold(d, domain) }) {
+			return "", fmt.Errorf("email domain %s is not allowed", domain)
+		}
+	}
+
+	return addr, nil
+}
+
+// link привязывает учетную запись провайдера к пользователю userUUID.
+func (f *Federation) link(ctx context.Context, log *zap.SugaredLogger, op, provider, userUUID string, info idp.UserInfo) error {
+	now := f.now()
+	err := f.identities.SaveIdentity(ctx, models.Identity{
+		Provider:    provider,
+		Subject:     info.Subject,
+		UserUUID:    userUUID,
+		Email:       info.Email,
+		LastLoginAt: &now,
+	})
+	if err != nil {
+		return handleStorageErr(log, "failed to link identity", op, err)
+	}
+
+	log.Infow("identity linked", "userUUID", userUUID)
+
+	return nil
+}
+
+// Identities возвращает внешние учетные записи пользователя.
+func (f *Federation) Identities(ctx context.Context, userUUID string) ([]models.Identity, error) {
+	const op = "federation.Identities"
+
+	identities, err := f.identities.Identities(ctx, userUUID)
+	if err != nil {
+		return nil, handleStorageErr(f.log.With("op", op, "userUUID", userUUID), "failed to list identities", op, err)
+	}
+
+	return identities, nil
+}
+
+// Unlink отвязывает от пользователя учетную запись провайдера.
+func (f *Federation) Unlink(ctx context.Context, userUUID, provider string) (err error) {
+	const op = "federation.Unlink"
+
+	log := f.log.With("op", op, "userUUID", userUUID, "provider", provider)
+
+	defer func() {
+		e := audit.Event(models.AuditIdentityUnlink, err)
+		e.Subject = userUUID
+		e.Details = map[string]string{"provider": provider}
+		f.auditor.Record(ctx, e)
+	}()
+
+	if err := f.identities.UnlinkIdentity(ctx, userUUID, provider); err != nil {
+		return handleStorageErr(log, "failed to unlink identity", op, err)
+	}
+
+	log.Infow("identity unlinked")
+
+	return nil
+}
+
+// randomToken возвращает случайную строку для state и nonce.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// handleProviderErr переводит ошибки провайдера в ошибки сервиса.
+func handleProviderErr(log *zap.SugaredLogger, op string, err error) error {
+	switch {
+	case errors.Is(err, idp.ErrInvalidGrant):
+		log.Infow("identity provider rejected the authorization", "error", err)
+		return fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	case errors.Is(err, idp.ErrUnavailable):
+		log.Errorw("identity provider unavailable", "error", err)
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	default:
+		return handleInternalErr(log, "identity provider request failed", op, err)
+	}
+}
+
+// handleStorageErr переводит ошибки хранилища в ошибки сервиса и логгирует их.
+func handleStorageErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	case errors.Is(err, storage.ErrIdentityExists):
+		return fmt.Errorf("%s: %w", op, ErrIdentityExists)
+	case errors.Is(err, storage.ErrIdentityNotFound):
+		return fmt.Errorf("%s: %w", op, ErrIdentityNotFound)
+	case errors.Is(err, storage.ErrLastIdentity):
+		return fmt.Errorf("%s: %w", op, ErrLastIdentity)
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Errorw("storage unavailable", "error", err)
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	default:
+		return handleInternalErr(log, msg, op, err)
+	}
+}
+
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,279 @@
+package federation
+
+import (
+	"context"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/idp"
+	"go-sso/internal/lib/idp/idptest"
+	"go-sso/internal/storage"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStorage хранит пользователей, учетные записи провайдеров и состояния входа в памяти.
+type fakeStorage struct {
+	users      map[string]models.User
+	identities map[string]models.Identity
+	states     map[string]models.FederationState
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		users:      make(map[string]models.User),
+		identities: make(map[string]models.Identity),
+		states:     make(map[string]models.FederationState),
+	}
+}
+
+func (s *fakeStorage) addUser(addr string) models.User {
+	u := models.User{UUID: "user-" + strconv.Itoa(len(s.users)+1), Email: addr, Status: models.UserStatusActive}
+	s.users[u.UUID] = u
+	return u
+}
+
+func (s *fakeStorage) SaveFederationState(_ context.Context, st models.FederationState) error {
+	s.states[st.State] = st
+	return nil
+}
+
+func (s *fakeStorage) ConsumeFederationState(_ context.Context, state string) (models.FederationState, error) {
+	st, ok := s.states[state]
+	if !ok {
+		return models.FederationState{}, storage.ErrStateNotFound
+	}
+	delete(s.states, state)
+	return st, nil
+}
+
+func (s *fakeStorage) Identity(_ context.Context, provider, subject string) (models.Identity, error) {
+	i, ok := s.identities[provider+"/"+subject]
+	if !ok {
+		return models.Identity{}, storage.ErrIdentityNotFound
+	}
+	return i, nil
+}
+
+func (s *fakeStorage) Identities(_ context.Context, userUUID string) ([]models.Identity, error) {
+	var out []models.Identity
+	for _, i := range s.identities {
+		if i.UserUUID == userUUID {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStorage) SaveIdentity(_ context.Context, identity models.Identity) error {
+	if _, ok := s.identities[identity.Provider+"/"+identity.Subject]; ok {
+		return storage.ErrIdentityExists
+	}
+	s.identities[identity.Provider+"/"+identity.Subject] = identity
+	return nil
+}
+
+func (s *fakeStorage) SaveFederatedUser(ctx context.Context, addr, _ string, identity models.Identity) (string, error) {
+	if _, err := s.User(ctx, addr); err == nil {
+		return "", storage.ErrUserExists
+	}
+	identity.UserUUID = s.addUser(addr).UUID
+	return identity.UserUUID, s.SaveIdentity(ctx, identity)
+}
+
+func (s *fakeStorage) TouchIdentity(context.Context, string, string, string) error { return nil }
+
+func (s *fakeStorage) UnlinkIdentity(_ context.Context, userUUID, provider string) error {
+	for k, i := range s.identities {
+		if i.UserUUID == userUUID && i.Provider == provider {
+			delete(s.identities, k)
+			return nil
+		}
+	}
+	return storage.ErrIdentityNotFound
+}
+
+func (s *fakeStorage) User(_ context.Context, addr string) (models.User, error) {
+	for _, u := range s.users {
+		if u.Email == addr {
+			return u, nil
+		}
+	}
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (s *fakeStorage) UserByUUID(_ context.Context, uuid string) (models.User, error) {
+	u, ok := s.users[uuid]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *fakeStorage) AppByName(_ context.Context, name string) (models.App, error) {
+	if name != "billing" {
+		return models.App{}, storage.ErrAppNotFound
+	}
+	return models.App{ID: 1, Name: name}, nil
+}
+
+type fakeTokens struct{}
+
+func (fakeTokens) IssueToken(_ context.Context, user models.User, appName string) (string, string, error) {
+	return "token:" + user.UUID + ":" + appName, "sid", nil
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, models.AuditEvent) {}
+
+func newTestFederation(t *testing.T, jit bool) (*Federation, *fakeStorage, *idptest.Server) {
+	t.Helper()
+
+	srv := idptest.NewServer(t, "go-sso", "secret")
+	srv.SetUser(idptest.User{Subject: "1001", Email: "Alice@Example.com", EmailVerified: true, Name: "Alice"})
+
+	st := newFakeStorage()
+	providers := map[string]Provider{
+		"corp": {
+			Provider: idp.NewOIDC(srv.Issuer(), idp.Config{
+				ClientID:     srv.ClientID,
+				ClientSecret: srv.ClientSecret,
+				RedirectURL:  "http://sso.local/v1/federation/corp/callback",
+			}),
+			JIT:            jit,
+			AllowedDomains: []string{"example.com"},
+		},
+	}
+
+	f := New(zap.NewNop().Sugar(), providers, st, st, st, st, fakeTokens{}, nopAuditor{}, email.Normalizer{}, time.Minute)
+
+	return f, st, srv
+}
+
+// login проходит вход через провайдера: Begin, согласие у провайдера, Complete.
+func login(t *testing.T, f *Federation, srv *idptest.Server, authURL string) (Result, error) {
+	t.Helper()
+
+	callback, err := srv.Authorize(authURL)
+	require.NoError(t, err)
+
+	return f.Complete(context.Background(), "corp", callback.Get("state"), callback.Get("code"))
+}
+
+func TestCompleteProvisionsUser(t *testing.T) {
+	f, st, srv := newTestFederation(t, true)
+	ctx := context.Background()
+
+	authURL, err := f.Begin(ctx, "corp", "billing")
+	require.NoError(t, err)
+
+	res, err := login(t, f, srv, authURL)
+	require.NoError(t, err)
+	assert.True(t, res.Created)
+	assert.Equal(t, "token:"+res.UserUUID+":billing", res.Token)
+	assert.Equal(t, "Alice@example.com", st.users[res.UserUUID].Email)
+
+	// Повторный вход находит того же пользователя по привязанной учетной записи.
+	authURL, err = f.Begin(ctx, "corp", "billing")
+	require.NoError(t, err)
+
+	again, err := login(t, f, srv, authURL)
+	require.NoError(t, err)
+	assert.False(t, again.Created)
+	assert.Equal(t, res.UserUUID, again.UserUUID)
+}
+
+func TestCompleteDoesNotProvision(t *testing.T) {
+	for name, tc := range map[string]struct {
+		jit      bool
+		user     idptest.User
+		existing string
+	}{
+		"jit disabled":       {user: idptest.User{Subject: "1", Email: "a@example.com", EmailVerified: true}},
+		"unverified email":   {jit: true, user: idptest.User{Subject: "1", Email: "a@example.com"}},
+		"domain not allowed": {jit: true, user: idptest.User{Subject: "1", Email: "a@evil.com", EmailVerified: true}},
+		// Совпадение email не дает войти в существующую учетную запись.
+		"email taken": {jit: true, user: idptest.User{Subject: "1", Email: "a@example.com", EmailVerified: true}, existing: "a@example.com"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, st, srv := newTestFederation(t, tc.jit)
+			srv.SetUser(tc.user)
+			if tc.existing != "" {
+				st.addUser(tc.existing)
+			}
+
+			authURL, err := f.Begin(context.Background(), "corp", "billing")
+			require.NoError(t, err)
+
+			_, err = login(t, f, srv, authURL)
+			assert.ErrorIs(t, err, ErrNotLinked)
+		})
+	}
+}
+
+func TestLinkAndUnlink(t *testing.T) {
+	f, st, srv := newTestFederation(t, false)
+	ctx := context.Background()
+	user := st.addUser("alice@example.com")
+
+	authURL, err := f.BeginLink(ctx, "corp", user.UUID)
+	require.NoError(t, err)
+
+	res, err := login(t, f, srv, authURL)
+	require.NoError(t, err)
+	assert.Equal(t, Result{UserUUID: user.UUID, Linked: true}, res)
+
+	authURL, err = f.Begin(ctx, "corp", "billing")
+	require.NoError(t, err)
+
+	res, err = login(t, f, srv, authURL)
+	require.NoError(t, err)
+	assert.Equal(t, user.UUID, res.UserUUID)
+
+	identities, err := f.Identities(ctx, user.UUID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	assert.Equal(t, "1001", identities[0].Subject)
+
+	require.NoError(t, f.Unlink(ctx, user.UUID, "corp"))
+	assert.ErrorIs(t, f.Unlink(ctx, user.UUID, "corp"), ErrIdentityNotFound)
+}
+
+func TestCompleteRejectsState(t *testing.T) {
+	f, _, srv := newTestFederation(t, true)
+	ctx := context.Background()
+
+	authURL, err := f.Begin(ctx, "corp", "billing")
+	require.NoError(t, err)
+	callback, err := srv.Authorize(authURL)
+	require.NoError(t, err)
+
+	_, err = f.Complete(ctx, "other", callback.Get("state"), callback.Get("code"))
+	assert.ErrorIs(t, err, ErrInvalidState, "state of another provider")
+
+	// Состояние одноразовое, даже если первая попытка не удалась.
+	_, err = f.Complete(ctx, "corp", callback.Get("state"), callback.Get("code"))
+	assert.ErrorIs(t, err, ErrInvalidState, "reused state")
+
+	authURL, err = f.Begin(ctx, "corp", "billing")
+	require.NoError(t, err)
+	f.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	_, err = login(t, f, srv, authURL)
+	assert.ErrorIs(t, err, ErrInvalidState, "expired state")
+}
+
+func TestBeginRejects(t *testing.T) {
+	f, _, _ := newTestFederation(t, true)
+
+	_, err := f.Begin(context.Background(), "corp", "unknown")
+	assert.ErrorIs(t, err, ErrInvalidApp)
+
+	_, err = f.Begin(context.Background(), "google", "billing")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
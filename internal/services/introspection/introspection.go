@@ -0,0 +1,176 @@
+package introspection
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/services/clients"
+	"go-sso/internal/services/session"
+	"go-sso/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Introspector отвечает зарегистрированным приложениям, действителен ли токен (RFC 7662).
+type Introspector struct {
+	log *zap.SugaredLogger
+
+	apps    AppProvider
+	keys    SigningKeyProvider
+	tokens  TokenAuthenticator
+	clients ClientTokenAuthenticator
+	auditor Auditor
+}
+
+type AppProvider interface {
+	AppByName(ctx context.Context, name string) (models.App, error)
+}
+
+type SigningKeyProvider interface {
+	Key(ctx context.Context, appName string) (string, error)
+}
+
+// TokenAuthenticator проверяет подпись, срок действия и сеанс токена.
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (models.Session, *jwt.Claims, error)
+}
+
+// ClientTokenAuthenticator проверяет токены клиентов, выпущенные по client credentials.
+type ClientTokenAuthenticator interface {
+	AuthenticateToken(ctx context.Context, token string) (*jwt.Claims, error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Ошибки, которые могут возникнуть при интроспекции.
+var (
+	ErrInvalidClient = errors.New("invalid client")
+	ErrUnavailable   = errors.New("dependency unavailable")
+)
+
+// New возвращает новый экземпляр сервиса интроспекции.
+func New(
+	log *zap.SugaredLogger,
+	apps AppProvider,
+	keys SigningKeyProvider,
+	tokens TokenAuthenticator,
+	clients ClientTokenAuthenticator,
+	auditor Auditor,
+) *Introspector {
+	return &Introspector{
+		log:     log,
+		apps:    apps,
+		keys:    keys,
+		tokens:  tokens,
+		clients: clients,
+		auditor: auditor,
+	}
+}
+
+// AuthenticateApp проверяет, что вызывающий — зарегистрированное приложение appName,
+// знающее свой ключ подписи.
+func (i *Introspector) AuthenticateApp(ctx context.Context, appName, secret string) (err error) {
+	const op = "introspection.AuthenticateApp"
+
+	log := i.log.With("op", op, "appName", appName)
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		e := audit.Event(models.AuditIntrospectClientAuth, err)
+		e.Actor, e.App = appName, appName
+		i.auditor.Record(ctx, e)
+	}()
+
+	if _, err := i.apps.AppByName(ctx, appName); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return fmt.Errorf("%s: %w: app is not registered", op, ErrInvalidClient)
+		}
+
+		return handleInternalErr(log, "failed to get app", op, err)
+	}
+
+	key, err := i.keys.Key(ctx, appName)
+	if errors.Is(err, auth.ErrKeyNotFound) {
+		return fmt.Errorf("%s: %w: app has no signing key", op, ErrInvalidClient)
+	}
+	if err != nil {
+		return handleInternalErr(log, "failed to get signing key", op, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key), []byte(secret)) != 1 {
+		return fmt.Errorf("%s: %w: wrong secret", op, ErrInvalidClient)
+	}
+
+	return nil
+}
+
+// Introspect возвращает claims токена, если он действителен и выпущен для callerApp
+// арендатора из ctx.
+// Для недействительного токена возвращает nil без ошибки: по RFC 7662 вызывающий
+// узнает только, что токен не активен.
+func (i *Introspector) Introspect(ctx context.Context, callerApp, token string) (*jwt.Claims, error) {
+	const op = "introspection.Introspect"
+
+	log := i.log.With("op", op, "appName", callerApp)
+
+	claims, err := i.authenticate(ctx, token)
+	if errors.Is(err, session.ErrInvalidToken) || errors.Is(err, clients.ErrInvalidToken) {
+		log.Debugw("token is not active", "error", err)
+		return nil, nil
+	}
+	if errors.Is(err, session.ErrUnavailable) || errors.Is(err, clients.ErrUnavailable) {
+		return nil, fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if claims.App != callerApp {
+		log.Infow("token is issued for another app", "tokenApp", claims.App)
+		return nil, nil
+	}
+
+	// Одноименное приложение другого арендатора не должно видеть чужие токены.
+	// Токены, выпущенные до появления арендаторов, не содержат tid.
+	if tokenTenant := cmp.Or(claims.Tenant, tenant.Default); tokenTenant != tenant.From(ctx) {
+		log.Infow("token is issued in another tenant", "tokenTenant", tokenTenant)
+		return nil, nil
+	}
+
+	return claims, nil
+}
+
+// authenticate проверяет токен пользователя по его сеансу, а токен клиента (с claim
+// client_id и без сеанса) — по записи клиента.
+func (i *Introspector) authenticate(ctx context.Context, token string) (*jwt.Claims, error) {
+	if c, err := jwt.Unverified(token); err == nil && c.ClientID != "" && c.SessionID == "" {
+		return i.clients.AuthenticateToken(ctx, token)
+	}
+
+	_, claims, err := i.tokens.Authenticate(ctx, token)
+	return claims, err
+}
+
+// handleInternalErr логгирует ошибку и переводит недоступность зависимостей в ErrUnavailable.
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+
+	if errors.Is(err, storage.ErrUnavailable) || errors.Is(err, auth.ErrUnavailable) {
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
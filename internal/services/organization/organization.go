@@ -0,0 +1,546 @@
+// Package organization реализует организации приложений: участников с ролями
+// и приглашения в организацию по email.
+package organization
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/mailer"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// maxNameLen максимальная длина названия организации в символах.
+const maxNameLen = 128
+
+// Organizations сервис организаций.
+type Organizations struct {
+	log *zap.SugaredLogger
+
+	storage   OrganizationStorage
+	users     UserProvider
+	registrar Registrar
+	mailer    mailer.Mailer
+	auditor   Auditor
+	emails    email.Normalizer
+
+	cfg atomic.Pointer[Config]
+	now func() time.Time
+}
+
+// Config параметры приглашений в организации.
+type Config struct {
+	// InvitationTTL сколько действует приглашение.
+	InvitationTTL time.Duration
+	// InvitationURLs страницы приложений, на которые ведет приглашение, по имени приложения.
+	// К адресу добавляется параметр token, который страница передает в AcceptInvitation.
+	// В организации приложений без страницы приглашать нельзя.
+	InvitationURLs map[string]string
+}
+
+type OrganizationStorage interface {
+	SaveOrganization(ctx context.Context, app, name, ownerUUID string) (models.Organization, error)
+	Organization(ctx context.Context, id string) (models.Organization, error)
+	Organizations(ctx context.Context, app string) ([]models.Organization, error)
+	MemberOrganizations(ctx context.Context, userUUID, app string) ([]models.Organization, error)
+	Members(ctx context.Context, orgID string) ([]models.Member, error)
+	Member(ctx context.Context, orgID, userUUID string) (models.Member, error)
+	RemoveMember(ctx context.Context, orgID, userUUID string) error
+	SaveInvitation(ctx context.Context, inv models.Invitation) (models.Invitation, error)
+	Invitation(ctx context.Context, tokenHash []byte) (models.Invitation, error)
+	ConsumeInvitation(ctx context.Context, id, userUUID string) error
+}
+
+type UserProvider interface {
+	User(ctx context.Context, email string) (models.User, error)
+}
+
+// Registrar регистрирует нового пользователя с паролем.
+type Registrar interface {
+	RegisterNewUser(ctx context.Context, email, password string) (userUUID string, err error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Ошибки, которые могут возникнуть при работе с организациями.
+var (
+	ErrInvalidName           = errors.New("invalid organization name")
+	ErrInvalidRole           = errors.New("invalid role")
+	ErrInvalidEmail          = errors.New("invalid email")
+	ErrAppNotFound           = errors.New("app not found")
+	ErrOrganizationNotFound  = errors.New("organization not found")
+	ErrOrganizationExists    = errors.New("organization already exists")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrMemberNotFound        = errors.New("member not found")
+	ErrLastOwner             = errors.New("organization must keep at least one owner")
+	ErrPermissionDenied      = errors.New("permission denied")
+	ErrInvitationsNotEnabled = errors.New("invitations are not enabled for the app")
+	// ErrInvalidInvitation приглашение неверное, истекло или уже принято.
+	ErrInvalidInvitation = errors.New("invalid or expired invitation")
+	// ErrPasswordRequired приглашенный еще не зарегистрирован: для регистрации нужен пароль.
+	ErrPasswordRequired = errors.New("password is required to register")
+	ErrUserInactive     = errors.New("user is not active")
+	ErrUnavailable      = errors.New("dependency unavailable")
+)
+
+// New возвращает новый экземпляр сервиса организаций.
+func New(
+	log *zap.SugaredLogger,
+	organizationStorage OrganizationStorage,
+	users UserProvider,
+	registrar Registrar,
+	mailer mailer.Mailer,
+	auditor Auditor,
+	emails email.Normalizer,
+	cfg Config,
+) *Organizations {
+	o := &Organizations{
+		log: log,
+
+		storage:   organizationStorage,
+		users:     users,
+		registrar: registrar,
+		mailer:    mailer,
+		auditor:   auditor,
+		emails:    emails,
+
+		now: time.Now,
+	}
+
+	o.SetConfig(cfg)
+
+	return o
+}
+
+// SetConfig задает параметры приглашений.
+func (o *Organizations) SetConfig(cfg Config) {
+	o.cfg.Store(&cfg)
+}
+
+// CreateOrganization создает в приложении appName организацию с владельцем ownerUUID.
+func (o *Organizations) CreateOrganization(ctx context.Context, appName, name, ownerUUID string) (org models.Organization, err error) {
+	const op = "organization.CreateOrganization"
+
+	log := o.log.With("op", op, "appName", appName, "ownerUUID", ownerUUID)
+
+	defer func() {
+		e := audit.Event(models.AuditOrganizationCreate, err)
+		e.Subject, e.App = ownerUUID, appName
+		e.Details = map[string]string{"organization": org.ID, "name": name}
+		o.auditor.Record(ctx, e)
+	}()
+
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLen {
+		return models.Organization{}, fmt.Errorf("%s: %w: must be 1 to %d characters", op, ErrInvalidName, maxNameLen)
+	}
+
+	org, err = o.storage.SaveOrganization(ctx, appName, name, ownerUUID)
+	if err != nil {
+		return models.Organization{}, handleStorageErr(log, "failed to save organization", op, err)
+	}
+
+	log.Infow("organization created", "organizationID", org.ID)
+
+	return org, nil
+}
+
+// Organizations возвращает организации приложения appName, в которых состоит
+// пользователь userUUID; для пустого userUUID — все организации приложения.
+func (o *Organizations) Organizations(ctx context.Context, appName, userUUID string) ([]models.Organization, error) {
+	const op = "organization.Organizations"
+
+	var (
+		orgs []models.Organization
+		err  error
+	)
+	if userUUID == "" {
+		orgs, err = o.storage.Organizations(ctx, appName)
+	} else {
+		orgs, err = o.storage.MemberOrganizations(ctx, userUUID, appName)
+	}
+	if err != nil {
+		return nil, handleStorageErr(o.log.With("op", op, "appName", appName), "failed to list organizations", op, err)
+	}
+
+	return orgs, nil
+}
+
+// Members возвращает участников организации. Пользователь actorUUID должен в ней
+// состоять; пустой actorUUID — оператор.
+func (o *Organizations) Members(ctx context.Context, actorUUID, orgID string) ([]models.Member, error) {
+	const op = "organization.Members"
+
+	log := o.log.With("op", op, "organizationID", orgID)
+
+	if _, _, err := o.authorize(ctx, actorUUID, orgID); err != nil {
+		return nil, handleStorageErr(log, "failed to authorize", op, err)
+	}
+
+	members, err := o.storage.Members(ctx, orgID)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to list members", op, err)
+	}
+
+	return members, nil
+}
+
+// InviteMember отправляет на адрес addr приглашение в организацию с ролью role.
+// Приглашать могут владельцы и администраторы, назначать владельцев — только владельцы;
+// пустой actorUUID — оператор. Токен приглашения есть только в письме.
+func (o *Organizations) InviteMember(ctx context.Context, actorUUID, orgID, addr, role string) (inv models.Invitation, err error) {
+	const op = "organization.InviteMember"
+
+	log := o.log.With("op", op, "organizationID", orgID, "email", addr, "role", role)
+
+	var (
+		org   models.Organization
+		actor models.Member
+	)
+	defer func() {
+		e := audit.Event(models.AuditOrganizationInvite, err)
+		e.Actor, e.Subject, e.App = actor.Email, addr, org.App
+		e.Details = map[string]string{"organization": orgID, "role": role}
+		o.auditor.Record(ctx, e)
+	}()
+
+	if !validRole(role) {
+		return models.Invitation{}, fmt.Errorf("%s: %w: %q", op, ErrInvalidRole, role)
+	}
+
+	normalized, err := o.emails.Normalize(addr)
+	if err != nil {
+		return models.Invitation{}, fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+	addr = normalized
+
+	org, actor, err = o.authorize(ctx, actorUUID, orgID)
+	if err != nil {
+		return models.Invitation{}, handleStorageErr(log, "failed to authorize", op, err)
+	}
+	if !canManage(actor.Role, role) {
+		return models.Invitation{}, fmt.Errorf("%s: %w: %s cannot invite %s", op, ErrPermissionDenied, actor.Role, role)
+	}
+
+	cfg := o.cfg.Load()
+	invitationURL, ok := cfg.InvitationURLs[org.App]
+	if !ok {
+		return models.Invitation{}, fmt.Errorf("%s: %w: app %q", op, ErrInvitationsNotEnabled, org.App)
+	}
+
+	token, err := randomString(32)
+	if err != nil {
+		return models.Invitation{}, handleInternalErr(log, "failed to generate token", op, err)
+	}
+
+	msg, err := invitationMessage(org, invitationURL, token, cfg.InvitationTTL)
+	if err != nil {
+		return models.Invitation{}, handleInternalErr(log, "failed to build email", op, err)
+	}
+	msg.To = addr
+
+	inv, err = o.storage.SaveInvitation(ctx, models.Invitation{
+		OrganizationID: org.ID,
+		Email:          addr,
+		Role:           role,
+		TokenHash:      hashToken(token),
+		InvitedBy:      actorUUID,
+		ExpiresAt:      o.now().Add(cfg.InvitationTTL),
+	})
+	if err != nil {
+		return models.Invitation{}, handleStorageErr(log, "failed to save invitation", op, err)
+	}
+
+	if err := o.mailer.Send(ctx, msg); err != nil {
+		log.Errorw("failed to send email", "error", err)
+		return models.Invitation{}, fmt.Errorf("%s: %w: send email", op, ErrUnavailable)
+	}
+
+	log.Infow("member invited", "invitationID", inv.ID)
+
+	return inv, nil
+}
+
+// invitationMessage письмо с приглашением в организацию.
+func invitationMessage(org models.Organization, invitationURL, token string, ttl time.Duration) (mailer.Message, error) {
+	link, err := url.Parse(invitationURL)
+	if err != nil {
+		return mailer.Message{}, fmt.Errorf("parse invitation_url: %w", err)
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	return mailer.Message{
+		Subject: fmt.Sprintf("You are invited to join %s", org.Name),
+		Body: fmt.Sprintf("You have been invited to join %s. Follow the link to accept the invitation:\n\n%s\n\n"+
+			"It expires in %s and can be used once. If you did not expect it, ignore this email.\n", org.Name, link, ttl),
+	}, nil
+}
+
+// AcceptInvitation принимает приглашение с токеном token из письма. Зарегистрированный
+// пользователь с адресом из приглашения добавляется в организацию; иначе он регистрируется
+// с паролем password, а без пароля возвращается ErrPasswordRequired и приглашение остается в силе.
+func (o *Organizations) AcceptInvitation(ctx context.Context, token, password string) (member models.Member, err error) {
+	const op = "organization.AcceptInvitation"
+
+	log := o.log.With("op", op)
+
+	var (
+		inv        models.Invitation
+		registered bool
+	)
+	defer func() {
+		e := audit.Event(models.AuditOrganizationAccept, err)
+		e.Actor, e.Subject = inv.Email, inv.Email
+		e.Details = map[string]string{"organization": inv.OrganizationID, "role": inv.Role}
+		if registered {
+			e.Details["registered"] = "true"
+		}
+		o.auditor.Record(ctx, e)
+	}()
+
+	if token == "" {
+		return models.Member{}, fmt.Errorf("%s: %w", op, ErrInvalidInvitation)
+	}
+
+	inv, err = o.storage.Invitation(ctx, hashToken(token))
+	if errors.Is(err, storage.ErrInvitationNotFound) {
+		return models.Member{}, fmt.Errorf("%s: %w", op, ErrInvalidInvitation)
+	}
+	if err != nil {
+		return models.Member{}, handleStorageErr(log, "failed to get invitation", op, err)
+	}
+
+	// Ссылка из письма не передает арендатора: он берется у организации приглашения.
+	ctx = tenant.With(ctx, inv.Tenant)
+	log = log.With("invitationID", inv.ID, "organizationID", inv.OrganizationID)
+
+	invitee, err := o.invitee(ctx, log, inv.Email, password)
+	if err != nil {
+		return models.Member{}, fmt.Errorf("%s: %w", op, err)
+	}
+	registered = invitee.registered
+
+	// Удаление приглашения — граница одноразовости: из параллельных запросов
+	// в организацию добавит только тот, кто удалил запись.
+	err = o.storage.ConsumeInvitation(ctx, inv.ID, invitee.uuid)
+	if errors.Is(err, storage.ErrInvitationNotFound) {
+		return models.Member{}, fmt.Errorf("%s: %w: already accepted", op, ErrInvalidInvitation)
+	}
+	if err != nil {
+		return models.Member{}, handleStorageErr(log, "failed to consume invitation", op, err)
+	}
+
+	member, err = o.storage.Member(ctx, inv.OrganizationID, invitee.uuid)
+	if err != nil {
+		return models.Member{}, handleStorageErr(log, "failed to get member", op, err)
+	}
+
+	log.Infow("invitation accepted", "userUUID", member.UserUUID, "role", member.Role)
+
+	return member, nil
+}
+
+// invitedUser пользователь, принимающий приглашение.
+type invitedUser struct {
+	uuid       string
+	registered bool
+}
+
+// invitee возвращает пользователя с адресом addr, регистрируя его с паролем password,
+// если он еще не зарегистрирован. Неожиданные ошибки логгирует.
+func (o *Organizations) invitee(ctx context.Context, log *zap.SugaredLogger, addr, password string) (invitedUser, error) {
+	user, err := o.users.User(ctx, addr)
+	switch {
+	case err == nil:
+		if user.Status != models.UserStatusActive {
+			return invitedUser{}, fmt.Errorf("%w: user is %s", ErrUserInactive, user.Status)
+		}
+
+		return invitedUser{uuid: user.UUID}, nil
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Errorw("storage unavailable", "error", err)
+		return invitedUser{}, ErrUnavailable
+	case !errors.Is(err, storage.ErrUserNotFound):
+		log.Errorw("failed to get user", "error", err)
+		return invitedUser{}, err
+	}
+
+	if password == "" {
+		return invitedUser{}, ErrPasswordRequired
+	}
+
+	uuid, err := o.registrar.RegisterNewUser(ctx, addr, password)
+	switch {
+	case errors.Is(err, auth.ErrUnavailable):
+		return invitedUser{}, ErrUnavailable
+	case errors.Is(err, auth.ErrInvalidEmail):
+		return invitedUser{}, ErrInvalidEmail
+	case err != nil:
+		log.Errorw("failed to register user", "error", err)
+		return invitedUser{}, err
+	}
+
+	return invitedUser{uuid: uuid, registered: true}, nil
+}
+
+// RemoveMember удаляет пользователя userUUID из организации. Участник может выйти сам;
+// удалять других могут владельцы и администраторы, владельцев — только владельцы;
+// пустой actorUUID — оператор. Последнего владельца удалить нельзя.
+func (o *Organizations) RemoveMember(ctx context.Context, actorUUID, orgID, userUUID string) (err error) {
+	const op = "organization.RemoveMember"
+
+	log := o.log.With("op", op, "organizationID", orgID, "userUUID", userUUID)
+
+	var (
+		org    models.Organization
+		actor  models.Member
+		target models.Member
+	)
+	defer func() {
+		subject := target.Email
+		if subject == "" {
+			subject = userUUID
+		}
+
+		e := audit.Event(models.AuditOrganizationRemoveMember, err)
+		e.Actor, e.Subject, e.App = actor.Email, subject, org.App
+		e.Details = map[string]string{"organization": orgID, "role": target.Role}
+		o.auditor.Record(ctx, e)
+	}()
+
+	org, actor, err = o.authorize(ctx, actorUUID, orgID)
+	if err != nil {
+		return handleStorageErr(log, "failed to authorize", op, err)
+	}
+
+	target, err = o.storage.Member(ctx, orgID, userUUID)
+	if err != nil {
+		return handleStorageErr(log, "failed to get member", op, err)
+	}
+
+	if actorUUID != userUUID && !canManage(actor.Role, target.Role) {
+		return fmt.Errorf("%s: %w: %s cannot remove %s", op, ErrPermissionDenied, actor.Role, target.Role)
+	}
+
+	if err := o.storage.RemoveMember(ctx, orgID, userUUID); err != nil {
+		return handleStorageErr(log, "failed to remove member", op, err)
+	}
+
+	log.Infow("member removed")
+
+	return nil
+}
+
+// authorize возвращает организацию арендатора из ctx и участника actorUUID.
+// Оператор (пустой actorUUID) получает права владельца. Для тех, кто не состоит
+// в организации, она не существует.
+func (o *Organizations) authorize(ctx context.Context, actorUUID, orgID string) (models.Organization, models.Member, error) {
+	org, err := o.storage.Organization(ctx, orgID)
+	if err != nil {
+		return models.Organization{}, models.Member{}, err
+	}
+
+	if actorUUID == "" {
+		return org, models.Member{OrganizationID: org.ID, Role: models.OrganizationOwner}, nil
+	}
+
+	actor, err := o.storage.Member(ctx, orgID, actorUUID)
+	if errors.Is(err, storage.ErrMemberNotFound) {
+		return models.Organization{}, models.Member{}, storage.ErrOrganizationNotFound
+	}
+	if err != nil {
+		return models.Organization{}, models.Member{}, err
+	}
+
+	return org, actor, nil
+}
+
+// canManage может ли участник с ролью actor приглашать и удалять участников с ролью role.
+func canManage(actor, role string) bool {
+	switch actor {
+	case models.OrganizationOwner:
+		return true
+	case models.OrganizationAdmin:
+		return role != models.OrganizationOwner
+	default:
+		return false
+	}
+}
+
+func validRole(role string) bool {
+	switch role {
+	case models.OrganizationOwner, models.OrganizationAdmin, models.OrganizationMember:
+		return true
+	default:
+		return false
+	}
+}
+
+// hashToken хэш токена приглашения для хранения. Токены случайные, поэтому соль не нужна.
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// randomString возвращает n случайных байт в base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// storageErrors ошибки хранилища, которые возвращаются вызывающему как ошибки сервиса.
+var storageErrors = []struct {
+	storage, service error
+}{
+	{storage.ErrAppNotFound, ErrAppNotFound},
+	{storage.ErrOrganizationNotFound, ErrOrganizationNotFound},
+	{storage.ErrOrganizationExists, ErrOrganizationExists},
+	{storage.ErrUserNotFound, ErrUserNotFound},
+	{storage.ErrMemberNotFound, ErrMemberNotFound},
+	{storage.ErrLastOwner, ErrLastOwner},
+	{storage.ErrUnavailable, ErrUnavailable},
+}
+
+// handleStorageErr переводит ошибки хранилища в ошибки сервиса и логгирует неожиданные.
+func handleStorageErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	for _, e := range storageErrors {
+		if errors.Is(err, e.storage) {
+			if e.service == ErrUnavailable {
+				log.Errorw("storage unavailable", "error", err)
+			}
+
+			return fmt.Errorf("%s: %w", op, e.service)
+		}
+	}
+
+	return handleInternalErr(log, msg, op, err)
+}
+
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+	return fmt.Errorf("%s: %w", op, err)
+}
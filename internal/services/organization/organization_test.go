@@ -0,0 +1,384 @@
+package organization
+
+import (
+	"context"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/mailer"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStorage хранит пользователей, организации и приглашения в памяти.
+type fakeStorage struct {
+	users       map[string]models.User
+	orgs        map[string]models.Organization
+	members     map[string]map[string]string
+	invitations map[string]models.Invitation
+	// tenants арендатор, в ctx которого вызван метод, по имени метода.
+	tenants map[string]string
+}
+
+func (s *fakeStorage) SaveOrganization(ctx context.Context, app, name, ownerUUID string) (models.Organization, error) {
+	if app != "shop" {
+		return models.Organization{}, storage.ErrAppNotFound
+	}
+	if _, ok := s.users[ownerUUID]; !ok {
+		return models.Organization{}, storage.ErrUserNotFound
+	}
+	org := models.Organization{ID: fmt.Sprintf("org-%d", len(s.orgs)+1), App: app, Name: name}
+	s.orgs[org.ID] = org
+	s.members[org.ID] = map[string]string{ownerUUID: models.OrganizationOwner}
+	return org, nil
+}
+
+func (s *fakeStorage) Organization(_ context.Context, id string) (models.Organization, error) {
+	org, ok := s.orgs[id]
+	if !ok {
+		return models.Organization{}, storage.ErrOrganizationNotFound
+	}
+	return org, nil
+}
+
+func (s *fakeStorage) Organizations(_ context.Context, app string) ([]models.Organization, error) {
+	var out []models.Organization
+	for _, org := range s.orgs {
+		if org.App == app {
+			out = append(out, org)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStorage) MemberOrganizations(_ context.Context, userUUID, app string) ([]models.Organization, error) {
+	var out []models.Organization
+	for id, members := range s.members {
+		if _, ok := members[userUUID]; ok && s.orgs[id].App == app {
+			out = append(out, s.orgs[id])
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStorage) Members(_ context.Context, orgID string) ([]models.Member, error) {
+	var out []models.Member
+	for userUUID := range s.members[orgID] {
+		m, _ := s.Member(context.Background(), orgID, userUUID)
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func (s *fakeStorage) Member(_ context.Context, orgID, userUUID string) (models.Member, error) {
+	role, ok := s.members[orgID][userUUID]
+	if !ok {
+		return models.Member{}, storage.ErrMemberNotFound
+	}
+	return models.Member{OrganizationID: orgID, UserUUID: userUUID, Email: s.users[userUUID].Email, Role: role}, nil
+}
+
+func (s *fakeStorage) RemoveMember(_ context.Context, orgID, userUUID string) error {
+	role, ok := s.members[orgID][userUUID]
+	if !ok {
+		return storage.ErrMemberNotFound
+	}
+	if role == models.OrganizationOwner {
+		owners := 0
+		for _, r := range s.members[orgID] {
+			if r == models.OrganizationOwner {
+				owners++
+			}
+		}
+		if owners == 1 {
+			return storage.ErrLastOwner
+		}
+	}
+	delete(s.members[orgID], userUUID)
+	return nil
+}
+
+func (s *fakeStorage) SaveInvitation(_ context.Context, inv models.Invitation) (models.Invitation, error) {
+	inv.ID = fmt.Sprintf("inv-%d", len(s.invitations)+1)
+	inv.Tenant = "acme"
+	s.invitations[inv.ID] = inv
+	return inv, nil
+}
+
+func (s *fakeStorage) Invitation(_ context.Context, tokenHash []byte) (models.Invitation, error) {
+	for _, inv := range s.invitations {
+		if string(inv.TokenHash) == string(tokenHash) && time.Now().Before(inv.ExpiresAt) {
+			return inv, nil
+		}
+	}
+	return models.Invitation{}, storage.ErrInvitationNotFound
+}
+
+func (s *fakeStorage) ConsumeInvitation(_ context.Context, id, userUUID string) error {
+	inv, ok := s.invitations[id]
+	if !ok {
+		return storage.ErrInvitationNotFound
+	}
+	delete(s.invitations, id)
+	if _, ok := s.members[inv.OrganizationID][userUUID]; !ok {
+		s.members[inv.OrganizationID][userUUID] = inv.Role
+	}
+	return nil
+}
+
+func (s *fakeStorage) User(ctx context.Context, addr string) (models.User, error) {
+	s.tenants["User"] = tenant.From(ctx)
+	for _, u := range s.users {
+		if u.Email == addr {
+			return u, nil
+		}
+	}
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (s *fakeStorage) RegisterNewUser(ctx context.Context, addr, _ string) (string, error) {
+	s.tenants["RegisterNewUser"] = tenant.From(ctx)
+	uuid := fmt.Sprintf("user-%d", len(s.users)+1)
+	s.users[uuid] = models.User{UUID: uuid, Email: addr, Status: models.UserStatusActive}
+	return uuid, nil
+}
+
+// fakeMailer запоминает отправленные письма.
+type fakeMailer struct {
+	sent []mailer.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+type recordingAuditor struct {
+	events []models.AuditEvent
+}
+
+func (a *recordingAuditor) Record(_ context.Context, e models.AuditEvent) {
+	a.events = append(a.events, e)
+}
+
+// newTestService возвращает сервис с организацией org-1 в приложении shop:
+// user-1 — владелец, user-2 — администратор, user-3 — участник.
+func newTestService(t *testing.T) (*Organizations, *fakeStorage, *fakeMailer, *recordingAuditor) {
+	t.Helper()
+
+	st := &fakeStorage{
+		users: map[string]models.User{
+			"user-1": {UUID: "user-1", Email: "owner@example.com", Status: models.UserStatusActive},
+			"user-2": {UUID: "user-2", Email: "admin@example.com", Status: models.UserStatusActive},
+			"user-3": {UUID: "user-3", Email: "member@example.com", Status: models.UserStatusActive},
+			"user-4": {UUID: "user-4", Email: "blocked@example.com", Status: models.UserStatusDisabled},
+		},
+		orgs:        make(map[string]models.Organization),
+		members:     make(map[string]map[string]string),
+		invitations: make(map[string]models.Invitation),
+		tenants:     make(map[string]string),
+	}
+	m := &fakeMailer{}
+	auditor := &recordingAuditor{}
+
+	svc := New(zap.NewNop().Sugar(), st, st, st, m, auditor, email.Normalizer{}, Config{
+		InvitationTTL:  time.Hour,
+		InvitationURLs: map[string]string{"shop": "https://shop.example.com/invite?from=email"},
+	})
+
+	org, err := svc.CreateOrganization(context.Background(), "shop", "  Acme  ", "user-1")
+	require.NoError(t, err)
+	require.Equal(t, "org-1", org.ID)
+	st.members["org-1"]["user-2"] = models.OrganizationAdmin
+	st.members["org-1"]["user-3"] = models.OrganizationMember
+
+	return svc, st, m, auditor
+}
+
+var linkRe = regexp.MustCompile(`https://\S+`)
+
+// invitationToken возвращает токен из ссылки в письме с приглашением.
+func invitationToken(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+
+	link, err := url.Parse(linkRe.FindString(msg.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "email", link.Query().Get("from"))
+
+	return link.Query().Get("token")
+}
+
+func TestCreateOrganization(t *testing.T) {
+	svc, st, _, auditor := newTestService(t)
+	ctx := context.Background()
+
+	assert.Equal(t, "Acme", st.orgs["org-1"].Name)
+	assert.Equal(t, models.AuditOrganizationCreate, auditor.events[0].Type)
+	assert.Equal(t, "org-1", auditor.events[0].Details["organization"])
+
+	_, err := svc.CreateOrganization(ctx, "shop", " ", "user-1")
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	_, err = svc.CreateOrganization(ctx, "unknown", "Acme", "user-1")
+	assert.ErrorIs(t, err, ErrAppNotFound)
+
+	_, err = svc.CreateOrganization(ctx, "shop", "Acme", "user-404")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestOrganizations(t *testing.T) {
+	svc, _, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	orgs, err := svc.Organizations(ctx, "shop", "user-3")
+	require.NoError(t, err)
+	assert.Len(t, orgs, 1)
+
+	orgs, err = svc.Organizations(ctx, "shop", "user-4")
+	require.NoError(t, err)
+	assert.Empty(t, orgs)
+
+	orgs, err = svc.Organizations(ctx, "shop", "")
+	require.NoError(t, err)
+	assert.Len(t, orgs, 1)
+}
+
+func TestMembers_HiddenFromOutsiders(t *testing.T) {
+	svc, _, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	members, err := svc.Members(ctx, "user-3", "org-1")
+	require.NoError(t, err)
+	assert.Len(t, members, 3)
+
+	_, err = svc.Members(ctx, "user-4", "org-1")
+	assert.ErrorIs(t, err, ErrOrganizationNotFound)
+}
+
+func TestInviteMember_Permissions(t *testing.T) {
+	svc, _, m, _ := newTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		actor   string
+		role    string
+		wantErr error
+	}{
+		{name: "owner invites owner", actor: "user-1", role: models.OrganizationOwner},
+		{name: "admin invites admin", actor: "user-2", role: models.OrganizationAdmin},
+		{name: "operator invites owner", actor: "", role: models.OrganizationOwner},
+		{name: "admin cannot invite owner", actor: "user-2", role: models.OrganizationOwner, wantErr: ErrPermissionDenied},
+		{name: "member cannot invite", actor: "user-3", role: models.OrganizationMember, wantErr: ErrPermissionDenied},
+		{name: "outsider", actor: "user-4", role: models.OrganizationMember, wantErr: ErrOrganizationNotFound},
+		{name: "unknown role", actor: "user-1", role: "guest", wantErr: ErrInvalidRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := len(m.sent)
+
+			_, err := svc.InviteMember(ctx, tt.actor, "org-1", "new@example.com", tt.role)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Len(t, m.sent, sent)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, m.sent, sent+1)
+		})
+	}
+}
+
+func TestInviteMember_NotEnabled(t *testing.T) {
+	svc, _, _, _ := newTestService(t)
+	svc.SetConfig(Config{InvitationTTL: time.Hour})
+
+	_, err := svc.InviteMember(context.Background(), "user-1", "org-1", "new@example.com", models.OrganizationMember)
+	assert.ErrorIs(t, err, ErrInvitationsNotEnabled)
+}
+
+func TestAcceptInvitation_RegistersNewUser(t *testing.T) {
+	svc, st, m, auditor := newTestService(t)
+	ctx := context.Background()
+
+	inv, err := svc.InviteMember(ctx, "user-2", "org-1", "New@Example.com", models.OrganizationAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, "New@example.com", inv.Email)
+	assert.Equal(t, "user-2", inv.InvitedBy)
+	assert.Equal(t, "New@example.com", m.sent[0].To)
+	assert.Contains(t, m.sent[0].Subject, "Acme")
+
+	token := invitationToken(t, m.sent[0])
+	require.NotEmpty(t, token)
+	assert.NotEqual(t, token, string(inv.TokenHash), "only the token hash is stored")
+
+	_, err = svc.AcceptInvitation(ctx, token, "")
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+
+	member, err := svc.AcceptInvitation(ctx, token, "secret-password")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrganizationAdmin, member.Role)
+	assert.Equal(t, "New@example.com", member.Email)
+	assert.Equal(t, "acme", st.tenants["RegisterNewUser"], "user is registered in the invitation tenant")
+
+	last := auditor.events[len(auditor.events)-1]
+	assert.Equal(t, models.AuditOrganizationAccept, last.Type)
+	assert.Equal(t, models.AuditSuccess, last.Outcome)
+	assert.Equal(t, "true", last.Details["registered"])
+
+	_, err = svc.AcceptInvitation(ctx, token, "secret-password")
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+}
+
+func TestAcceptInvitation_LinksExistingUser(t *testing.T) {
+	svc, st, m, _ := newTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.InviteMember(ctx, "user-1", "org-1", "owner@example.com", models.OrganizationMember)
+	require.NoError(t, err)
+	_, err = svc.InviteMember(ctx, "user-1", "org-1", "blocked@example.com", models.OrganizationMember)
+	require.NoError(t, err)
+	blocked := invitationToken(t, m.sent[1])
+
+	_, err = svc.AcceptInvitation(ctx, blocked, "")
+	assert.ErrorIs(t, err, ErrUserInactive)
+
+	// Роль того, кто уже состоит в организации, не меняется.
+	member, err := svc.AcceptInvitation(ctx, invitationToken(t, m.sent[0]), "")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", member.UserUUID)
+	assert.Equal(t, models.OrganizationOwner, member.Role)
+	assert.Equal(t, "acme", st.tenants["User"])
+
+	_, err = svc.AcceptInvitation(ctx, "unknown", "")
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+}
+
+func TestRemoveMember(t *testing.T) {
+	svc, st, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	err := svc.RemoveMember(ctx, "user-2", "org-1", "user-1")
+	assert.ErrorIs(t, err, ErrPermissionDenied, "admin cannot remove owner")
+
+	err = svc.RemoveMember(ctx, "user-3", "org-1", "user-2")
+	assert.ErrorIs(t, err, ErrPermissionDenied, "member cannot remove others")
+
+	err = svc.RemoveMember(ctx, "user-1", "org-1", "user-1")
+	assert.ErrorIs(t, err, ErrLastOwner)
+
+	require.NoError(t, svc.RemoveMember(ctx, "user-3", "org-1", "user-3"), "member can leave")
+	require.NoError(t, svc.RemoveMember(ctx, "user-2", "org-1", "user-2"), "admin can leave")
+	assert.Equal(t, map[string]string{"user-1": models.OrganizationOwner}, st.members["org-1"])
+
+	err = svc.RemoveMember(ctx, "", "org-1", "user-3")
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+}
@@ -0,0 +1,710 @@
+// Package passkey реализует регистрацию ключей WebAuthn (passkey) и вход по ним: как
+// самостоятельный вход без пароля и как второй фактор после проверки пароля в auth.Login.
+package passkey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.uber.org/zap"
+)
+
+// Passkey сервис ключей WebAuthn.
+type Passkey struct {
+	log *zap.SugaredLogger
+
+	webauthn   *webauthn.WebAuthn
+	passkeys   PasskeyStorage
+	ceremonies CeremonyStorage
+	users      UserProvider
+	apps       AppProvider
+	tokens     TokenIssuer
+	auditor    Auditor
+	timeout    time.Duration
+
+	now func() time.Time
+}
+
+// Config параметры проверяющей стороны (relying party) WebAuthn.
+type Config struct {
+	// RPID домен, к которому привязываются ключи, например example.com.
+	RPID          string
+	RPDisplayName string
+	// RPOrigins адреса страниц, с которых разрешены регистрация и вход, например https://admin.example.com.
+	RPOrigins []string
+	// Timeout время на завершение регистрации или входа.
+	Timeout time.Duration
+}
+
+type PasskeyStorage interface {
+	SavePasskey(ctx context.Context, p models.Passkey) error
+	Passkeys(ctx context.Context, userUUID string) ([]models.Passkey, error)
+	UpdatePasskeyUsage(ctx context.Context, id []byte, signCount uint32, backupState bool) error
+	DeletePasskey(ctx context.Context, userUUID string, id []byte) error
+}
+
+type CeremonyStorage interface {
+	SaveWebAuthnCeremony(ctx context.Context, c models.WebAuthnCeremony) error
+	WebAuthnCeremony(ctx context.Context, id string) (models.WebAuthnCeremony, error)
+	ConsumeWebAuthnCeremony(ctx context.Context, id string) (models.WebAuthnCeremony, error)
+}
+
+type UserProvider interface {
+	UserByUUID(ctx context.Context, uuid string) (models.User, error)
+}
+
+type AppProvider interface {
+	AppByName(ctx context.Context, name string) (models.App, error)
+}
+
+// TokenIssuer создает сеанс и выпускает токен аутентифицированному пользователю.
+type TokenIssuer interface {
+	IssueToken(ctx context.Context, user models.User, appName string) (token, sessionID string, err error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Ошибки, которые могут возникнуть при работе с ключами.
+var (
+	ErrInvalidApp = errors.New("invalid app")
+	// ErrInvalidCeremony церемония неизвестна, истекла, уже завершена или начата для другого пользователя.
+	ErrInvalidCeremony = errors.New("invalid or expired ceremony")
+	// ErrInvalidCredential ответ аутентификатора не прошел проверку.
+	ErrInvalidCredential = errors.New("invalid credential")
+	ErrInvalidName       = errors.New("invalid passkey name")
+	ErrPasskeyExists     = errors.New("passkey already registered")
+	ErrPasskeyNotFound   = errors.New("passkey not found")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserInactive      = errors.New("user is not active")
+	ErrUnavailable       = errors.New("dependency unavailable")
+)
+
+// maxNameLength ограничение длины названия ключа в символах.
+const maxNameLength = 64
+
+// Ceremony начатая регистрация или вход.
+type Ceremony struct {
+	ID string
+	// Options параметры для navigator.credentials.create или navigator.credentials.get.
+	Options json.RawMessage
+}
+
+// New возвращает новый экземпляр сервиса ключей.
+func New(
+	log *zap.SugaredLogger,
+	passkeys PasskeyStorage,
+	ceremonies CeremonyStorage,
+	users UserProvider,
+	apps AppProvider,
+	tokens TokenIssuer,
+	auditor Auditor,
+	cfg Config,
+) (*Passkey, error) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.Timeout, TimeoutUVD: cfg.Timeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.Timeout, TimeoutUVD: cfg.Timeout},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: %w", err)
+	}
+
+	return &Passkey{
+		log: log,
+
+		webauthn:   wa,
+		passkeys:   passkeys,
+		ceremonies: ceremonies,
+		users:      users,
+		apps:       apps,
+		tokens:     tokens,
+		auditor:    auditor,
+		timeout:    cfg.Timeout,
+
+		now: time.Now,
+	}, nil
+}
+
+// BeginRegistration начинает регистрацию нового ключа пользователя. Ключ создается
+// как passkey (discoverable credential) с проверкой пользователя, чтобы по нему можно
+// было войти без пароля; уже зарегистрированные ключи исключаются.
+func (p *Passkey) BeginRegistration(ctx context.Context, userUUID string) (Ceremony, error) {
+	const op = "passkey.BeginRegistration"
+
+	log := p.log.With("op", op, "userUUID", userUUID)
+
+	user, err := p.webAuthnUser(ctx, log, op, userUUID)
+	if err != nil {
+		return Ceremony{}, err
+	}
+
+	creation, session, err := p.webauthn.BeginRegistration(user,
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		}),
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		return Ceremony{}, handleInternalErr(log, "failed to begin registration", op, err)
+	}
+
+	return p.saveCeremony(ctx, log, op, models.CeremonyRegistration, userUUID, "", session, creation)
+}
+
+// FinishRegistration проверяет ответ аутентификатора на церемонию ceremonyID и сохраняет ключ
+// под названием name.
+func (p *Passkey) FinishRegistration(
+	ctx context.Context,
+	userUUID, ceremonyID, name string,
+	response []byte,
+) (passkey models.Passkey, err error) {
+	const op = "passkey.FinishRegistration"
+
+	log := p.log.With("op", op, "userUUID", userUUID, "ceremonyID", ceremonyID)
+
+	var subject string
+	defer func() {
+		if subject == "" {
+			subject = userUUID
+		}
+
+		e := audit.Event(models.AuditPasskeyRegister, err)
+		e.Actor, e.Subject = subject, subject
+		e.Details = map[string]string{"name": name}
+		p.auditor.Record(ctx, e)
+	}()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return models.Passkey{}, fmt.Errorf("%s: %w: longer than %d characters", op, ErrInvalidName, maxNameLength)
+	}
+
+	c, session, err := p.consumeCeremony(ctx, log, op, ceremonyID, models.CeremonyRegistration)
+	if err != nil {
+		return models.Passkey{}, err
+	}
+	if c.UserUUID != userUUID {
+		log.Warnw("ceremony belongs to another user", "ceremonyUserUUID", c.UserUUID)
+		return models.Passkey{}, fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		log.Infow("invalid attestation response", "error", err)
+		return models.Passkey{}, fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	user, err := p.webAuthnUser(ctx, log, op, userUUID)
+	if err != nil {
+		return models.Passkey{}, err
+	}
+	subject = user.user.Email
+
+	cred, err := p.webauthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		log.Infow("attestation verification failed", "error", err)
+		return models.Passkey{}, fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	passkey = fromCredential(userUUID, name, cred)
+
+	err = p.passkeys.SavePasskey(ctx, passkey)
+	switch {
+	case errors.Is(err, storage.ErrPasskeyExists):
+		return models.Passkey{}, fmt.Errorf("%s: %w", op, ErrPasskeyExists)
+	case err != nil:
+		return models.Passkey{}, handleStorageErr(log, "failed to save passkey", op, err)
+	}
+
+	log.Infow("passkey registered")
+
+	return passkey, nil
+}
+
+// BeginLogin начинает вход по passkey без пароля в приложение appName. Пользователь
+// определяется по ключу, который выберет в браузере. Области доступа из контекста
+// (см. auth.WithScopeRequest) сохраняются в церемонии до завершения входа.
+func (p *Passkey) BeginLogin(ctx context.Context, appName string) (Ceremony, error) {
+	const op = "passkey.BeginLogin"
+
+	log := p.log.With("op", op, "appName", appName)
+
+	if _, err := p.apps.AppByName(ctx, appName); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return Ceremony{}, fmt.Errorf("%s: %w: app %q is not registered", op, ErrInvalidApp, appName)
+		}
+		return Ceremony{}, handleStorageErr(log, "failed to get app", op, err)
+	}
+
+	assertion, session, err := p.webauthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return Ceremony{}, handleInternalErr(log, "failed to begin login", op, err)
+	}
+
+	return p.saveCeremony(ctx, log, op, models.CeremonyLogin, "", appName, session, assertion)
+}
+
+// BeginSecondFactor начинает подтверждение входа по паролю одним из ключей пользователя.
+// Если у пользователя нет ключей, возвращает пустой идентификатор. Реализует auth.SecondFactor.
+func (p *Passkey) BeginSecondFactor(ctx context.Context, u models.User, appName string) (string, error) {
+	const op = "passkey.BeginSecondFactor"
+
+	log := p.log.With("op", op, "userUUID", u.UUID, "appName", appName)
+
+	passkeys, err := p.passkeys.Passkeys(ctx, u.UUID)
+	if err != nil {
+		return "", toAuthErr(handleStorageErr(log, "failed to get passkeys", op, err))
+	}
+	if len(passkeys) == 0 {
+		return "", nil
+	}
+
+	user := newWebAuthnUser(u, passkeys)
+
+	// Пароль уже проверен, поэтому ключ подтверждает владение, а проверка
+	// пользователя на аутентификаторе не обязательна.
+	assertion, session, err := p.webauthn.BeginLogin(user, webauthn.WithUserVerification(protocol.VerificationPreferred))
+	if err != nil {
+		return "", handleInternalErr(log, "failed to begin login", op, err)
+	}
+
+	c, err := p.saveCeremony(ctx, log, op, models.CeremonyMFA, u.UUID, appName, session, assertion)
+	if err != nil {
+		return "", toAuthErr(err)
+	}
+
+	return c.ID, nil
+}
+
+// Options возвращает параметры для navigator.credentials.get начатого входа. Нужен
+// для второго фактора: auth.Login возвращает только идентификатор церемонии.
+func (p *Passkey) Options(ctx context.Context, ceremonyID string) (json.RawMessage, error) {
+	const op = "passkey.Options"
+
+	log := p.log.With("op", op, "ceremonyID", ceremonyID)
+
+	c, err := p.ceremonies.WebAuthnCeremony(ctx, ceremonyID)
+	if errors.Is(err, storage.ErrCeremonyNotFound) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+	}
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to get ceremony", op, err)
+	}
+	if c.Kind == models.CeremonyRegistration {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+	}
+
+	return c.Options, nil
+}
+
+// FinishLogin проверяет подпись аутентификатора на церемонию входа ceremonyID и выпускает
+// токен приложения, для которого был начат вход. Счетчик подписей ключа должен расти:
+// иначе ключ, возможно, скопирован, и вход отклоняется.
+func (p *Passkey) FinishLogin(ctx context.Context, ceremonyID string, response []byte) (token string, err error) {
+	const op = "passkey.FinishLogin"
+
+	log := p.log.With("op", op, "ceremonyID", ceremonyID)
+
+	var (
+		c    models.WebAuthnCeremony
+		user models.User
+	)
+	defer func() {
+		subject := user.Email
+		if subject == "" {
+			subject = c.UserUUID
+		}
+
+		factor := "first"
+		if c.Kind == models.CeremonyMFA {
+			factor = "second"
+		}
+
+		e := audit.Event(models.AuditPasskeyLogin, err)
+		e.Actor, e.Subject, e.App = subject, subject, c.App
+		e.Details = map[string]string{"factor": factor}
+		p.auditor.Record(ctx, e)
+	}()
+
+	c, session, err := p.consumeCeremony(ctx, log, op, ceremonyID, models.CeremonyLogin, models.CeremonyMFA)
+	if err != nil {
+		return "", err
+	}
+	// Вход завершается в арендаторе, в котором был начат.
+	ctx = tenant.With(ctx, c.Tenant)
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		log.Infow("invalid assertion response", "error", err)
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	var cred *webauthn.Credential
+	user, cred, err = p.validateLogin(ctx, log, op, c, session, parsed)
+	if err != nil {
+		return "", err
+	}
+	// При входе без пароля пользователь находится по ключу и может относиться к другому арендатору.
+	if user.Tenant != c.Tenant {
+		log.Warnw("passkey belongs to another tenant", "userUUID", user.UUID, "userTenant", user.Tenant)
+		return "", fmt.Errorf("%s: %w: passkey belongs to another tenant", op, ErrInvalidCredential)
+	}
+
+	if cred.Authenticator.CloneWarning {
+		log.Warnw("passkey sign count did not increase, possible cloned authenticator", "userUUID", user.UUID)
+		return "", fmt.Errorf("%s: %w: sign count did not increase", op, ErrInvalidCredential)
+	}
+
+	err = p.passkeys.UpdatePasskeyUsage(ctx, cred.ID, cred.Authenticator.SignCount, cred.Flags.BackupState)
+	switch {
+	case errors.Is(err, storage.ErrStaleSignCount):
+		log.Warnw("passkey sign count is stale, possible cloned authenticator", "userUUID", user.UUID)
+		return "", fmt.Errorf("%s: %w: sign count did not increase", op, ErrInvalidCredential)
+	case errors.Is(err, storage.ErrPasskeyNotFound):
+		return "", fmt.Errorf("%s: %w: passkey was deleted", op, ErrInvalidCredential)
+	case err != nil:
+		return "", handleStorageErr(log, "failed to update passkey", op, err)
+	}
+
+	if user.Status != models.UserStatusActive {
+		log.Infow("user is not active", "userUUID", user.UUID, "status", user.Status)
+
+		return "", fmt.Errorf("%s: %w: user is %s", op, ErrUserInactive, user.Status)
+	}
+
+	// Области берутся из церемонии, а согласие — из запроса на завершение входа.
+	scopes := auth.ScopeRequestFrom(ctx)
+	scopes.Scopes = c.Scopes
+
+	token, _, err = p.tokens.IssueToken(auth.WithScopeRequest(ctx, scopes), user, c.App)
+	if errors.Is(err, auth.ErrUnavailable) {
+		return "", fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+	if errors.Is(err, auth.ErrConsentRequired) || errors.Is(err, auth.ErrInvalidScope) {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err != nil {
+		return "", handleInternalErr(log, "failed to issue token", op, err)
+	}
+
+	log.Infow("user logged in with passkey", "userUUID", user.UUID, "kind", c.Kind)
+
+	return token, nil
+}
+
+// validateLogin проверяет подпись: при входе без пароля пользователь определяется
+// по user handle ключа, при втором факторе он известен из церемонии.
+func (p *Passkey) validateLogin(
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	op string,
+	c models.WebAuthnCeremony,
+	session webauthn.SessionData,
+	parsed *protocol.ParsedCredentialAssertionData,
+) (models.User, *webauthn.Credential, error) {
+	if c.Kind == models.CeremonyMFA {
+		user, err := p.webAuthnUser(ctx, log, op, c.UserUUID)
+		if err != nil {
+			return models.User{}, nil, err
+		}
+
+		cred, err := p.webauthn.ValidateLogin(user, session, parsed)
+		if err != nil {
+			log.Infow("assertion verification failed", "error", err)
+			return models.User{}, nil, fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+		}
+
+		return user.user, cred, nil
+	}
+
+	// lookupErr ошибка загрузки пользователя; библиотека возвращает ее в обертке без цепочки.
+	var lookupErr error
+	found, cred, err := p.webauthn.ValidatePasskeyLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		user, err := p.webAuthnUser(ctx, log, op, string(userHandle))
+		if err != nil {
+			lookupErr = err
+			return nil, err
+		}
+		return user, nil
+	}, session, parsed)
+	if lookupErr != nil && !errors.Is(lookupErr, ErrUserNotFound) {
+		return models.User{}, nil, lookupErr
+	}
+	if err != nil {
+		log.Infow("assertion verification failed", "error", err)
+		return models.User{}, nil, fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	return found.(*webAuthnUser).user, cred, nil
+}
+
+// Passkeys возвращает ключи пользователя.
+func (p *Passkey) Passkeys(ctx context.Context, userUUID string) ([]models.Passkey, error) {
+	const op = "passkey.Passkeys"
+
+	log := p.log.With("op", op, "userUUID", userUUID)
+
+	passkeys, err := p.passkeys.Passkeys(ctx, userUUID)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to get passkeys", op, err)
+	}
+
+	return passkeys, nil
+}
+
+// DeletePasskey удаляет ключ пользователя.
+func (p *Passkey) DeletePasskey(ctx context.Context, userUUID string, id []byte) (err error) {
+	const op = "passkey.DeletePasskey"
+
+	log := p.log.With("op", op, "userUUID", userUUID)
+
+	defer func() {
+		e := audit.Event(models.AuditPasskeyDelete, err)
+		e.Actor, e.Subject = userUUID, userUUID
+		e.Details = map[string]string{"id": base64.RawURLEncoding.EncodeToString(id)}
+		p.auditor.Record(ctx, e)
+	}()
+
+	err = p.passkeys.DeletePasskey(ctx, userUUID, id)
+	if errors.Is(err, storage.ErrPasskeyNotFound) {
+		return fmt.Errorf("%s: %w", op, ErrPasskeyNotFound)
+	}
+	if err != nil {
+		return handleStorageErr(log, "failed to delete passkey", op, err)
+	}
+
+	log.Infow("passkey deleted")
+
+	return nil
+}
+
+// webAuthnUser загружает пользователя и его ключи.
+func (p *Passkey) webAuthnUser(ctx context.Context, log *zap.SugaredLogger, op, userUUID string) (*webAuthnUser, error) {
+	user, err := p.users.UserByUUID(ctx, userUUID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to get user", op, err)
+	}
+
+	passkeys, err := p.passkeys.Passkeys(ctx, userUUID)
+	if err != nil {
+		return nil, handleStorageErr(log, "failed to get passkeys", op, err)
+	}
+
+	return newWebAuthnUser(user, passkeys), nil
+}
+
+// saveCeremony сохраняет состояние церемонии и возвращает параметры для браузера.
+func (p *Passkey) saveCeremony(
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	op, kind, userUUID, appName string,
+	session *webauthn.SessionData,
+	options any,
+) (Ceremony, error) {
+	id, err := randomString(16)
+	if err != nil {
+		return Ceremony{}, handleInternalErr(log, "failed to generate ceremony id", op, err)
+	}
+
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return Ceremony{}, handleInternalErr(log, "failed to encode session", op, err)
+	}
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return Ceremony{}, handleInternalErr(log, "failed to encode options", op, err)
+	}
+
+	err = p.ceremonies.SaveWebAuthnCeremony(ctx, models.WebAuthnCeremony{
+		ID:       id,
+		Kind:     kind,
+		UserUUID: userUUID,
+		App:      appName,
+		// Запрошенные при начале входа области проверяются при выпуске токена.
+		Scopes:    auth.ScopeRequestFrom(ctx).Scopes,
+		Session:   sessionJSON,
+		Options:   optionsJSON,
+		ExpiresAt: p.now().Add(p.timeout),
+	})
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return Ceremony{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	if err != nil {
+		return Ceremony{}, handleStorageErr(log, "failed to save ceremony", op, err)
+	}
+
+	return Ceremony{ID: id, Options: optionsJSON}, nil
+}
+
+// consumeCeremony удаляет церемонию одного из видов kinds и возвращает ее состояние.
+// Удаление — граница одноразовости: ответ аутентификатора принимается только один раз.
+func (p *Passkey) consumeCeremony(
+	ctx context.Context,
+	log *zap.SugaredLogger,
+	op, id string,
+	kinds ...string,
+) (models.WebAuthnCeremony, webauthn.SessionData, error) {
+	if id == "" {
+		return models.WebAuthnCeremony{}, webauthn.SessionData{}, fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+	}
+
+	c, err := p.ceremonies.ConsumeWebAuthnCeremony(ctx, id)
+	if errors.Is(err, storage.ErrCeremonyNotFound) {
+		return models.WebAuthnCeremony{}, webauthn.SessionData{}, fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+	}
+	if err != nil {
+		return models.WebAuthnCeremony{}, webauthn.SessionData{}, handleStorageErr(log, "failed to consume ceremony", op, err)
+	}
+
+	if !slices.Contains(kinds, c.Kind) || !p.now().Before(c.ExpiresAt) {
+		return c, webauthn.SessionData{}, fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(c.Session, &session); err != nil {
+		return c, webauthn.SessionData{}, handleInternalErr(log, "failed to decode session", op, err)
+	}
+
+	return c, session, nil
+}
+
+// webAuthnUser пользователь с ключами в представлении библиотеки WebAuthn.
+// User handle ключа — UUID пользователя, по нему пользователь находится при входе без пароля.
+type webAuthnUser struct {
+	user        models.User
+	credentials []webauthn.Credential
+}
+
+func newWebAuthnUser(user models.User, passkeys []models.Passkey) *webAuthnUser {
+	credentials := make([]webauthn.Credential, 0, len(passkeys))
+	for _, p := range passkeys {
+		credentials = append(credentials, toCredential(p))
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(u.user.UUID)
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if u.user.Profile.DisplayName != "" {
+		return u.user.Profile.DisplayName
+	}
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func toCredential(p models.Passkey) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, 0, len(p.Transports))
+	for _, t := range p.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(t))
+	}
+
+	return webauthn.Credential{
+		ID:              p.ID,
+		PublicKey:       p.PublicKey,
+		AttestationType: p.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: p.BackupEligible,
+			BackupState:    p.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    p.AAGUID,
+			SignCount: p.SignCount,
+		},
+	}
+}
+
+func fromCredential(userUUID, name string, c *webauthn.Credential) models.Passkey {
+	transports := make([]string, 0, len(c.Transport))
+	for _, t := range c.Transport {
+		transports = append(transports, string(t))
+	}
+
+	return models.Passkey{
+		ID:              c.ID,
+		UserUUID:        userUUID,
+		Name:            name,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transports:      transports,
+		AAGUID:          c.Authenticator.AAGUID,
+		SignCount:       c.Authenticator.SignCount,
+		BackupEligible:  c.Flags.BackupEligible,
+		BackupState:     c.Flags.BackupState,
+	}
+}
+
+// randomString возвращает n случайных байт в base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// toAuthErr переводит недоступность зависимостей в ошибку auth для вызова из auth.Login.
+func toAuthErr(err error) error {
+	if errors.Is(err, ErrUnavailable) {
+		return fmt.Errorf("%w: %w", auth.ErrUnavailable, err)
+	}
+	return err
+}
+
+// handleStorageErr переводит ошибки хранилища в ошибки сервиса и логгирует их.
+func handleStorageErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Errorw("storage unavailable", "error", err)
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	default:
+		return handleInternalErr(log, msg, op, err)
+	}
+}
+
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+	return fmt.Errorf("%s: %w", op, err)
+}
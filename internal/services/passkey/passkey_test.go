@@ -0,0 +1,387 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/storage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://admin.example.com"
+)
+
+// fakeStorage хранит пользователей, ключи и церемонии в памяти.
+type fakeStorage struct {
+	users      map[string]models.User
+	passkeys   []models.Passkey
+	ceremonies map[string]models.WebAuthnCeremony
+}
+
+func (s *fakeStorage) SavePasskey(_ context.Context, p models.Passkey) error {
+	for _, existing := range s.passkeys {
+		if bytes.Equal(existing.ID, p.ID) {
+			return storage.ErrPasskeyExists
+		}
+	}
+	s.passkeys = append(s.passkeys, p)
+	return nil
+}
+
+func (s *fakeStorage) Passkeys(_ context.Context, userUUID string) ([]models.Passkey, error) {
+	var passkeys []models.Passkey
+	for _, p := range s.passkeys {
+		if p.UserUUID == userUUID {
+			passkeys = append(passkeys, p)
+		}
+	}
+	return passkeys, nil
+}
+
+func (s *fakeStorage) UpdatePasskeyUsage(_ context.Context, id []byte, signCount uint32, backupState bool) error {
+	for i, p := range s.passkeys {
+		if !bytes.Equal(p.ID, id) {
+			continue
+		}
+		if p.SignCount >= signCount && (p.SignCount != 0 || signCount != 0) {
+			return storage.ErrStaleSignCount
+		}
+		s.passkeys[i].SignCount, s.passkeys[i].BackupState = signCount, backupState
+		return nil
+	}
+	return storage.ErrPasskeyNotFound
+}
+
+func (s *fakeStorage) DeletePasskey(_ context.Context, userUUID string, id []byte) error {
+	for i, p := range s.passkeys {
+		if p.UserUUID == userUUID && bytes.Equal(p.ID, id) {
+			s.passkeys = append(s.passkeys[:i], s.passkeys[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrPasskeyNotFound
+}
+
+func (s *fakeStorage) SaveWebAuthnCeremony(_ context.Context, c models.WebAuthnCeremony) error {
+	s.ceremonies[c.ID] = c
+	return nil
+}
+
+func (s *fakeStorage) WebAuthnCeremony(_ context.Context, id string) (models.WebAuthnCeremony, error) {
+	c, ok := s.ceremonies[id]
+	if !ok {
+		return models.WebAuthnCeremony{}, storage.ErrCeremonyNotFound
+	}
+	return c, nil
+}
+
+func (s *fakeStorage) ConsumeWebAuthnCeremony(_ context.Context, id string) (models.WebAuthnCeremony, error) {
+	c, ok := s.ceremonies[id]
+	if !ok {
+		return models.WebAuthnCeremony{}, storage.ErrCeremonyNotFound
+	}
+	delete(s.ceremonies, id)
+	return c, nil
+}
+
+func (s *fakeStorage) UserByUUID(_ context.Context, uuid string) (models.User, error) {
+	u, ok := s.users[uuid]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *fakeStorage) AppByName(_ context.Context, name string) (models.App, error) {
+	if name != "admin" {
+		return models.App{}, storage.ErrAppNotFound
+	}
+	return models.App{ID: 1, Name: name}, nil
+}
+
+type fakeTokens struct{}
+
+func (fakeTokens) IssueToken(_ context.Context, user models.User, appName string) (string, string, error) {
+	return "token-" + user.UUID + "-" + appName, "session", nil
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, models.AuditEvent) {}
+
+func newTestService(t *testing.T) (*Passkey, *fakeStorage) {
+	t.Helper()
+
+	st := &fakeStorage{
+		users: map[string]models.User{
+			"user-1": {UUID: "user-1", Email: "alice@example.com", Status: models.UserStatusActive},
+			"user-2": {UUID: "user-2", Email: "bob@example.com", Status: models.UserStatusActive},
+		},
+		ceremonies: make(map[string]models.WebAuthnCeremony),
+	}
+
+	p, err := New(zap.NewNop().Sugar(), st, st, st, st, fakeTokens{}, nopAuditor{}, Config{
+		RPID:          testRPID,
+		RPDisplayName: "Example",
+		RPOrigins:     []string{testOrigin},
+		Timeout:       time.Minute,
+	})
+	require.NoError(t, err)
+
+	return p, st
+}
+
+// softAuthenticator программный аутентификатор с ключом P-256 и аттестацией none.
+type softAuthenticator struct {
+	key        *ecdsa.PrivateKey
+	id         []byte
+	userHandle []byte
+}
+
+func newSoftAuthenticator(t *testing.T, userUUID string) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+
+	return &softAuthenticator{key: key, id: id, userHandle: []byte(userUUID)}
+}
+
+// authData возвращает данные аутентификатора: хэш RP ID, флаги UP и UV и счетчик подписей.
+func (a *softAuthenticator) authData(flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+
+	data := append(rpIDHash[:], flags|0x01|0x04)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func clientData(t *testing.T, typ string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{"type": typ, "challenge": challenge.String(), "origin": testOrigin})
+	require.NoError(t, err)
+	return data
+}
+
+// create отвечает на параметры navigator.credentials.create.
+func (a *softAuthenticator) create(t *testing.T, options json.RawMessage) []byte {
+	t.Helper()
+
+	var creation protocol.CredentialCreation
+	require.NoError(t, json.Unmarshal(options, &creation))
+
+	pub, err := a.key.ECDH()
+	require.NoError(t, err)
+	point := pub.PublicKey().Bytes()
+
+	coseKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{KeyType: int64(webauthncose.EllipticKey), Algorithm: int64(webauthncose.AlgES256)},
+		Curve:         1,
+		XCoord:        point[1:33],
+		YCoord:        point[33:],
+	})
+	require.NoError(t, err)
+
+	authData := a.authData(0x40, 0)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.id)))
+	authData = append(authData, a.id...)
+	authData = append(authData, coseKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{"fmt": "none", "attStmt": map[string]any{}, "authData": authData})
+	require.NoError(t, err)
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    b64(clientData(t, "webauthn.create", creation.Response.Challenge)),
+		"attestationObject": b64(attestation),
+	})
+}
+
+// get отвечает на параметры navigator.credentials.get со счетчиком подписей signCount.
+func (a *softAuthenticator) get(t *testing.T, options json.RawMessage, signCount uint32) []byte {
+	t.Helper()
+
+	var assertion protocol.CredentialAssertion
+	require.NoError(t, json.Unmarshal(options, &assertion))
+
+	cd := clientData(t, "webauthn.get", assertion.Response.Challenge)
+	authData := a.authData(0, signCount)
+
+	cdHash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(bytes.Clone(authData), cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    b64(cd),
+		"authenticatorData": b64(authData),
+		"signature":         b64(sig),
+		"userHandle":        b64(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) response(t *testing.T, resp map[string]string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"id": b64(a.id), "rawId": b64(a.id), "type": "public-key", "response": resp})
+	require.NoError(t, err)
+	return data
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// register регистрирует ключ authenticator пользователю userUUID.
+func register(t *testing.T, p *Passkey, a *softAuthenticator, userUUID string) models.Passkey {
+	t.Helper()
+	ctx := context.Background()
+
+	c, err := p.BeginRegistration(ctx, userUUID)
+	require.NoError(t, err)
+
+	passkey, err := p.FinishRegistration(ctx, userUUID, c.ID, "YubiKey", a.create(t, c.Options))
+	require.NoError(t, err)
+
+	return passkey
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	p, st := newTestService(t)
+	ctx := context.Background()
+	a := newSoftAuthenticator(t, "user-1")
+
+	passkey := register(t, p, a, "user-1")
+	assert.Equal(t, a.id, passkey.ID)
+	assert.Equal(t, "YubiKey", passkey.Name)
+	require.Len(t, st.passkeys, 1)
+
+	c, err := p.BeginLogin(ctx, "admin")
+	require.NoError(t, err)
+
+	resp := a.get(t, c.Options, 1)
+	token, err := p.FinishLogin(ctx, c.ID, resp)
+	require.NoError(t, err)
+	assert.Equal(t, "token-user-1-admin", token)
+	assert.EqualValues(t, 1, st.passkeys[0].SignCount)
+
+	_, err = p.FinishLogin(ctx, c.ID, resp)
+	assert.ErrorIs(t, err, ErrInvalidCeremony, "ceremony is single-use")
+}
+
+func TestLoginRejectsStaleSignCount(t *testing.T) {
+	p, st := newTestService(t)
+	ctx := context.Background()
+	a := newSoftAuthenticator(t, "user-1")
+	register(t, p, a, "user-1")
+	st.passkeys[0].SignCount = 5
+
+	c, err := p.BeginLogin(ctx, "admin")
+	require.NoError(t, err)
+
+	_, err = p.FinishLogin(ctx, c.ID, a.get(t, c.Options, 5))
+	assert.ErrorIs(t, err, ErrInvalidCredential, "counter did not increase, authenticator may be cloned")
+	assert.EqualValues(t, 5, st.passkeys[0].SignCount)
+}
+
+func TestSecondFactor(t *testing.T) {
+	p, _ := newTestService(t)
+	ctx := context.Background()
+	alice := models.User{UUID: "user-1", Email: "alice@example.com", Status: models.UserStatusActive}
+
+	id, err := p.BeginSecondFactor(ctx, alice, "admin")
+	require.NoError(t, err)
+	assert.Empty(t, id, "no passkeys enrolled")
+
+	a := newSoftAuthenticator(t, "user-1")
+	register(t, p, a, "user-1")
+
+	id, err = p.BeginSecondFactor(ctx, alice, "admin")
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	options, err := p.Options(ctx, id)
+	require.NoError(t, err)
+	assert.Contains(t, string(options), b64(a.id), "options allow only the user's passkeys")
+
+	// Ключ другого пользователя не подходит для подтверждения входа alice.
+	other := newSoftAuthenticator(t, "user-2")
+	register(t, p, other, "user-2")
+	_, err = p.FinishLogin(ctx, id, other.get(t, options, 1))
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+
+	id, err = p.BeginSecondFactor(ctx, alice, "admin")
+	require.NoError(t, err)
+	options, err = p.Options(ctx, id)
+	require.NoError(t, err)
+
+	token, err := p.FinishLogin(ctx, id, a.get(t, options, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "token-user-1-admin", token)
+}
+
+func TestFinishRegistrationRejects(t *testing.T) {
+	p, _ := newTestService(t)
+	ctx := context.Background()
+	a := newSoftAuthenticator(t, "user-1")
+
+	c, err := p.BeginRegistration(ctx, "user-1")
+	require.NoError(t, err)
+	_, err = p.FinishRegistration(ctx, "user-2", c.ID, "", a.create(t, c.Options))
+	assert.ErrorIs(t, err, ErrInvalidCeremony, "ceremony of another user")
+
+	c, err = p.BeginRegistration(ctx, "user-1")
+	require.NoError(t, err)
+	_, err = p.FinishRegistration(ctx, "user-1", c.ID, "", []byte(`{"id":"x"}`))
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+
+	_, err = p.FinishRegistration(ctx, "user-1", c.ID, strings.Repeat("k", maxNameLength+1), a.create(t, c.Options))
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	register(t, p, a, "user-1")
+	c, err = p.BeginRegistration(ctx, "user-1")
+	require.NoError(t, err)
+	_, err = p.FinishRegistration(ctx, "user-1", c.ID, "", a.create(t, c.Options))
+	assert.ErrorIs(t, err, ErrPasskeyExists)
+}
+
+func TestLoginRejectsInactiveUser(t *testing.T) {
+	p, st := newTestService(t)
+	ctx := context.Background()
+	a := newSoftAuthenticator(t, "user-1")
+	register(t, p, a, "user-1")
+
+	u := st.users["user-1"]
+	u.Status = models.UserStatusDisabled
+	st.users["user-1"] = u
+
+	c, err := p.BeginLogin(ctx, "admin")
+	require.NoError(t, err)
+	_, err = p.FinishLogin(ctx, c.ID, a.get(t, c.Options, 1))
+	assert.ErrorIs(t, err, ErrUserInactive)
+
+	_, err = p.BeginLogin(ctx, "billing")
+	assert.ErrorIs(t, err, ErrInvalidApp)
+}
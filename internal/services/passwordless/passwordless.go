@@ -0,0 +1,468 @@
+// Package passwordless реализует вход без пароля: пользователь получает на email
+// одноразовую ссылку или шестизначный код и обменивает его на токен приложения.
+package passwordless
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/mailer"
+	"go-sso/internal/lib/ratelimit"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"math/big"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Passwordless сервис входа по одноразовым ссылкам и кодам.
+type Passwordless struct {
+	log *zap.SugaredLogger
+
+	challenges ChallengeStorage
+	users      UserProvider
+	apps       AppProvider
+	tokens     TokenIssuer
+	mailer     mailer.Mailer
+	limiter    ratelimit.Limiter
+	auditor    Auditor
+	emails     email.Normalizer
+
+	cfg atomic.Pointer[Config]
+	now func() time.Time
+
+	// deliveries письма, которые еще отправляются после ответа на Start.
+	deliveries sync.WaitGroup
+}
+
+// deliverTimeout сколько может длиться сохранение кода и отправка письма после ответа на Start.
+const deliverTimeout = 30 * time.Second
+
+// Config параметры входа без пароля.
+type Config struct {
+	CodeTTL time.Duration
+	LinkTTL time.Duration
+	// MaxAttempts сколько раз можно ввести неверный код, прежде чем он перестанет действовать.
+	MaxAttempts int
+	// SendLimit ограничение частоты писем на один адрес.
+	SendLimit ratelimit.Limit
+	// Apps приложения, в которых разрешен вход без пароля.
+	Apps map[string]AppConfig
+}
+
+// AppConfig вход без пароля в приложение.
+type AppConfig struct {
+	// Methods разрешенные способы: models.PasswordlessLink и models.PasswordlessCode.
+	Methods []string
+	// LinkURL страница приложения, на которую ведет ссылка из письма. К адресу
+	// добавляются параметры challenge_id и token, которые страница передает в Complete.
+	LinkURL string
+}
+
+type ChallengeStorage interface {
+	SavePasswordlessChallenge(ctx context.Context, c models.PasswordlessChallenge) error
+	AttemptPasswordlessChallenge(ctx context.Context, id string) (models.PasswordlessChallenge, error)
+	ConsumePasswordlessChallenge(ctx context.Context, id string) error
+}
+
+type UserProvider interface {
+	User(ctx context.Context, email string) (models.User, error)
+	UserByUUID(ctx context.Context, uuid string) (models.User, error)
+}
+
+type AppProvider interface {
+	AppByName(ctx context.Context, name string) (models.App, error)
+}
+
+// TokenIssuer создает сеанс и выпускает токен аутентифицированному пользователю.
+type TokenIssuer interface {
+	IssueToken(ctx context.Context, user models.User, appName string) (token, sessionID string, err error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Ошибки, которые могут возникнуть при входе без пароля.
+var (
+	ErrNotEnabled = errors.New("passwordless login is not enabled for the app")
+	ErrInvalidApp = errors.New("invalid app")
+	// ErrInvalidEmail адрес не похож на адрес электронной почты.
+	ErrInvalidEmail = errors.New("invalid email")
+	// ErrInvalidCode код или ссылка неверные, истекли, уже использованы или исчерпаны попытки.
+	ErrInvalidCode     = errors.New("invalid or expired code")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrUserInactive    = errors.New("user is not active")
+	ErrUnavailable     = errors.New("dependency unavailable")
+)
+
+// errUnknownRecipient причина в журнале аудита, по которой письмо не отправлено.
+var errUnknownRecipient = errors.New("no active user with this email")
+
+// RateLimitError письмо на адрес не отправлено из-за ограничения частоты.
+type RateLimitError struct {
+	// RetryAfter через сколько можно запросить письмо снова.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyRequests, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrTooManyRequests
+}
+
+// New возвращает новый экземпляр сервиса входа без пароля.
+func New(
+	log *zap.SugaredLogger,
+	challenges ChallengeStorage,
+	users UserProvider,
+	apps AppProvider,
+	tokens TokenIssuer,
+	mailer mailer.Mailer,
+	limiter ratelimit.Limiter,
+	auditor Auditor,
+	emails email.Normalizer,
+	cfg Config,
+) *Passwordless {
+	p := &Passwordless{
+		log: log,
+
+		challenges: challenges,
+		users:      users,
+		apps:       apps,
+		tokens:     tokens,
+		mailer:     mailer,
+		limiter:    limiter,
+		auditor:    auditor,
+		emails:     emails,
+
+		now: time.Now,
+	}
+
+	p.SetConfig(cfg)
+
+	return p
+}
+
+// SetConfig задает параметры входа без пароля.
+func (p *Passwordless) SetConfig(cfg Config) {
+	p.cfg.Store(&cfg)
+}
+
+// Start отправляет пользователю с адресом addr одноразовую ссылку или код для входа
+// в приложение appName и возвращает идентификатор, который передается в Complete.
+//
+// Чтобы по ответу нельзя было узнать, зарегистрирован ли адрес, для неизвестного
+// или неактивного пользователя возвращается такой же идентификатор, но письмо не отправляется.
+// По той же причине код сохраняется и письмо отправляется уже после ответа: ни время
+// ответа, ни ошибка почтового сервера не зависят от того, есть ли такой пользователь.
+func (p *Passwordless) Start(ctx context.Context, addr, appName, method string) (challengeID string, err error) {
+	const op = "passwordless.Start"
+
+	log := p.log.With("op", op, "email", addr, "appName", appName, "method", method)
+
+	// skipped причина, по которой письмо не отправлено, хотя вызывающий получил успешный ответ.
+	var skipped error
+	defer func() {
+		reason := err
+		if skipped != nil {
+			reason = skipped
+		}
+
+		e := audit.Event(models.AuditPasswordlessStart, reason)
+		e.Actor, e.Subject, e.App = addr, addr, appName
+		e.Details = map[string]string{"method": method}
+		p.auditor.Record(ctx, e)
+	}()
+
+	cfg := p.cfg.Load()
+	appCfg, ok := cfg.Apps[appName]
+	if !ok || !slices.Contains(appCfg.Methods, method) {
+		return "", fmt.Errorf("%s: %w: method %q", op, ErrNotEnabled, method)
+	}
+
+	normalized, err := p.emails.Normalize(addr)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+	addr = normalized
+
+	if _, err := p.apps.AppByName(ctx, appName); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return "", fmt.Errorf("%s: %w: app %q is not registered", op, ErrInvalidApp, appName)
+		}
+
+		return "", handleStorageErr(log, "failed to get app", op, err)
+	}
+
+	// Ограничение действует и для незарегистрированных адресов: иначе по нему
+	// можно было бы отличить их от зарегистрированных.
+	allowed, retryAfter, err := p.limiter.Allow(ctx, "passwordless:"+addr, cfg.SendLimit)
+	if err != nil {
+		log.Warnw("rate limiter failed, request allowed", "error", err)
+	} else if !allowed {
+		return "", fmt.Errorf("%s: %w", op, &RateLimitError{RetryAfter: retryAfter})
+	}
+
+	if challengeID, err = randomString(16); err != nil {
+		return "", handleInternalErr(log, "failed to generate challenge id", op, err)
+	}
+
+	user, err := p.users.User(ctx, addr)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		log.Infow("passwordless login requested for unknown email")
+		skipped = fmt.Errorf("%w: user not found", errUnknownRecipient)
+
+		return challengeID, nil
+	}
+	if err != nil {
+		return "", handleStorageErr(log, "failed to get user", op, err)
+	}
+	if user.Status != models.UserStatusActive {
+		log.Infow("passwordless login requested for inactive user", "userUUID", user.UUID, "status", user.Status)
+		skipped = fmt.Errorf("%w: user is %s", errUnknownRecipient, user.Status)
+
+		return challengeID, nil
+	}
+
+	ttl := cfg.CodeTTL
+	if method == models.PasswordlessLink {
+		ttl = cfg.LinkTTL
+	}
+
+	secret, msg, err := p.message(method, challengeID, appCfg, ttl)
+	if err != nil {
+		return "", handleInternalErr(log, "failed to generate secret", op, err)
+	}
+	msg.To = user.Email
+
+	c := models.PasswordlessChallenge{
+		ID:         challengeID,
+		UserUUID:   user.UUID,
+		App:        appName,
+		Method:     method,
+		SecretHash: hashSecret(secret),
+		ExpiresAt:  p.now().Add(ttl),
+	}
+
+	p.deliveries.Add(1)
+	go func() {
+		defer p.deliveries.Done()
+		p.deliver(ctx, log, c, msg)
+	}()
+
+	return challengeID, nil
+}
+
+// deliver сохраняет ссылку или код и отправляет письмо. Ошибки только записываются
+// в журнал: вызывающий уже получил ответ.
+func (p *Passwordless) deliver(ctx context.Context, log *zap.SugaredLogger, c models.PasswordlessChallenge, msg mailer.Message) {
+	// WithoutCancel сохраняет арендатора и прочие значения ctx.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deliverTimeout)
+	defer cancel()
+
+	if err := p.challenges.SavePasswordlessChallenge(ctx, c); err != nil {
+		log.Errorw("failed to save challenge", "error", err)
+		return
+	}
+
+	if err := p.mailer.Send(ctx, msg); err != nil {
+		log.Errorw("failed to send email", "error", err)
+		return
+	}
+
+	log.Infow("passwordless login started", "userUUID", c.UserUUID)
+}
+
+// Close дожидается отправки писем, запрошенных до остановки сервиса.
+func (p *Passwordless) Close() error {
+	p.deliveries.Wait()
+	return nil
+}
+
+// message создает секрет и письмо с ним.
+func (p *Passwordless) message(method, challengeID string, appCfg AppConfig, ttl time.Duration) (string, mailer.Message, error) {
+	if method == models.PasswordlessCode {
+		code, err := randomCode()
+		if err != nil {
+			return "", mailer.Message{}, err
+		}
+
+		return code, mailer.Message{
+			Subject: "Your sign-in code",
+			Body: fmt.Sprintf("Your sign-in code is %s.\n\nIt expires in %s. "+
+				"If you did not request it, ignore this email.\n", code, ttl),
+		}, nil
+	}
+
+	token, err := randomString(32)
+	if err != nil {
+		return "", mailer.Message{}, err
+	}
+
+	link, err := url.Parse(appCfg.LinkURL)
+	if err != nil {
+		return "", mailer.Message{}, fmt.Errorf("parse link_url: %w", err)
+	}
+	q := link.Query()
+	q.Set("challenge_id", challengeID)
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	return token, mailer.Message{
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Follow the link to sign in:\n\n%s\n\nIt expires in %s and can be used once. "+
+			"If you did not request it, ignore this email.\n", link, ttl),
+	}, nil
+}
+
+// Complete проверяет код или токен из ссылки и выпускает токен приложения, для которого
+// был начат вход. Ссылка или код действуют один раз; после MaxAttempts неверных попыток
+// код перестает действовать.
+func (p *Passwordless) Complete(ctx context.Context, challengeID, secret string) (token string, err error) {
+	const op = "passwordless.Complete"
+
+	log := p.log.With("op", op, "challengeID", challengeID)
+
+	var (
+		c    models.PasswordlessChallenge
+		user models.User
+	)
+	defer func() {
+		// До загрузки пользователя известен только его UUID из записи.
+		subject := user.Email
+		if subject == "" {
+			subject = c.UserUUID
+		}
+
+		e := audit.Event(models.AuditPasswordlessLogin, err)
+		e.Actor, e.Subject, e.App = subject, subject, c.App
+		e.Details = map[string]string{"method": c.Method}
+		p.auditor.Record(ctx, e)
+	}()
+
+	if challengeID == "" || secret == "" {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	c, err = p.challenges.AttemptPasswordlessChallenge(ctx, challengeID)
+	if errors.Is(err, storage.ErrChallengeNotFound) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+	if err != nil {
+		return "", handleStorageErr(log, "failed to get challenge", op, err)
+	}
+
+	if c.Attempts > p.cfg.Load().MaxAttempts {
+		if err := p.challenges.ConsumePasswordlessChallenge(ctx, c.ID); err != nil && !errors.Is(err, storage.ErrChallengeNotFound) {
+			log.Warnw("failed to delete challenge", "error", err)
+		}
+
+		return "", fmt.Errorf("%s: %w: too many attempts", op, ErrInvalidCode)
+	}
+
+	if subtle.ConstantTimeCompare(hashSecret(secret), c.SecretHash) != 1 {
+		log.Infow("wrong passwordless secret", "attempts", c.Attempts)
+		return "", fmt.Errorf("%s: %w: wrong secret", op, ErrInvalidCode)
+	}
+
+	// Удаление — граница одноразовости: из параллельных запросов с верным секретом
+	// токен получит только тот, кто удалил запись.
+	err = p.challenges.ConsumePasswordlessChallenge(ctx, c.ID)
+	if errors.Is(err, storage.ErrChallengeNotFound) {
+		return "", fmt.Errorf("%s: %w: already used", op, ErrInvalidCode)
+	}
+	if err != nil {
+		return "", handleStorageErr(log, "failed to consume challenge", op, err)
+	}
+
+	user, err = p.users.UserByUUID(ctx, c.UserUUID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+	if err != nil {
+		return "", handleStorageErr(log, "failed to get user", op, err)
+	}
+
+	if user.Status != models.UserStatusActive {
+		log.Infow("user is not active", "userUUID", user.UUID, "status", user.Status)
+
+		return "", fmt.Errorf("%s: %w: user is %s", op, ErrUserInactive, user.Status)
+	}
+
+	// Ссылка из письма не передает арендатора: он берется у пользователя, которому отправлен код.
+	ctx = tenant.With(ctx, user.Tenant)
+
+	token, _, err = p.tokens.IssueToken(ctx, user, c.App)
+	if errors.Is(err, auth.ErrUnavailable) {
+		return "", fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+	if errors.Is(err, auth.ErrConsentRequired) || errors.Is(err, auth.ErrInvalidScope) {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err != nil {
+		return "", handleInternalErr(log, "failed to issue token", op, err)
+	}
+
+	log.Infow("user logged in without password", "userUUID", user.UUID, "method", c.Method)
+
+	return token, nil
+}
+
+// hashSecret хэш секрета для хранения. Секреты случайные, поэтому соль не нужна.
+func hashSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// randomString возвращает n случайных байт в base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// randomCode возвращает случайный шестизначный код.
+func randomCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// handleStorageErr переводит ошибки хранилища в ошибки сервиса и логгирует их.
+func handleStorageErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Errorw("storage unavailable", "error", err)
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	default:
+		return handleInternalErr(log, msg, op, err)
+	}
+}
+
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+	return fmt.Errorf("%s: %w", op, err)
+}
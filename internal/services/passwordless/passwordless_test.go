@@ -0,0 +1,285 @@
+package passwordless
+
+import (
+	"context"
+	"errors"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/email"
+	"go-sso/internal/lib/mailer"
+	"go-sso/internal/lib/ratelimit"
+	"go-sso/internal/storage"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStorage хранит пользователей и одноразовые коды в памяти.
+type fakeStorage struct {
+	users      map[string]models.User
+	challenges map[string]models.PasswordlessChallenge
+}
+
+func (s *fakeStorage) SavePasswordlessChallenge(_ context.Context, c models.PasswordlessChallenge) error {
+	for id, old := range s.challenges {
+		if old.UserUUID == c.UserUUID && old.App == c.App {
+			delete(s.challenges, id)
+		}
+	}
+	s.challenges[c.ID] = c
+	return nil
+}
+
+func (s *fakeStorage) AttemptPasswordlessChallenge(_ context.Context, id string) (models.PasswordlessChallenge, error) {
+	c, ok := s.challenges[id]
+	if !ok || !time.Now().Before(c.ExpiresAt) {
+		return models.PasswordlessChallenge{}, storage.ErrChallengeNotFound
+	}
+	c.Attempts++
+	s.challenges[id] = c
+	return c, nil
+}
+
+func (s *fakeStorage) ConsumePasswordlessChallenge(_ context.Context, id string) error {
+	if _, ok := s.challenges[id]; !ok {
+		return storage.ErrChallengeNotFound
+	}
+	delete(s.challenges, id)
+	return nil
+}
+
+func (s *fakeStorage) User(_ context.Context, addr string) (models.User, error) {
+	for _, u := range s.users {
+		if u.Email == addr {
+			return u, nil
+		}
+	}
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (s *fakeStorage) UserByUUID(_ context.Context, uuid string) (models.User, error) {
+	u, ok := s.users[uuid]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *fakeStorage) AppByName(_ context.Context, name string) (models.App, error) {
+	if name != "shop" {
+		return models.App{}, storage.ErrAppNotFound
+	}
+	return models.App{ID: 1, Name: name}, nil
+}
+
+type fakeTokens struct{}
+
+func (fakeTokens) IssueToken(_ context.Context, user models.User, appName string) (string, string, error) {
+	return "token-" + user.UUID + "-" + appName, "session", nil
+}
+
+// fakeMailer запоминает отправленные письма или возвращает err.
+type fakeMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, models.AuditEvent) {}
+
+func newTestService() (*Passwordless, *fakeStorage, *fakeMailer) {
+	st := &fakeStorage{
+		users: map[string]models.User{
+			"user-1": {UUID: "user-1", Email: "alice@example.com", Status: models.UserStatusActive},
+			"user-2": {UUID: "user-2", Email: "bob@example.com", Status: models.UserStatusDisabled},
+		},
+		challenges: make(map[string]models.PasswordlessChallenge),
+	}
+	m := &fakeMailer{}
+
+	p := New(zap.NewNop().Sugar(), st, st, st, fakeTokens{}, m, ratelimit.NewMemory(), nopAuditor{}, email.Normalizer{}, Config{
+		CodeTTL:     10 * time.Minute,
+		LinkTTL:     15 * time.Minute,
+		MaxAttempts: 3,
+		SendLimit:   ratelimit.Limit{Rate: 1, Burst: 2},
+		Apps: map[string]AppConfig{
+			"shop": {
+				Methods: []string{models.PasswordlessCode, models.PasswordlessLink},
+				LinkURL: "https://shop.example.com/login/callback?from=email",
+			},
+		},
+	})
+
+	return p, st, m
+}
+
+var codeRe = regexp.MustCompile(`\b\d{6}\b`)
+
+func TestCodeLogin(t *testing.T) {
+	p, st, m := newTestService()
+	ctx := context.Background()
+
+	id, err := p.Start(ctx, "alice@Example.COM", "shop", models.PasswordlessCode)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, "alice@example.com", m.sent[0].To)
+
+	code := codeRe.FindString(m.sent[0].Body)
+	require.NotEmpty(t, code)
+	assert.NotContains(t, string(st.challenges[id].SecretHash), code, "only the hash is stored")
+
+	token, err := p.Complete(ctx, id, code)
+	require.NoError(t, err)
+	assert.Equal(t, "token-user-1-shop", token)
+
+	_, err = p.Complete(ctx, id, code)
+	assert.ErrorIs(t, err, ErrInvalidCode, "code is single-use")
+}
+
+func TestLinkLogin(t *testing.T) {
+	p, _, m := newTestService()
+	ctx := context.Background()
+
+	id, err := p.Start(ctx, "alice@example.com", "shop", models.PasswordlessLink)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+	require.Len(t, m.sent, 1)
+
+	raw := regexp.MustCompile(`https://\S+`).FindString(m.sent[0].Body)
+	link, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "/login/callback", link.Path)
+	assert.Equal(t, "email", link.Query().Get("from"))
+	assert.Equal(t, id, link.Query().Get("challenge_id"))
+
+	token, err := p.Complete(ctx, id, link.Query().Get("token"))
+	require.NoError(t, err)
+	assert.Equal(t, "token-user-1-shop", token)
+}
+
+func TestStartDoesNotRevealUnknownEmail(t *testing.T) {
+	p, st, m := newTestService()
+	ctx := context.Background()
+
+	for _, addr := range []string{"nobody@example.com", "bob@example.com"} {
+		id, err := p.Start(ctx, addr, "shop", models.PasswordlessCode)
+		require.NoError(t, err, addr)
+		assert.NotEmpty(t, id)
+		require.NoError(t, p.Close())
+
+		_, err = p.Complete(ctx, id, "000000")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	assert.Empty(t, m.sent)
+	assert.Empty(t, st.challenges)
+}
+
+func TestStartDoesNotRevealMailerFailure(t *testing.T) {
+	p, st, m := newTestService()
+	m.err = errors.New("smtp is down")
+	ctx := context.Background()
+
+	id, err := p.Start(ctx, "alice@example.com", "shop", models.PasswordlessCode)
+	require.NoError(t, err, "the caller gets the same answer as for an unknown email")
+	assert.NotEmpty(t, id)
+	require.NoError(t, p.Close())
+
+	assert.Empty(t, m.sent)
+	assert.Contains(t, st.challenges, id)
+}
+
+func TestStartReplacesPreviousCode(t *testing.T) {
+	p, st, m := newTestService()
+	ctx := context.Background()
+
+	first, err := p.Start(ctx, "alice@example.com", "shop", models.PasswordlessCode)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+	second, err := p.Start(ctx, "alice@example.com", "shop", models.PasswordlessCode)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+	require.Len(t, m.sent, 2)
+
+	assert.NotContains(t, st.challenges, first, "a new code does not add attempts to the old one")
+	assert.Contains(t, st.challenges, second)
+}
+
+func TestStartRejects(t *testing.T) {
+	p, _, _ := newTestService()
+	ctx := context.Background()
+
+	_, err := p.Start(ctx, "alice@example.com", "billing", models.PasswordlessCode)
+	assert.ErrorIs(t, err, ErrNotEnabled, "app is not configured")
+
+	_, err = p.Start(ctx, "alice@example.com", "shop", "sms")
+	assert.ErrorIs(t, err, ErrNotEnabled, "unknown method")
+
+	_, err = p.Start(ctx, "not-an-email", "shop", models.PasswordlessCode)
+	assert.ErrorIs(t, err, ErrInvalidEmail)
+
+	for range 2 {
+		_, err = p.Start(ctx, "carol@example.com", "shop", models.PasswordlessCode)
+		require.NoError(t, err)
+	}
+	_, err = p.Start(ctx, "carol@example.com", "shop", models.PasswordlessCode)
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+
+	var rlErr *RateLimitError
+	require.ErrorAs(t, err, &rlErr)
+	assert.Positive(t, rlErr.RetryAfter)
+}
+
+func TestCompleteAttemptLimit(t *testing.T) {
+	p, st, m := newTestService()
+	ctx := context.Background()
+
+	id, err := p.Start(ctx, "alice@example.com", "shop", models.PasswordlessCode)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+	code := codeRe.FindString(m.sent[0].Body)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for range 3 {
+		_, err = p.Complete(ctx, id, wrong)
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	_, err = p.Complete(ctx, id, code)
+	assert.ErrorIs(t, err, ErrInvalidCode, "attempts are exhausted")
+	assert.Empty(t, st.challenges)
+}
+
+func TestCompleteExpired(t *testing.T) {
+	p, st, m := newTestService()
+	ctx := context.Background()
+
+	id, err := p.Start(ctx, "alice@example.com", "shop", models.PasswordlessCode)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+
+	c := st.challenges[id]
+	c.ExpiresAt = time.Now().Add(-time.Second)
+	st.challenges[id] = c
+
+	_, err = p.Complete(ctx, id, codeRe.FindString(m.sent[0].Body))
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
@@ -0,0 +1,191 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/audit"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/services/auth"
+	"go-sso/internal/storage"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Sessions сервис просмотра и отзыва сеансов пользователей.
+type Sessions struct {
+	log *zap.SugaredLogger
+
+	storage SessionStorage
+	keys    SigningKeyProvider
+	auditor Auditor
+	issuer  string
+
+	now func() time.Time
+}
+
+type SessionStorage interface {
+	Session(ctx context.Context, id string) (models.Session, error)
+	Sessions(ctx context.Context, userUUID string) ([]models.Session, error)
+	TouchSession(ctx context.Context, id string) error
+	RevokeSession(ctx context.Context, userUUID, id string) error
+	RevokeSessions(ctx context.Context, userUUID string) (int64, error)
+}
+
+type SigningKeyProvider interface {
+	Key(ctx context.Context, appName string) (string, error)
+}
+
+// Auditor записывает события аудита. Ошибки записи обрабатывает сама реализация.
+type Auditor interface {
+	Record(ctx context.Context, e models.AuditEvent)
+}
+
+// Ошибки, которые могут возникнуть при работе с сервисом сеансов.
+var (
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrSessionNotFound = errors.New("session not found")
+	ErrUnavailable     = errors.New("dependency unavailable")
+)
+
+// New возвращает новый экземпляр сервиса сеансов.
+func New(
+	log *zap.SugaredLogger,
+	sessionStorage SessionStorage,
+	keys SigningKeyProvider,
+	auditor Auditor,
+	issuer string,
+) *Sessions {
+	return &Sessions{
+		log:     log,
+		storage: sessionStorage,
+		keys:    keys,
+		auditor: auditor,
+		issuer:  issuer,
+		now:     time.Now,
+	}
+}
+
+// Authenticate проверяет токен и возвращает его активный сеанс и claims.
+// Токен отклоняется, если его сеанс отозван или истек, даже при верной подписи.
+func (s *Sessions) Authenticate(ctx context.Context, token string) (models.Session, *jwt.Claims, error) {
+	const op = "session.Authenticate"
+
+	log := s.log.With("op", op)
+
+	sid, err := jwt.SessionID(token)
+	if err != nil {
+		return models.Session{}, nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	session, err := s.storage.Session(ctx, sid)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return models.Session{}, nil, fmt.Errorf("%s: %w: unknown session", op, ErrInvalidToken)
+	}
+	if err != nil {
+		return models.Session{}, nil, handleInternalErr(log, "failed to get session", op, err)
+	}
+
+	// Токен подписан ключом приложения арендатора сеанса, а не арендатора запроса.
+	key, err := s.keys.Key(tenant.With(ctx, session.Tenant), session.App)
+	if errors.Is(err, auth.ErrKeyNotFound) {
+		return models.Session{}, nil, fmt.Errorf("%s: %w: no signing key", op, ErrInvalidToken)
+	}
+	if err != nil {
+		return models.Session{}, nil, handleInternalErr(log, "failed to get signing key", op, err)
+	}
+
+	claims, err := jwt.Verify(token, key, s.issuer, session.App)
+	if err != nil {
+		return models.Session{}, nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	if claims.Subject != session.UserUUID {
+		return models.Session{}, nil, fmt.Errorf("%s: %w: session belongs to another user", op, ErrInvalidToken)
+	}
+
+	if !session.Active(s.now()) {
+		return models.Session{}, nil, fmt.Errorf("%s: %w: session is revoked or expired", op, ErrInvalidToken)
+	}
+
+	if err := s.storage.TouchSession(ctx, session.ID); err != nil {
+		log.Warnw("failed to update session last seen time", "sid", session.ID, "error", err)
+	}
+
+	return session, claims, nil
+}
+
+// List возвращает активные сеансы пользователя.
+func (s *Sessions) List(ctx context.Context, userUUID string) ([]models.Session, error) {
+	const op = "session.List"
+
+	sessions, err := s.storage.Sessions(ctx, userUUID)
+	if err != nil {
+		return nil, handleInternalErr(s.log.With("op", op, "userUUID", userUUID), "failed to list sessions", op, err)
+	}
+
+	return sessions, nil
+}
+
+// Revoke отзывает сеанс пользователя. Выданные в нем токены перестают приниматься.
+func (s *Sessions) Revoke(ctx context.Context, userUUID, id string) (err error) {
+	const op = "session.Revoke"
+
+	log := s.log.With("op", op, "userUUID", userUUID, "sid", id)
+
+	defer func() {
+		e := audit.Event(models.AuditSessionRevoke, err)
+		e.Subject = userUUID
+		e.Details = map[string]string{"sid": id}
+		s.auditor.Record(ctx, e)
+	}()
+
+	err = s.storage.RevokeSession(ctx, userUUID, id)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+	}
+	if err != nil {
+		return handleInternalErr(log, "failed to revoke session", op, err)
+	}
+
+	log.Infow("session revoked")
+
+	return nil
+}
+
+// RevokeAll отзывает все активные сеансы пользователя и возвращает их количество.
+func (s *Sessions) RevokeAll(ctx context.Context, userUUID string) (n int64, err error) {
+	const op = "session.RevokeAll"
+
+	log := s.log.With("op", op, "userUUID", userUUID)
+
+	defer func() {
+		e := audit.Event(models.AuditSessionRevokeAll, err)
+		e.Subject = userUUID
+		e.Details = map[string]string{"revoked": strconv.FormatInt(n, 10)}
+		s.auditor.Record(ctx, e)
+	}()
+
+	n, err = s.storage.RevokeSessions(ctx, userUUID)
+	if err != nil {
+		return 0, handleInternalErr(log, "failed to revoke sessions", op, err)
+	}
+
+	log.Infow("sessions revoked", "count", n)
+
+	return n, nil
+}
+
+// handleInternalErr логгирует ошибку и переводит недоступность зависимостей в ErrUnavailable.
+func handleInternalErr(log *zap.SugaredLogger, msg, op string, err error) error {
+	log.Errorw(msg, "error", err)
+
+	if errors.Is(err, storage.ErrUnavailable) || errors.Is(err, auth.ErrUnavailable) {
+		return fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"context"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/jwt"
+	"go-sso/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeStorage struct {
+	sessions map[string]models.Session
+	touched  []string
+}
+
+func (f *fakeStorage) Session(_ context.Context, id string) (models.Session, error) {
+	s, ok := f.sessions[id]
+	if !ok {
+		return models.Session{}, storage.ErrSessionNotFound
+	}
+	return s, nil
+}
+
+func (f *fakeStorage) Sessions(context.Context, string) ([]models.Session, error) { return nil, nil }
+
+func (f *fakeStorage) TouchSession(_ context.Context, id string) error {
+	f.touched = append(f.touched, id)
+	return nil
+}
+
+func (f *fakeStorage) RevokeSession(context.Context, string, string) error { return nil }
+
+func (f *fakeStorage) RevokeSessions(context.Context, string) (int64, error) { return 0, nil }
+
+type fakeKeys map[string]string
+
+func (k fakeKeys) Key(_ context.Context, app string) (string, error) { return k[app], nil }
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, models.AuditEvent) {}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+
+	st := &fakeStorage{sessions: map[string]models.Session{
+		"active":  {ID: "active", UserUUID: "u1", App: "billing", ExpiresAt: now.Add(time.Hour)},
+		"revoked": {ID: "revoked", UserUUID: "u1", App: "billing", ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt},
+		"foreign": {ID: "foreign", UserUUID: "u2", App: "billing", ExpiresAt: now.Add(time.Hour)},
+	}}
+	svc := New(zap.NewNop().Sugar(), st, fakeKeys{"billing": "secret"}, nopAuditor{}, "sso")
+
+	token := func(sid, secret string) string {
+		tok, err := jwt.NewToken(models.User{UUID: "u1"}, jwt.TokenOptions{
+			Issuer:    "sso",
+			App:       "billing",
+			SessionID: sid,
+			TTL:       time.Hour,
+		}, secret)
+		require.NoError(t, err)
+		return tok
+	}
+
+	s, claims, err := svc.Authenticate(context.Background(), token("active", "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "active", s.ID)
+	assert.Equal(t, "u1", claims.Subject)
+	assert.Equal(t, []string{"active"}, st.touched)
+
+	for name, tok := range map[string]string{
+		"revoked":   token("revoked", "secret"),
+		"foreign":   token("foreign", "secret"),
+		"unknown":   token("missing", "secret"),
+		"bad key":   token("active", "other"),
+		"malformed": "garbage",
+	} {
+		_, _, err := svc.Authenticate(context.Background(), tok)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+}
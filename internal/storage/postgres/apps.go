@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// appColumns колонки apps в порядке, ожидаемом scanApp.
+const appColumns = `id, tenant, name, COALESCE(secret, '')`
+
+func scanApp(row scanner, app *models.App) error {
+	return row.Scan(&app.ID, &app.Tenant, &app.Name, &app.Secret)
+}
+
+// SaveApp регистрирует приложение арендатора из ctx с заданным именем.
+func (s *Storage) SaveApp(ctx context.Context, name string) (models.App, error) {
+	const op = "storage.postgres.SaveApp"
+
+	query := `
+		INSERT INTO apps (name, tenant)
+		VALUES ($1, $2)
+		RETURNING ` + appColumns
+
+	var app models.App
+	err := s.write(ctx, func(ctx context.Context) error {
+		return scanApp(s.db.QueryRowContext(ctx, query, name, tenant.From(ctx)), &app)
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrUniqueViolation {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+		}
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrTenantNotFound)
+		}
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+// AppByName возвращает приложение арендатора из ctx по имени.
+func (s *Storage) AppByName(ctx context.Context, name string) (models.App, error) {
+	const op = "storage.postgres.AppByName"
+
+	query := `
+		SELECT ` + appColumns + `
+		FROM apps
+		WHERE name = $1 AND tenant = $2`
+
+	var app models.App
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanApp(s.db.QueryRowContext(ctx, query, name, tenant.From(ctx)), &app)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+// Apps возвращает все приложения арендатора из ctx.
+func (s *Storage) Apps(ctx context.Context) ([]models.App, error) {
+	const op = "storage.postgres.Apps"
+
+	query := `
+		SELECT ` + appColumns + `
+		FROM apps
+		WHERE tenant = $1
+		ORDER BY id`
+
+	var apps []models.App
+	err := s.read(ctx, func(ctx context.Context) error {
+		rows, err := s.db.QueryContext(ctx, query, tenant.From(ctx))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		apps = apps[:0]
+		for rows.Next() {
+			var app models.App
+			if err := scanApp(rows, &app); err != nil {
+				return err
+			}
+			apps = append(apps, app)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return apps, nil
+}
+
+// DeleteApp удаляет приложение арендатора из ctx и назначенные в нем роли.
+func (s *Storage) DeleteApp(ctx context.Context, name string) error {
+	const op = "storage.postgres.DeleteApp"
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		// Согласия хранятся по имени приложения и без внешнего ключа, поэтому удаляются явно.
+		res, err := s.db.ExecContext(ctx, `
+			WITH removed_consents AS (
+				DELETE FROM consents c
+				USING users u
+				WHERE c.user_uuid = u.uuid AND c.app = $1 AND u.tenant = $2
+			)
+			DELETE FROM apps WHERE name = $1 AND tenant = $2`, name, tenant.From(ctx))
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	return nil
+}
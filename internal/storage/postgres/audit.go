@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"strings"
+)
+
+// auditColumns колонки audit_log в порядке, ожидаемом queryAuditEvents.
+const auditColumns = `id, created_at, event, outcome, actor, subject, app, ip, user_agent, reason, details`
+
+// SaveAuditEvent добавляет событие в журнал аудита арендатора из ctx.
+func (s *Storage) SaveAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	const op = "storage.postgres.SaveAuditEvent"
+
+	details := []byte("{}")
+	if len(e.Details) > 0 {
+		var err error
+		details, err = json.Marshal(e.Details)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	query := `
+		INSERT INTO audit_log (created_at, event, outcome, actor, subject, app, ip, user_agent, reason, details, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query,
+			e.Time, e.Type, e.Outcome, e.Actor, e.Subject, e.App, e.IP, e.UserAgent, e.Reason, details, tenant.From(ctx))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AuditEvents возвращает события аудита, подходящие под фильтр, от новых к старым.
+func (s *Storage) AuditEvents(ctx context.Context, f models.AuditFilter) ([]models.AuditEvent, error) {
+	const op = "storage.postgres.AuditEvents"
+
+	query, args := auditQuery(f)
+
+	var events []models.AuditEvent
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		events, err = queryAuditEvents(ctx, s.db, query, args...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+// auditQuery строит запрос выборки событий аудита по фильтру.
+func auditQuery(f models.AuditFilter) (string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.Type != "" {
+		add("event = $%d", f.Type)
+	}
+	if f.Outcome != "" {
+		add("outcome = $%d", f.Outcome)
+	}
+	if f.Actor != "" {
+		add("actor = $%d", f.Actor)
+	}
+	if f.Subject != "" {
+		add("subject = $%d", f.Subject)
+	}
+	if f.App != "" {
+		add("app = $%d", f.App)
+	}
+	if !f.Since.IsZero() {
+		add("created_at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("created_at < $%d", f.Until)
+	}
+	if f.BeforeID > 0 {
+		add("id < $%d", f.BeforeID)
+	}
+
+	var b strings.Builder
+	b.WriteString(`
+		SELECT ` + auditColumns + `
+		FROM audit_log`)
+
+	if len(where) > 0 {
+		b.WriteString("\n\t\tWHERE ")
+		b.WriteString(strings.Join(where, " AND "))
+	}
+
+	b.WriteString("\n\t\tORDER BY id DESC")
+
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		fmt.Fprintf(&b, "\n\t\tLIMIT $%d", len(args))
+	}
+
+	return b.String(), args
+}
+
+// queryAuditEvents выполняет запрос, выбирающий auditColumns.
+func queryAuditEvents(ctx context.Context, q querier, query string, args ...any) ([]models.AuditEvent, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var (
+			e       models.AuditEvent
+			details []byte
+		)
+		err := rows.Scan(&e.ID, &e.Time, &e.Type, &e.Outcome, &e.Actor, &e.Subject,
+			&e.App, &e.IP, &e.UserAgent, &e.Reason, &details)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(details, &e.Details); err != nil {
+			return nil, err
+		}
+		if len(e.Details) == 0 {
+			e.Details = nil
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
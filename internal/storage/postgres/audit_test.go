@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"go-sso/internal/domain/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditQuery(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args := auditQuery(models.AuditFilter{
+		Type:     models.AuditLogin,
+		Outcome:  models.AuditFailure,
+		Since:    since,
+		BeforeID: 42,
+		Limit:    10,
+	})
+
+	assert.Contains(t, query, "WHERE event = $1 AND outcome = $2 AND created_at >= $3 AND id < $4")
+	assert.True(t, strings.HasSuffix(query, "ORDER BY id DESC\n\t\tLIMIT $5"))
+	assert.Equal(t, []any{models.AuditLogin, models.AuditFailure, since, int64(42), 10}, args)
+}
+
+func TestAuditQueryWithoutFilter(t *testing.T) {
+	query, args := auditQuery(models.AuditFilter{})
+
+	assert.NotContains(t, query, "WHERE")
+	assert.NotContains(t, query, "LIMIT")
+	assert.Empty(t, args)
+}
@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// clientColumns колонки clients и apps в порядке, ожидаемом scanClient.
+const clientColumns = `c.id, c.app_id, a.tenant, a.name, c.name, c.scopes, c.secret_hash, c.previous_secret_hash,
+	c.previous_secret_expires_at, c.created_at, c.secret_rotated_at`
+
+func scanClient(row scanner, c *models.Client) error {
+	var previousExpiresAt, rotatedAt sql.NullTime
+
+	err := row.Scan(&c.ID, &c.AppID, &c.Tenant, &c.App, &c.Name, pq.Array(&c.Scopes), &c.SecretHash, &c.PreviousSecretHash,
+		&previousExpiresAt, &c.CreatedAt, &rotatedAt)
+	if err != nil {
+		return err
+	}
+
+	c.PreviousSecretExpiresAt, c.SecretRotatedAt = nil, nil
+	if previousExpiresAt.Valid {
+		c.PreviousSecretExpiresAt = &previousExpiresAt.Time
+	}
+	if rotatedAt.Valid {
+		c.SecretRotatedAt = &rotatedAt.Time
+	}
+
+	return nil
+}
+
+// SaveClient регистрирует клиента приложения c.App арендатора из ctx.
+func (s *Storage) SaveClient(ctx context.Context, c models.Client) (models.Client, error) {
+	const op = "storage.postgres.SaveClient"
+
+	query := `
+		WITH c AS (
+			INSERT INTO clients (id, app_id, name, scopes, secret_hash)
+			SELECT $1, id, $3, $4, $5 FROM apps WHERE name = $2 AND tenant = $6
+			RETURNING *
+		)
+		SELECT ` + clientColumns + `
+		FROM c JOIN apps a ON a.id = c.app_id`
+
+	var saved models.Client
+	err := s.write(ctx, func(ctx context.Context) error {
+		return scanClient(s.db.QueryRowContext(ctx, query, c.ID, c.App, c.Name, pq.Array(c.Scopes), c.SecretHash,
+			tenant.From(ctx)), &saved)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Client{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrUniqueViolation {
+			return models.Client{}, fmt.Errorf("%s: %w", op, storage.ErrClientExists)
+		}
+
+		return models.Client{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// Client возвращает клиента по идентификатору. Идентификаторы клиентов уникальны
+// для всех арендаторов, поэтому поиск не ограничен арендатором из ctx.
+func (s *Storage) Client(ctx context.Context, id string) (models.Client, error) {
+	const op = "storage.postgres.Client"
+
+	query := `
+		SELECT ` + clientColumns + `
+		FROM clients c JOIN apps a ON a.id = c.app_id
+		WHERE c.id = $1`
+
+	var c models.Client
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanClient(s.db.QueryRowContext(ctx, query, id), &c)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Client{}, fmt.Errorf("%s: %w", op, storage.ErrClientNotFound)
+	}
+	if err != nil {
+		return models.Client{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// Clients возвращает клиентов приложения арендатора из ctx.
+func (s *Storage) Clients(ctx context.Context, appName string) ([]models.Client, error) {
+	const op = "storage.postgres.Clients"
+
+	query := `
+		SELECT ` + clientColumns + `
+		FROM clients c JOIN apps a ON a.id = c.app_id
+		WHERE a.name = $1 AND a.tenant = $2
+		ORDER BY c.created_at`
+
+	var clients []models.Client
+	err := s.read(ctx, func(ctx context.Context) error {
+		rows, err := s.db.QueryContext(ctx, query, appName, tenant.From(ctx))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		clients = nil
+		for rows.Next() {
+			var c models.Client
+			if err := scanClient(rows, &c); err != nil {
+				return err
+			}
+			clients = append(clients, c)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return clients, nil
+}
+
+// RotateClientSecret заменяет секрет клиента. Если previousExpiresAt не nil, прежний
+// секрет принимается до этого момента; иначе он перестает действовать сразу.
+func (s *Storage) RotateClientSecret(ctx context.Context, id string, secretHash []byte, previousExpiresAt *time.Time) error {
+	const op = "storage.postgres.RotateClientSecret"
+
+	query := `
+		UPDATE clients
+		SET previous_secret_hash = CASE WHEN $3::timestamptz IS NULL THEN NULL ELSE secret_hash END,
+			previous_secret_expires_at = $3,
+			secret_hash = $2,
+			secret_rotated_at = now()
+		WHERE id = $1`
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, id, secretHash, previousExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrClientNotFound)
+	}
+
+	return nil
+}
+
+// DeleteClient удаляет клиента.
+func (s *Storage) DeleteClient(ctx context.Context, id string) error {
+	const op = "storage.postgres.DeleteClient"
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, `DELETE FROM clients WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrClientNotFound)
+	}
+
+	return nil
+}
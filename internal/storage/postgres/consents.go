@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// consentColumns колонки consents в порядке, ожидаемом scanConsent.
+const consentColumns = `user_uuid, app, scopes, created_at, updated_at`
+
+func scanConsent(row scanner, c *models.Consent) error {
+	return row.Scan(&c.UserUUID, &c.App, pq.Array(&c.Scopes), &c.CreatedAt, &c.UpdatedAt)
+}
+
+// Consent возвращает согласие пользователя для приложения.
+func (s *Storage) Consent(ctx context.Context, userUUID, app string) (models.Consent, error) {
+	const op = "storage.postgres.Consent"
+
+	query := `SELECT ` + consentColumns + ` FROM consents WHERE user_uuid = $1 AND app = $2`
+
+	var c models.Consent
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanConsent(s.db.QueryRowContext(ctx, query, userUUID, app), &c)
+	})
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return models.Consent{}, fmt.Errorf("%s: %w", op, storage.ErrConsentNotFound)
+	}
+	if err != nil {
+		return models.Consent{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// Consents возвращает согласия пользователя, упорядоченные по приложению.
+func (s *Storage) Consents(ctx context.Context, userUUID string) ([]models.Consent, error) {
+	const op = "storage.postgres.Consents"
+
+	var consents []models.Consent
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		consents, err = queryConsents(ctx, s.db, `
+			SELECT `+consentColumns+`
+			FROM consents
+			WHERE user_uuid = $1
+			ORDER BY app`, userUUID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return consents, nil
+}
+
+// GrantConsent добавляет области к согласию пользователя для приложения
+// и возвращает согласие с объединенным набором областей.
+func (s *Storage) GrantConsent(ctx context.Context, userUUID, app string, scopes []string) (models.Consent, error) {
+	const op = "storage.postgres.GrantConsent"
+
+	query := `
+		INSERT INTO consents (user_uuid, app, scopes)
+		VALUES ($1, $2, ARRAY(SELECT DISTINCT unnest($3::TEXT[]) ORDER BY 1))
+		ON CONFLICT (user_uuid, app) DO UPDATE
+		SET scopes = ARRAY(SELECT DISTINCT unnest(consents.scopes || EXCLUDED.scopes) ORDER BY 1),
+			updated_at = now()
+		RETURNING ` + consentColumns
+
+	var c models.Consent
+	err := s.write(ctx, func(ctx context.Context) error {
+		return scanConsent(s.db.QueryRowContext(ctx, query, userUUID, app, pq.Array(scopes)), &c)
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return models.Consent{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return models.Consent{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// RevokeConsent удаляет согласие пользователя для приложения и отзывает его активные
+// сеансы в этом приложении. Возвращает количество отозванных сеансов.
+// Если согласия нет, возвращает storage.ErrConsentNotFound.
+func (s *Storage) RevokeConsent(ctx context.Context, userUUID, app string) (int64, error) {
+	const op = "storage.postgres.RevokeConsent"
+
+	query := `
+		WITH deleted AS (
+			DELETE FROM consents WHERE user_uuid = $1 AND app = $2
+			RETURNING user_uuid
+		), revoked AS (
+			UPDATE sessions SET revoked_at = now()
+			WHERE user_uuid IN (SELECT user_uuid FROM deleted) AND app = $2
+				AND revoked_at IS NULL AND expires_at > now()
+			RETURNING id
+		)
+		SELECT (SELECT count(*) FROM deleted), (SELECT count(*) FROM revoked)`
+
+	var deleted, revoked int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, query, userUUID, app).Scan(&deleted, &revoked)
+	})
+	if isInvalidText(err) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrConsentNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if deleted == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrConsentNotFound)
+	}
+
+	return revoked, nil
+}
+
+// queryConsents выполняет запрос, выбирающий consentColumns.
+func queryConsents(ctx context.Context, q querier, query string, args ...any) ([]models.Consent, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var consents []models.Consent
+	for rows.Next() {
+		var c models.Consent
+		if err := scanConsent(rows, &c); err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+
+	return consents, rows.Err()
+}
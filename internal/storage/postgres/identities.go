@@ -0,0 +1,313 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// identityColumns колонки identities в порядке, ожидаемом scanIdentity.
+const identityColumns = `provider, subject, user_uuid, email, created_at, last_login_at`
+
+func scanIdentity(row scanner, i *models.Identity) error {
+	var lastLoginAt sql.NullTime
+
+	if err := row.Scan(&i.Provider, &i.Subject, &i.UserUUID, &i.Email, &i.CreatedAt, &lastLoginAt); err != nil {
+		return err
+	}
+
+	i.LastLoginAt = nil
+	if lastLoginAt.Valid {
+		i.LastLoginAt = &lastLoginAt.Time
+	}
+
+	return nil
+}
+
+func queryIdentities(ctx context.Context, q querier, query string, args ...any) ([]models.Identity, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []models.Identity
+	for rows.Next() {
+		var i models.Identity
+		if err := scanIdentity(rows, &i); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+
+	return identities, rows.Err()
+}
+
+// Identity возвращает учетную запись провайдера provider с идентификатором subject,
+// привязанную в арендаторе из ctx.
+func (s *Storage) Identity(ctx context.Context, provider, subject string) (models.Identity, error) {
+	const op = "storage.postgres.Identity"
+
+	query := `SELECT ` + identityColumns + ` FROM identities WHERE provider = $1 AND subject = $2 AND tenant = $3`
+
+	var identity models.Identity
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanIdentity(s.db.QueryRowContext(ctx, query, provider, subject, tenant.From(ctx)), &identity)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Identity{}, fmt.Errorf("%s: %w", op, storage.ErrIdentityNotFound)
+	}
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return identity, nil
+}
+
+// Identities возвращает внешние учетные записи пользователя.
+func (s *Storage) Identities(ctx context.Context, userUUID string) ([]models.Identity, error) {
+	const op = "storage.postgres.Identities"
+
+	query := `
+		SELECT ` + identityColumns + `
+		FROM identities
+		WHERE user_uuid = $1
+		ORDER BY created_at`
+
+	var identities []models.Identity
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		identities, err = queryIdentities(ctx, s.db, query, userUUID)
+		return err
+	})
+	if isInvalidText(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return identities, nil
+}
+
+// SaveIdentity привязывает внешнюю учетную запись к пользователю арендатора из ctx.
+func (s *Storage) SaveIdentity(ctx context.Context, identity models.Identity) error {
+	const op = "storage.postgres.SaveIdentity"
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		return insertIdentity(ctx, s.db, identity)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveFederatedUser создает пользователя арендатора из ctx без пароля вместе с привязанной
+// учетной записью провайдера.
+func (s *Storage) SaveFederatedUser(ctx context.Context, email, displayName string, identity models.Identity) (string, error) {
+	const op = "storage.postgres.SaveFederatedUser"
+
+	var uuid string
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		// Пустой хэш не совпадает ни с одним паролем: войти можно только через провайдера.
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (email, pass_hash, display_name, tenant)
+			VALUES ($1, '', $2, $3)
+			RETURNING uuid`, email, displayName, tenant.From(ctx)).Scan(&uuid)
+		if err != nil {
+			var psqlErr *pq.Error
+			if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrUniqueViolation {
+				return storage.ErrUserExists
+			}
+
+			return err
+		}
+
+		identity.UserUUID = uuid
+
+		return insertIdentity(ctx, tx, identity)
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return uuid, nil
+}
+
+// insertIdentity привязывает учетную запись в арендаторе из ctx: в других арендаторах
+// тот же subject привязывается к их пользователям независимо.
+func insertIdentity(ctx context.Context, db execer, identity models.Identity) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO identities (provider, subject, user_uuid, email, last_login_at, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		identity.Provider, identity.Subject, identity.UserUUID, identity.Email, identity.LastLoginAt, tenant.From(ctx))
+
+	var psqlErr *pq.Error
+	if errors.As(err, &psqlErr) {
+		switch psqlErr.Code {
+		case storage.ErrUniqueViolation:
+			return storage.ErrIdentityExists
+		case storage.ErrForeignKeyViolation, storage.ErrInvalidTextRepresentation:
+			return storage.ErrUserNotFound
+		}
+	}
+
+	return err
+}
+
+// TouchIdentity отмечает вход через учетную запись провайдера в арендаторе из ctx
+// и обновляет ее email.
+func (s *Storage) TouchIdentity(ctx context.Context, provider, subject, email string) error {
+	const op = "storage.postgres.TouchIdentity"
+
+	query := `
+		UPDATE identities SET last_login_at = now(), email = $3
+		WHERE provider = $1 AND subject = $2 AND tenant = $4`
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, provider, subject, email, tenant.From(ctx))
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrIdentityNotFound)
+	}
+
+	return nil
+}
+
+// UnlinkIdentity отвязывает от пользователя учетную запись провайдера. Если у пользователя
+// нет пароля, единственную учетную запись отвязать нельзя: он потеряет доступ.
+func (s *Storage) UnlinkIdentity(ctx context.Context, userUUID, provider string) error {
+	const op = "storage.postgres.UnlinkIdentity"
+
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		// Блокировка пользователя не дает параллельно отвязать две последние учетные записи.
+		var hasPassword bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT length(pass_hash) > 0
+			FROM users
+			WHERE uuid = $1
+			FOR UPDATE`, userUUID).Scan(&hasPassword)
+		if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+			return storage.ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var others int
+		err = tx.QueryRowContext(ctx, `
+			SELECT count(*)
+			FROM identities
+			WHERE user_uuid = $1 AND provider <> $2`, userUUID, provider).Scan(&others)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM identities WHERE user_uuid = $1 AND provider = $2`, userUUID, provider)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return storage.ErrIdentityNotFound
+		}
+
+		if !hasPassword && others == 0 {
+			return storage.ErrLastIdentity
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveFederationState сохраняет незавершенный вход через провайдера и удаляет истекшие.
+// Состояние запоминает арендатора из ctx.
+func (s *Storage) SaveFederationState(ctx context.Context, st models.FederationState) error {
+	const op = "storage.postgres.SaveFederationState"
+
+	query := `
+		WITH expired AS (
+			DELETE FROM federation_states WHERE expires_at < now()
+		)
+		INSERT INTO federation_states (state, provider, app, nonce, verifier, link_user_uuid, expires_at, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	var linkUserUUID any
+	if st.LinkUserUUID != "" {
+		linkUserUUID = st.LinkUserUUID
+	}
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query,
+			st.State, st.Provider, st.App, st.Nonce, st.Verifier, linkUserUUID, st.ExpiresAt, tenant.From(ctx))
+		return err
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ConsumeFederationState удаляет и возвращает неистекший вход через провайдера.
+// Каждое состояние можно использовать только один раз.
+func (s *Storage) ConsumeFederationState(ctx context.Context, state string) (models.FederationState, error) {
+	const op = "storage.postgres.ConsumeFederationState"
+
+	query := `
+		DELETE FROM federation_states
+		WHERE state = $1
+		RETURNING state, tenant, provider, app, nonce, verifier, link_user_uuid, expires_at`
+
+	var (
+		st           models.FederationState
+		linkUserUUID sql.NullString
+	)
+	err := s.write(ctx, func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, query, state).Scan(
+			&st.State, &st.Tenant, &st.Provider, &st.App, &st.Nonce, &st.Verifier, &linkUserUUID, &st.ExpiresAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.FederationState{}, fmt.Errorf("%s: %w", op, storage.ErrStateNotFound)
+	}
+	if err != nil {
+		return models.FederationState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	st.LinkUserUUID = linkUserUUID.String
+
+	return st, nil
+}
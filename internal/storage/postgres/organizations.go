@@ -0,0 +1,429 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// organizationColumns колонки организации и ее приложения в порядке, ожидаемом scanOrganization.
+const organizationColumns = `o.id, a.name, o.name, o.created_at`
+
+func scanOrganization(row scanner, o *models.Organization) error {
+	return row.Scan(&o.ID, &o.App, &o.Name, &o.CreatedAt)
+}
+
+func queryOrganizations(ctx context.Context, q querier, query string, args ...any) ([]models.Organization, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var o models.Organization
+		if err := scanOrganization(rows, &o); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+
+	return orgs, rows.Err()
+}
+
+// memberColumns колонки участника в порядке, ожидаемом scanMember.
+const memberColumns = `m.organization_id, m.user_uuid, u.email, m.role, m.created_at`
+
+func scanMember(row scanner, m *models.Member) error {
+	return row.Scan(&m.OrganizationID, &m.UserUUID, &m.Email, &m.Role, &m.CreatedAt)
+}
+
+func queryMembers(ctx context.Context, q querier, query string, args ...any) ([]models.Member, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.Member
+	for rows.Next() {
+		var m models.Member
+		if err := scanMember(rows, &m); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
+
+// SaveOrganization создает организацию в приложении app арендатора из ctx и делает
+// пользователя ownerUUID того же арендатора ее владельцем.
+func (s *Storage) SaveOrganization(ctx context.Context, app, name, ownerUUID string) (models.Organization, error) {
+	const op = "storage.postgres.SaveOrganization"
+
+	org := models.Organization{App: app, Name: name}
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO organizations (app_id, name)
+			SELECT id, $2 FROM apps WHERE name = $1 AND tenant = $3
+			RETURNING id, created_at`, app, name, tenant.From(ctx)).Scan(&org.ID, &org.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrAppNotFound
+		}
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrUniqueViolation {
+			return storage.ErrOrganizationExists
+		}
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO organization_members (organization_id, user_uuid, role)
+			SELECT $1, uuid, $3 FROM users WHERE uuid = $2 AND tenant = $4`,
+			org.ID, ownerUUID, models.OrganizationOwner, tenant.From(ctx))
+		if isInvalidText(err) {
+			return storage.ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return storage.ErrUserNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return org, nil
+}
+
+// Organization возвращает организацию арендатора из ctx.
+func (s *Storage) Organization(ctx context.Context, id string) (models.Organization, error) {
+	const op = "storage.postgres.Organization"
+
+	query := `
+		SELECT ` + organizationColumns + `
+		FROM organizations o
+		JOIN apps a ON a.id = o.app_id
+		WHERE o.id = $1 AND a.tenant = $2`
+
+	var org models.Organization
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanOrganization(s.db.QueryRowContext(ctx, query, id, tenant.From(ctx)), &org)
+	})
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, storage.ErrOrganizationNotFound)
+	}
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return org, nil
+}
+
+// Organizations возвращает организации приложения app арендатора из ctx.
+func (s *Storage) Organizations(ctx context.Context, app string) ([]models.Organization, error) {
+	const op = "storage.postgres.Organizations"
+
+	query := `
+		SELECT ` + organizationColumns + `
+		FROM organizations o
+		JOIN apps a ON a.id = o.app_id
+		WHERE a.name = $1 AND a.tenant = $2
+		ORDER BY o.name`
+
+	var orgs []models.Organization
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		orgs, err = queryOrganizations(ctx, s.db, query, app, tenant.From(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orgs, nil
+}
+
+// MemberOrganizations возвращает организации приложения app, в которых состоит пользователь.
+func (s *Storage) MemberOrganizations(ctx context.Context, userUUID, app string) ([]models.Organization, error) {
+	const op = "storage.postgres.MemberOrganizations"
+
+	query := `
+		SELECT ` + organizationColumns + `
+		FROM organization_members m
+		JOIN organizations o ON o.id = m.organization_id
+		JOIN apps a ON a.id = o.app_id
+		WHERE m.user_uuid = $1 AND a.name = $2 AND a.tenant = $3
+		ORDER BY o.name`
+
+	var orgs []models.Organization
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		orgs, err = queryOrganizations(ctx, s.db, query, userUUID, app, tenant.From(ctx))
+		return err
+	})
+	if isInvalidText(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orgs, nil
+}
+
+// Members возвращает участников организации в порядке вступления.
+func (s *Storage) Members(ctx context.Context, orgID string) ([]models.Member, error) {
+	const op = "storage.postgres.Members"
+
+	query := `
+		SELECT ` + memberColumns + `
+		FROM organization_members m
+		JOIN users u ON u.uuid = m.user_uuid
+		WHERE m.organization_id = $1
+		ORDER BY m.created_at, u.email`
+
+	var members []models.Member
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		members, err = queryMembers(ctx, s.db, query, orgID)
+		return err
+	})
+	if isInvalidText(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return members, nil
+}
+
+// Member возвращает участника организации.
+func (s *Storage) Member(ctx context.Context, orgID, userUUID string) (models.Member, error) {
+	const op = "storage.postgres.Member"
+
+	query := `
+		SELECT ` + memberColumns + `
+		FROM organization_members m
+		JOIN users u ON u.uuid = m.user_uuid
+		WHERE m.organization_id = $1 AND m.user_uuid = $2`
+
+	var m models.Member
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanMember(s.db.QueryRowContext(ctx, query, orgID, userUUID), &m)
+	})
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return models.Member{}, fmt.Errorf("%s: %w", op, storage.ErrMemberNotFound)
+	}
+	if err != nil {
+		return models.Member{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return m, nil
+}
+
+// RemoveMember удаляет участника из организации. Последнего владельца удалить нельзя:
+// организацией станет некому управлять.
+func (s *Storage) RemoveMember(ctx context.Context, orgID, userUUID string) error {
+	const op = "storage.postgres.RemoveMember"
+
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		// Блокировка организации не дает параллельно удалить двух последних владельцев.
+		var locked string
+		err := tx.QueryRowContext(ctx, `SELECT id FROM organizations WHERE id = $1 FOR UPDATE`, orgID).Scan(&locked)
+		if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+			return storage.ErrOrganizationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var role string
+		err = tx.QueryRowContext(ctx, `
+			DELETE FROM organization_members
+			WHERE organization_id = $1 AND user_uuid = $2
+			RETURNING role`, orgID, userUUID).Scan(&role)
+		if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+			return storage.ErrMemberNotFound
+		}
+		if err != nil || role != models.OrganizationOwner {
+			return err
+		}
+
+		var owners int
+		err = tx.QueryRowContext(ctx, `
+			SELECT count(*)
+			FROM organization_members
+			WHERE organization_id = $1 AND role = $2`, orgID, models.OrganizationOwner).Scan(&owners)
+		if err != nil {
+			return err
+		}
+		if owners == 0 {
+			return storage.ErrLastOwner
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// invitationColumns колонки organization_invitations и арендатор организации
+// в порядке, ожидаемом scanInvitation.
+const invitationColumns = `id, organization_id, email, role, token_hash, invited_by, created_at, expires_at,
+	(SELECT a.tenant FROM organizations o JOIN apps a ON a.id = o.app_id
+		WHERE o.id = organization_invitations.organization_id)`
+
+func scanInvitation(row scanner, inv *models.Invitation) error {
+	var invitedBy sql.NullString
+
+	err := row.Scan(&inv.ID, &inv.OrganizationID, &inv.Email, &inv.Role, &inv.TokenHash, &invitedBy,
+		&inv.CreatedAt, &inv.ExpiresAt, &inv.Tenant)
+	if err != nil {
+		return err
+	}
+
+	inv.InvitedBy = invitedBy.String
+
+	return nil
+}
+
+func queryInvitations(ctx context.Context, q querier, query string, args ...any) ([]models.Invitation, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invs []models.Invitation
+	for rows.Next() {
+		var inv models.Invitation
+		if err := scanInvitation(rows, &inv); err != nil {
+			return nil, err
+		}
+		invs = append(invs, inv)
+	}
+
+	return invs, rows.Err()
+}
+
+// SaveInvitation сохраняет приглашение в организацию и удаляет истекшие.
+func (s *Storage) SaveInvitation(ctx context.Context, inv models.Invitation) (models.Invitation, error) {
+	const op = "storage.postgres.SaveInvitation"
+
+	query := `
+		WITH expired AS (
+			DELETE FROM organization_invitations WHERE expires_at < now()
+		)
+		INSERT INTO organization_invitations (organization_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + invitationColumns
+
+	var invitedBy any
+	if inv.InvitedBy != "" {
+		invitedBy = inv.InvitedBy
+	}
+
+	var saved models.Invitation
+	err := s.write(ctx, func(ctx context.Context) error {
+		return scanInvitation(s.db.QueryRowContext(ctx, query,
+			inv.OrganizationID, inv.Email, inv.Role, inv.TokenHash, invitedBy, inv.ExpiresAt), &saved)
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return models.Invitation{}, fmt.Errorf("%s: %w", op, storage.ErrOrganizationNotFound)
+		}
+
+		return models.Invitation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// Invitation возвращает неистекшее приглашение по хэшу токена среди всех арендаторов:
+// ссылка из письма арендатора не передает.
+func (s *Storage) Invitation(ctx context.Context, tokenHash []byte) (models.Invitation, error) {
+	const op = "storage.postgres.Invitation"
+
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE token_hash = $1 AND expires_at > now()`
+
+	var inv models.Invitation
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanInvitation(s.db.QueryRowContext(ctx, query, tokenHash), &inv)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Invitation{}, fmt.Errorf("%s: %w", op, storage.ErrInvitationNotFound)
+	}
+	if err != nil {
+		return models.Invitation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return inv, nil
+}
+
+// ConsumeInvitation удаляет неистекшее приглашение и добавляет пользователя в организацию
+// с ролью из приглашения. Каждое приглашение можно принять только один раз; роль того,
+// кто уже состоит в организации, не меняется.
+func (s *Storage) ConsumeInvitation(ctx context.Context, id, userUUID string) error {
+	const op = "storage.postgres.ConsumeInvitation"
+
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var orgID, role string
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM organization_invitations
+			WHERE id = $1 AND expires_at > now()
+			RETURNING organization_id, role`, id).Scan(&orgID, &role)
+		if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+			return storage.ErrInvitationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO organization_members (organization_id, user_uuid, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (organization_id, user_uuid) DO NOTHING`, orgID, userUUID, role)
+
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) {
+			switch psqlErr.Code {
+			case storage.ErrForeignKeyViolation, storage.ErrInvalidTextRepresentation:
+				return storage.ErrUserNotFound
+			}
+		}
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// passkeyColumns колонки passkeys в порядке, ожидаемом scanPasskey.
+const passkeyColumns = `id, user_uuid, name, public_key, attestation_type, transports, aaguid,
+	sign_count, backup_eligible, backup_state, created_at, last_used_at`
+
+func scanPasskey(row scanner, p *models.Passkey) error {
+	var (
+		signCount  int64
+		lastUsedAt sql.NullTime
+	)
+
+	err := row.Scan(&p.ID, &p.UserUUID, &p.Name, &p.PublicKey, &p.AttestationType, pq.Array(&p.Transports),
+		&p.AAGUID, &signCount, &p.BackupEligible, &p.BackupState, &p.CreatedAt, &lastUsedAt)
+	if err != nil {
+		return err
+	}
+
+	p.SignCount = uint32(signCount)
+	p.LastUsedAt = nil
+	if lastUsedAt.Valid {
+		p.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return nil
+}
+
+func queryPasskeys(ctx context.Context, q querier, query string, args ...any) ([]models.Passkey, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var passkeys []models.Passkey
+	for rows.Next() {
+		var p models.Passkey
+		if err := scanPasskey(rows, &p); err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, p)
+	}
+
+	return passkeys, rows.Err()
+}
+
+// SavePasskey сохраняет зарегистрированный пользователем ключ.
+func (s *Storage) SavePasskey(ctx context.Context, p models.Passkey) error {
+	const op = "storage.postgres.SavePasskey"
+
+	query := `
+		INSERT INTO passkeys (id, user_uuid, name, public_key, attestation_type, transports, aaguid,
+			sign_count, backup_eligible, backup_state)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query, p.ID, p.UserUUID, p.Name, p.PublicKey, p.AttestationType,
+			pq.Array(p.Transports), p.AAGUID, int64(p.SignCount), p.BackupEligible, p.BackupState)
+		return err
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) {
+			switch psqlErr.Code {
+			case storage.ErrUniqueViolation:
+				return fmt.Errorf("%s: %w", op, storage.ErrPasskeyExists)
+			case storage.ErrForeignKeyViolation, storage.ErrInvalidTextRepresentation:
+				return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+			}
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Passkeys возвращает ключи пользователя.
+func (s *Storage) Passkeys(ctx context.Context, userUUID string) ([]models.Passkey, error) {
+	const op = "storage.postgres.Passkeys"
+
+	query := `
+		SELECT ` + passkeyColumns + `
+		FROM passkeys
+		WHERE user_uuid = $1
+		ORDER BY created_at`
+
+	var passkeys []models.Passkey
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		passkeys, err = queryPasskeys(ctx, s.db, query, userUUID)
+		return err
+	})
+	if isInvalidText(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return passkeys, nil
+}
+
+// UpdatePasskeyUsage отмечает вход по ключу и сохраняет новое значение счетчика подписей.
+// Если счетчик не больше сохраненного (кроме случая, когда аутентификатор не ведет его
+// и оба значения нулевые), возвращает ErrStaleSignCount: проверка в запросе не дает
+// двум параллельным входам с одним значением счетчика пройти оба.
+func (s *Storage) UpdatePasskeyUsage(ctx context.Context, id []byte, signCount uint32, backupState bool) error {
+	const op = "storage.postgres.UpdatePasskeyUsage"
+
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE passkeys
+			SET sign_count = $2, backup_state = $3, last_used_at = now()
+			WHERE id = $1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))`,
+			id, int64(signCount), backupState)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil || affected > 0 {
+			return err
+		}
+
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM passkeys WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return storage.ErrPasskeyNotFound
+		}
+
+		return storage.ErrStaleSignCount
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeletePasskey удаляет ключ пользователя.
+func (s *Storage) DeletePasskey(ctx context.Context, userUUID string, id []byte) error {
+	const op = "storage.postgres.DeletePasskey"
+
+	query := `DELETE FROM passkeys WHERE user_uuid = $1 AND id = $2`
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, userUUID, id)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if isInvalidText(err) || (err == nil && affected == 0) {
+		return fmt.Errorf("%s: %w", op, storage.ErrPasskeyNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveWebAuthnCeremony сохраняет начатую регистрацию ключа или вход по нему и удаляет истекшие.
+// Церемония запоминает арендатора из ctx.
+func (s *Storage) SaveWebAuthnCeremony(ctx context.Context, c models.WebAuthnCeremony) error {
+	const op = "storage.postgres.SaveWebAuthnCeremony"
+
+	query := `
+		WITH expired AS (
+			DELETE FROM webauthn_ceremonies WHERE expires_at < now()
+		)
+		INSERT INTO webauthn_ceremonies (id, kind, user_uuid, app, scopes, session, options, expires_at, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	var userUUID any
+	if c.UserUUID != "" {
+		userUUID = c.UserUUID
+	}
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query, c.ID, c.Kind, userUUID, c.App, pq.Array(c.Scopes), c.Session, c.Options, c.ExpiresAt,
+			tenant.From(ctx))
+		return err
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ceremonyColumns колонки webauthn_ceremonies в порядке, ожидаемом scanCeremony.
+const ceremonyColumns = `id, tenant, kind, user_uuid, app, scopes, session, options, expires_at`
+
+func scanCeremony(row scanner, c *models.WebAuthnCeremony) error {
+	var userUUID sql.NullString
+
+	if err := row.Scan(&c.ID, &c.Tenant, &c.Kind, &userUUID, &c.App, pq.Array(&c.Scopes), &c.Session, &c.Options, &c.ExpiresAt); err != nil {
+		return err
+	}
+
+	c.UserUUID = userUUID.String
+
+	return nil
+}
+
+// WebAuthnCeremony возвращает неистекшую церемонию, не завершая ее.
+func (s *Storage) WebAuthnCeremony(ctx context.Context, id string) (models.WebAuthnCeremony, error) {
+	const op = "storage.postgres.WebAuthnCeremony"
+
+	query := `SELECT ` + ceremonyColumns + ` FROM webauthn_ceremonies WHERE id = $1 AND expires_at > now()`
+
+	var c models.WebAuthnCeremony
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanCeremony(s.db.QueryRowContext(ctx, query, id), &c)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WebAuthnCeremony{}, fmt.Errorf("%s: %w", op, storage.ErrCeremonyNotFound)
+	}
+	if err != nil {
+		return models.WebAuthnCeremony{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// ConsumeWebAuthnCeremony удаляет и возвращает церемонию. Каждую церемонию можно
+// завершить только один раз; срок действия проверяет вызывающий.
+func (s *Storage) ConsumeWebAuthnCeremony(ctx context.Context, id string) (models.WebAuthnCeremony, error) {
+	const op = "storage.postgres.ConsumeWebAuthnCeremony"
+
+	query := `DELETE FROM webauthn_ceremonies WHERE id = $1 RETURNING ` + ceremonyColumns
+
+	var c models.WebAuthnCeremony
+	err := s.write(ctx, func(ctx context.Context) error {
+		return scanCeremony(s.db.QueryRowContext(ctx, query, id), &c)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WebAuthnCeremony{}, fmt.Errorf("%s: %w", op, storage.ErrCeremonyNotFound)
+	}
+	if err != nil {
+		return models.WebAuthnCeremony{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// SavePasswordlessChallenge сохраняет новую ссылку или код для входа. Прежняя ссылка или код
+// пользователя в том же приложении заменяется вместе со счетчиком попыток, истекшие удаляются.
+// Уникальный индекс (user_uuid, app) не дает одновременным запросам оставить несколько кодов.
+func (s *Storage) SavePasswordlessChallenge(ctx context.Context, c models.PasswordlessChallenge) error {
+	const op = "storage.postgres.SavePasswordlessChallenge"
+
+	query := `
+		WITH expired AS (
+			DELETE FROM passwordless_challenges
+			WHERE expires_at < now() AND NOT (user_uuid = $2 AND app = $3)
+		)
+		INSERT INTO passwordless_challenges (id, user_uuid, app, method, secret_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_uuid, app) DO UPDATE
+		SET id = EXCLUDED.id,
+			method = EXCLUDED.method,
+			secret_hash = EXCLUDED.secret_hash,
+			attempts = 0,
+			created_at = now(),
+			expires_at = EXCLUDED.expires_at`
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query, c.ID, c.UserUUID, c.App, c.Method, c.SecretHash, c.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AttemptPasswordlessChallenge учитывает попытку ввести секрет и возвращает неистекшую
+// ссылку или код с уже увеличенным счетчиком попыток.
+func (s *Storage) AttemptPasswordlessChallenge(ctx context.Context, id string) (models.PasswordlessChallenge, error) {
+	const op = "storage.postgres.AttemptPasswordlessChallenge"
+
+	query := `
+		UPDATE passwordless_challenges
+		SET attempts = attempts + 1
+		WHERE id = $1 AND expires_at > now()
+		RETURNING id, user_uuid, app, method, secret_hash, attempts, created_at, expires_at`
+
+	var c models.PasswordlessChallenge
+	err := s.write(ctx, func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, query, id).Scan(
+			&c.ID, &c.UserUUID, &c.App, &c.Method, &c.SecretHash, &c.Attempts, &c.CreatedAt, &c.ExpiresAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PasswordlessChallenge{}, fmt.Errorf("%s: %w", op, storage.ErrChallengeNotFound)
+	}
+	if err != nil {
+		return models.PasswordlessChallenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// ConsumePasswordlessChallenge удаляет использованную ссылку или код. Если их уже
+// использовал параллельный запрос, возвращает ErrChallengeNotFound.
+func (s *Storage) ConsumePasswordlessChallenge(ctx context.Context, id string) error {
+	const op = "storage.postgres.ConsumePasswordlessChallenge"
+
+	query := `DELETE FROM passwordless_challenges WHERE id = $1`
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrChallengeNotFound)
+	}
+
+	return nil
+}
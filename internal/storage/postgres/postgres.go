@@ -3,10 +3,16 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/retry"
+	"go-sso/internal/lib/tenant"
 	"go-sso/internal/storage"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 	_ "github.com/lib/pq" // Importing pq for PostgreSQL driver
@@ -14,10 +20,27 @@ import (
 
 type Storage struct {
 	db *sql.DB
+
+	queryTimeout time.Duration
+	retryPolicy  retry.Policy
 }
 
-// New создает новое подключение к базе данных PostgreSQL
-func New(connStr string) (*Storage, error) {
+// Options настройки пула соединений и политики выполнения запросов.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// QueryTimeout ограничение времени одного запроса.
+	QueryTimeout time.Duration
+	// Retry политика повторов идемпотентных запросов и начального подключения.
+	Retry retry.Policy
+}
+
+// New создает новое подключение к базе данных PostgreSQL.
+// Начальная проверка соединения повторяется согласно opts.Retry.
+func New(ctx context.Context, connStr string, opts Options) (*Storage, error) {
 	const op = "storage.postgres.New"
 
 	db, err := sql.Open("postgres", connStr)
@@ -25,14 +48,48 @@ func New(connStr string) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := db.Ping(); err != nil {
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	s := &Storage{
+		db:           db,
+		queryTimeout: opts.QueryTimeout,
+		retryPolicy:  opts.Retry,
+	}
+
+	if err := s.read(ctx, db.PingContext); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	return s, nil
+}
+
+// Close закрывает пул соединений с базой данных.
+func (s *Storage) Close() error {
+	const op = "storage.postgres.Close"
+
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Ping проверяет соединение с базой данных.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgres.Ping"
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
 }
 
-// SaveUser сохраняет пользователя в базе данных
+// SaveUser сохраняет пользователя арендатора из ctx в базе данных
 func (s *Storage) SaveUser(
 	ctx context.Context,
 	email string,
@@ -41,18 +98,23 @@ func (s *Storage) SaveUser(
 	const op = "storage.postgres.SaveUser"
 
 	query := `
-        INSERT INTO users (email, pass_hash)
-        VALUES ($1, $2)
+        INSERT INTO users (email, pass_hash, tenant)
+        VALUES ($1, $2, $3)
         RETURNING uuid`
 
 	var uuid string
-	err := s.db.QueryRowContext(ctx, query, email, passHash).Scan(&uuid)
+	err := s.write(ctx, func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, query, email, passHash, tenant.From(ctx)).Scan(&uuid)
+	})
 	if err != nil {
 		var psqlErr *pq.Error
 
 		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrUniqueViolation {
 			return "", fmt.Errorf("%s: %w", op, storage.ErrUserExists)
 		}
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrTenantNotFound)
+		}
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
@@ -60,23 +122,19 @@ func (s *Storage) SaveUser(
 	return uuid, nil
 }
 
-// User возвращает пользователя по его email
+// User возвращает пользователя арендатора из ctx по его email
 func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 	const op = "storage.postgres.User"
 
-	stmt, err := s.db.PrepareContext(ctx, `
-		SELECT uuid, email, pass_hash
+	query := `
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE email = $1`)
-	if err != nil {
-		return models.User{}, fmt.Errorf("%s: %w", op, err)
-	}
-	defer stmt.Close()
-
-	row := stmt.QueryRowContext(ctx, email)
+		WHERE email = $1 AND tenant = $2`
 
 	var user models.User
-	err = row.Scan(&user.UUID, &user.Email, &user.PassHash)
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanUser(s.db.QueryRowContext(ctx, query, email, tenant.From(ctx)), &user)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -91,19 +149,15 @@ func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 func (s *Storage) IsAdmin(ctx context.Context, uid int64) (bool, error) {
 	const op = "storage.postgres.IsAdmin"
 
-	stmt, err := s.db.PrepareContext(ctx, `
+	query := `
 		SELECT is_admin
 		FROM users
-		WHERE id = $1`)
-	if err != nil {
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
-	defer stmt.Close()
-
-	row := stmt.QueryRowContext(ctx, uid)
+		WHERE id = $1`
 
 	var isAdmin bool
-	err = row.Scan(&isAdmin)
+	err := s.read(ctx, func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, query, uid).Scan(&isAdmin)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
@@ -118,19 +172,15 @@ func (s *Storage) IsAdmin(ctx context.Context, uid int64) (bool, error) {
 func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
 	const op = "storage.postgres.App"
 
-	stmt, err := s.db.PrepareContext(ctx, `
-		SELECT id, name, secret
+	query := `
+		SELECT ` + appColumns + `
 		FROM apps
-		WHERE id = $1`)
-	if err != nil {
-		return models.App{}, fmt.Errorf("%s: %w", op, err)
-	}
-	defer stmt.Close()
-
-	row := stmt.QueryRowContext(ctx, appID)
+		WHERE id = $1`
 
 	var app models.App
-	err = row.Scan(&app.ID, &app.Name, &app.Secret)
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanApp(s.db.QueryRowContext(ctx, query, appID), &app)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
@@ -141,3 +191,92 @@ func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
 
 	return app, nil
 }
+
+// read выполняет идемпотентный запрос с таймаутом и повторами при временных ошибках.
+func (s *Storage) read(ctx context.Context, fn func(ctx context.Context) error) error {
+	p := s.retryPolicy
+	p.AttemptTimeout = s.queryTimeout
+
+	return wrapUnavailable(ctx, retry.Do(ctx, p, transient(ctx), fn))
+}
+
+// write выполняет неидемпотентный запрос с таймаутом, без повторов.
+func (s *Storage) write(ctx context.Context, fn func(ctx context.Context) error) error {
+	p := retry.Policy{Attempts: 1, AttemptTimeout: s.queryTimeout}
+
+	return wrapUnavailable(ctx, retry.Do(ctx, p, transient(ctx), fn))
+}
+
+// tx выполняет fn в транзакции с таймаутом, без повторов.
+// Транзакция фиксируется, если fn не вернула ошибку.
+func (s *Storage) tx(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return s.write(ctx, func(ctx context.Context) error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback() // после Commit ничего не делает
+
+		if err := fn(ctx, tx); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// querier выполняет запросы в пуле соединений или в транзакции.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// execer выполняет изменяющие запросы в пуле соединений или в транзакции.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// wrapUnavailable помечает временные ошибки как storage.ErrUnavailable, сохраняя
+// исходную ошибку в цепочке.
+func wrapUnavailable(ctx context.Context, err error) error {
+	if err != nil && transient(ctx)(err) {
+		return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
+	}
+
+	return err
+}
+
+// transient возвращает проверку временных ошибок запроса с контекстом ctx.
+// Ошибки после отмены или истечения ctx вызывающего временными не считаются:
+// они не повторяются и не выдаются за недоступность PostgreSQL, а истечение
+// таймаута одной попытки (queryTimeout) остается временной ошибкой.
+func transient(ctx context.Context) func(error) bool {
+	return func(err error) bool {
+		return ctx.Err() == nil && isTransient(err)
+	}
+}
+
+// isTransient сообщает, является ли ошибка временной (обрыв соединения,
+// перегрузка или перезапуск сервера, таймаут), после которой запрос можно повторить.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var psqlErr *pq.Error
+	if errors.As(err, &psqlErr) {
+		code := string(psqlErr.Code)
+
+		return strings.HasPrefix(code, "08") || // connection_exception
+			strings.HasPrefix(code, "53") || // insufficient_resources
+			code == "57P01" || code == "57P02" || code == "57P03" || // admin_shutdown, crash_shutdown, cannot_connect_now
+			code == "40001" || code == "40P01" // serialization_failure, deadlock_detected
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr)
+}
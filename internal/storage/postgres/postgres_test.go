@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapUnavailable(t *testing.T) {
+	attemptErr := fmt.Errorf("query: %w", context.DeadlineExceeded)
+
+	// Истек таймаут попытки, а запрос вызывающего еще жив: PostgreSQL недоступен.
+	err := wrapUnavailable(context.Background(), attemptErr)
+	assert.ErrorIs(t, err, storage.ErrUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Запрос вызывающего отменен: ошибка возвращается как есть.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.New("client gone"))
+
+	err = wrapUnavailable(ctx, attemptErr)
+	assert.NotErrorIs(t, err, storage.ErrUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
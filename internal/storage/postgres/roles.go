@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// AssignRole назначает пользователю роль в приложении. Повторное назначение не является ошибкой.
+func (s *Storage) AssignRole(ctx context.Context, userUUID string, appID int, role string) error {
+	const op = "storage.postgres.AssignRole"
+
+	query := `
+		INSERT INTO user_roles (user_uuid, app_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query, userUUID, appID, role)
+		return err
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeRole снимает с пользователя роль в приложении.
+func (s *Storage) RevokeRole(ctx context.Context, userUUID string, appID int, role string) error {
+	const op = "storage.postgres.RevokeRole"
+
+	query := `
+		DELETE FROM user_roles
+		WHERE user_uuid = $1 AND app_id = $2 AND role = $3`
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, userUUID, appID, role)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	return nil
+}
+
+// UserRoles возвращает роли пользователя во всех приложениях.
+func (s *Storage) UserRoles(ctx context.Context, userUUID string) ([]models.Role, error) {
+	const op = "storage.postgres.UserRoles"
+
+	var roles []models.Role
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		roles, err = queryRoles(ctx, s.db, userUUID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+func queryRoles(ctx context.Context, q querier, userUUID string) ([]models.Role, error) {
+	query := `
+		SELECT r.user_uuid, r.app_id, a.name, r.role, r.created_at
+		FROM user_roles r
+		JOIN apps a ON a.id = r.app_id
+		WHERE r.user_uuid = $1
+		ORDER BY a.name, r.role`
+
+	rows, err := q.QueryContext(ctx, query, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var r models.Role
+		if err := rows.Scan(&r.UserUUID, &r.AppID, &r.AppName, &r.Role, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+
+	return roles, rows.Err()
+}
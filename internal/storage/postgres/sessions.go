@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// sessionColumns колонки sessions в порядке, ожидаемом scanSession.
+const sessionColumns = `id, tenant, user_uuid, app, device, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row scanner, s *models.Session) error {
+	var revokedAt sql.NullTime
+
+	err := row.Scan(&s.ID, &s.Tenant, &s.UserUUID, &s.App, &s.Device, &s.IP, &s.UserAgent,
+		&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &revokedAt)
+	if err != nil {
+		return err
+	}
+
+	s.RevokedAt = nil
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+
+	return nil
+}
+
+// SaveSession создает сеанс и возвращает его идентификатор.
+// Сеанс относится к арендатору пользователя.
+func (s *Storage) SaveSession(ctx context.Context, session models.Session) (string, error) {
+	const op = "storage.postgres.SaveSession"
+
+	query := `
+		INSERT INTO sessions (user_uuid, app, device, ip, user_agent, expires_at, tenant)
+		SELECT uuid, $2, $3, $4, $5, $6, tenant FROM users WHERE uuid = $1
+		RETURNING id`
+
+	var id string
+	err := s.write(ctx, func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, query,
+			session.UserUUID, session.App, session.Device, session.IP, session.UserAgent, session.ExpiresAt,
+		).Scan(&id)
+	})
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrForeignKeyViolation {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// Session возвращает сеанс по идентификатору, в том числе отозванный или истекший.
+func (s *Storage) Session(ctx context.Context, id string) (models.Session, error) {
+	const op = "storage.postgres.Session"
+
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+
+	var session models.Session
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanSession(s.db.QueryRowContext(ctx, query, id), &session)
+	})
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	if err != nil {
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+// Sessions возвращает активные сеансы пользователя, начиная с последних.
+func (s *Storage) Sessions(ctx context.Context, userUUID string) ([]models.Session, error) {
+	const op = "storage.postgres.Sessions"
+
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_uuid = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC`
+
+	var sessions []models.Session
+	err := s.read(ctx, func(ctx context.Context) error {
+		var err error
+		sessions, err = querySessions(ctx, s.db, query, userUUID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// TouchSession обновляет время последней активности сеанса.
+func (s *Storage) TouchSession(ctx context.Context, id string) error {
+	const op = "storage.postgres.TouchSession"
+
+	query := `UPDATE sessions SET last_seen_at = now() WHERE id = $1`
+
+	err := s.write(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeSession отзывает активный сеанс пользователя.
+// Если такого активного сеанса нет, возвращает storage.ErrSessionNotFound.
+func (s *Storage) RevokeSession(ctx context.Context, userUUID, id string) error {
+	const op = "storage.postgres.RevokeSession"
+
+	query := `
+		UPDATE sessions SET revoked_at = now()
+		WHERE id = $1 AND user_uuid = $2 AND revoked_at IS NULL`
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, id, userUUID)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if isInvalidText(err) || (err == nil && affected == 0) {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeSessions отзывает все активные сеансы пользователя и возвращает их количество.
+func (s *Storage) RevokeSessions(ctx context.Context, userUUID string) (int64, error) {
+	const op = "storage.postgres.RevokeSessions"
+
+	query := `
+		UPDATE sessions SET revoked_at = now()
+		WHERE user_uuid = $1 AND revoked_at IS NULL AND expires_at > now()`
+
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, userUUID)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+// querySessions выполняет запрос, выбирающий sessionColumns.
+func querySessions(ctx context.Context, q querier, query string, args ...any) ([]models.Session, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func isInvalidText(err error) bool {
+	var psqlErr *pq.Error
+	return errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrInvalidTextRepresentation
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// SaveTenant создает арендатора.
+func (s *Storage) SaveTenant(ctx context.Context, t models.Tenant) (models.Tenant, error) {
+	const op = "storage.postgres.SaveTenant"
+
+	query := `
+		INSERT INTO tenants (id, name)
+		VALUES ($1, $2)
+		RETURNING id, name, created_at`
+
+	var saved models.Tenant
+	err := s.write(ctx, func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, query, t.ID, t.Name).Scan(&saved.ID, &saved.Name, &saved.CreatedAt)
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrUniqueViolation {
+			return models.Tenant{}, fmt.Errorf("%s: %w", op, storage.ErrTenantExists)
+		}
+
+		return models.Tenant{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// Tenants возвращает всех арендаторов.
+func (s *Storage) Tenants(ctx context.Context) ([]models.Tenant, error) {
+	const op = "storage.postgres.Tenants"
+
+	query := `SELECT id, name, created_at FROM tenants ORDER BY id`
+
+	var tenants []models.Tenant
+	err := s.read(ctx, func(ctx context.Context) error {
+		rows, err := s.db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		tenants = tenants[:0]
+		for rows.Next() {
+			var t models.Tenant
+			if err := rows.Scan(&t.ID, &t.Name, &t.CreatedAt); err != nil {
+				return err
+			}
+			tenants = append(tenants, t)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tenants, nil
+}
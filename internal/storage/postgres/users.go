@@ -0,0 +1,493 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"go-sso/internal/storage"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// userColumns колонки users в порядке, ожидаемом scanUser.
+const userColumns = `uuid, tenant, email, pass_hash, status, created_at, updated_at, deleted_at,
+	display_name, locale, timezone, avatar_url, metadata`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner, user *models.User) error {
+	var (
+		deletedAt sql.NullTime
+		metadata  []byte
+		p         = &user.Profile
+	)
+
+	err := row.Scan(&user.UUID, &user.Tenant, &user.Email, &user.PassHash, &user.Status, &user.CreatedAt, &user.UpdatedAt, &deletedAt,
+		&p.DisplayName, &p.Locale, &p.Timezone, &p.AvatarURL, &metadata)
+	if err != nil {
+		return err
+	}
+
+	p.Metadata = nil
+	if err := json.Unmarshal(metadata, &p.Metadata); err != nil {
+		return err
+	}
+	if len(p.Metadata) == 0 {
+		p.Metadata = nil
+	}
+
+	user.DeletedAt = nil
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+
+	return nil
+}
+
+// Users возвращает страницу пользователей арендатора из ctx, упорядоченных по дате регистрации.
+func (s *Storage) Users(ctx context.Context, limit, offset int) ([]models.User, error) {
+	const op = "storage.postgres.Users"
+
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE tenant = $3
+		ORDER BY created_at, uuid
+		LIMIT $1 OFFSET $2`
+
+	var users []models.User
+	err := s.read(ctx, func(ctx context.Context) error {
+		rows, err := s.db.QueryContext(ctx, query, limit, offset, tenant.From(ctx))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		users = users[:0]
+		for rows.Next() {
+			var user models.User
+			if err := scanUser(rows, &user); err != nil {
+				return err
+			}
+			users = append(users, user)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// AllUsers возвращает UUID, арендатора, email, статус и время создания пользователей
+// всех арендаторов постранично. Запрос читает только колонки, которые есть в схеме
+// до миграции 7_email_citext, чтобы подготовить к ней базу можно было заранее;
+// на схеме без арендаторов все пользователи относятся к арендатору по умолчанию.
+func (s *Storage) AllUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
+	const op = "storage.postgres.AllUsers"
+
+	query := `
+		SELECT uuid, coalesce(to_jsonb(u)->>'tenant', $3), email, status, created_at
+		FROM users u
+		ORDER BY uuid
+		LIMIT $1 OFFSET $2`
+
+	var users []models.User
+	err := s.read(ctx, func(ctx context.Context) error {
+		rows, err := s.db.QueryContext(ctx, query, limit, offset, tenant.Default)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		users = users[:0]
+		for rows.Next() {
+			var user models.User
+			if err := rows.Scan(&user.UUID, &user.Tenant, &user.Email, &user.Status, &user.CreatedAt); err != nil {
+				return err
+			}
+			users = append(users, user)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// SetUserEmail заменяет email пользователя с заданным UUID любого арендатора.
+// Как и AllUsers, работает на схеме до миграции 7_email_citext.
+func (s *Storage) SetUserEmail(ctx context.Context, uuid, email string) error {
+	const op = "storage.postgres.SetUserEmail"
+
+	query := `UPDATE users SET email = $2 WHERE uuid = $1`
+
+	return s.updateUser(ctx, op, query, uuid, email)
+}
+
+// UserByUUID возвращает пользователя по UUID любого арендатора: UUID глобально уникален,
+// а по нему находят пользователя входы, в которых арендатор еще не известен.
+func (s *Storage) UserByUUID(ctx context.Context, uuid string) (models.User, error) {
+	const op = "storage.postgres.UserByUUID"
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE uuid = $1`
+
+	var user models.User
+	err := s.read(ctx, func(ctx context.Context) error {
+		return scanUser(s.db.QueryRowContext(ctx, query, uuid), &user)
+	})
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// UpdateProfile применяет изменение профиля пользователя арендатора из ctx и возвращает
+// пользователя после изменения.
+func (s *Storage) UpdateProfile(ctx context.Context, uuid string, upd models.ProfileUpdate) (models.User, error) {
+	const op = "storage.postgres.UpdateProfile"
+
+	var metadata []byte
+	if upd.Metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(upd.Metadata); err != nil {
+			return models.User{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// NULL в параметре оставляет колонку без изменений.
+	query := `
+		UPDATE users SET
+			display_name = COALESCE($2, display_name),
+			locale = COALESCE($3, locale),
+			timezone = COALESCE($4, timezone),
+			avatar_url = COALESCE($5, avatar_url),
+			metadata = COALESCE($6::jsonb, metadata),
+			updated_at = now()
+		WHERE uuid = $1 AND tenant = $7
+		RETURNING ` + userColumns
+
+	var user models.User
+	err := s.write(ctx, func(ctx context.Context) error {
+		row := s.db.QueryRowContext(ctx, query, uuid,
+			upd.DisplayName, upd.Locale, upd.Timezone, upd.AvatarURL, nullBytes(metadata), tenant.From(ctx))
+		return scanUser(row, &user)
+	})
+	if errors.Is(err, sql.ErrNoRows) || isInvalidText(err) {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// nullBytes возвращает nil-интерфейс для пустого b, чтобы драйвер передал NULL.
+func nullBytes(b []byte) any {
+	if b == nil {
+		return nil
+	}
+
+	return string(b)
+}
+
+// SetUserStatus меняет статус пользователя арендатора из ctx с заданным email.
+// Для удаленного пользователя это отменяет удаление. Если новый статус не active,
+// в той же транзакции отзываются сеансы пользователя; возвращает число отозванных.
+func (s *Storage) SetUserStatus(ctx context.Context, email string, status models.UserStatus) (int64, error) {
+	const op = "storage.postgres.SetUserStatus"
+
+	var revoked int64
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var uuid string
+		err := tx.QueryRowContext(ctx, `
+			UPDATE users SET status = $2, deleted_at = NULL
+			WHERE email = $1 AND tenant = $3
+			RETURNING uuid`, email, status, tenant.From(ctx)).Scan(&uuid)
+		if err != nil {
+			return err
+		}
+
+		if status == models.UserStatusActive {
+			return nil
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE sessions SET revoked_at = now()
+			WHERE user_uuid = $1 AND revoked_at IS NULL`, uuid)
+		if err != nil {
+			return err
+		}
+
+		revoked, err = res.RowsAffected()
+
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
+
+// UpdatePassword заменяет хэш пароля пользователя арендатора из ctx с заданным email.
+func (s *Storage) UpdatePassword(ctx context.Context, email string, passHash []byte) error {
+	const op = "storage.postgres.UpdatePassword"
+
+	query := `UPDATE users SET pass_hash = $2 WHERE email = $1 AND tenant = $3`
+
+	return s.updateUser(ctx, op, query, email, passHash, tenant.From(ctx))
+}
+
+// updateUser выполняет UPDATE одного пользователя и возвращает
+// storage.ErrUserNotFound, если ни одна строка не изменилась, и storage.ErrUserExists,
+// если новый email уже занят.
+func (s *Storage) updateUser(ctx context.Context, op, query string, args ...any) error {
+	var affected int64
+	err := s.write(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		var psqlErr *pq.Error
+		if errors.As(err, &psqlErr) && psqlErr.Code == storage.ErrUniqueViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// DeleteUser помечает пользователя арендатора из ctx удаленным и отзывает его сеансы.
+// Строки пользователя стираются позже в PurgeDeletedUsers.
+func (s *Storage) DeleteUser(ctx context.Context, email string) (string, error) {
+	const op = "storage.postgres.DeleteUser"
+
+	var uuid string
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			UPDATE users SET status = 'deleted', deleted_at = now()
+			WHERE email = $1 AND tenant = $2 AND status <> 'deleted'
+			RETURNING uuid`, email, tenant.From(ctx)).Scan(&uuid)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE sessions SET revoked_at = now()
+			WHERE user_uuid = $1 AND revoked_at IS NULL`, uuid)
+
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return uuid, nil
+}
+
+// PurgeDeletedUsers окончательно стирает пользователей, удаленных раньше before:
+// строки users, sessions, user_roles, identities, passwordless_challenges, passkeys,
+// webauthn_ceremonies, участие в организациях и приглашения на email пользователя удаляются,
+// а записи журнала аудита обезличиваются.
+// Возвращает число стертых пользователей.
+func (s *Storage) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.PurgeDeletedUsers"
+
+	var purged int64
+	err := s.tx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		// Строки блокируются, чтобы параллельная отмена удаления не потеряла данные.
+		rows, err := tx.QueryContext(ctx, `
+			SELECT uuid, email, tenant
+			FROM users
+			WHERE status = 'deleted' AND deleted_at < $1
+			FOR UPDATE`, before)
+		if err != nil {
+			return err
+		}
+
+		var uuids, emails, tenants []string
+		for rows.Next() {
+			var uuid, email, t string
+			if err := rows.Scan(&uuid, &email, &t); err != nil {
+				rows.Close()
+				return err
+			}
+			uuids, emails, tenants = append(uuids, uuid), append(emails, email), append(tenants, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(uuids) == 0 {
+			return nil
+		}
+
+		// actor и subject обезличиваются отдельными запросами: в одной записи
+		// они могут относиться к разным пользователям. Записи других арендаторов
+		// с тем же email не затрагиваются.
+		for _, column := range []string{"actor", "subject"} {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE audit_log a
+				SET `+column+` = 'erased:' || p.uuid, ip = '', user_agent = ''
+				FROM unnest($1::text[], $2::text[], $3::text[]) AS p (uuid, email, tenant)
+				WHERE a.`+column+` IN (p.uuid, p.email) AND a.tenant = p.tenant`,
+				pq.Array(uuids), pq.Array(emails), pq.Array(tenants))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Приглашения не ссылаются на пользователя и удаляются по email в его арендаторе.
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM organization_invitations i
+			USING organizations o, apps a, unnest($1::citext[], $2::text[]) AS p (email, tenant)
+			WHERE o.id = i.organization_id AND a.id = o.app_id
+				AND i.email = p.email AND a.tenant = p.tenant`, pq.Array(emails), pq.Array(tenants))
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE uuid = ANY ($1::uuid[])`, pq.Array(uuids))
+		if err != nil {
+			return err
+		}
+
+		purged, err = res.RowsAffected()
+
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return purged, nil
+}
+
+// UserData возвращает все данные пользователя арендатора из ctx: учетную запись, роли,
+// все сеансы (в том числе отозванные), внешние учетные записи, участие в организациях,
+// приглашения на его email и события аудита арендатора, где он инициатор или субъект.
+func (s *Storage) UserData(ctx context.Context, email string) (models.UserData, error) {
+	const op = "storage.postgres.UserData"
+
+	var data models.UserData
+	err := s.read(ctx, func(ctx context.Context) error {
+		err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1 AND tenant = $2`,
+			email, tenant.From(ctx)), &data.User)
+		if err != nil {
+			return err
+		}
+
+		data.Roles, err = queryRoles(ctx, s.db, data.User.UUID)
+		if err != nil {
+			return err
+		}
+
+		data.Sessions, err = querySessions(ctx, s.db, `
+			SELECT `+sessionColumns+`
+			FROM sessions
+			WHERE user_uuid = $1
+			ORDER BY created_at`, data.User.UUID)
+		if err != nil {
+			return err
+		}
+
+		data.Identities, err = queryIdentities(ctx, s.db, `
+			SELECT `+identityColumns+`
+			FROM identities
+			WHERE user_uuid = $1
+			ORDER BY created_at`, data.User.UUID)
+		if err != nil {
+			return err
+		}
+
+		data.Passkeys, err = queryPasskeys(ctx, s.db, `
+			SELECT `+passkeyColumns+`
+			FROM passkeys
+			WHERE user_uuid = $1
+			ORDER BY created_at`, data.User.UUID)
+		if err != nil {
+			return err
+		}
+
+		data.Consents, err = queryConsents(ctx, s.db, `
+			SELECT `+consentColumns+`
+			FROM consents
+			WHERE user_uuid = $1
+			ORDER BY app`, data.User.UUID)
+		if err != nil {
+			return err
+		}
+
+		data.Memberships, err = queryMembers(ctx, s.db, `
+			SELECT `+memberColumns+`
+			FROM organization_members m
+			JOIN users u ON u.uuid = m.user_uuid
+			WHERE m.user_uuid = $1
+			ORDER BY m.created_at`, data.User.UUID)
+		if err != nil {
+			return err
+		}
+
+		data.Invitations, err = queryInvitations(ctx, s.db, `
+			SELECT `+invitationColumns+`
+			FROM organization_invitations
+			WHERE email = $1 AND organization_id IN (
+				SELECT o.id FROM organizations o JOIN apps a ON a.id = o.app_id WHERE a.tenant = $2)
+			ORDER BY created_at`, data.User.Email, tenant.From(ctx))
+		if err != nil {
+			return err
+		}
+
+		data.AuditEvents, err = queryAuditEvents(ctx, s.db, `
+			SELECT `+auditColumns+`
+			FROM audit_log
+			WHERE (actor IN ($1, $2) OR subject IN ($1, $2)) AND tenant = $3
+			ORDER BY id`, data.User.UUID, data.User.Email, tenant.From(ctx))
+
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserData{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return data, nil
+}
@@ -5,11 +5,37 @@ import (
 )
 
 var (
-	ErrUserExists   = errors.New("user already exists")
-	ErrUserNotFound = errors.New("user not found")
-	ErrAppNotFound  = errors.New("app not found")
+	ErrUserExists           = errors.New("user already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrAppNotFound          = errors.New("app not found")
+	ErrAppExists            = errors.New("app already exists")
+	ErrRoleNotFound         = errors.New("role not found")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrIdentityNotFound     = errors.New("identity not found")
+	ErrIdentityExists       = errors.New("identity already linked")
+	ErrLastIdentity         = errors.New("last sign-in method")
+	ErrStateNotFound        = errors.New("federation state not found")
+	ErrChallengeNotFound    = errors.New("passwordless challenge not found")
+	ErrPasskeyNotFound      = errors.New("passkey not found")
+	ErrPasskeyExists        = errors.New("passkey already registered")
+	ErrCeremonyNotFound     = errors.New("webauthn ceremony not found")
+	ErrStaleSignCount       = errors.New("stale passkey sign count")
+	ErrClientNotFound       = errors.New("client not found")
+	ErrClientExists         = errors.New("client already exists")
+	ErrConsentNotFound      = errors.New("consent not found")
+	ErrTenantNotFound       = errors.New("tenant not found")
+	ErrTenantExists         = errors.New("tenant already exists")
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrOrganizationExists   = errors.New("organization already exists")
+	ErrMemberNotFound       = errors.New("organization member not found")
+	ErrLastOwner            = errors.New("last organization owner")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrUnavailable          = errors.New("storage unavailable")
 )
 
 const (
-	ErrUniqueViolation = "23505"
+	ErrUniqueViolation     = "23505"
+	ErrForeignKeyViolation = "23503"
+	// ErrInvalidTextRepresentation значение неверного формата, например UUID.
+	ErrInvalidTextRepresentation = "22P02"
 )
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS passkeys (
+	id BYTEA PRIMARY KEY,
+	user_uuid UUID NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+	name TEXT NOT NULL DEFAULT '',
+	public_key BYTEA NOT NULL,
+	attestation_type TEXT NOT NULL DEFAULT '',
+	transports TEXT[] NOT NULL DEFAULT '{}',
+	aaguid BYTEA,
+	sign_count BIGINT NOT NULL DEFAULT 0,
+	backup_eligible BOOLEAN NOT NULL DEFAULT false,
+	backup_state BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_passkeys_user_uuid ON passkeys (user_uuid);
+
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies (
+	id TEXT PRIMARY KEY,
+	kind TEXT NOT NULL CHECK (kind IN ('registration', 'login', 'mfa')),
+	user_uuid UUID REFERENCES users (uuid) ON DELETE CASCADE,
+	app TEXT NOT NULL DEFAULT '',
+	session JSONB NOT NULL,
+	options JSONB NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_ceremonies_expires_at ON webauthn_ceremonies (expires_at);
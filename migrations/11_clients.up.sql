@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS clients (
+	id TEXT PRIMARY KEY,
+	app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+	name TEXT NOT NULL DEFAULT '',
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	secret_hash BYTEA NOT NULL,
+	previous_secret_hash BYTEA,
+	previous_secret_expires_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	secret_rotated_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_clients_app_id ON clients (app_id);
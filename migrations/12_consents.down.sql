@@ -0,0 +1,2 @@
+ALTER TABLE webauthn_ceremonies DROP COLUMN IF EXISTS scopes;
+DROP TABLE IF EXISTS consents;
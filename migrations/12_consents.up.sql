@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS consents (
+	user_uuid UUID NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+	app TEXT NOT NULL,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_uuid, app)
+);
+
+ALTER TABLE webauthn_ceremonies ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
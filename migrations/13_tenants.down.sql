@@ -0,0 +1,14 @@
+-- Откат возможен, только если email и имена приложений уникальны среди всех арендаторов.
+ALTER TABLE federation_states DROP COLUMN IF EXISTS tenant;
+ALTER TABLE webauthn_ceremonies DROP COLUMN IF EXISTS tenant;
+ALTER TABLE sessions DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE apps DROP CONSTRAINT IF EXISTS apps_tenant_name_key;
+ALTER TABLE apps ADD CONSTRAINT apps_name_key UNIQUE (name);
+ALTER TABLE apps DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN IF EXISTS tenant;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS tenants (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Существующие пользователи и приложения относятся к арендатору по умолчанию.
+INSERT INTO tenants (id, name) VALUES ('default', 'Default') ON CONFLICT DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant, email);
+
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE apps DROP CONSTRAINT IF EXISTS apps_name_key;
+ALTER TABLE apps ADD CONSTRAINT apps_tenant_name_key UNIQUE (tenant, name);
+
+-- Арендатор сеанса совпадает с арендатором пользователя и нужен, чтобы найти ключ подписи его токенов.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+
+-- Вход, начатый в арендаторе, завершается в нем же, даже если завершающий запрос его не передает.
+ALTER TABLE webauthn_ceremonies ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE federation_states ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default';
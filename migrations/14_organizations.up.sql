@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS organizations (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+	app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (app_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+	organization_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+	user_uuid UUID NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+	role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (organization_id, user_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_uuid ON organization_members (user_uuid);
+
+-- Токен приглашения не хранится — только его хэш.
+CREATE TABLE IF NOT EXISTS organization_invitations (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+	organization_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+	email CITEXT NOT NULL,
+	role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+	token_hash BYTEA NOT NULL UNIQUE,
+	invited_by UUID REFERENCES users (uuid) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_organization_id ON organization_invitations (organization_id);
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_expires_at ON organization_invitations (expires_at);
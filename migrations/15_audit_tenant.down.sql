@@ -0,0 +1,23 @@
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'UPDATE'
+		AND NEW.id = OLD.id
+		AND NEW.created_at = OLD.created_at
+		AND NEW.event = OLD.event
+		AND NEW.outcome = OLD.outcome
+		AND NEW.app = OLD.app
+		AND NEW.reason = OLD.reason
+		AND NEW.details = OLD.details
+		AND (NEW.actor = OLD.actor OR NEW.actor LIKE 'erased:%')
+		AND (NEW.subject = OLD.subject OR NEW.subject LIKE 'erased:%')
+		AND (NEW.ip = OLD.ip OR NEW.ip = '')
+		AND (NEW.user_agent = OLD.user_agent OR NEW.user_agent = '')
+	THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE audit_log DROP COLUMN IF EXISTS tenant;
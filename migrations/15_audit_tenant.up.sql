@@ -0,0 +1,30 @@
+-- Арендатор записи нужен, чтобы обезличивание и выгрузка данных пользователя
+-- не задевали записи одноименных пользователей других арендаторов.
+-- Раньше арендатор, отличный от арендатора по умолчанию, хранился только в details.
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default';
+
+UPDATE audit_log SET tenant = details->>'tenant' WHERE details ? 'tenant';
+
+-- Арендатор записи, как и остальные поля, менять нельзя.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'UPDATE'
+		AND NEW.id = OLD.id
+		AND NEW.created_at = OLD.created_at
+		AND NEW.event = OLD.event
+		AND NEW.outcome = OLD.outcome
+		AND NEW.app = OLD.app
+		AND NEW.reason = OLD.reason
+		AND NEW.details = OLD.details
+		AND NEW.tenant = OLD.tenant
+		AND (NEW.actor = OLD.actor OR NEW.actor LIKE 'erased:%')
+		AND (NEW.subject = OLD.subject OR NEW.subject LIKE 'erased:%')
+		AND (NEW.ip = OLD.ip OR NEW.ip = '')
+		AND (NEW.user_agent = OLD.user_agent OR NEW.user_agent = '')
+	THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_passwordless_challenges_user_app;
+CREATE INDEX IF NOT EXISTS idx_passwordless_challenges_user_app ON passwordless_challenges (user_uuid, app);
@@ -0,0 +1,10 @@
+-- У пользователя в приложении действует не больше одной ссылки или кода: иначе каждый
+-- новый запрос добавлял бы еще MaxAttempts попыток подобрать код.
+DELETE FROM passwordless_challenges c
+USING passwordless_challenges newer
+WHERE c.user_uuid = newer.user_uuid
+	AND c.app = newer.app
+	AND (c.created_at, c.id) < (newer.created_at, newer.id);
+
+DROP INDEX IF EXISTS idx_passwordless_challenges_user_app;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_passwordless_challenges_user_app ON passwordless_challenges (user_uuid, app);
@@ -0,0 +1,15 @@
+-- Без арендатора учетная запись провайдера может быть привязана только один раз:
+-- из привязок одного subject в разных арендаторах остается самая ранняя.
+DELETE FROM identities i
+USING identities earlier
+WHERE i.provider = earlier.provider
+	AND i.subject = earlier.subject
+	AND (i.created_at, i.tenant) > (earlier.created_at, earlier.tenant);
+
+ALTER TABLE identities DROP CONSTRAINT IF EXISTS identities_tenant_user_uuid_provider_key;
+ALTER TABLE identities ADD CONSTRAINT identities_user_uuid_provider_key UNIQUE (user_uuid, provider);
+
+ALTER TABLE identities DROP CONSTRAINT IF EXISTS identities_pkey;
+ALTER TABLE identities ADD CONSTRAINT identities_pkey PRIMARY KEY (provider, subject);
+
+ALTER TABLE identities DROP COLUMN IF EXISTS tenant;
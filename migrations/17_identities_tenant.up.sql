@@ -0,0 +1,11 @@
+-- Учетная запись провайдера привязывается к пользователю в каждом арендаторе отдельно:
+-- один и тот же человек может входить через провайдера в несколько арендаторов.
+ALTER TABLE identities ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default';
+
+UPDATE identities i SET tenant = u.tenant FROM users u WHERE u.uuid = i.user_uuid;
+
+ALTER TABLE identities DROP CONSTRAINT IF EXISTS identities_pkey;
+ALTER TABLE identities ADD CONSTRAINT identities_pkey PRIMARY KEY (tenant, provider, subject);
+
+ALTER TABLE identities DROP CONSTRAINT IF EXISTS identities_user_uuid_provider_key;
+ALTER TABLE identities ADD CONSTRAINT identities_tenant_user_uuid_provider_key UNIQUE (tenant, user_uuid, provider);
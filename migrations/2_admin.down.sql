@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS user_roles;
+
+UPDATE apps SET secret = 'app-' || id WHERE secret IS NULL;
+ALTER TABLE apps ALTER COLUMN secret SET NOT NULL;
+
+ALTER TABLE users
+	DROP COLUMN IF EXISTS created_at,
+	DROP COLUMN IF EXISTS status;
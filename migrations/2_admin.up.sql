@@ -0,0 +1,15 @@
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+		CONSTRAINT users_status_check CHECK (status IN ('active', 'disabled')),
+	ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Ключи подписи приложений хранятся в Vault.
+ALTER TABLE apps ALTER COLUMN secret DROP NOT NULL;
+
+CREATE TABLE IF NOT EXISTS user_roles (
+	user_uuid UUID NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+	app_id INT NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+	role TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_uuid, app_id, role)
+);
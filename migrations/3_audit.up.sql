@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	event TEXT NOT NULL,
+	outcome TEXT NOT NULL CONSTRAINT audit_log_outcome_check CHECK (outcome IN ('success', 'failure')),
+	actor TEXT NOT NULL DEFAULT '',
+	subject TEXT NOT NULL DEFAULT '',
+	app TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	reason TEXT NOT NULL DEFAULT '',
+	details JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_event ON audit_log (event, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_subject ON audit_log (subject, id);
+
+-- Журнал только дополняется: изменение и удаление записей запрещены.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+	BEFORE UPDATE OR DELETE ON audit_log
+	FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
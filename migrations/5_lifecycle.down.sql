@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_audit_log_actor;
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+UPDATE users SET status = 'disabled' WHERE status IN ('pending', 'deleted');
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users
+	DROP COLUMN IF EXISTS deleted_at,
+	ADD CONSTRAINT users_status_check CHECK (status IN ('active', 'disabled'));
@@ -0,0 +1,33 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users
+	ADD CONSTRAINT users_status_check CHECK (status IN ('active', 'disabled', 'pending', 'deleted')),
+	ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Журнал по-прежнему только дополняется, но при окончательном удалении пользователя
+-- его персональные данные в записях обезличиваются: actor и subject заменяются
+-- на 'erased:<uuid>', ip и user_agent очищаются. Остальные поля менять нельзя.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'UPDATE'
+		AND NEW.id = OLD.id
+		AND NEW.created_at = OLD.created_at
+		AND NEW.event = OLD.event
+		AND NEW.outcome = OLD.outcome
+		AND NEW.app = OLD.app
+		AND NEW.reason = OLD.reason
+		AND NEW.details = OLD.details
+		AND (NEW.actor = OLD.actor OR NEW.actor LIKE 'erased:%')
+		AND (NEW.subject = OLD.subject OR NEW.subject LIKE 'erased:%')
+		AND (NEW.ip = OLD.ip OR NEW.ip = '')
+		AND (NEW.user_agent = OLD.user_agent OR NEW.user_agent = '')
+	THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor, id);
@@ -0,0 +1,7 @@
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}',
+	ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
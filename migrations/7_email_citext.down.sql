@@ -0,0 +1,3 @@
+ALTER TABLE users ALTER COLUMN email TYPE TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
@@ -0,0 +1,49 @@
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- Адреса должны быть заранее приведены к виду, который сервис получает из email
+-- при входе (домен в punycode, без точки в конце): это делает ssoctl user normalize-emails.
+-- Ключ совпадения lower(btrim(email)) для таких адресов тот же, что у ssoctl user collisions.
+-- Адреса, которые после перехода на citext совпадут, нужно сначала объединить
+-- или переименовать вручную: миграция перечисляет их и не применяется.
+-- Неудавшаяся миграция оставляет версию 7 помеченной как dirty, поэтому перед
+-- повторным запуском нужно выполнить migrator force 6.
+DO $$
+DECLARE
+	unnormalized TEXT;
+	collisions TEXT;
+BEGIN
+	SELECT string_agg(format('%s (%s)', email, uuid), E'\n' ORDER BY email)
+	INTO unnormalized
+	FROM (
+		SELECT uuid, email, btrim(substring(email FROM '@([^@]*)$')) AS domain
+		FROM users
+	) u
+	WHERE octet_length(domain) <> char_length(domain) OR domain LIKE '%.';
+
+	IF unnormalized IS NOT NULL THEN
+		RAISE EXCEPTION E'users with emails not in normalized form found; run ssoctl user normalize-emails, then migrator force 6 and rerun the migration:\n%', unnormalized;
+	END IF;
+
+	SELECT string_agg(format('%s: %s', key, accounts), E'\n' ORDER BY key)
+	INTO collisions
+	FROM (
+		SELECT lower(btrim(email)) AS key,
+			string_agg(format('%s (%s, %s)', email, uuid, status), ', ' ORDER BY created_at) AS accounts
+		FROM users
+		GROUP BY 1
+		HAVING count(*) > 1
+	) c;
+
+	IF collisions IS NOT NULL THEN
+		RAISE EXCEPTION E'users with emails differing only in case or surrounding spaces found; merge or rename them (ssoctl user collisions), then run migrator force 6 and rerun the migration:\n%', collisions;
+	END IF;
+END;
+$$;
+
+UPDATE users SET email = btrim(email) WHERE email <> btrim(email);
+
+-- Уникальность email становится нечувствительной к регистру.
+ALTER TABLE users ALTER COLUMN email TYPE CITEXT;
+
+-- Дублирует индекс ограничения UNIQUE.
+DROP INDEX IF EXISTS idx_email;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS identities (
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	user_uuid UUID NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+	email TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_login_at TIMESTAMPTZ,
+	PRIMARY KEY (provider, subject),
+	-- У пользователя не больше одной учетной записи каждого провайдера.
+	UNIQUE (user_uuid, provider)
+);
+
+CREATE TABLE IF NOT EXISTS federation_states (
+	state TEXT PRIMARY KEY,
+	provider TEXT NOT NULL,
+	app TEXT NOT NULL DEFAULT '',
+	nonce TEXT NOT NULL,
+	verifier TEXT NOT NULL,
+	link_user_uuid UUID REFERENCES users (uuid) ON DELETE CASCADE,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_federation_states_expires_at ON federation_states (expires_at);
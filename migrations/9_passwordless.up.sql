@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS passwordless_challenges (
+	id TEXT PRIMARY KEY,
+	user_uuid UUID NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+	app TEXT NOT NULL,
+	method TEXT NOT NULL CHECK (method IN ('link', 'code')),
+	secret_hash BYTEA NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_passwordless_challenges_user_app ON passwordless_challenges (user_uuid, app);
+CREATE INDEX IF NOT EXISTS idx_passwordless_challenges_expires_at ON passwordless_challenges (expires_at);
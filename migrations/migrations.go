@@ -0,0 +1,9 @@
+// Package migrations содержит SQL-миграции схемы, встроенные в бинарники сервиса и мигратора.
+package migrations
+
+import "embed"
+
+// FS файлы миграций в формате golang-migrate (<version>_<name>.{up,down}.sql).
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,61 @@
+package ssoclient
+
+import (
+	"encoding/json"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// registered claims, которые выставляет go-sso; остальные попадают в Claims.Custom.
+var registered = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+	"uuid": true, "email": true, "sid": true, "app": true, "client_id": true, "scope": true,
+	"tid": true,
+}
+
+// Claims содержимое токена go-sso.
+type Claims struct {
+	jwt.RegisteredClaims
+
+	// UUID совпадает с Subject и оставлен для совместимости.
+	UUID      string `json:"uuid"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+	App       string `json:"app"`
+	// Tenant арендатор пользователя или сервиса.
+	Tenant string `json:"tid"`
+
+	// ClientID сервис, получивший токен по client credentials; у токенов пользователей пуст.
+	ClientID string `json:"client_id"`
+	// Scope области доступа клиента через пробел.
+	Scope string `json:"scope"`
+
+	// Custom дополнительные claims, настроенные для приложения в go-sso.
+	Custom map[string]any `json:"-"`
+}
+
+type claimsJSON Claims
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*claimsJSON)(c)); err != nil {
+		return err
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	c.Custom = nil
+	for k, v := range m {
+		if registered[k] {
+			continue
+		}
+		if c.Custom == nil {
+			c.Custom = make(map[string]any)
+		}
+		c.Custom[k] = v
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package ssoclient
+
+import "context"
+
+type claimsKey struct{}
+
+// WithClaims возвращает контекст с claims проверенного токена.
+func WithClaims(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// FromContext возвращает claims, сохраненные интерсептором или middleware.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(*Claims)
+	return c, ok
+}
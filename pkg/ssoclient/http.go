@@ -0,0 +1,33 @@
+package ssoclient
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Middleware проверяет токен из заголовка "Authorization: Bearer <token>"
+// и кладет его claims в контекст запроса.
+func Middleware(v *Validator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "token is required", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := v.Validate(r.Context(), token)
+		if errors.Is(err, ErrUnavailable) {
+			http.Error(w, "token validation is temporarily unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
@@ -0,0 +1,70 @@
+package ssoclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Introspection проверяет отзыв токена через эндпоинт интроспекции go-sso
+// (POST /v1/introspect). Приложение App аутентифицируется своим ключом подписи из Keys.
+// Каждая проверка — это запрос к go-sso, поэтому она добавляет задержку к каждому запросу.
+type Introspection struct {
+	// Endpoint полный адрес эндпоинта, например "http://sso:8080/v1/introspect".
+	Endpoint string
+	App      string
+	// Keys источник ключа подписи App. Если не задан, Validator, которому передан
+	// Introspection, подставляет свой кэш ключей. Некэширующий источник (например,
+	// GRPCKeySource) запрашивал бы SigningKey на каждую проверку — оберните его в CachedKeySource.
+	Keys KeySource
+	// Client HTTP-клиент; по умолчанию http.DefaultClient.
+	Client *http.Client
+}
+
+// Revoked сообщает, что go-sso больше не считает токен активным.
+func (i *Introspection) Revoked(ctx context.Context, token string, _ *Claims) (bool, error) {
+	if i.Keys == nil {
+		return false, errors.New("ssoclient: introspection key source is not set")
+	}
+
+	secret, err := i.Keys.Key(ctx, i.App)
+	if err != nil {
+		return false, fmt.Errorf("signing key of %s: %w", i.App, err)
+	}
+
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(i.App, secret)
+
+	client := i.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("introspection returned %s", resp.Status)
+	}
+
+	var body struct {
+		Active bool `json:"active"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, fmt.Errorf("decode introspection response: %w", err)
+	}
+
+	return !body.Active, nil
+}
@@ -0,0 +1,146 @@
+package ssoclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gossov1 "github.com/passwordhash/protos/gen/go/go-sso"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// GRPCKeySource получает ключи подписи через gRPC-метод SigningKey go-sso.
+func GRPCKeySource(conn grpc.ClientConnInterface) KeySource {
+	client := gossov1.NewAuthClient(conn)
+
+	return KeySourceFunc(func(ctx context.Context, app string) (string, error) {
+		resp, err := client.SigningKey(ctx, &gossov1.SigningKeyRequest{AppName: app})
+		if err != nil {
+			return "", err
+		}
+
+		return resp.GetSigningKey(), nil
+	})
+}
+
+// GRPCTenantKeySource получает ключи подписи приложений арендатора tenant: одноименные
+// приложения разных арендаторов подписывают токены разными ключами.
+func GRPCTenantKeySource(conn grpc.ClientConnInterface, tenant string) KeySource {
+	src := GRPCKeySource(conn)
+
+	return KeySourceFunc(func(ctx context.Context, app string) (string, error) {
+		return src.Key(metadata.AppendToOutgoingContext(ctx, "x-tenant", tenant), app)
+	})
+}
+
+// fetchTimeout ограничивает общий запрос ключа: он не зависит от контекста
+// вызывающего, поэтому без ограничения мог бы зависнуть навсегда.
+const fetchTimeout = 30 * time.Second
+
+type cachedKey struct {
+	key       string
+	fetchedAt time.Time
+}
+
+// keyCache кэширует ключи приложений и схлопывает одновременные запросы.
+type keyCache struct {
+	src          KeySource
+	ttl          time.Duration
+	minRefresh   time.Duration
+	fetchTimeout time.Duration
+	now          func() time.Time
+
+	mu    sync.RWMutex
+	keys  map[string]cachedKey
+	group singleflight.Group
+}
+
+func newKeyCache(src KeySource, ttl, minRefresh time.Duration) *keyCache {
+	return &keyCache{
+		src:          src,
+		ttl:          ttl,
+		minRefresh:   minRefresh,
+		fetchTimeout: fetchTimeout,
+		now:          time.Now,
+		keys:         make(map[string]cachedKey),
+	}
+}
+
+// CachedKeySource возвращает источник, кэширующий ключи из src на ttl
+// и схлопывающий одновременные запросы одного приложения.
+func CachedKeySource(src KeySource, ttl time.Duration) KeySource {
+	if ttl <= 0 {
+		ttl = defaultKeyTTL
+	}
+
+	return newKeyCache(src, ttl, defaultMinRefreshInterval)
+}
+
+// Key реализует KeySource.
+func (c *keyCache) Key(ctx context.Context, app string) (string, error) {
+	return c.get(ctx, app)
+}
+
+// get возвращает ключ из кэша или запрашивает его, если он отсутствует или устарел.
+func (c *keyCache) get(ctx context.Context, app string) (string, error) {
+	c.mu.RLock()
+	k, ok := c.keys[app]
+	c.mu.RUnlock()
+
+	if ok && c.now().Sub(k.fetchedAt) < c.ttl {
+		return k.key, nil
+	}
+
+	return c.fetch(ctx, app)
+}
+
+// refresh запрашивает ключ заново, если с прошлого запроса прошло не меньше minRefresh.
+// Иначе возвращает ключ из кэша и refreshed = false.
+func (c *keyCache) refresh(ctx context.Context, app string) (key string, refreshed bool, err error) {
+	c.mu.RLock()
+	k, ok := c.keys[app]
+	c.mu.RUnlock()
+
+	if ok && c.now().Sub(k.fetchedAt) < c.minRefresh {
+		return k.key, false, nil
+	}
+
+	key, err = c.fetch(ctx, app)
+
+	return key, err == nil, err
+}
+
+// fetch запрашивает ключ; одновременные запросы одного приложения схлопываются.
+// Общий запрос не отменяется вместе с ctx первого вызывающего: каждый вызывающий
+// перестает ждать только по своему ctx.
+func (c *keyCache) fetch(ctx context.Context, app string) (string, error) {
+	ch := c.group.DoChan(app, func() (any, error) {
+		// WithoutCancel сохраняет значения ctx, например метаданные запроса.
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.fetchTimeout)
+		defer cancel()
+
+		key, err := c.src.Key(fetchCtx, app)
+		if err != nil {
+			return "", err
+		}
+
+		c.mu.Lock()
+		c.keys[app] = cachedKey{key: key, fetchedAt: c.now()}
+		c.mu.Unlock()
+
+		return key, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+
+		return res.Val.(string), nil
+	}
+}
@@ -0,0 +1,176 @@
+// Package ssoclient проверяет токены go-sso в сервисах на Go.
+//
+// Validator получает ключи подписи приложений у go-sso (gRPC SigningKey), кэширует их
+// и проверяет подпись, exp/nbf, iss и aud токена, а при наличии RevocationChecker —
+// еще и отзыв сеанса. UnaryServerInterceptor, StreamServerInterceptor и Middleware
+// кладут claims проверенного токена в контекст; достать их можно через FromContext.
+//
+//	conn, _ := grpc.NewClient("sso:50055", ...)
+//	v, err := ssoclient.New(ssoclient.GRPCKeySource(conn), ssoclient.Options{
+//		Issuer:    "sso",
+//		Audiences: []string{"billing"},
+//	})
+//	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(ssoclient.UnaryServerInterceptor(v)))
+package ssoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultKeyTTL             = 5 * time.Minute
+	defaultMinRefreshInterval = 30 * time.Second
+)
+
+var (
+	// ErrInvalidToken токен поврежден, подписан не тем ключом, истек, выпущен
+	// не для этого сервиса или отозван.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrUnavailable не удалось получить ключ подписи или проверить отзыв.
+	ErrUnavailable = errors.New("sso unavailable")
+)
+
+// KeySource выдает ключ подписи приложения.
+type KeySource interface {
+	Key(ctx context.Context, app string) (string, error)
+}
+
+// KeySourceFunc позволяет использовать функцию как KeySource.
+type KeySourceFunc func(ctx context.Context, app string) (string, error)
+
+func (f KeySourceFunc) Key(ctx context.Context, app string) (string, error) {
+	return f(ctx, app)
+}
+
+// RevocationChecker проверяет, не отозван ли токен с корректной подписью.
+type RevocationChecker interface {
+	Revoked(ctx context.Context, token string, claims *Claims) (bool, error)
+}
+
+// Options настройки Validator.
+type Options struct {
+	// Issuer ожидаемый claim iss. Если пуст, издатель не проверяется.
+	Issuer string
+	// Audiences приложения, токены которых принимает сервис. Обязательно.
+	Audiences []string
+	// KeyTTL сколько использовать полученный ключ до повторного запроса (по умолчанию 5m).
+	KeyTTL time.Duration
+	// MinRefreshInterval минимальный интервал между внеочередными запросами ключа
+	// после неверной подписи, например после ротации (по умолчанию 30s).
+	MinRefreshInterval time.Duration
+	// Leeway допустимое расхождение часов при проверке exp и nbf.
+	Leeway time.Duration
+	// Revocation, если задан, проверяет отзыв сеанса токена.
+	Revocation RevocationChecker
+}
+
+// Validator проверяет токены go-sso.
+type Validator struct {
+	opts Options
+	keys *keyCache
+}
+
+// New возвращает Validator, получающий ключи из keys. Если opts.Revocation — Introspection
+// без Keys, New передает ему кэш ключей Validator.
+func New(keys KeySource, opts Options) (*Validator, error) {
+	if keys == nil {
+		return nil, errors.New("ssoclient: key source is required")
+	}
+	if len(opts.Audiences) == 0 {
+		return nil, errors.New("ssoclient: at least one audience is required")
+	}
+	if opts.KeyTTL <= 0 {
+		opts.KeyTTL = defaultKeyTTL
+	}
+	if opts.MinRefreshInterval <= 0 {
+		opts.MinRefreshInterval = defaultMinRefreshInterval
+	}
+
+	cache := newKeyCache(keys, opts.KeyTTL, opts.MinRefreshInterval)
+
+	// Интроспекция без своего источника использует ключи из кэша Validator,
+	// а не запрашивает SigningKey на каждый токен.
+	if i, ok := opts.Revocation.(*Introspection); ok && i.Keys == nil {
+		i.Keys = cache
+	}
+
+	return &Validator{
+		opts: opts,
+		keys: cache,
+	}, nil
+}
+
+// Validate проверяет токен и возвращает его claims.
+// Возвращает ошибку, оборачивающую ErrInvalidToken или ErrUnavailable.
+func (v *Validator) Validate(ctx context.Context, token string) (*Claims, error) {
+	var unverified Claims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &unverified); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	app := unverified.App
+	if !slices.Contains(v.opts.Audiences, app) {
+		return nil, fmt.Errorf("%w: token is issued for %q", ErrInvalidToken, app)
+	}
+
+	key, err := v.keys.get(ctx, app)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signing key of %s: %v", ErrUnavailable, app, err)
+	}
+
+	claims, err := v.verify(token, key, app)
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		// Ключ мог быть заменен: запрашиваем его заново, но не чаще MinRefreshInterval.
+		fresh, refreshed, ferr := v.keys.refresh(ctx, app)
+		if ferr != nil {
+			return nil, fmt.Errorf("%w: signing key of %s: %v", ErrUnavailable, app, ferr)
+		}
+		if refreshed && fresh != key {
+			claims, err = v.verify(token, fresh, app)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if v.opts.Revocation != nil {
+		revoked, err := v.opts.Revocation.Revoked(ctx, token, claims)
+		if err != nil {
+			return nil, fmt.Errorf("%w: revocation check: %v", ErrUnavailable, err)
+		}
+		if revoked {
+			return nil, fmt.Errorf("%w: token is revoked", ErrInvalidToken)
+		}
+	}
+
+	return claims, nil
+}
+
+func (v *Validator) verify(token, key, app string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(app),
+		jwt.WithLeeway(v.opts.Leeway),
+	}
+	if v.opts.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.opts.Issuer))
+	}
+
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return []byte(key), nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,268 @@
+package ssoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/jwt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKeys struct {
+	mu    sync.Mutex
+	keys  map[string]string
+	calls int
+	err   error
+}
+
+func (f *fakeKeys) Key(_ context.Context, app string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+
+	return f.keys[app], nil
+}
+
+func (f *fakeKeys) set(app, key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.keys[app] = key
+}
+
+type revokedSessions map[string]bool
+
+func (r revokedSessions) Revoked(_ context.Context, _ string, c *Claims) (bool, error) {
+	return r[c.SessionID], nil
+}
+
+func issue(t *testing.T, app, key string, ttl time.Duration) string {
+	t.Helper()
+
+	token, err := jwt.NewToken(models.User{UUID: "u1", Email: "user@example.com"}, jwt.TokenOptions{
+		Issuer:    "sso",
+		App:       app,
+		SessionID: "s1",
+		TTL:       ttl,
+		Claims:    map[string]string{"tenant": "acme"},
+	}, key)
+	require.NoError(t, err)
+
+	return token
+}
+
+func newValidator(t *testing.T, keys KeySource, opts Options) *Validator {
+	t.Helper()
+
+	if opts.Issuer == "" {
+		opts.Issuer = "sso"
+	}
+	if opts.Audiences == nil {
+		opts.Audiences = []string{"billing"}
+	}
+
+	v, err := New(keys, opts)
+	require.NoError(t, err)
+
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]string{"billing": "k1", "crm": "k2"}}
+	v := newValidator(t, keys, Options{})
+
+	claims, err := v.Validate(context.Background(), issue(t, "billing", "k1", time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "u1", claims.Subject)
+	assert.Equal(t, "user@example.com", claims.Email)
+	assert.Equal(t, "s1", claims.SessionID)
+	assert.Equal(t, map[string]any{"tenant": "acme"}, claims.Custom)
+
+	// Ключ закэширован.
+	_, err = v.Validate(context.Background(), issue(t, "billing", "k1", time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, keys.calls)
+
+	for name, token := range map[string]string{
+		"other audience": issue(t, "crm", "k2", time.Minute),
+		"expired":        issue(t, "billing", "k1", -time.Minute),
+		"wrong key":      issue(t, "billing", "forged", time.Minute),
+		"garbage":        "garbage",
+	} {
+		_, err := v.Validate(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+}
+
+func TestValidateWrongIssuer(t *testing.T) {
+	v := newValidator(t, &fakeKeys{keys: map[string]string{"billing": "k1"}}, Options{Issuer: "other"})
+
+	_, err := v.Validate(context.Background(), issue(t, "billing", "k1", time.Minute))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestValidateRefetchesRotatedKey(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]string{"billing": "old"}}
+	v := newValidator(t, keys, Options{MinRefreshInterval: time.Minute})
+
+	now := time.Now()
+	v.keys.now = func() time.Time { return now }
+
+	_, err := v.Validate(context.Background(), issue(t, "billing", "old", time.Minute))
+	require.NoError(t, err)
+
+	keys.set("billing", "new")
+	token := issue(t, "billing", "new", time.Minute)
+
+	// Сразу после запроса ключ не перезапрашивается.
+	_, err = v.Validate(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, 1, keys.calls)
+
+	now = now.Add(2 * time.Minute)
+
+	_, err = v.Validate(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, 2, keys.calls)
+}
+
+func TestKeyCacheCancelledCallerDoesNotFailOthers(t *testing.T) {
+	block := make(chan struct{})
+	var reads atomic.Int32
+
+	c := newKeyCache(KeySourceFunc(func(ctx context.Context, app string) (string, error) {
+		reads.Add(1)
+
+		select {
+		case <-block:
+			return "secret", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}), time.Minute, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.get(ctx, "billing")
+		first <- err
+	}()
+
+	// Даем первому вызову начать запрос ключа.
+	time.Sleep(50 * time.Millisecond)
+
+	second := make(chan string, 1)
+	go func() {
+		key, err := c.get(context.Background(), "billing")
+		assert.NoError(t, err)
+		second <- key
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-first, context.Canceled)
+
+	close(block)
+	assert.Equal(t, "secret", <-second)
+	assert.EqualValues(t, 1, reads.Load())
+}
+
+func TestValidateRevocationAndUnavailable(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]string{"billing": "k1"}}
+	v := newValidator(t, keys, Options{Revocation: revokedSessions{"s1": true}})
+
+	_, err := v.Validate(context.Background(), issue(t, "billing", "k1", time.Minute))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	keys.err = errors.New("connection refused")
+	v = newValidator(t, keys, Options{})
+
+	_, err = v.Validate(context.Background(), issue(t, "billing", "k1", time.Minute))
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestMiddleware(t *testing.T) {
+	v := newValidator(t, &fakeKeys{keys: map[string]string{"billing": "k1"}}, Options{})
+
+	h := Middleware(v, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := FromContext(r.Context())
+		require.True(t, ok)
+		_, _ = w.Write([]byte(claims.Subject))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req.Header.Set("Authorization", "Bearer "+issue(t, "billing", "k1", time.Minute))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "u1", rec.Body.String())
+}
+
+func TestIntrospection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app, secret, _ := r.BasicAuth()
+		if app != "billing" || secret != "k1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = fmt.Fprintf(w, `{"active": %t}`, r.PostFormValue("token") == "live")
+	}))
+	defer srv.Close()
+
+	i := &Introspection{
+		Endpoint: srv.URL,
+		App:      "billing",
+		Keys:     &fakeKeys{keys: map[string]string{"billing": "k1"}},
+	}
+
+	revoked, err := i.Revoked(context.Background(), "live", nil)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = i.Revoked(context.Background(), "revoked", nil)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	i.App = "crm"
+	_, err = i.Revoked(context.Background(), "live", nil)
+	assert.Error(t, err)
+}
+
+func TestIntrospectionUsesValidatorKeyCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, secret, _ := r.BasicAuth(); secret != "k1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"active": true}`))
+	}))
+	defer srv.Close()
+
+	keys := &fakeKeys{keys: map[string]string{"billing": "k1"}}
+	v := newValidator(t, keys, Options{Revocation: &Introspection{Endpoint: srv.URL, App: "billing"}})
+
+	for range 3 {
+		_, err := v.Validate(context.Background(), issue(t, "billing", "k1", time.Minute))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, keys.calls)
+}
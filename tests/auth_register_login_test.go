@@ -62,7 +62,13 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	fmt.Println(claims)
 	assert.Equal(t, respReg.GetUserUuid(), claims["uuid"].(string))
 	assert.Equal(t, email, claims["email"].(string))
-	assert.Equal(t, appName, int(claims["app_id"].(float64)))
+	assert.Equal(t, appName, claims["app"].(string))
+	assert.Equal(t, respReg.GetUserUuid(), claims["sub"].(string))
+	assert.Equal(t, []any{appName}, claims["aud"])
+	assert.Equal(t, st.Cfg.Issuer(), claims["iss"].(string))
+	assert.NotEmpty(t, claims["jti"])
+	assert.NotEmpty(t, claims["sid"])
+	assert.InDelta(t, loginTime.Unix(), claims["iat"].(float64), 1)
 
 	const deltaSeconds = 1
 
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"go-sso/internal/app/bootstrap"
+	"go-sso/internal/domain/models"
+	"go-sso/internal/lib/tenant"
+	"go-sso/tests/suite"
+
+	"github.com/brianvoe/gofakeit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentities_SameSubjectInTwoTenants(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	storage, err := bootstrap.Storage(ctx, st.Cfg)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	email := gofakeit.Email()
+	subject := gofakeit.UUID()
+
+	users := make(map[string]string)
+	for range 2 {
+		id := "t-" + strings.ToLower(gofakeit.Password(true, false, true, false, false, 12))
+		_, err := storage.SaveTenant(ctx, models.Tenant{ID: id})
+		require.NoError(t, err)
+
+		// Первый вход через провайдера в каждом арендаторе создает его пользователя.
+		tctx := tenant.With(ctx, id)
+		uuid, err := storage.SaveFederatedUser(tctx, email, "", models.Identity{
+			Provider: "github",
+			Subject:  subject,
+			Email:    email,
+		})
+		require.NoError(t, err, id)
+
+		users[id] = uuid
+	}
+
+	for id, uuid := range users {
+		identity, err := storage.Identity(tenant.With(ctx, id), "github", subject)
+		require.NoError(t, err, id)
+		assert.Equal(t, uuid, identity.UserUUID, "identity resolves to the user of its tenant")
+
+		// Вход в одном арендаторе не меняет учетную запись в другом.
+		require.NoError(t, storage.TouchIdentity(tenant.With(ctx, id), "github", subject, id+"@example.com"))
+	}
+
+	for id := range users {
+		identity, err := storage.Identity(tenant.With(ctx, id), "github", subject)
+		require.NoError(t, err, id)
+		assert.Equal(t, id+"@example.com", identity.Email)
+	}
+}
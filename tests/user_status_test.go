@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-sso/internal/app/bootstrap"
+	"go-sso/internal/domain/models"
+	"go-sso/tests/suite"
+
+	"github.com/brianvoe/gofakeit"
+	gossov1 "github.com/passwordhash/protos/gen/go/go-sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserStatus_DisabledTokenIsInactive(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &gossov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &gossov1.LoginRequest{Email: email, Password: pass, AppName: appName})
+	require.NoError(t, err)
+
+	token := respLogin.GetToken()
+	require.True(t, introspect(t, st, token))
+
+	storage, err := bootstrap.Storage(ctx, st.Cfg)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	revoked, err := storage.SetUserStatus(ctx, email, models.UserStatusDisabled)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, revoked)
+
+	assert.False(t, introspect(t, st, token))
+}
+
+// introspect возвращает active из ответа /v1/introspect для токена приложения appName.
+func introspect(t *testing.T, st *suite.Suite, token string) bool {
+	t.Helper()
+
+	endpoint := "http://localhost:" + strconv.Itoa(st.Cfg.HTTP.Port) + "/v1/introspect"
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(url.Values{"token": {token}}.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(appName, appSecret)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Active bool `json:"active"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	return body.Active
+}